@@ -31,6 +31,8 @@ type ClusterConfigSpec struct {
 	DiscoveryConfig     DiscoveryConfig     `json:"discoveryConfig"`
 	LiqonetConfig       LiqonetConfig       `json:"liqonetConfig"`
 	DispatcherConfig    DispatcherConfig    `json:"dispatcherConfig,omitempty"`
+	//VirtualKubeletConfig defines the configuration of the virtual kubelets
+	VirtualKubeletConfig VirtualKubeletConfig `json:"virtualKubeletConfig,omitempty"`
 	//AgentConfig defines the configuration for Liqo Agent.
 	AgentConfig AgentConfig `json:"agentConfig"`
 }
@@ -132,6 +134,31 @@ type LiqonetConfig struct {
 	PodCIDR string `json:"podCIDR"`
 	//the subnet used by the cluster for the services, in CIDR notation
 	ServiceCIDR string `json:"serviceCIDR"`
+	//the IPv6 subnet used by the cluster for the pods in dual-stack clusters, in CIDR notation
+	IPv6PodCIDR string `json:"ipv6PodCIDR,omitempty"`
+	//the IPv4 pool from which the subnets used to remap the POD CIDRs of the peering clusters are allocated (default 10.0.0.0/8)
+	IPv4Pool string `json:"ipv4Pool,omitempty"`
+	//the prefix length of the IPv4 subnets allocated from the pool (default 16)
+	IPv4PrefixLength int `json:"ipv4PrefixLength,omitempty"`
+	//the IPv6 pool from which the subnets used to remap the IPv6 POD CIDRs of the peering clusters are allocated.
+	//If not set, the IPv6 POD CIDRs are used only when they do not conflict with the local ones.
+	IPv6Pool string `json:"ipv6Pool,omitempty"`
+	//the prefix length of the IPv6 subnets allocated from the pool (default 64)
+	IPv6PrefixLength int `json:"ipv6PrefixLength,omitempty"`
+	//the driver used to create the tunnels towards the peering clusters: gre (default) or wireguard.
+	//Changes are applied only after a restart of the network operators.
+	TunnelDriver string `json:"tunnelDriver,omitempty"`
+	//the UDP port where the gateway listens for the WireGuard tunnels (default 51820)
+	WireGuardPort int `json:"wireGuardPort,omitempty"`
+	//the MTU of the path between the gateways of the peering clusters. If not set, it is probed when the path MTU
+	//discovery is enabled, otherwise the MTU of the default interface of the gateway is used
+	PathMTU int `json:"pathMTU,omitempty"`
+	//enables the discovery of the MTU of the path towards each remote gateway
+	PathMTUDiscovery bool `json:"pathMTUDiscovery,omitempty"`
+	//the MSS the TCP connections towards the peering clusters are clamped to. If not set, it is derived from the MTU of the tunnels
+	TCPMSS int `json:"tcpMSS,omitempty"`
+	//disables the clamping of the MSS of the TCP connections towards the peering clusters
+	DisableMSSClamping bool `json:"disableMSSClamping,omitempty"`
 	//the configuration for the VXLAN overlay network which handles the traffic in the local cluster destined to remote peering clusters
 	VxlanNetConfig liqonet.VxlanNetConfig `json:"vxlanNetConfig,omitempty"`
 }
@@ -146,6 +173,37 @@ type DispatcherConfig struct {
 	ResourcesToReplicate []Resource `json:"resourcesToReplicate,omitempty"`
 }
 
+// VirtualKubeletConfig defines the configuration of the virtual kubelets
+type VirtualKubeletConfig struct {
+	// ResourcesToReflect is the list of namespaced resources reflected by the generic reflector
+	// from the home cluster to the foreign ones, in addition to the built-in ones.
+	ResourcesToReflect []ReflectedResource `json:"resourcesToReflect,omitempty"`
+	// ReflectionBlacklist contains the objects not to be reflected by the built-in reflectors, in addition to
+	// the kubernetes service of the default namespace, which is never reflected.
+	ReflectionBlacklist []BlacklistedResource `json:"reflectionBlacklist,omitempty"`
+	// StorageClassMapping maps the storage classes of the home cluster to the ones of the foreign clusters,
+	// for the PersistentVolumeClaims reflected by the virtual kubelets (e.g. standard: gp2).
+	StorageClassMapping map[string]string `json:"storageClassMapping,omitempty"`
+}
+
+// BlacklistedResource contains the objects of a built-in reflected resource which are not reflected
+type BlacklistedResource struct {
+	// Resource is the name of the built-in reflected resource (e.g. services).
+	Resource string `json:"resource"`
+	// Objects are the objects not to be reflected, in the namespace/name format.
+	Objects []string `json:"objects"`
+}
+
+// ReflectedResource contains a resource to be reflected, along with the rules applied when reflecting it
+type ReflectedResource struct {
+	Resource `json:",inline"`
+	// StrippedFields are the fields removed from the reflected objects, as dot-separated paths
+	// (e.g. spec.tls), in addition to the server-populated metadata and the status.
+	StrippedFields []string `json:"strippedFields,omitempty"`
+	// Blacklist contains the objects not to be reflected, in the namespace/name format.
+	Blacklist []string `json:"blacklist,omitempty"`
+}
+
 type DashboardConfig struct {
 	// Namespace defines the namespace LiqoDash resources belongs to.
 	Namespace string `json:"namespace"`
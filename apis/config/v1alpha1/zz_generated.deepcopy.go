@@ -76,6 +76,26 @@ func (in *AgentConfig) DeepCopy() *AgentConfig {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BlacklistedResource) DeepCopyInto(out *BlacklistedResource) {
+	*out = *in
+	if in.Objects != nil {
+		in, out := &in.Objects, &out.Objects
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BlacklistedResource.
+func (in *BlacklistedResource) DeepCopy() *BlacklistedResource {
+	if in == nil {
+		return nil
+	}
+	out := new(BlacklistedResource)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *BroadcasterConfig) DeepCopyInto(out *BroadcasterConfig) {
 	*out = *in
@@ -157,6 +177,7 @@ func (in *ClusterConfigSpec) DeepCopyInto(out *ClusterConfigSpec) {
 	out.DiscoveryConfig = in.DiscoveryConfig
 	in.LiqonetConfig.DeepCopyInto(&out.LiqonetConfig)
 	in.DispatcherConfig.DeepCopyInto(&out.DispatcherConfig)
+	in.VirtualKubeletConfig.DeepCopyInto(&out.VirtualKubeletConfig)
 	out.AgentConfig = in.AgentConfig
 }
 
@@ -271,6 +292,32 @@ func (in *LiqonetConfig) DeepCopy() *LiqonetConfig {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReflectedResource) DeepCopyInto(out *ReflectedResource) {
+	*out = *in
+	out.Resource = in.Resource
+	if in.StrippedFields != nil {
+		in, out := &in.StrippedFields, &out.StrippedFields
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Blacklist != nil {
+		in, out := &in.Blacklist, &out.Blacklist
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReflectedResource.
+func (in *ReflectedResource) DeepCopy() *ReflectedResource {
+	if in == nil {
+		return nil
+	}
+	out := new(ReflectedResource)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Resource) DeepCopyInto(out *Resource) {
 	*out = *in
@@ -285,3 +332,39 @@ func (in *Resource) DeepCopy() *Resource {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VirtualKubeletConfig) DeepCopyInto(out *VirtualKubeletConfig) {
+	*out = *in
+	if in.ResourcesToReflect != nil {
+		in, out := &in.ResourcesToReflect, &out.ResourcesToReflect
+		*out = make([]ReflectedResource, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ReflectionBlacklist != nil {
+		in, out := &in.ReflectionBlacklist, &out.ReflectionBlacklist
+		*out = make([]BlacklistedResource, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.StorageClassMapping != nil {
+		in, out := &in.StorageClassMapping, &out.StorageClassMapping
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VirtualKubeletConfig.
+func (in *VirtualKubeletConfig) DeepCopy() *VirtualKubeletConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(VirtualKubeletConfig)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -64,6 +64,29 @@ type ForeignClusterSpec struct {
 	ApiUrl string `json:"apiUrl"`
 	// How this ForeignCluster has been discovered
 	DiscoveryType DiscoveryType `json:"discoveryType"`
+	// Restricts the local pods the foreign cluster can reach through the tunnel. If not set, the foreign cluster
+	// can reach all the local pods
+	NetworkPolicy *PeeringNetworkPolicy `json:"networkPolicy,omitempty"`
+}
+
+// PeeringNetworkPolicy lists the destinations the traffic coming from a foreign cluster is allowed to reach.
+// The answers to the connections opened by the local pods are always allowed
+type PeeringNetworkPolicy struct {
+	// Local namespaces whose pods can be reached
+	AllowedNamespaces []string `json:"allowedNamespaces,omitempty"`
+	// Local IPv4 CIDRs which can be reached. If neither namespaces nor CIDRs are set, any destination can be reached
+	AllowedCIDRs []string `json:"allowedCIDRs,omitempty"`
+	// Ports which can be reached on the allowed destinations. If not set, all the ports can be reached
+	AllowedPorts []PeeringNetworkPolicyPort `json:"allowedPorts,omitempty"`
+}
+
+type PeeringNetworkPolicyPort struct {
+	// +kubebuilder:validation:Enum="TCP";"UDP";"SCTP"
+	// +kubebuilder:default="TCP"
+	// Protocol of the port, TCP if not set
+	Protocol v1.Protocol `json:"protocol,omitempty"`
+	// Number of the port
+	Port int32 `json:"port"`
 }
 
 type ClusterIdentity struct {
@@ -42,10 +42,31 @@ type PeeringRequestSpec struct {
 	KubeConfigRef *v1.ObjectReference `json:"kubeConfigRef,omitempty"`
 }
 
+// PeeringApproval describes whether an incoming peering has been approved
+type PeeringApproval string
+
+const (
+	// PeeringApprovalPending means that the peering is waiting for an administrator to approve or reject it
+	PeeringApprovalPending PeeringApproval = "Pending"
+	// PeeringApprovalApproved means that the peering has been approved, either automatically or by an administrator
+	PeeringApprovalApproved PeeringApproval = "Approved"
+	// PeeringApprovalRejected means that the peering has been rejected by an administrator
+	PeeringApprovalRejected PeeringApproval = "Rejected"
+)
+
+// PeeringApprovalAnnotation is set by an administrator on a PeeringRequest to approve or reject it, when the
+// manual approval of the incoming peerings is enabled. The accepted values are Approved and Rejected.
+// The rejection is recorded on the ForeignCluster of the remote cluster with the same annotation: its following
+// PeeringRequests are rejected as well, until the annotation is removed from the ForeignCluster
+const PeeringApprovalAnnotation = "discovery.liqo.io/peering-approval"
+
 // PeeringRequestStatus defines the observed state of PeeringRequest
 type PeeringRequestStatus struct {
 	BroadcasterRef      *object_references.DeploymentReference `json:"broadcasterRef,omitempty"`
 	AdvertisementStatus advtypes.AdvPhase                      `json:"advertisementStatus,omitempty"`
+	// +kubebuilder:validation:Enum="Pending";"Approved";"Rejected"
+	// Indicates whether the peering has been approved
+	ApprovalStatus PeeringApproval `json:"approvalStatus,omitempty"`
 }
 
 // +kubebuilder:object:root=true
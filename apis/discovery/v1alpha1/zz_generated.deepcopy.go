@@ -45,7 +45,7 @@ func (in *ForeignCluster) DeepCopyInto(out *ForeignCluster) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	out.Spec = in.Spec
+	in.Spec.DeepCopyInto(&out.Spec)
 	in.Status.DeepCopyInto(&out.Status)
 }
 
@@ -103,6 +103,11 @@ func (in *ForeignClusterList) DeepCopyObject() runtime.Object {
 func (in *ForeignClusterSpec) DeepCopyInto(out *ForeignClusterSpec) {
 	*out = *in
 	out.ClusterIdentity = in.ClusterIdentity
+	if in.NetworkPolicy != nil {
+		in, out := &in.NetworkPolicy, &out.NetworkPolicy
+		*out = new(PeeringNetworkPolicy)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ForeignClusterSpec.
@@ -221,6 +226,51 @@ func (in *Outgoing) DeepCopy() *Outgoing {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PeeringNetworkPolicy) DeepCopyInto(out *PeeringNetworkPolicy) {
+	*out = *in
+	if in.AllowedNamespaces != nil {
+		in, out := &in.AllowedNamespaces, &out.AllowedNamespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.AllowedCIDRs != nil {
+		in, out := &in.AllowedCIDRs, &out.AllowedCIDRs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.AllowedPorts != nil {
+		in, out := &in.AllowedPorts, &out.AllowedPorts
+		*out = make([]PeeringNetworkPolicyPort, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PeeringNetworkPolicy.
+func (in *PeeringNetworkPolicy) DeepCopy() *PeeringNetworkPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(PeeringNetworkPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PeeringNetworkPolicyPort) DeepCopyInto(out *PeeringNetworkPolicyPort) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PeeringNetworkPolicyPort.
+func (in *PeeringNetworkPolicyPort) DeepCopy() *PeeringNetworkPolicyPort {
+	if in == nil {
+		return nil
+	}
+	out := new(PeeringNetworkPolicyPort)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PeeringRequest) DeepCopyInto(out *PeeringRequest) {
 	*out = *in
@@ -0,0 +1,60 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// IpamStorageGroupVersionResource is the GroupVersionResource of the IpamStorage resources.
+var IpamStorageGroupVersionResource = GroupVersion.WithResource("ipamstorages")
+
+// ClusterSubnets contains the subnets allocated to a peering cluster, one for each address family.
+// A subnet equal to the original PodCIDR of the cluster means that the NAT is not needed.
+type ClusterSubnets struct {
+	PodCIDR     string `json:"podCIDR,omitempty"`
+	IPv6PodCIDR string `json:"ipv6PodCIDR,omitempty"`
+}
+
+// IpamSpec defines the allocation table of the IPAM
+type IpamSpec struct {
+	// ClusterSubnets contains the subnets allocated to the peering clusters, indexed by cluster ID
+	ClusterSubnets map[string]ClusterSubnets `json:"clusterSubnets,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster
+
+// IpamStorage is the Schema for the ipamstorages API, used to persist the state of the IPAM
+type IpamStorage struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec IpamSpec `json:"spec,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// IpamStorageList contains a list of IpamStorage
+type IpamStorageList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []IpamStorage `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&IpamStorage{}, &IpamStorageList{})
+}
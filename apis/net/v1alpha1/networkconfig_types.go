@@ -32,8 +32,14 @@ type NetworkConfigSpec struct {
 	ClusterID string `json:"clusterID"`
 	//network subnet used in the local cluster for the pod IPs
 	PodCIDR string `json:"podCIDR"`
+	//IPv6 network subnet used in the local cluster for the pod IPs, set only in dual-stack clusters
+	IPv6PodCIDR string `json:"ipv6PodCIDR,omitempty"`
 	//public IP of the node where the VPN tunnel is created
 	TunnelPublicIP string `json:"tunnelPublicIP"`
+	//the driver used by the local cluster to create the tunnel (gre if not set)
+	BackendType string `json:"backendType,omitempty"`
+	//the parameters of the tunnel driver needed by the remote cluster (e.g. the WireGuard public key)
+	BackendConfig map[string]string `json:"backendConfig,omitempty"`
 }
 
 // NetworkConfigStatus defines the observed state of NetworkConfig
@@ -44,6 +50,8 @@ type NetworkConfigStatus struct {
 	NATEnabled string `json:"natEnabled,omitempty"`
 	//the new subnet used to NAT the pods' subnet of the remote cluster
 	PodCIDRNAT string `json:"podCIDRNAT,omitempty"`
+	//the new subnet used to NAT the pods' IPv6 subnet of the remote cluster
+	IPv6PodCIDRNAT string `json:"ipv6PodCIDRNAT,omitempty"`
 }
 
 // +kubebuilder:object:root=true
@@ -16,6 +16,7 @@ limitations under the License.
 package v1alpha1
 
 import (
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -26,23 +27,64 @@ import (
 type TunnelEndpointSpec struct {
 	// INSERT ADDITIONAL SPEC FIELDS - desired state of cluster
 	// Important: Run "make" to regenerate code after modifying this file
-	ClusterID      string `json:"clusterID"`
-	PodCIDR        string `json:"podCIDR"`
-	TunnelPublicIP string `json:"tunnelPublicIP"`
+	ClusterID      string            `json:"clusterID"`
+	PodCIDR        string            `json:"podCIDR"`
+	IPv6PodCIDR    string            `json:"ipv6PodCIDR,omitempty"`
+	TunnelPublicIP string            `json:"tunnelPublicIP"`
+	BackendType    string            `json:"backendType,omitempty"`
+	BackendConfig  map[string]string `json:"backendConfig,omitempty"`
 }
 
 // TunnelEndpointStatus defines the observed state of TunnelEndpoint
 type TunnelEndpointStatus struct {
 	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
 	// Important: Run "make" to regenerate code after modifying this file\
-	Phase                 string `json:"phase,omitempty"` //two phases: New, Processed
-	LocalRemappedPodCIDR  string `json:"localRemappedPodCIDR,omitempty"`
-	RemoteRemappedPodCIDR string `json:"remoteRemappedPodCIDR,omitempty"`
-	NATEnabled            bool   `json:"NAT,omitempty"`
-	RemoteTunnelPublicIP  string `json:"remoteTunnelPublicIP,omitempty"`
-	LocalTunnelPublicIP   string `json:"localTunnelPublicIP,omitempty"`
-	TunnelIFaceIndex      int    `json:"tunnelIFaceIndex,omitempty"`
-	TunnelIFaceName       string `json:"tunnelIFaceName,omitempty"`
+	Phase                     string `json:"phase,omitempty"` //two phases: New, Processed
+	LocalRemappedPodCIDR      string `json:"localRemappedPodCIDR,omitempty"`
+	RemoteRemappedPodCIDR     string `json:"remoteRemappedPodCIDR,omitempty"`
+	LocalRemappedIPv6PodCIDR  string `json:"localRemappedIPv6PodCIDR,omitempty"`
+	RemoteRemappedIPv6PodCIDR string `json:"remoteRemappedIPv6PodCIDR,omitempty"`
+	NATEnabled                bool   `json:"NAT,omitempty"`
+	RemoteTunnelPublicIP      string `json:"remoteTunnelPublicIP,omitempty"`
+	LocalTunnelPublicIP       string `json:"localTunnelPublicIP,omitempty"`
+	TunnelIFaceIndex          int    `json:"tunnelIFaceIndex,omitempty"`
+	TunnelIFaceName           string `json:"tunnelIFaceName,omitempty"`
+	//the MTU of the path towards the remote gateway, the one of the tunnel interface and the MSS the TCP
+	//connections towards the remote cluster are clamped to (zero if the clamping is disabled)
+	PathMTU   int `json:"pathMTU,omitempty"`
+	TunnelMTU int `json:"tunnelMTU,omitempty"`
+	TCPMSS    int `json:"tcpMSS,omitempty"`
+	//the state of the connection with the remote gateway, as measured by the probes sent through the tunnel
+	Connection TunnelConnection  `json:"connection,omitempty"`
+	Conditions []TunnelCondition `json:"conditions,omitempty"`
+}
+
+// TunnelConnection contains the statistics of the probes sent to the remote gateway through the tunnel
+type TunnelConnection struct {
+	//the average round trip time of the last probes, e.g. 1.5ms
+	Latency string `json:"latency,omitempty"`
+	//the percentage of the last probes which did not receive an answer
+	PacketLoss int `json:"packetLoss,omitempty"`
+	//the last time the remote gateway answered to a probe
+	LastSeen *metav1.Time `json:"lastSeen,omitempty"`
+}
+
+// TunnelConditionType is the type of a condition of a TunnelEndpoint
+type TunnelConditionType string
+
+const (
+	// TunnelConnected is true if the remote gateway answers to the probes sent through the tunnel
+	TunnelConnected TunnelConditionType = "Connected"
+)
+
+// TunnelCondition describes the state of the tunnel towards a remote cluster
+type TunnelCondition struct {
+	Type               TunnelConditionType    `json:"type"`
+	Status             corev1.ConditionStatus `json:"status"`
+	LastProbeTime      metav1.Time            `json:"lastProbeTime,omitempty"`
+	LastTransitionTime metav1.Time            `json:"lastTransitionTime,omitempty"`
+	Reason             string                 `json:"reason,omitempty"`
+	Message            string                 `json:"message,omitempty"`
 }
 
 // +kubebuilder:object:root=true
@@ -5,17 +5,21 @@ import (
 	advop "github.com/liqotech/liqo/internal/advertisement-operator"
 	"k8s.io/klog"
 	"os"
+	"time"
 )
 
 func main() {
 	var localKubeconfig, clusterId string
 	var peeringRequestName string
 	var saName string
+	var rotationPeriod, gracePeriod time.Duration
 
 	flag.StringVar(&localKubeconfig, "local-kubeconfig", "", "The path to the kubeconfig of your local cluster.")
 	flag.StringVar(&clusterId, "cluster-id", "", "The cluster ID of your cluster")
 	flag.StringVar(&peeringRequestName, "peering-request", "", "Name of PeeringRequest CR containing configurations")
 	flag.StringVar(&saName, "service-account", "vk-remote", "The name of the ServiceAccount used to create the kubeconfig that will be sent to the foreign cluster")
+	flag.DurationVar(&rotationPeriod, "credentials-rotation-period", 0, "The age after which the credentials sent to the foreign cluster are replaced, 0 to disable the rotation")
+	flag.DurationVar(&gracePeriod, "credentials-grace-period", time.Hour, "The time the replaced credentials are still valid, to let the foreign cluster reload the new ones")
 	flag.Parse()
 
 	if peeringRequestName == "" {
@@ -23,7 +27,7 @@ func main() {
 		os.Exit(1)
 	}
 
-	err := advop.StartBroadcaster(clusterId, localKubeconfig, peeringRequestName, saName)
+	err := advop.StartBroadcaster(clusterId, localKubeconfig, peeringRequestName, saName, rotationPeriod, gracePeriod)
 	if err != nil {
 		klog.Errorln(err, "Unable to start broadcaster: exiting")
 		os.Exit(1)
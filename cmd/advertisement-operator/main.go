@@ -32,6 +32,7 @@ import (
 	advtypes "github.com/liqotech/liqo/apis/sharing/v1alpha1"
 	advop "github.com/liqotech/liqo/internal/advertisement-operator"
 	"github.com/liqotech/liqo/pkg/csrApprover"
+	"github.com/liqotech/liqo/pkg/virtualKubelet"
 	ctrl "sigs.k8s.io/controller-runtime"
 	// +kubebuilder:scaffold:imports
 )
@@ -61,6 +62,7 @@ func main() {
 	var enableLeaderElection bool
 	var kubeletNamespace, kubeletImage, initKubeletImage string
 	var runsInKindEnv bool
+	var identityRequester string
 
 	flag.StringVar(&metricsAddr, "metrics-addr", defaultMetricsaddr, "The address the metric endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "enable-leader-election", false, "Enable leader election for controller manager. Enabling this will ensure there is only one active controller manager.")
@@ -70,6 +72,7 @@ func main() {
 	flag.StringVar(&kubeletImage, "kubelet-image", defaultVKImage, "The image of the virtual kubelet to be deployed")
 	flag.StringVar(&initKubeletImage, "init-kubelet-image", defaultInitVKImage, "The image of the virtual kubelet init container to be deployed")
 	flag.BoolVar(&runsInKindEnv, "run-in-kind", false, "The cluster in which the controller runs is managed by kind")
+	flag.StringVar(&identityRequester, "identity-csr-requester", "system:serviceaccount:"+defaultNamespace+":auth-service", "The user allowed to request the certificates of the remote clusters, i.e. the ServiceAccount of the auth service")
 	flag.Parse()
 
 	if clusterId == "" {
@@ -101,7 +104,9 @@ func main() {
 		klog.Error(err)
 		os.Exit(1)
 	}
-	go csrApprover.WatchCSR(clientset, "liqo.io/csr=true", 5*time.Second)
+	// the virtual kubelets request their certificates with the ServiceAccounts named after them
+	kubeletRequesterPrefix := "system:serviceaccount:" + kubeletNamespace + ":" + virtualKubelet.VirtualKubeletPrefix
+	go csrApprover.WatchCSR(clientset, "liqo.io/csr=true", 5*time.Second, identityRequester, kubeletRequesterPrefix)
 
 	// get the number of already accepted advertisements
 	advClient, err := advtypes.CreateAdvertisementClient(localKubeconfig, nil, true)
@@ -16,16 +16,22 @@ func main() {
 	var kubeconfigPath string
 	var resyncSeconds int64
 	var listeningPort string
+	var sharedToken bool
+	var certificateMaxLifetime time.Duration
 
 	flag.StringVar(&namespace, "namespace", "default", "Namespace where your configs are stored.")
 	flag.StringVar(&kubeconfigPath, "kubeconfigPath", filepath.Join(os.Getenv("HOME"), ".kube", "config"), "For debug purpose, set path to local kubeconfig")
 	flag.Int64Var(&resyncSeconds, "resyncSeconds", 30, "Resync seconds for the informers")
 	flag.StringVar(&listeningPort, "listeningPort", "5000", "Sets the port where the service will listen")
+	flag.BoolVar(&sharedToken, "sharedToken", true, "Accept the token shared with every remote cluster, in addition to the scoped ones")
+	flag.DurationVar(&certificateMaxLifetime, "certificateMaxLifetime", 30*24*time.Hour,
+		"Maximum lifetime of the client certificates issued to the remote clusters, which are renewed by the remote clusters before expiring. "+
+			"The signing duration of the kube-controller-manager (--cluster-signing-duration) must not be longer, otherwise no certificate is issued")
 	flag.Parse()
 
 	klog.Info("Namespace: ", namespace)
 
-	authService, err := auth_service.NewAuthServiceCtrl(namespace, kubeconfigPath, time.Duration(resyncSeconds)*time.Second)
+	authService, err := auth_service.NewAuthServiceCtrl(namespace, kubeconfigPath, time.Duration(resyncSeconds)*time.Second, sharedToken, certificateMaxLifetime)
 	if err != nil {
 		klog.Error(err)
 		os.Exit(1)
@@ -37,11 +37,14 @@ func main() {
 	var requeueAfter int64 // seconds
 	var kubeconfigPath string
 	var resolveContextRefreshTime int // minutes
+	var credentialsRotationPeriod, credentialsGracePeriod time.Duration
 
 	flag.StringVar(&namespace, "namespace", "default", "Namespace where your configs are stored.")
 	flag.Int64Var(&requeueAfter, "requeueAfter", 30, "Period after that PeeringRequests status is rechecked (seconds)")
 	flag.StringVar(&kubeconfigPath, "kubeconfigPath", filepath.Join(os.Getenv("HOME"), ".kube", "config"), "For debug purpose, set path to local kubeconfig")
 	flag.IntVar(&resolveContextRefreshTime, "resolveContextRefreshTime", 10, "Period after that mDNS resolve context is refreshed (minutes)")
+	flag.DurationVar(&credentialsRotationPeriod, "credentials-rotation-period", 0, "Age after that the credentials granted to the foreign clusters are replaced, 0 to disable the rotation")
+	flag.DurationVar(&credentialsGracePeriod, "credentials-grace-period", time.Hour, "Time the replaced credentials are still valid, to let the foreign clusters reload the new ones")
 	flag.Parse()
 
 	klog.Info("Namespace: ", namespace)
@@ -87,7 +90,7 @@ func main() {
 	search_domain_operator.StartOperator(&mgr, time.Duration(requeueAfter)*time.Second, discoveryCtl, kubeconfigPath)
 
 	klog.Info("Starting ForeignCluster operator")
-	foreign_cluster_operator.StartOperator(&mgr, namespace, time.Duration(requeueAfter)*time.Second, discoveryCtl, kubeconfigPath)
+	foreign_cluster_operator.StartOperator(&mgr, namespace, time.Duration(requeueAfter)*time.Second, credentialsRotationPeriod, credentialsGracePeriod, discoveryCtl, kubeconfigPath)
 
 	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
 		klog.Error(err, "problem running manager")
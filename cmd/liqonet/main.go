@@ -18,13 +18,12 @@ package main
 import (
 	"context"
 	"flag"
-	"github.com/coreos/go-iptables/iptables"
 	clusterConfig "github.com/liqotech/liqo/apis/config/v1alpha1"
+	discoveryv1alpha1 "github.com/liqotech/liqo/apis/discovery/v1alpha1"
 	netv1alpha1 "github.com/liqotech/liqo/apis/net/v1alpha1"
 	"github.com/liqotech/liqo/internal/liqonet"
 	"github.com/liqotech/liqo/pkg/liqonet"
 	"github.com/vishvananda/netlink"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/dynamic/dynamicinformer"
@@ -32,16 +31,22 @@ import (
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	_ "k8s.io/client-go/plugin/pkg/client/auth/gcp"
 	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/leaderelection"
 	"k8s.io/klog/v2"
 	"net"
 	"os"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 	// +kubebuilder:scaffold:imports
 )
 
+// the maximum time the operators wait for their configuration, before exiting to be restarted
+const configurationTimeout = 2 * time.Minute
+
 var (
 	scheme        = runtime.NewScheme()
 	defaultConfig = liqonet.VxlanNetConfig{
@@ -56,6 +61,7 @@ func init() {
 	_ = clientgoscheme.AddToScheme(scheme)
 
 	_ = netv1alpha1.AddToScheme(scheme)
+	_ = discoveryv1alpha1.AddToScheme(scheme)
 
 }
 
@@ -64,6 +70,7 @@ func main() {
 	var enableLeaderElection bool
 	var runAsRouteOperator bool
 	var runAs string
+	var firewallBackend string
 
 	flag.StringVar(&metricsAddr, "metrics-addr", ":0", "The address the metric endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "enable-leader-election", false,
@@ -71,6 +78,7 @@ func main() {
 	flag.BoolVar(&runAsRouteOperator, "run-as-route-operator", false,
 		"Runs the controller as Route-Operator, the default value is false and will run as Tunnel-Operator")
 	flag.StringVar(&runAs, "run-as", "tunnel-operator", "The accepted values are: tunnel-operator, route-operator, tunnelEndpointCreator-operator. The default value is \"tunnel-operator\"")
+	flag.StringVar(&firewallBackend, "firewall-backend", liqonet.IPTablesBackend, "The backend used by the route-operator to configure the firewall rules. The accepted values are: iptables, nftables")
 	flag.Parse()
 	waitCleanUp := make(chan struct{})
 	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
@@ -89,6 +97,8 @@ func main() {
 	if err != nil {
 		panic(err.Error())
 	}
+	//the namespace where the operator runs, used to store its secrets
+	namespace := os.Getenv("POD_NAMESPACE")
 	// +kubebuilder:scaffold:builder
 	switch runAs {
 	case "route-operator":
@@ -110,25 +120,15 @@ func main() {
 			klog.Errorf("an error occurred while enabling loose mode reverse path filtering: %s", err)
 			os.Exit(3)
 		}
-		isGatewayNode, err := liqonet.IsGatewayNode(clientset)
-		if err != nil {
-			klog.Errorf("an error occurred while checking if the node is the GatewayNode: %s", err)
-			os.Exit(2)
-		}
 		//get node name
 		nodeName, err := liqonet.GetNodeName()
 		if err != nil {
 			klog.Errorf("unable to get node nome: %s", err)
 			os.Exit(4)
 		}
-		gatewayVxlanIP, err := liqonet.GetGatewayVxlanIP(clientset, vxlanConfig)
-		if err != nil {
-			klog.Errorf("unable to build gateway vxlanIP: %s", err)
-			os.Exit(5)
-		}
-		ipt, err := iptables.New()
+		ipt, err := liqonet.NewIPTables(firewallBackend)
 		if err != nil {
-			klog.Errorf("unable to initialize iptables, check if the binaries are present in the sysetm: %s", err)
+			klog.Errorf("unable to initialize %s, check if the binaries are present in the sysetm: %s", firewallBackend, err)
 			os.Exit(6)
 		}
 		r := &liqonetOperators.RouteController{
@@ -136,7 +136,6 @@ func main() {
 			Scheme:                             mgr.GetScheme(),
 			Recorder:                           mgr.GetEventRecorderFor(strings.Join([]string{"route-OP", nodeName}, "-")),
 			ClientSet:                          clientset,
-			IsGateway:                          isGatewayNode,
 			VxlanNetwork:                       vxlanConfig.Network,
 			VxlanIfaceName:                     vxlanConfig.DeviceName,
 			VxlanPort:                          vxlanPort,
@@ -144,7 +143,6 @@ func main() {
 			IPTablesChains:                     make(map[string]liqonet.IPTableChain),
 			RoutesPerRemoteCluster:             make(map[string]netlink.Route),
 			NodeName:                           nodeName,
-			GatewayVxlanIP:                     gatewayVxlanIP,
 			RetryTimeout:                       30 * time.Second,
 			IPtables:                           ipt,
 			NetLink:                            &liqonet.RouteManager{},
@@ -152,13 +150,22 @@ func main() {
 		}
 		r.WatchConfiguration(config, &clusterConfig.GroupVersion)
 		if !r.IsConfigured {
-			<-r.Configured
+			waitForConfiguration(r.Configured, "route-operator")
 			r.IsConfigured = true
 			klog.Infof("route-operator configured with podCIDR %s", r.ClusterPodCIDR)
 		}
+		quit := make(chan struct{})
+		//this go routine keeps track of the node acting as gateway, which changes when the
+		//current gateway fails and another node is elected
+		gatewayConfigured := make(chan struct{})
+		var gatewayOnce sync.Once
+		go liqonet.WatchGateway(clientset, namespace, liqonet.GatewayCheckPeriod, quit, func(gatewayIP string) {
+			r.SetGateway(gatewayIP == os.Getenv("POD_IP"), liqonet.GetVxlanIP(vxlanConfig, gatewayIP))
+			gatewayOnce.Do(func() { close(gatewayConfigured) })
+		})
+		<-gatewayConfigured
 		//this go routing ensures that the general chains and rulespecs for LIQO exist and are
 		//at the first position
-		quit := make(chan struct{})
 		go func() {
 			for {
 				if err := r.CreateAndEnsureIPTablesChains(); err != nil {
@@ -184,37 +191,66 @@ func main() {
 		<-waitCleanUp
 
 	case "tunnel-operator":
+		nodeName, err := liqonet.GetNodeName()
+		if err != nil {
+			klog.Errorf("unable to get node nome: %s", err)
+			os.Exit(4)
+		}
 		r := &liqonetOperators.TunnelController{
 			Client:                       mgr.GetClient(),
 			Scheme:                       mgr.GetScheme(),
 			Recorder:                     mgr.GetEventRecorderFor("tunnel-operator"),
 			TunnelIFacesPerRemoteCluster: make(map[string]int),
+			ClientSet:                    clientset,
+			Namespace:                    namespace,
+			Configured:                   make(chan bool, 1),
+			Prober:                       liqonet.NewUDPTunnelProber(),
+			ProbeInterval:                liqonetOperators.DefaultProbeInterval,
+			MTUProber:                    liqonet.NewUDPTunnelProber(),
+		}
+		r.WatchConfiguration(config, &clusterConfig.GroupVersion)
+		if !r.IsConfigured {
+			waitForConfiguration(r.Configured, "tunnel-operator")
+			klog.Infof("tunnel-operator configured with tunnel driver %s", r.DriverName)
 		}
 		if err = r.SetupWithManager(mgr); err != nil {
 			klog.Errorf("unable to setup controller: %s", err)
 			os.Exit(1)
 		}
-		klog.Info("Starting manager as Tunnel-Operator")
-		if err := mgr.Start(r.SetupSignalHandlerForTunnelOperator()); err != nil {
-			klog.Errorf("unable to start controller: %s", err)
-			os.Exit(1)
-		}
-
-	case "tunnelEndpointCreator-operator":
-
-		//get IP of gatewayNode
-		nodeList, err := clientset.CoreV1().Nodes().List(context.Background(), metav1.ListOptions{
-			LabelSelector: "net.liqo.io/gateway=true",
+		stop := r.SetupSignalHandlerForTunnelOperator()
+		ctx, cancel := context.WithCancel(context.Background())
+		go func() {
+			<-stop
+			cancel()
+		}()
+		//only the operator running on the node elected as gateway installs the tunnels
+		klog.Infof("node %s is candidate to be the gateway", nodeName)
+		err = liqonet.RunGatewayLeaderElection(ctx, clientset, namespace, nodeName, leaderelection.LeaderCallbacks{
+			OnStartedLeading: func(ctx context.Context) {
+				klog.Info("Starting manager as Tunnel-Operator")
+				if err := mgr.Start(ctx.Done()); err != nil {
+					klog.Errorf("unable to start controller: %s", err)
+					os.Exit(1)
+				}
+			},
+			OnStoppedLeading: func() {
+				klog.Infof("node %s is no longer the gateway: removing the tunnels", nodeName)
+				r.RemoveAllTunnels()
+			},
 		})
 		if err != nil {
-			klog.Errorf("an error occurred while getting nodes %s", err)
-			os.Exit(-1)
+			klog.Error(err)
+			os.Exit(1)
 		}
-		if len(nodeList.Items) != 1 {
-			klog.Errorf("no node or multiple nodes found with label \"net.liqo.io/gateway=true\"")
-			os.Exit(-1)
+		select {
+		case <-stop:
+		default:
+			//the leadership has been lost: restart as a candidate
+			klog.Errorf("gateway leadership lost by node %s", nodeName)
+			os.Exit(1)
 		}
-		gatewayIP := nodeList.Items[0].Status.Addresses[0].Address
+
+	case "tunnelEndpointCreator-operator":
 		//creating dynamic client
 		dynClient := dynamic.NewForConfigOrDie(mgr.GetConfig())
 		//creating dynamicSharedInformerFactory
@@ -224,16 +260,19 @@ func main() {
 			Scheme:                     mgr.GetScheme(),
 			DynClient:                  dynClient,
 			DynFactory:                 dynFactory,
-			GatewayIP:                  gatewayIP,
+			ClientSet:                  clientset,
+			Namespace:                  namespace,
 			ReservedSubnets:            make(map[string]*net.IPNet),
 			Configured:                 make(chan bool, 1),
 			ForeignClusterStartWatcher: make(chan bool, 1),
 			ForeignClusterStopWatcher:  make(chan struct{}),
 			IPManager: liqonet.IpManager{
-				UsedSubnets:        make(map[string]*net.IPNet),
-				FreeSubnets:        make(map[string]*net.IPNet),
-				SubnetPerCluster:   make(map[string]*net.IPNet),
-				ConflictingSubnets: make(map[string]*net.IPNet),
+				UsedSubnets:          make(map[string]*net.IPNet),
+				FreeSubnets:          make(map[string]*net.IPNet),
+				SubnetPerCluster:     make(map[string]*net.IPNet),
+				IPv6SubnetPerCluster: make(map[string]*net.IPNet),
+				ConflictingSubnets:   make(map[string]*net.IPNet),
+				Storage:              liqonet.NewIpamCRDStorage(dynClient),
 			},
 			RetryTimeout: 30 * time.Second,
 		}
@@ -249,6 +288,18 @@ func main() {
 			klog.Errorf("unable to create controller controller TunnelEndpointCreator: %s", err)
 			os.Exit(1)
 		}
+		//the public IP of the tunnel changes when another node is elected as gateway
+		if err = mgr.Add(manager.RunnableFunc(func(stop <-chan struct{}) error {
+			liqonet.WatchGateway(clientset, namespace, liqonet.GatewayCheckPeriod, stop, func(gatewayIP string) {
+				if err := r.SetGatewayIP(gatewayIP); err != nil {
+					klog.Error(err)
+				}
+			})
+			return nil
+		})); err != nil {
+			klog.Errorf("unable to add the gateway watcher: %s", err)
+			os.Exit(1)
+		}
 		klog.Info("starting manager as tunnelEndpointCreator-operator")
 		if err := mgr.Start(r.SetupSignalHandlerForTunEndCreator()); err != nil {
 			klog.Errorf("an error occurred while starting manager: %s", err)
@@ -257,3 +308,14 @@ func main() {
 	}
 
 }
+
+// waitForConfiguration waits for the operator to be configured, and exits if the configuration
+// is not received within configurationTimeout, so that the pod is restarted
+func waitForConfiguration(configured <-chan bool, operator string) {
+	select {
+	case <-configured:
+	case <-time.After(configurationTimeout):
+		klog.Errorf("%s not configured within %s: check the ClusterConfig", operator, configurationTimeout)
+		os.Exit(1)
+	}
+}
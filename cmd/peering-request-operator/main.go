@@ -8,6 +8,7 @@ import (
 	"k8s.io/klog"
 	"os"
 	"path/filepath"
+	"time"
 )
 
 func main() {
@@ -16,11 +17,14 @@ func main() {
 	var broadcasterImage, broadcasterServiceAccount, vkServiceAccount string
 	var inputEnvFile string
 	var kubeconfigPath string
+	var credentialsRotationPeriod, credentialsGracePeriod time.Duration
 
 	flag.StringVar(&inputEnvFile, "input-env-file", "/etc/environment/liqo/env", "The environment variable file to source at startup")
 	flag.StringVar(&broadcasterImage, "broadcaster-image", "liqo/advertisement-broadcaster", "Broadcaster-operator image name")
 	flag.StringVar(&broadcasterServiceAccount, "broadcaster-sa", "broadcaster", "Broadcaster-operator ServiceAccount name")
 	flag.StringVar(&vkServiceAccount, "vk-sa", "vk-remote", "Remote VirtualKubelet ServiceAccount name")
+	flag.DurationVar(&credentialsRotationPeriod, "credentials-rotation-period", 0, "The age after which the credentials granted to the virtual kubelets of the foreign clusters are replaced, 0 to disable the rotation")
+	flag.DurationVar(&credentialsGracePeriod, "credentials-grace-period", time.Hour, "The time the replaced credentials are still valid, to let the foreign clusters reload the new ones")
 	flag.StringVar(&kubeconfigPath, "kubeconfigPath", filepath.Join(os.Getenv("HOME"), ".kube", "config"), "For debug purpose, set path to local kubeconfig")
 	flag.Parse()
 
@@ -39,12 +43,15 @@ func main() {
 	}
 	keyPath, ok := os.LookupEnv("liqokey")
 	if !ok {
-		certPath = "/etc/ssl/liqo/server-key.pem"
+		keyPath = "/etc/ssl/liqo/server-key.pem"
 	}
 
 	klog.Info("Starting admission webhook")
-	_ = peering_request_admission.StartWebhook(certPath, keyPath, namespace, kubeconfigPath)
+	if _, err := peering_request_admission.StartWebhook(certPath, keyPath, namespace, kubeconfigPath); err != nil {
+		klog.Error(err, "unable to start the admission webhook")
+		os.Exit(1)
+	}
 
 	klog.Info("Starting peering-request operator")
-	peering_request_operator.StartOperator(namespace, broadcasterImage, broadcasterServiceAccount, vkServiceAccount, kubeconfigPath)
+	peering_request_operator.StartOperator(namespace, broadcasterImage, broadcasterServiceAccount, vkServiceAccount, credentialsRotationPeriod, credentialsGracePeriod, kubeconfigPath)
 }
@@ -82,6 +82,10 @@ func InstallFlags(flags *pflag.FlagSet, c *Opts) {
 	flags.StringVar(&c.ForeignClusterId, "foreign-cluster-id", c.ForeignClusterId, "The Id of the foreign cluster")
 	flags.StringVar(&c.KubeletNamespace, "kubelet-namespace", c.KubeletNamespace, "The namespace of the virtual kubelet")
 	flags.StringVar(&c.HomeClusterId, "home-cluster-id", c.HomeClusterId, "The Id of the home cluster")
+	flags.StringToStringVar(&c.StorageClassMapping, "storage-class-mapping", c.StorageClassMapping, "The mapping between home and foreign storage classes, for the reflected PersistentVolumeClaims (e.g. standard=gp2,fast=io1)")
+	flags.StringVar(&c.EvictionPolicy, "eviction-policy", c.EvictionPolicy, "What to do with the offloaded pods when the foreign cluster is no longer available (None, Fail or Delete)")
+	flags.DurationVar(&c.EvictionGracePeriod, "eviction-grace-period", c.EvictionGracePeriod, "How long the foreign cluster can be unreachable before the offloaded pods are evicted")
+	flags.BoolVar(&c.DisableDNSTranslation, "disable-dns-translation", c.DisableDNSTranslation, "Do not configure the offloaded pods and the foreign cluster DNS to resolve the names of the home services")
 	flags.BoolVar(&c.Profiling, "enable-profiling", c.Profiling, "Enable pprof profiling")
 
 	flagset := flag.NewFlagSet("klog", flag.PanicOnError)
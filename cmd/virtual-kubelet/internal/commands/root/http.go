@@ -85,9 +85,11 @@ func setupHTTPServer(ctx context.Context, p provider.Provider, cfg *apiServerCon
 		mux := http.NewServeMux()
 
 		podRoutes := api.PodHandlerConfig{
-			RunInContainer:   p.RunInContainer,
-			GetContainerLogs: p.GetContainerLogs,
-			GetPods:          p.GetPods,
+			RunInContainer:    p.RunInContainer,
+			AttachToContainer: p.AttachToContainer,
+			PortForward:       p.PortForward,
+			GetContainerLogs:  p.GetContainerLogs,
+			GetPods:           p.GetPods,
 		}
 		api.AttachPodRoutes(podRoutes, mux, true)
 
@@ -39,6 +39,9 @@ const (
 	DefaultTaintKey         = "virtual-kubelet.io/provider"
 	DefaultKubeletNamespace = "default"
 	DefaultHomeClusterId    = "cluster1"
+
+	DefaultEvictionPolicy      = "None"
+	DefaultEvictionGracePeriod = 5 * time.Minute
 )
 
 // Opts stores all the options for configuring the root virtual-kubelet command.
@@ -85,6 +88,17 @@ type Opts struct {
 	HomeClusterId    string
 	KubeletNamespace string
 
+	// StorageClassMapping maps the home storage classes to the foreign ones, for the reflected PersistentVolumeClaims
+	StorageClassMapping map[string]string
+
+	// EvictionPolicy defines what happens to the offloaded pods when the foreign cluster is no longer available (None, Fail or Delete)
+	EvictionPolicy string
+	// EvictionGracePeriod is how long the foreign cluster can be unreachable before the offloaded pods are evicted
+	EvictionGracePeriod time.Duration
+
+	// DisableDNSTranslation disables the resolution of the home service names to the reflected services in the foreign cluster
+	DisableDNSTranslation bool
+
 	Version   string
 	Profiling bool
 }
@@ -137,6 +151,12 @@ func SetDefaultOpts(c *Opts) error {
 	if c.KubeletNamespace == "" {
 		c.KubeletNamespace = DefaultKubeletNamespace
 	}
+	if c.EvictionPolicy == "" {
+		c.EvictionPolicy = DefaultEvictionPolicy
+	}
+	if c.EvictionGracePeriod == 0 {
+		c.EvictionGracePeriod = DefaultEvictionGracePeriod
+	}
 	if c.HomeKubeconfig == "" {
 		c.HomeKubeconfig = os.Getenv("KUBECONFIG")
 		if c.HomeKubeconfig == "" {
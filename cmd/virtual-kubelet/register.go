@@ -15,6 +15,11 @@ func registerKubernetes(s *provider.Store) error {
 			cfg.DaemonPort,
 			cfg.ConfigPath,
 			cfg.RemoteKubeConfig,
+			cfg.StorageClassMapping,
+			cfg.EvictionPolicy,
+			cfg.EvictionGracePeriod,
+			cfg.KubeClusterDomain,
+			!cfg.DisableDNSTranslation,
 		)
 	})
 }
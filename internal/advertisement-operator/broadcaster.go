@@ -10,6 +10,7 @@ import (
 	pkg "github.com/liqotech/liqo/pkg/virtualKubelet"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/klog"
 	"strings"
 	"sync"
@@ -56,7 +57,9 @@ type AdvResources struct {
 // - localKubeconfigPath: the path to the kubeconfig of the local cluster. Set it only when you are debugging and need to launch the program as a process and not inside Kubernetes
 // - peeringRequestName: the name of the PeeringRequest containing the reference to the secret with the kubeconfig for creating Advertisements CR on foreign cluster
 // - saName: The name of the ServiceAccount used to create the kubeconfig that will be sent to the foreign cluster with the permissions to create resources on local cluster
-func StartBroadcaster(homeClusterId, localKubeconfigPath, peeringRequestName, saName string) error {
+// - rotationPeriod: the age after which the credentials sent to the foreign cluster are replaced, zero to disable the rotation
+// - gracePeriod: the time the replaced credentials are still valid, to let the virtual kubelet reload the new ones
+func StartBroadcaster(homeClusterId, localKubeconfigPath, peeringRequestName, saName string, rotationPeriod, gracePeriod time.Duration) error {
 	klog.V(6).Info("starting broadcaster")
 
 	// create the Advertisement client to the local cluster
@@ -100,11 +103,12 @@ func StartBroadcaster(homeClusterId, localKubeconfigPath, peeringRequestName, sa
 
 	// create the Advertisement client to the remote cluster, using the retrieved Secret
 	var remoteClient *crdClient.CRDClient
+	var remoteToken *crdClient.ReloadableToken
 	var retry int
 
 	// create a CRD-client to the foreign cluster
 	for retry = 0; retry < 3; retry++ {
-		remoteClient, err = advtypes.CreateAdvertisementClient("", secretForAdvertisementCreation, true)
+		remoteClient, remoteToken, err = createRemoteClient(secretForAdvertisementCreation)
 		if err != nil {
 			klog.Errorln(err, "Unable to create client to remote cluster "+foreignClusterId+". Retry in 1 minute")
 			time.Sleep(1 * time.Minute)
@@ -130,8 +134,28 @@ func StartBroadcaster(homeClusterId, localKubeconfigPath, peeringRequestName, sa
 
 	kubeconfigSecretName := pkg.VirtualKubeletSecPrefix + homeClusterId
 
+	// the credentials granted to the foreign cluster are dedicated to it, and deleted with the PeeringRequest
+	rotator := &kubeconfig.CredentialsRotator{
+		Clientset:       localClient.Client(),
+		Namespace:       pr.Spec.Namespace,
+		ServiceAccount:  saName,
+		RemoteClusterID: foreignClusterId,
+		Consumer:        "virtual-kubelet",
+		OwnerReferences: []metav1.OwnerReference{
+			{
+				APIVersion: discoveryv1alpha1.GroupVersion.String(),
+				Kind:       "PeeringRequest",
+				Name:       pr.Name,
+				UID:        pr.UID,
+			},
+		},
+		Period:      rotationPeriod,
+		GracePeriod: gracePeriod,
+		Push:        broadcaster.pushKubeconfig,
+	}
+
 	// create the kubeconfig to allow the foreign cluster to create resources on local cluster
-	kubeconfigForForeignCluster, err := kubeconfig.CreateKubeConfig(localClient.Client(), saName, pr.Spec.Namespace)
+	kubeconfigForForeignCluster, err := rotator.Kubeconfig()
 	if err != nil {
 		klog.Errorln(err, "Unable to create Kubeconfig")
 		return err
@@ -157,6 +181,10 @@ func StartBroadcaster(homeClusterId, localKubeconfigPath, peeringRequestName, sa
 	}
 	// secret correctly created on foreign cluster, now launch the broadcaster to create Advertisement
 
+	// the new credentials are pushed to the foreign cluster, which pushes its own ones updating the PeeringRequest secret
+	go rotator.Run(wait.NeverStop)
+	go broadcaster.reloadRemoteCredentials(secretForAdvertisementCreation, remoteToken, wait.NeverStop)
+
 	broadcaster.WatchConfiguration(localKubeconfigPath, nil)
 
 	broadcaster.GenerateAdvertisement()
@@ -171,7 +199,7 @@ func (b *AdvertisementBroadcaster) GenerateAdvertisement() {
 	var once sync.Once
 
 	for {
-		_, err := b.SendSecretToForeignCluster(b.KubeconfigSecretForForeign)
+		_, err := b.SendSecretToForeignCluster(b.getKubeconfigSecret())
 		if err != nil {
 			klog.Errorln(err, "Error while sending Secret for virtual-kubelet to cluster "+b.ForeignClusterId)
 			time.Sleep(1 * time.Minute)
@@ -72,6 +72,9 @@ func (b *AdvertisementBroadcaster) updateAdvertisement() {
 
 func (r *AdvertisementReconciler) WatchConfiguration(kubeconfigPath string, client *crdClient.CRDClient) {
 	go clusterConfig.WatchConfiguration(func(configuration *configv1alpha1.ClusterConfig) {
+		// the configuration of the virtual kubelets is applied to the ones created or updated from now on
+		r.VirtualKubeletConfig = *configuration.Spec.VirtualKubeletConfig.DeepCopy()
+
 		newConfig := configuration.Spec.AdvertisementConfig
 		if newConfig.IngoingConfig != r.ClusterConfig.IngoingConfig {
 			// the config update is related to the advertisement operator
@@ -58,6 +58,9 @@ type AdvertisementReconciler struct {
 	RetryTimeout       time.Duration
 	garbaceCollector   sync.Once
 	checkRemoteCluster map[string]*sync.Once
+
+	// VirtualKubeletConfig is the configuration of the virtual kubelets, passed to the ones created
+	VirtualKubeletConfig configv1alpha1.VirtualKubeletConfig
 }
 
 // +kubebuilder:rbac:groups=sharing.liqo.io,resources=advertisements,verbs=get;list;watch;create;update;patch;delete
@@ -296,7 +299,8 @@ func (r *AdvertisementReconciler) createVirtualKubelet(ctx context.Context, adv
 		return err
 	}
 	// Create the virtual Kubelet
-	deploy := advpkg.CreateVkDeployment(adv, name, r.KubeletNamespace, r.VKImage, r.InitVKImage, nodeName, r.HomeClusterId)
+	deploy := advpkg.CreateVkDeployment(adv, name, r.KubeletNamespace, r.VKImage, r.InitVKImage, nodeName, r.HomeClusterId,
+		r.VirtualKubeletConfig.StorageClassMapping)
 	err = advpkg.CreateOrUpdate(r.Client, ctx, deploy)
 	if err != nil {
 		return err
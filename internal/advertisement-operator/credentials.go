@@ -0,0 +1,65 @@
+package advertisementOperator
+
+import (
+	"context"
+	advtypes "github.com/liqotech/liqo/apis/sharing/v1alpha1"
+	"github.com/liqotech/liqo/pkg/crdClient"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog"
+	"time"
+)
+
+// period between two consecutive reloads of the credentials granted by the foreign cluster
+const remoteCredentialsReloadPeriod = time.Minute
+
+// create a client to the foreign cluster whose token can be replaced when the foreign cluster rotates the credentials
+func createRemoteClient(secret *corev1.Secret) (*crdClient.CRDClient, *crdClient.ReloadableToken, error) {
+	config, err := crdClient.NewKubeconfigFromSecret(secret, &advtypes.GroupVersion)
+	if err != nil {
+		return nil, nil, err
+	}
+	token := crdClient.NewReloadableToken(config)
+	client, err := crdClient.NewFromConfig(config)
+	if err != nil {
+		return nil, nil, err
+	}
+	return client, token, nil
+}
+
+// reload the credentials from the PeeringRequest secret, which is updated by the foreign cluster when it rotates them
+func (b *AdvertisementBroadcaster) reloadRemoteCredentials(secret *corev1.Secret, token *crdClient.ReloadableToken, stop <-chan struct{}) {
+	wait.Until(func() {
+		secret, err := b.LocalClient.Client().CoreV1().Secrets(secret.Namespace).Get(context.TODO(), secret.Name, metav1.GetOptions{})
+		if err != nil {
+			klog.Error(err)
+			return
+		}
+		if changed, err := token.SetFromKubeconfig(secret.Data["kubeconfig"]); err != nil {
+			klog.Errorf("unable to reload the credentials of cluster %v: %v", b.ForeignClusterId, err)
+		} else if changed {
+			klog.Infof("credentials of cluster %v reloaded", b.ForeignClusterId)
+		}
+	}, remoteCredentialsReloadPeriod, stop)
+}
+
+// send to the foreign cluster the kubeconfig with the rotated credentials for the virtual kubelet
+func (b *AdvertisementBroadcaster) pushKubeconfig(kubeconfig string) error {
+	b.mutex.Lock()
+	secret := b.KubeconfigSecretForForeign.DeepCopy()
+	secret.StringData = map[string]string{
+		"kubeconfig": kubeconfig,
+	}
+	b.KubeconfigSecretForForeign = secret
+	b.mutex.Unlock()
+
+	_, err := b.SendSecretToForeignCluster(b.getKubeconfigSecret())
+	return err
+}
+
+func (b *AdvertisementBroadcaster) getKubeconfigSecret() *corev1.Secret {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.KubeconfigSecretForForeign.DeepCopy()
+}
@@ -23,9 +23,13 @@ type AuthServiceCtrl struct {
 	saInformer     cache.SharedIndexInformer
 	nodeInformer   cache.SharedIndexInformer
 	secretInformer cache.SharedIndexInformer
+	// whether the token shared with every remote cluster is accepted, in addition to the scoped ones
+	sharedToken bool
+	// maximum lifetime accepted for the client certificates issued to the remote clusters
+	certificateMaxLifetime time.Duration
 }
 
-func NewAuthServiceCtrl(namespace string, kubeconfigPath string, resyncTime time.Duration) (*AuthServiceCtrl, error) {
+func NewAuthServiceCtrl(namespace string, kubeconfigPath string, resyncTime time.Duration, sharedToken bool, certificateMaxLifetime time.Duration) (*AuthServiceCtrl, error) {
 	config, err := crdClient.NewKubeconfig(kubeconfigPath, &discoveryv1alpha1.GroupVersion)
 	if err != nil {
 		return nil, err
@@ -55,6 +59,9 @@ func NewAuthServiceCtrl(namespace string, kubeconfigPath string, resyncTime time
 		saInformer:     saInformer,
 		nodeInformer:   nodeInformer,
 		secretInformer: secretInformer,
+		sharedToken:    sharedToken,
+
+		certificateMaxLifetime: certificateMaxLifetime,
 	}, nil
 }
 
@@ -76,17 +83,32 @@ func (authService *AuthServiceCtrl) Start(listeningPort string) error {
 }
 
 func (authService *AuthServiceCtrl) configureToken() error {
-	if err := authService.createToken(); err != nil {
-		return err
+	if authService.sharedToken {
+		if err := authService.createToken(); err != nil {
+			return err
+		}
 	}
 
 	authService.secretInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			newSecret, ok := obj.(*v1.Secret)
+			if !ok {
+				return
+			}
+			if err := authService.initScopedToken(newSecret); err != nil {
+				klog.Error(err)
+			}
+		},
 		UpdateFunc: func(oldObj interface{}, newObj interface{}) {
 			newSecret, ok := newObj.(*v1.Secret)
 			if !ok {
 				return
 			}
-			if newSecret.Name != AuthTokenSecretName {
+			if err := authService.initScopedToken(newSecret); err != nil {
+				klog.Error(err)
+				return
+			}
+			if !authService.sharedToken || newSecret.Name != AuthTokenSecretName {
 				return
 			}
 
@@ -103,7 +125,7 @@ func (authService *AuthServiceCtrl) configureToken() error {
 			if !ok {
 				return
 			}
-			if newSecret.Name != AuthTokenSecretName {
+			if !authService.sharedToken || newSecret.Name != AuthTokenSecretName {
 				return
 			}
 
@@ -0,0 +1,65 @@
+package auth_service
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"github.com/liqotech/liqo/pkg/auth"
+	"github.com/liqotech/liqo/pkg/csrApprover"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog"
+	"time"
+)
+
+// validateCertificateRequest checks that the certificate request of the remote cluster only asks for its own identity
+func validateCertificateRequest(request []byte, clusterID string) error {
+	csr, err := csrApprover.ParseIdentityRequest(request)
+	if err != nil {
+		return forbidden(fmt.Sprintf("invalid certificate request: %v", err))
+	}
+	if err = csrApprover.ValidateIdentityRequest(csr, clusterID); err != nil {
+		return forbidden(fmt.Sprintf("invalid certificate request: %v", err))
+	}
+	return nil
+}
+
+// issueCertificate submits the certificate request of the remote cluster to the Kubernetes CSR API, where it is
+// approved by the Liqo CSR approver, and returns the PEM encoded client certificate once it has been signed.
+func (authService *AuthServiceCtrl) issueCertificate(clusterID string, request []byte) ([]byte, error) {
+	csr, err := auth.RequestIdentityCertificate(authService.clientset, clusterID, request)
+	if errors.Is(err, auth.ErrIdentityDenied) {
+		return nil, forbidden(err.Error())
+	} else if err != nil {
+		return nil, err
+	}
+
+	if err = authService.validateCertificateLifetime(csr.Status.Certificate, time.Now()); err != nil {
+		// the certificate is not handed out, and the CSR is removed so that it cannot be retrieved later
+		if err2 := authService.clientset.CertificatesV1beta1().CertificateSigningRequests().Delete(context.TODO(), csr.Name, metav1.DeleteOptions{}); err2 != nil {
+			klog.Error(err2)
+		}
+		return nil, err
+	}
+	return csr.Status.Certificate, nil
+}
+
+// validateCertificateLifetime checks that the signed certificate does not outlive the maximum lifetime. The CSR API
+// does not let the requester choose the duration of the certificates, which is set by the signing duration of the
+// kube-controller-manager: that duration must not be longer than the maximum lifetime, or no certificate is issued.
+func (authService *AuthServiceCtrl) validateCertificateLifetime(certificate []byte, now time.Time) error {
+	block, _ := pem.Decode(certificate)
+	if block == nil {
+		return errors.New("the signed certificate is not PEM encoded")
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return err
+	}
+	if authService.certificateMaxLifetime > 0 && cert.NotAfter.Sub(now) > authService.certificateMaxLifetime {
+		return fmt.Errorf("the signed certificate expires at %v, after the maximum lifetime of %v: the signing duration of the kube-controller-manager has to be reduced",
+			cert.NotAfter, authService.certificateMaxLifetime)
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package auth_service
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"github.com/stretchr/testify/assert"
+	certificatesv1beta1 "k8s.io/api/certificates/v1beta1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	"math/big"
+	"testing"
+	"time"
+)
+
+func getCertificateRequest(t *testing.T, commonName string) []byte {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+	request, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{Subject: pkix.Name{CommonName: commonName}}, key)
+	assert.Nil(t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: request})
+}
+
+// getSigningClientset returns a clientset which acts as the CSR approver and the signer, issuing certificates with
+// the given lifetime, or denying the CSRs if the lifetime is zero
+func getSigningClientset(t *testing.T, lifetime time.Duration) *fake.Clientset {
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+
+	clientset := fake.NewSimpleClientset()
+	clientset.PrependReactor("create", "certificatesigningrequests", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		csr := action.(k8stesting.CreateAction).GetObject().(*certificatesv1beta1.CertificateSigningRequest)
+		csr.Name = csr.GenerateName + "1"
+		if lifetime == 0 {
+			csr.Status.Conditions = append(csr.Status.Conditions, certificatesv1beta1.CertificateSigningRequestCondition{
+				Type:    certificatesv1beta1.CertificateDenied,
+				Message: "denied",
+			})
+			return false, csr, nil
+		}
+
+		block, _ := pem.Decode(csr.Spec.Request)
+		request, err := x509.ParseCertificateRequest(block.Bytes)
+		assert.Nil(t, err)
+		template := &x509.Certificate{
+			SerialNumber: big.NewInt(1),
+			Subject:      request.Subject,
+			NotBefore:    time.Now(),
+			NotAfter:     time.Now().Add(lifetime),
+			ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		}
+		cert, err := x509.CreateCertificate(rand.Reader, template, template, request.PublicKey, caKey)
+		assert.Nil(t, err)
+		csr.Status.Certificate = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert})
+		return false, csr, nil
+	})
+	return clientset
+}
+
+func TestValidateCertificateRequest(t *testing.T) {
+	assert.Nil(t, validateCertificateRequest(getCertificateRequest(t, "liqo-cluster:cluster-1"), "cluster-1"))
+	assert.True(t, kerrors.IsForbidden(validateCertificateRequest(getCertificateRequest(t, "liqo-cluster:cluster-2"), "cluster-1")))
+	assert.True(t, kerrors.IsForbidden(validateCertificateRequest(getCertificateRequest(t, "cluster-1"), "cluster-1")))
+	assert.True(t, kerrors.IsForbidden(validateCertificateRequest(getCertificateRequest(t, "system:kube-scheduler"), "kube-scheduler")))
+	assert.True(t, kerrors.IsForbidden(validateCertificateRequest([]byte("not a request"), "cluster-1")))
+}
+
+func TestIssueCertificate(t *testing.T) {
+	// the certificate is returned if its lifetime is bounded
+	authService := &AuthServiceCtrl{
+		clientset:              getSigningClientset(t, time.Hour),
+		certificateMaxLifetime: 24 * time.Hour,
+	}
+	certificate, err := authService.issueCertificate("cluster-1", getCertificateRequest(t, "liqo-cluster:cluster-1"))
+	assert.Nil(t, err)
+	block, _ := pem.Decode(certificate)
+	assert.NotNil(t, block)
+	cert, err := x509.ParseCertificate(block.Bytes)
+	assert.Nil(t, err)
+	assert.Equal(t, "liqo-cluster:cluster-1", cert.Subject.CommonName)
+
+	// the certificate is refused if it lives longer than the maximum lifetime, and its CSR is removed
+	clientset := getSigningClientset(t, 48*time.Hour)
+	authService.clientset = clientset
+	_, err = authService.issueCertificate("cluster-1", getCertificateRequest(t, "liqo-cluster:cluster-1"))
+	assert.NotNil(t, err)
+	csrs, err := clientset.CertificatesV1beta1().CertificateSigningRequests().List(context.TODO(), metav1.ListOptions{})
+	assert.Nil(t, err)
+	assert.Empty(t, csrs.Items)
+
+	// the denial of the CSR is reported as forbidden
+	authService.clientset = getSigningClientset(t, 0)
+	_, err = authService.issueCertificate("cluster-1", getCertificateRequest(t, "liqo-cluster:cluster-1"))
+	assert.True(t, kerrors.IsForbidden(err))
+}
@@ -3,12 +3,16 @@ package auth_service
 import (
 	"context"
 	discoveryv1alpha1 "github.com/liqotech/liqo/apis/discovery/v1alpha1"
+	"github.com/liqotech/liqo/pkg/csrApprover"
+	certificatesv1beta1 "k8s.io/api/certificates/v1beta1"
 	v1 "k8s.io/api/core/v1"
 	rbacv1 "k8s.io/api/rbac/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
-func (authService *AuthServiceCtrl) createClusterRole(remoteClusterId string, sa *v1.ServiceAccount) (*rbacv1.ClusterRole, error) {
+// a remote cluster authenticated by a client certificate is also allowed to create the CSRs renewing it, and to get
+// and replace its own CSR only, since the CSRs of the other clusters and of the virtual kubelets are not its business
+func (authService *AuthServiceCtrl) createClusterRole(remoteClusterId string, sa *v1.ServiceAccount, certificateIdentity bool) (*rbacv1.ClusterRole, error) {
 	role := &rbacv1.ClusterRole{
 		ObjectMeta: metav1.ObjectMeta{
 			Name: remoteClusterId,
@@ -35,5 +39,17 @@ func (authService *AuthServiceCtrl) createClusterRole(remoteClusterId string, sa
 			},
 		},
 	}
+	if certificateIdentity {
+		role.Rules = append(role.Rules, rbacv1.PolicyRule{
+			APIGroups: []string{certificatesv1beta1.GroupName},
+			Resources: []string{"certificatesigningrequests"},
+			Verbs:     []string{"create"},
+		}, rbacv1.PolicyRule{
+			APIGroups:     []string{certificatesv1beta1.GroupName},
+			Resources:     []string{"certificatesigningrequests"},
+			Verbs:         []string{"get", "delete"},
+			ResourceNames: []string{csrApprover.IdentityCSRName(remoteClusterId)},
+		})
+	}
 	return authService.clientset.RbacV1().ClusterRoles().Create(context.TODO(), role, metav1.CreateOptions{})
 }
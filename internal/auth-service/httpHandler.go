@@ -4,9 +4,11 @@ import (
 	"encoding/json"
 	"github.com/julienschmidt/httprouter"
 	"github.com/liqotech/liqo/pkg/auth"
+	"github.com/liqotech/liqo/pkg/csrApprover"
 	"io/ioutil"
+	v1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
 	kerrors "k8s.io/apimachinery/pkg/api/errors"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/klog"
 	"net/http"
 )
@@ -27,28 +29,47 @@ func (authService *AuthServiceCtrl) role(w http.ResponseWriter, r *http.Request,
 		return
 	}
 
-	if token, err := authService.getToken(); err != nil {
+	// the cluster ID is part of the names of the resources granted to the remote cluster, and of its identity
+	if err = csrApprover.ValidateClusterID(roleRequest.ClusterID); err != nil {
 		klog.Error(err)
-		authService.handleError(w, err)
+		authService.handleError(w, kerrors.NewBadRequest(err.Error()))
 		return
-	} else if token != roleRequest.Token {
-		err = &kerrors.StatusError{ErrStatus: metav1.Status{
-			Status: metav1.StatusFailure,
-			Code:   http.StatusForbidden,
-			Reason: metav1.StatusReasonForbidden,
-		}}
+	}
+
+	certificateIdentity := len(roleRequest.CertificateSigningRequest) > 0
+	if certificateIdentity {
+		if err = validateCertificateRequest(roleRequest.CertificateSigningRequest, roleRequest.ClusterID); err != nil {
+			klog.Error(err)
+			authService.handleError(w, err)
+			return
+		}
+	}
+
+	tokenName, err := authService.checkToken(roleRequest.Token, roleRequest.ClusterID)
+	if err != nil {
 		klog.Error(err)
 		authService.handleError(w, err)
 		return
 	}
+	// the use of the token is given back if the kubeconfig is not issued, so that the remote cluster can retry
+	issued := false
+	defer func() {
+		if !issued {
+			if err := authService.releaseToken(tokenName); err != nil {
+				klog.Error(err)
+			}
+		}
+	}()
 
-	sa, err := authService.createServiceAccount(roleRequest.ClusterID)
+	sa, err := authService.createServiceAccount(roleRequest.ClusterID, tokenName)
 	if err != nil {
 		klog.Error(err)
 		authService.handleError(w, err)
 		return
 	}
 
+	subjects := getSubjects(sa, roleRequest.ClusterID, certificateIdentity)
+
 	role, err := authService.createRole(roleRequest.ClusterID, sa)
 	if err != nil {
 		klog.Error(err)
@@ -56,21 +77,21 @@ func (authService *AuthServiceCtrl) role(w http.ResponseWriter, r *http.Request,
 		return
 	}
 
-	_, err = authService.createRoleBinding(roleRequest.ClusterID, sa, role)
+	_, err = authService.createRoleBinding(roleRequest.ClusterID, sa, role, subjects)
 	if err != nil {
 		klog.Error(err)
 		authService.handleError(w, err)
 		return
 	}
 
-	clusterRole, err := authService.createClusterRole(roleRequest.ClusterID, sa)
+	clusterRole, err := authService.createClusterRole(roleRequest.ClusterID, sa, certificateIdentity)
 	if err != nil {
 		klog.Error(err)
 		authService.handleError(w, err)
 		return
 	}
 
-	_, err = authService.createClusterRoleBinding(roleRequest.ClusterID, sa, clusterRole)
+	_, err = authService.createClusterRoleBinding(roleRequest.ClusterID, sa, clusterRole, subjects)
 	if err != nil {
 		klog.Error(err)
 		authService.handleError(w, err)
@@ -84,13 +105,22 @@ func (authService *AuthServiceCtrl) role(w http.ResponseWriter, r *http.Request,
 		return
 	}
 
-	kubeconfig, err := authService.createKubeConfig(sa)
+	var kubeconfig string
+	if certificateIdentity {
+		var certificate []byte
+		if certificate, err = authService.issueCertificate(roleRequest.ClusterID, roleRequest.CertificateSigningRequest); err == nil {
+			kubeconfig, err = authService.createCertificateKubeConfig(sa, roleRequest.ClusterID, certificate)
+		}
+	} else {
+		kubeconfig, err = authService.createKubeConfig(sa, roleRequest.ClusterID)
+	}
 	if err != nil {
 		klog.Error(err)
 		authService.handleError(w, err)
 		return
 	}
 
+	issued = true
 	w.WriteHeader(http.StatusCreated)
 	_, err = w.Write([]byte(kubeconfig))
 	if err != nil {
@@ -100,7 +130,15 @@ func (authService *AuthServiceCtrl) role(w http.ResponseWriter, r *http.Request,
 }
 
 func (authService *AuthServiceCtrl) handleError(w http.ResponseWriter, err error) {
-	// TODO: switch on error type
+	// TODO: switch on other error types
+	if kerrors.IsForbidden(err) {
+		authService.sendError(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	if kerrors.IsBadRequest(err) {
+		authService.sendError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 	authService.sendError(w, err.Error(), http.StatusInternalServerError)
 }
 
@@ -116,3 +154,24 @@ func (authService *AuthServiceCtrl) sendError(w http.ResponseWriter, resp interf
 	}
 	http.Error(w, string(bytes), code)
 }
+
+// returns the subjects bound to the roles granted to the remote cluster: the user authenticated by the client
+// certificate if the identity is certificate-based, the ServiceAccount otherwise
+func getSubjects(sa *v1.ServiceAccount, clusterID string, certificateIdentity bool) []rbacv1.Subject {
+	if certificateIdentity {
+		return []rbacv1.Subject{
+			{
+				Kind:     rbacv1.UserKind,
+				APIGroup: rbacv1.GroupName,
+				Name:     csrApprover.IdentityUserName(clusterID),
+			},
+		}
+	}
+	return []rbacv1.Subject{
+		{
+			Kind:      "ServiceAccount",
+			Name:      sa.Name,
+			Namespace: sa.Namespace,
+		},
+	}
+}
@@ -3,6 +3,7 @@ package auth_service
 import (
 	"context"
 	"errors"
+	"github.com/liqotech/liqo/internal/discovery/kubeconfig"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -12,13 +13,51 @@ import (
 	"os"
 )
 
-// this function creates a kube-config file for a specified ServiceAccount
-func (authService *AuthServiceCtrl) createKubeConfig(serviceAccount *v1.ServiceAccount) (string, error) {
+// this function creates a kube-config file for a specified ServiceAccount. The token is not the one generated by
+// Kubernetes, which is revoked when the credentials of the remote cluster are rotated, but is issued in its own
+// Secret, so that it can be revoked without affecting the other credentials of the remote cluster
+func (authService *AuthServiceCtrl) createKubeConfig(serviceAccount *v1.ServiceAccount, clusterID string) (string, error) {
+	rotator := &kubeconfig.CredentialsRotator{
+		Clientset:       authService.clientset,
+		Namespace:       authService.namespace,
+		ServiceAccount:  serviceAccount.Name,
+		RemoteClusterID: clusterID,
+		Consumer:        "discovery",
+		OwnerReferences: []metav1.OwnerReference{
+			{
+				APIVersion: "v1",
+				Kind:       "ServiceAccount",
+				Name:       serviceAccount.Name,
+				UID:        serviceAccount.UID,
+			},
+		},
+	}
+	return rotator.Kubeconfig()
+}
+
+// this function creates a kube-config file authenticating the remote cluster with a client certificate
+func (authService *AuthServiceCtrl) createCertificateKubeConfig(serviceAccount *v1.ServiceAccount, clusterID string, certificate []byte) (string, error) {
 	secret, err := authService.clientset.CoreV1().Secrets(authService.namespace).Get(context.TODO(), serviceAccount.Secrets[0].Name, metav1.GetOptions{})
 	if err != nil {
 		return "", err
 	}
 
+	server, err := authService.getServerURL()
+	if err != nil {
+		return "", err
+	}
+
+	// the private key is not included, since it never leaves the remote cluster
+	cnf := kubeconfigutil.CreateWithCerts(server, "service-cluster", clusterID, secret.Data["ca.crt"], nil, certificate)
+	r, err := runtime.Encode(clientcmdlatest.Codec, cnf)
+	if err != nil {
+		return "", err
+	}
+	return string(r), nil
+}
+
+// returns the URL of the API server reachable by the remote clusters
+func (authService *AuthServiceCtrl) getServerURL() (string, error) {
 	address, ok := os.LookupEnv("APISERVER")
 	if !ok || address == "" {
 		nodes := authService.nodeInformer.GetStore().List()
@@ -35,7 +74,7 @@ func (authService *AuthServiceCtrl) createKubeConfig(serviceAccount *v1.ServiceA
 		}
 
 		if node == nil {
-			err = errors.New("no APISERVER env variable found and no master node found, one of the two values must be present")
+			err := errors.New("no APISERVER env variable found and no master node found, one of the two values must be present")
 			klog.Error(err)
 			return "", err
 		}
@@ -47,13 +86,5 @@ func (authService *AuthServiceCtrl) createKubeConfig(serviceAccount *v1.ServiceA
 		port = "6443"
 	}
 
-	token := string(secret.Data["token"])
-	server := "https://" + address + ":" + port
-
-	cnf := kubeconfigutil.CreateWithToken(server, "service-cluster", serviceAccount.Name, secret.Data["ca.crt"], token)
-	r, err := runtime.Encode(clientcmdlatest.Codec, cnf)
-	if err != nil {
-		return "", err
-	}
-	return string(r), nil
+	return "https://" + address + ":" + port, nil
 }
@@ -29,7 +29,7 @@ func (authService *AuthServiceCtrl) createRole(remoteClusterId string, sa *v1.Se
 			{
 				APIGroups:     []string{v1.SchemeGroupVersion.Group},
 				Resources:     []string{"secrets"},
-				Verbs:         []string{"get", "delete"},
+				Verbs:         []string{"get", "update", "delete"},
 				ResourceNames: []string{remoteClusterId},
 			},
 			{
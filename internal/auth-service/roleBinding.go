@@ -7,7 +7,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
-func (authService *AuthServiceCtrl) createRoleBinding(remoteClusterId string, sa *v1.ServiceAccount, role *rbacv1.Role) (*rbacv1.RoleBinding, error) {
+func (authService *AuthServiceCtrl) createRoleBinding(remoteClusterId string, sa *v1.ServiceAccount, role *rbacv1.Role, subjects []rbacv1.Subject) (*rbacv1.RoleBinding, error) {
 	rb := &rbacv1.RoleBinding{
 		ObjectMeta: metav1.ObjectMeta{
 			Name: remoteClusterId,
@@ -20,13 +20,7 @@ func (authService *AuthServiceCtrl) createRoleBinding(remoteClusterId string, sa
 				},
 			},
 		},
-		Subjects: []rbacv1.Subject{
-			{
-				Kind:      "ServiceAccount",
-				Name:      sa.Name,
-				Namespace: sa.Namespace,
-			},
-		},
+		Subjects: subjects,
 		RoleRef: rbacv1.RoleRef{
 			APIGroup: rbacv1.SchemeGroupVersion.Group,
 			Kind:     "Role",
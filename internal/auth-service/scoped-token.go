@@ -0,0 +1,178 @@
+package auth_service
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	v1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+	"k8s.io/klog"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// A scoped token is a Secret, in the namespace of the auth service, labelled with TokenLabel=true. Its name
+// identifies the token, while its data contains the token itself, generated by the auth service if not provided.
+// The annotations optionally limit the validity of the token, which is revoked by deleting the Secret or
+// removing the label.
+const (
+	TokenLabel = "auth.liqo.io/token"
+	// expiration of the token, in the RFC3339 format
+	TokenExpirationAnnotation = "auth.liqo.io/expiration"
+	// maximum number of ServiceAccounts the token can be used to create
+	TokenMaxUsesAnnotation = "auth.liqo.io/max-uses"
+	// number of times the token has been used, updated by the auth service
+	TokenUsesAnnotation = "auth.liqo.io/uses"
+	// ID of the only remote cluster allowed to use the token
+	TokenClusterIDAnnotation = "auth.liqo.io/cluster-id"
+
+	// annotation set on the ServiceAccounts with the name of the token used to create them
+	ServiceAccountTokenAnnotation = "auth.liqo.io/token-name"
+)
+
+func isScopedToken(secret *v1.Secret) bool {
+	return secret.Labels[TokenLabel] == "true"
+}
+
+// checkToken validates the token of a RoleRequest and returns the name of the token used. A scoped token
+// is consumed, i.e. its counter of uses is incremented, as soon as it is accepted, so that concurrent requests
+// cannot exceed its maximum uses: the use is given back by releaseToken if the request then fails.
+func (authService *AuthServiceCtrl) checkToken(token string, clusterID string) (string, error) {
+	if authService.sharedToken {
+		if sharedToken, err := authService.getToken(); err != nil {
+			klog.Error(err)
+		} else if tokenEquals(sharedToken, token) {
+			return AuthTokenSecretName, nil
+		}
+	}
+
+	for _, obj := range authService.secretInformer.GetStore().List() {
+		secret, ok := obj.(*v1.Secret)
+		if !ok || secret.Namespace != authService.namespace || !isScopedToken(secret) {
+			continue
+		}
+		if tokenEquals(string(secret.Data["token"]), token) {
+			return secret.Name, authService.consumeToken(secret.Name, clusterID)
+		}
+	}
+	return "", forbidden("invalid token")
+}
+
+// consumeToken increments the uses of a scoped token, after checking that it can still be used by the
+// given cluster. The Secret is read from the API server, so that a token is never used more times than allowed.
+func (authService *AuthServiceCtrl) consumeToken(name string, clusterID string) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		secret, err := authService.clientset.CoreV1().Secrets(authService.namespace).Get(context.TODO(), name, metav1.GetOptions{})
+		if kerrors.IsNotFound(err) {
+			return forbidden(fmt.Sprintf("token %s has been revoked", name))
+		} else if err != nil {
+			return err
+		}
+		if !isScopedToken(secret) {
+			return forbidden(fmt.Sprintf("token %s has been revoked", name))
+		}
+		if err = validateScopedToken(secret, clusterID, time.Now()); err != nil {
+			return err
+		}
+
+		uses, _ := strconv.Atoi(secret.Annotations[TokenUsesAnnotation])
+		if secret.Annotations == nil {
+			secret.Annotations = map[string]string{}
+		}
+		secret.Annotations[TokenUsesAnnotation] = strconv.Itoa(uses + 1)
+		_, err = authService.clientset.CoreV1().Secrets(authService.namespace).Update(context.TODO(), secret, metav1.UpdateOptions{})
+		return err
+	})
+}
+
+// releaseToken decrements the uses of a scoped token consumed by a request which has not been fulfilled. The
+// shared token, and the scoped tokens revoked in the meantime, are left untouched.
+func (authService *AuthServiceCtrl) releaseToken(name string) error {
+	if name == AuthTokenSecretName {
+		return nil
+	}
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		secret, err := authService.clientset.CoreV1().Secrets(authService.namespace).Get(context.TODO(), name, metav1.GetOptions{})
+		if kerrors.IsNotFound(err) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		uses, _ := strconv.Atoi(secret.Annotations[TokenUsesAnnotation])
+		if !isScopedToken(secret) || uses <= 0 {
+			return nil
+		}
+		secret.Annotations[TokenUsesAnnotation] = strconv.Itoa(uses - 1)
+		_, err = authService.clientset.CoreV1().Secrets(authService.namespace).Update(context.TODO(), secret, metav1.UpdateOptions{})
+		return err
+	})
+}
+
+// validateScopedToken checks that the token has not expired, is not bound to another cluster and has
+// not been used the maximum number of times.
+func validateScopedToken(secret *v1.Secret, clusterID string, now time.Time) error {
+	if value, ok := secret.Annotations[TokenExpirationAnnotation]; ok {
+		expiration, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return forbidden(fmt.Sprintf("token %s has an invalid expiration: %v", secret.Name, err))
+		}
+		if !now.Before(expiration) {
+			return forbidden(fmt.Sprintf("token %s expired at %s", secret.Name, value))
+		}
+	}
+
+	if value, ok := secret.Annotations[TokenClusterIDAnnotation]; ok && value != clusterID {
+		return forbidden(fmt.Sprintf("token %s cannot be used by cluster %s", secret.Name, clusterID))
+	}
+
+	if value, ok := secret.Annotations[TokenMaxUsesAnnotation]; ok {
+		maxUses, err := strconv.Atoi(value)
+		if err != nil {
+			return forbidden(fmt.Sprintf("token %s has an invalid maximum number of uses: %v", secret.Name, err))
+		}
+		uses, _ := strconv.Atoi(secret.Annotations[TokenUsesAnnotation])
+		if uses >= maxUses {
+			return forbidden(fmt.Sprintf("token %s has already been used %d times", secret.Name, uses))
+		}
+	}
+	return nil
+}
+
+// initScopedToken generates the token of a labelled Secret created without it.
+func (authService *AuthServiceCtrl) initScopedToken(secret *v1.Secret) error {
+	if !isScopedToken(secret) || len(secret.Data["token"]) > 0 {
+		return nil
+	}
+	token, err := generateToken()
+	if err != nil {
+		return err
+	}
+
+	secret = secret.DeepCopy()
+	if secret.Data == nil {
+		secret.Data = map[string][]byte{}
+	}
+	secret.Data["token"] = []byte(token)
+	_, err = authService.clientset.CoreV1().Secrets(authService.namespace).Update(context.TODO(), secret, metav1.UpdateOptions{})
+	if err != nil && !kerrors.IsConflict(err) {
+		klog.Error(err)
+		return err
+	}
+	return nil
+}
+
+func tokenEquals(expected string, token string) bool {
+	return expected != "" && subtle.ConstantTimeCompare([]byte(expected), []byte(token)) == 1
+}
+
+func forbidden(message string) error {
+	return &kerrors.StatusError{ErrStatus: metav1.Status{
+		Status:  metav1.StatusFailure,
+		Code:    http.StatusForbidden,
+		Reason:  metav1.StatusReasonForbidden,
+		Message: message,
+	}}
+}
@@ -0,0 +1,118 @@
+package auth_service
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
+	"testing"
+	"time"
+)
+
+func getTokenSecret(name string, token string, annotations map[string]string) *v1.Secret {
+	return &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Namespace:   "liqo",
+			Labels:      map[string]string{TokenLabel: "true"},
+			Annotations: annotations,
+		},
+		Data: map[string][]byte{"token": []byte(token)},
+	}
+}
+
+func TestValidateScopedToken(t *testing.T) {
+	now := time.Date(2021, time.January, 4, 10, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name        string
+		annotations map[string]string
+		valid       bool
+	}{
+		{name: "no limits", valid: true},
+		{name: "not expired", annotations: map[string]string{TokenExpirationAnnotation: "2021-01-05T00:00:00Z"}, valid: true},
+		{name: "expired", annotations: map[string]string{TokenExpirationAnnotation: "2021-01-04T09:00:00Z"}},
+		{name: "invalid expiration", annotations: map[string]string{TokenExpirationAnnotation: "tomorrow"}},
+		{name: "bound cluster", annotations: map[string]string{TokenClusterIDAnnotation: "cluster-1"}, valid: true},
+		{name: "other cluster", annotations: map[string]string{TokenClusterIDAnnotation: "cluster-2"}},
+		{name: "uses left", annotations: map[string]string{TokenMaxUsesAnnotation: "2", TokenUsesAnnotation: "1"}, valid: true},
+		{name: "uses exhausted", annotations: map[string]string{TokenMaxUsesAnnotation: "2", TokenUsesAnnotation: "2"}},
+		{name: "invalid max uses", annotations: map[string]string{TokenMaxUsesAnnotation: "many"}},
+	}
+
+	for _, tc := range testCases {
+		err := validateScopedToken(getTokenSecret("token", "secret", tc.annotations), "cluster-1", now)
+		if tc.valid {
+			assert.Nil(t, err, tc.name)
+		} else {
+			assert.True(t, kerrors.IsForbidden(err), tc.name)
+		}
+	}
+}
+
+func TestCheckToken(t *testing.T) {
+	clientset := fake.NewSimpleClientset(
+		getTokenSecret("partner-1", "token-1", map[string]string{TokenMaxUsesAnnotation: "1"}),
+		getTokenSecret("partner-2", "token-2", map[string]string{TokenClusterIDAnnotation: "cluster-2"}),
+		&v1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: AuthTokenSecretName, Namespace: "liqo"},
+			Data:       map[string][]byte{"token": []byte("shared")},
+		},
+	)
+	informerFactory := informers.NewSharedInformerFactoryWithOptions(clientset, 0, informers.WithNamespace("liqo"))
+	secretInformer := informerFactory.Core().V1().Secrets().Informer()
+	stop := make(chan struct{})
+	defer close(stop)
+	informerFactory.Start(stop)
+	informerFactory.WaitForCacheSync(stop)
+
+	authService := &AuthServiceCtrl{
+		namespace:      "liqo",
+		clientset:      clientset,
+		secretInformer: secretInformer,
+		sharedToken:    true,
+	}
+
+	name, err := authService.checkToken("shared", "cluster-1")
+	assert.Nil(t, err)
+	assert.Equal(t, AuthTokenSecretName, name)
+
+	name, err = authService.checkToken("token-1", "cluster-1")
+	assert.Nil(t, err)
+	assert.Equal(t, "partner-1", name)
+	secret, err := clientset.CoreV1().Secrets("liqo").Get(context.TODO(), "partner-1", metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, "1", secret.Annotations[TokenUsesAnnotation])
+
+	// the token can be used only once
+	_, err = authService.checkToken("token-1", "cluster-3")
+	assert.True(t, kerrors.IsForbidden(err))
+
+	// the use is given back if the request fails, and the token can be used again
+	assert.Nil(t, authService.releaseToken("partner-1"))
+	secret, err = clientset.CoreV1().Secrets("liqo").Get(context.TODO(), "partner-1", metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, "0", secret.Annotations[TokenUsesAnnotation])
+	name, err = authService.checkToken("token-1", "cluster-3")
+	assert.Nil(t, err)
+	assert.Equal(t, "partner-1", name)
+	assert.Nil(t, authService.releaseToken(AuthTokenSecretName))
+
+	// the token is bound to another cluster
+	_, err = authService.checkToken("token-2", "cluster-1")
+	assert.True(t, kerrors.IsForbidden(err))
+	name, err = authService.checkToken("token-2", "cluster-2")
+	assert.Nil(t, err)
+	assert.Equal(t, "partner-2", name)
+
+	_, err = authService.checkToken("unknown", "cluster-1")
+	assert.True(t, kerrors.IsForbidden(err))
+
+	// the shared token can be disabled
+	authService.sharedToken = false
+	_, err = authService.checkToken("shared", "cluster-1")
+	assert.True(t, kerrors.IsForbidden(err))
+}
@@ -75,10 +75,14 @@ func (authService *AuthServiceCtrl) getServiceAccount(remoteClusterId string) (*
 	return sa, nil
 }
 
-func (authService *AuthServiceCtrl) createServiceAccount(remoteClusterId string) (*v1.ServiceAccount, error) {
+// the ServiceAccount records the name of the token used by the remote cluster to request it
+func (authService *AuthServiceCtrl) createServiceAccount(remoteClusterId string, tokenName string) (*v1.ServiceAccount, error) {
 	sa := &v1.ServiceAccount{
 		ObjectMeta: metav1.ObjectMeta{
 			Name: remoteClusterId,
+			Annotations: map[string]string{
+				ServiceAccountTokenAnnotation: tokenName,
+			},
 		},
 	}
 	return authService.clientset.CoreV1().ServiceAccounts(authService.namespace).Create(context.TODO(), sa, metav1.CreateOptions{})
@@ -0,0 +1,60 @@
+package foreign_cluster_operator
+
+import (
+	"context"
+	goerrors "errors"
+	discoveryv1alpha1 "github.com/liqotech/liqo/apis/discovery/v1alpha1"
+	"github.com/liqotech/liqo/internal/discovery/kubeconfig"
+	"github.com/liqotech/liqo/pkg/crdClient"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// returns the rotator of the credentials granted to the foreign cluster, which are sent to it by updating the secret
+// referenced by the PeeringRequest. The broadcaster of the foreign cluster reloads them from that secret
+func (r *ForeignClusterReconciler) getCredentialsRotator(fc *discoveryv1alpha1.ForeignCluster, foreignClient *crdClient.CRDClient) *kubeconfig.CredentialsRotator {
+	return &kubeconfig.CredentialsRotator{
+		Clientset:       r.crdClient.Client(),
+		Namespace:       r.Namespace,
+		ServiceAccount:  fc.Name,
+		RemoteClusterID: fc.Spec.ClusterIdentity.ClusterID,
+		Consumer:        "advertisement-broadcaster",
+		OwnerReferences: []metav1.OwnerReference{
+			{
+				APIVersion: discoveryv1alpha1.GroupVersion.String(),
+				Kind:       "ForeignCluster",
+				Name:       fc.Name,
+				UID:        fc.UID,
+			},
+		},
+		Period:      r.CredentialsRotationPeriod,
+		GracePeriod: r.CredentialsGracePeriod,
+		Push: func(config string) error {
+			return pushPeeringRequestKubeconfig(foreignClient, fc.Status.Outgoing.RemotePeeringRequestName, config)
+		},
+	}
+}
+
+func pushPeeringRequestKubeconfig(foreignClient *crdClient.CRDClient, peeringRequestName string, config string) error {
+	tmp, err := foreignClient.Resource("peeringrequests").Get(peeringRequestName, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	pr, ok := tmp.(*discoveryv1alpha1.PeeringRequest)
+	if !ok {
+		return goerrors.New("retrieved object is not a PeeringRequest")
+	}
+	if pr.Spec.KubeConfigRef == nil {
+		return goerrors.New("PeeringRequest " + pr.Name + " has no kubeconfig secret")
+	}
+
+	secret, err := foreignClient.Client().CoreV1().Secrets(pr.Spec.KubeConfigRef.Namespace).Get(context.TODO(), pr.Spec.KubeConfigRef.Name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	if secret.Data == nil {
+		secret.Data = map[string][]byte{}
+	}
+	secret.Data["kubeconfig"] = []byte(config)
+	_, err = foreignClient.Client().CoreV1().Secrets(secret.Namespace).Update(context.TODO(), secret, metav1.UpdateOptions{})
+	return err
+}
@@ -53,6 +53,9 @@ type ForeignClusterReconciler struct {
 	networkClient       *crdClient.CRDClient
 	clusterID           *clusterID.ClusterID
 	RequeueAfter        time.Duration
+	// rotation of the credentials granted to the foreign clusters, disabled if the period is zero
+	CredentialsRotationPeriod time.Duration
+	CredentialsGracePeriod    time.Duration
 
 	DiscoveryCtrl *discovery.DiscoveryCtrl
 
@@ -337,7 +340,19 @@ func (r *ForeignClusterReconciler) Reconcile(req ctrl.Request) (ctrl.Result, err
 
 	// check if peering request really exists on foreign cluster
 	if fc.Spec.Join && fc.Status.Outgoing.Joined {
-		_, err = r.checkJoined(fc, foreignDiscoveryClient)
+		fc, err = r.checkJoined(fc, foreignDiscoveryClient)
+		if err != nil {
+			klog.Error(err, err.Error())
+			return ctrl.Result{
+				Requeue:      true,
+				RequeueAfter: r.RequeueAfter,
+			}, err
+		}
+	}
+
+	// replace the credentials granted to the foreign cluster when they get too old
+	if fc.Spec.Join && fc.Status.Outgoing.Joined && r.CredentialsRotationPeriod > 0 {
+		err = r.getCredentialsRotator(fc, foreignDiscoveryClient).Rotate(time.Now())
 		if err != nil {
 			klog.Error(err, err.Error())
 			return ctrl.Result{
@@ -631,6 +646,10 @@ func (r *ForeignClusterReconciler) getForeignConfig(clusterID string, owner *dis
 			}
 		}
 	}
+	if r.CredentialsRotationPeriod > 0 {
+		// the foreign cluster gets its own credentials, which are then periodically rotated
+		return r.getCredentialsRotator(owner, nil).Kubeconfig()
+	}
 	cnf, err := kubeconfig.CreateKubeConfig(r.crdClient.Client(), clusterID, r.Namespace)
 	return cnf, err
 }
@@ -26,7 +26,7 @@ func init() {
 	// +kubebuilder:scaffold:scheme
 }
 
-func StartOperator(mgr *manager.Manager, namespace string, requeueAfter time.Duration, discoveryCtrl *discovery.DiscoveryCtrl, kubeconfigPath string) {
+func StartOperator(mgr *manager.Manager, namespace string, requeueAfter time.Duration, credentialsRotationPeriod time.Duration, credentialsGracePeriod time.Duration, discoveryCtrl *discovery.DiscoveryCtrl, kubeconfigPath string) {
 	config, err := crdClient.NewKubeconfig(kubeconfigPath, &discoveryv1alpha1.GroupVersion)
 	if err != nil {
 		klog.Error(err, "unable to get kube config")
@@ -55,7 +55,7 @@ func StartOperator(mgr *manager.Manager, namespace string, requeueAfter time.Dur
 		os.Exit(1)
 	}
 
-	if err = (GetFCReconciler(
+	reconciler := GetFCReconciler(
 		(*mgr).GetScheme(),
 		namespace,
 		discoveryClient,
@@ -64,7 +64,10 @@ func StartOperator(mgr *manager.Manager, namespace string, requeueAfter time.Dur
 		clusterId,
 		requeueAfter,
 		discoveryCtrl,
-	)).SetupWithManager(*mgr); err != nil {
+	)
+	reconciler.CredentialsRotationPeriod = credentialsRotationPeriod
+	reconciler.CredentialsGracePeriod = credentialsGracePeriod
+	if err = reconciler.SetupWithManager(*mgr); err != nil {
 		klog.Error(err, "unable to create controller", "controller", "ForeignCluster")
 		os.Exit(1)
 	}
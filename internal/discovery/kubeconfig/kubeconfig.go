@@ -3,6 +3,7 @@ package kubeconfig
 import (
 	"context"
 	"errors"
+	corev1 "k8s.io/api/core/v1"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/kubernetes"
@@ -23,6 +24,11 @@ func CreateKubeConfig(clientset kubernetes.Interface, serviceAccountName string,
 	if err != nil {
 		return "", err
 	}
+	return CreateKubeConfigFromSecret(clientset, serviceAccountName, secret)
+}
+
+// this function creates a kube-config file for a specified ServiceAccount, using the token stored in the given Secret
+func CreateKubeConfigFromSecret(clientset kubernetes.Interface, serviceAccountName string, secret *corev1.Secret) (string, error) {
 
 	address, ok := os.LookupEnv("APISERVER")
 	if !ok || address == "" {
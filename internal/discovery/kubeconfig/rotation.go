@@ -0,0 +1,306 @@
+package kubeconfig
+
+import (
+	"context"
+	"fmt"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog"
+	"time"
+)
+
+const (
+	// labels set on the Secrets containing the credentials issued for a remote cluster
+	CredentialsClusterIDLabel      = "credentials.liqo.io/cluster-id"
+	CredentialsServiceAccountLabel = "credentials.liqo.io/service-account"
+	CredentialsConsumerLabel       = "credentials.liqo.io/consumer"
+	// annotation set on the credentials replaced by newer ones, with the time of the replacement
+	CredentialsSupersededAnnotation = "credentials.liqo.io/superseded-at"
+	// annotation set on the new credentials until the remote cluster has received them
+	CredentialsPendingAnnotation = "credentials.liqo.io/pending"
+
+	// interval between two checks of the age of the credentials
+	rotationCheckPeriod = time.Minute
+	// maximum time waited for the token controller to populate a new token
+	tokenCreationTimeout = 30 * time.Second
+)
+
+// builds the kubeconfig containing the credentials, replaceable in tests
+var createKubeConfigFromSecret = CreateKubeConfigFromSecret
+
+// CredentialsRotator issues the credentials granted to a remote cluster and periodically replaces them.
+// Each credential is a token of the ServiceAccount stored in its own Secret, so that it can be revoked, once the
+// remote cluster has received the new one, by deleting the Secret without affecting the other tokens. The tokens
+// generated by Kubernetes for the ServiceAccount are revoked as well, once the remote cluster uses the issued ones.
+type CredentialsRotator struct {
+	Clientset       kubernetes.Interface
+	Namespace       string
+	ServiceAccount  string
+	RemoteClusterID string
+	// component of the remote cluster using the credentials, which keeps them apart from the other credentials
+	// granted with the same ServiceAccount
+	Consumer string
+	// owners of the Secrets, which are garbage collected when the peering ends
+	OwnerReferences []metav1.OwnerReference
+	// age after which the credentials are replaced, rotation is disabled if zero
+	Period time.Duration
+	// time the replaced credentials are still accepted, to let the remote cluster reload the new ones
+	GracePeriod time.Duration
+	// sends the kubeconfig containing the new credentials to the remote cluster
+	Push func(kubeconfig string) error
+}
+
+// Kubeconfig returns a kubeconfig containing the current credentials, which are issued if they do not exist yet.
+func (r *CredentialsRotator) Kubeconfig() (string, error) {
+	secrets, err := r.listCredentials()
+	if err != nil {
+		return "", err
+	}
+	secret := getCurrentCredentials(secrets)
+	if secret == nil {
+		if secret, err = r.issueCredentials(false); err != nil {
+			return "", err
+		}
+	}
+	return createKubeConfigFromSecret(r.Clientset, r.ServiceAccount, secret)
+}
+
+// Rotate issues new credentials if the current ones are older than the rotation period and pushes them to the
+// remote cluster. Then the replaced credentials are revoked once their grace period has elapsed.
+func (r *CredentialsRotator) Rotate(now time.Time) error {
+	secrets, err := r.listCredentials()
+	if err != nil {
+		return err
+	}
+
+	legacy, err := r.listLegacyTokens()
+	if err != nil {
+		return err
+	}
+
+	// revoke the credentials replaced for longer than the grace period
+	for _, secret := range append(secrets, legacy...) {
+		supersededAt, ok := secret.Annotations[CredentialsSupersededAnnotation]
+		if !ok {
+			continue
+		}
+		if t, err := time.Parse(time.RFC3339, supersededAt); err == nil && now.Sub(t) < r.GracePeriod {
+			continue
+		}
+		err = r.Clientset.CoreV1().Secrets(r.Namespace).Delete(context.TODO(), secret.Name, metav1.DeleteOptions{})
+		if err != nil && !k8serrors.IsNotFound(err) {
+			return err
+		}
+		klog.Infof("credentials %s of cluster %s revoked", secret.Name, r.RemoteClusterID)
+	}
+
+	// a previous rotation has been interrupted before the remote cluster confirmed the new credentials
+	if pending := getPendingCredentials(secrets); pending != nil {
+		return r.pushCredentials(pending, secrets, now)
+	}
+
+	current := getCurrentCredentials(secrets)
+	// the credentials replaced by the current ones are marked, also if marking them failed in a previous rotation
+	if err = r.supersede(secrets, current, now); err != nil {
+		return err
+	}
+	if current != nil && (r.Period <= 0 || now.Sub(current.CreationTimestamp.Time) < r.Period) {
+		return nil
+	}
+
+	secret, err := r.issueCredentials(true)
+	if err != nil {
+		return err
+	}
+	return r.pushCredentials(secret, secrets, now)
+}
+
+// pushCredentials sends the pending credentials to the remote cluster, and then makes them the current ones.
+func (r *CredentialsRotator) pushCredentials(secret *corev1.Secret, secrets []corev1.Secret, now time.Time) error {
+	kubeconfig, err := createKubeConfigFromSecret(r.Clientset, r.ServiceAccount, secret)
+	if err == nil {
+		err = r.Push(kubeconfig)
+	}
+	if err != nil {
+		// the remote cluster keeps using the current credentials, the rotation is retried later
+		if err2 := r.Clientset.CoreV1().Secrets(r.Namespace).Delete(context.TODO(), secret.Name, metav1.DeleteOptions{}); err2 != nil {
+			klog.Error(err2)
+		}
+		return err
+	}
+
+	// if the confirmation fails, the credentials are pushed again by the next rotation
+	confirmed := secret.DeepCopy()
+	delete(confirmed.Annotations, CredentialsPendingAnnotation)
+	if confirmed, err = r.Clientset.CoreV1().Secrets(r.Namespace).Update(context.TODO(), confirmed, metav1.UpdateOptions{}); err != nil {
+		return err
+	}
+	klog.Infof("credentials of cluster %s rotated, new credentials %s", r.RemoteClusterID, confirmed.Name)
+
+	return r.supersede(secrets, confirmed, now)
+}
+
+// supersede marks as superseded the credentials replaced by the current ones, so that they are revoked after the
+// grace period. The tokens generated by Kubernetes for the ServiceAccount before the current credentials are
+// superseded as well, since they may have been granted before the rotation was enabled.
+func (r *CredentialsRotator) supersede(secrets []corev1.Secret, current *corev1.Secret, now time.Time) error {
+	if current == nil {
+		return nil
+	}
+	legacy, err := r.listLegacyTokens()
+	if err != nil {
+		return err
+	}
+	var replaced []corev1.Secret
+	for i := range legacy {
+		// the tokens generated by Kubernetes after the current credentials have never been granted
+		if legacy[i].CreationTimestamp.Before(&current.CreationTimestamp) {
+			replaced = append(replaced, legacy[i])
+		}
+	}
+	for i := range secrets {
+		if _, pending := secrets[i].Annotations[CredentialsPendingAnnotation]; !pending && secrets[i].Name != current.Name {
+			replaced = append(replaced, secrets[i])
+		}
+	}
+
+	for _, secret := range replaced {
+		if _, ok := secret.Annotations[CredentialsSupersededAnnotation]; ok {
+			continue
+		}
+		superseded := secret.DeepCopy()
+		if superseded.Annotations == nil {
+			superseded.Annotations = map[string]string{}
+		}
+		superseded.Annotations[CredentialsSupersededAnnotation] = now.UTC().Format(time.RFC3339)
+		_, err = r.Clientset.CoreV1().Secrets(r.Namespace).Update(context.TODO(), superseded, metav1.UpdateOptions{})
+		if err != nil && !k8serrors.IsNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// Run rotates the credentials until the stop channel is closed. It returns immediately if rotation is disabled.
+func (r *CredentialsRotator) Run(stop <-chan struct{}) {
+	if r.Period <= 0 {
+		return
+	}
+	wait.Until(func() {
+		if err := r.Rotate(time.Now()); err != nil {
+			klog.Errorf("unable to rotate the credentials of cluster %s: %v", r.RemoteClusterID, err)
+		}
+	}, rotationCheckPeriod, stop)
+}
+
+func (r *CredentialsRotator) listCredentials() ([]corev1.Secret, error) {
+	secrets, err := r.Clientset.CoreV1().Secrets(r.Namespace).List(context.TODO(), metav1.ListOptions{
+		LabelSelector: labels.SelectorFromSet(r.getLabels()).String(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return secrets.Items, nil
+}
+
+// listLegacyTokens returns the tokens of the ServiceAccount not issued by a CredentialsRotator, i.e. the ones
+// generated by Kubernetes.
+func (r *CredentialsRotator) listLegacyTokens() ([]corev1.Secret, error) {
+	secrets, err := r.Clientset.CoreV1().Secrets(r.Namespace).List(context.TODO(), metav1.ListOptions{
+		FieldSelector: fields.OneTermEqualSelector("type", string(corev1.SecretTypeServiceAccountToken)).String(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	var legacy []corev1.Secret
+	for i := range secrets.Items {
+		secret := &secrets.Items[i]
+		if _, issued := secret.Labels[CredentialsClusterIDLabel]; issued || secret.Type != corev1.SecretTypeServiceAccountToken ||
+			secret.Annotations[corev1.ServiceAccountNameKey] != r.ServiceAccount {
+			continue
+		}
+		legacy = append(legacy, *secret)
+	}
+	return legacy, nil
+}
+
+// issueCredentials creates a new token of the ServiceAccount, and waits for the token controller to populate it.
+// The pending credentials are not used until the remote cluster has received them.
+func (r *CredentialsRotator) issueCredentials(pending bool) (*corev1.Secret, error) {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName:    r.ServiceAccount + "-token-",
+			Namespace:       r.Namespace,
+			Labels:          r.getLabels(),
+			OwnerReferences: r.OwnerReferences,
+			Annotations: map[string]string{
+				corev1.ServiceAccountNameKey: r.ServiceAccount,
+			},
+		},
+		Type: corev1.SecretTypeServiceAccountToken,
+	}
+	if pending {
+		secret.Annotations[CredentialsPendingAnnotation] = "true"
+	}
+	secret, err := r.Clientset.CoreV1().Secrets(r.Namespace).Create(context.TODO(), secret, metav1.CreateOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	name := secret.Name
+	err = wait.PollImmediate(500*time.Millisecond, tokenCreationTimeout, func() (bool, error) {
+		secret, err = r.Clientset.CoreV1().Secrets(r.Namespace).Get(context.TODO(), name, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+		return len(secret.Data[corev1.ServiceAccountTokenKey]) > 0, nil
+	})
+	if err != nil {
+		if err2 := r.Clientset.CoreV1().Secrets(r.Namespace).Delete(context.TODO(), name, metav1.DeleteOptions{}); err2 != nil {
+			klog.Error(err2)
+		}
+		return nil, fmt.Errorf("token of ServiceAccount %s not populated: %w", r.ServiceAccount, err)
+	}
+	return secret, nil
+}
+
+func (r *CredentialsRotator) getLabels() map[string]string {
+	return map[string]string{
+		CredentialsClusterIDLabel:      r.RemoteClusterID,
+		CredentialsServiceAccountLabel: r.ServiceAccount,
+		CredentialsConsumerLabel:       r.Consumer,
+	}
+}
+
+// getCurrentCredentials returns the most recent credentials received by the remote cluster and not yet replaced.
+func getCurrentCredentials(secrets []corev1.Secret) *corev1.Secret {
+	var current *corev1.Secret
+	for i := range secrets {
+		_, superseded := secrets[i].Annotations[CredentialsSupersededAnnotation]
+		_, pending := secrets[i].Annotations[CredentialsPendingAnnotation]
+		if superseded || pending || len(secrets[i].Data[corev1.ServiceAccountTokenKey]) == 0 {
+			continue
+		}
+		if current == nil || current.CreationTimestamp.Before(&secrets[i].CreationTimestamp) {
+			current = &secrets[i]
+		}
+	}
+	return current
+}
+
+// getPendingCredentials returns the credentials issued by an interrupted rotation, if any.
+func getPendingCredentials(secrets []corev1.Secret) *corev1.Secret {
+	for i := range secrets {
+		_, superseded := secrets[i].Annotations[CredentialsSupersededAnnotation]
+		_, pending := secrets[i].Annotations[CredentialsPendingAnnotation]
+		if pending && !superseded && len(secrets[i].Data[corev1.ServiceAccountTokenKey]) > 0 {
+			return &secrets[i]
+		}
+	}
+	return nil
+}
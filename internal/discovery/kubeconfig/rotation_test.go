@@ -0,0 +1,203 @@
+package kubeconfig
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	"testing"
+	"time"
+)
+
+func TestCredentialsRotator(t *testing.T) {
+	createKubeConfigFromSecret = func(_ kubernetes.Interface, serviceAccountName string, secret *corev1.Secret) (string, error) {
+		return serviceAccountName + ":" + string(secret.Data[corev1.ServiceAccountTokenKey]), nil
+	}
+	defer func() { createKubeConfigFromSecret = CreateKubeConfigFromSecret }()
+
+	now := time.Date(2021, time.January, 4, 10, 0, 0, 0, time.UTC)
+	issued := 0
+	// the token generated by Kubernetes for the ServiceAccount, granted before the rotation was enabled
+	legacyToken := func(name string, created time.Time) *corev1.Secret {
+		return &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              name,
+				Namespace:         "liqo",
+				CreationTimestamp: metav1.NewTime(created),
+				Annotations:       map[string]string{corev1.ServiceAccountNameKey: "vk-remote"},
+			},
+			Type: corev1.SecretTypeServiceAccountToken,
+			Data: map[string][]byte{corev1.ServiceAccountTokenKey: []byte(name)},
+		}
+	}
+	clientset := fake.NewSimpleClientset(legacyToken("vk-remote-token-legacy", now.Add(-time.Hour)))
+	// act as the token controller, which populates the tokens of the ServiceAccounts
+	clientset.PrependReactor("create", "secrets", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		secret := action.(k8stesting.CreateAction).GetObject().(*corev1.Secret)
+		if secret.GenerateName == "" {
+			return false, nil, nil
+		}
+		issued++
+		secret.Name = fmt.Sprintf("%s%d", secret.GenerateName, issued)
+		secret.CreationTimestamp = metav1.NewTime(now)
+		secret.Data = map[string][]byte{corev1.ServiceAccountTokenKey: []byte(secret.Name)}
+		return false, secret, nil
+	})
+	var updateErr error
+	clientset.PrependReactor("update", "secrets", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return updateErr != nil, nil, updateErr
+	})
+
+	var pushed []string
+	var pushErr error
+	rotator := &CredentialsRotator{
+		Clientset:       clientset,
+		Namespace:       "liqo",
+		ServiceAccount:  "vk-remote",
+		RemoteClusterID: "cluster-1",
+		Consumer:        "virtual-kubelet",
+		Period:          time.Hour,
+		GracePeriod:     10 * time.Minute,
+		Push: func(kubeconfig string) error {
+			pushed = append(pushed, kubeconfig)
+			return pushErr
+		},
+	}
+	getSecrets := func() map[string]corev1.Secret {
+		secrets, err := clientset.CoreV1().Secrets("liqo").List(context.TODO(), metav1.ListOptions{})
+		assert.Nil(t, err)
+		res := map[string]corev1.Secret{}
+		for i := range secrets.Items {
+			res[secrets.Items[i].Name] = secrets.Items[i]
+		}
+		return res
+	}
+	isSuperseded := func(name string) bool {
+		_, superseded := getSecrets()[name].Annotations[CredentialsSupersededAnnotation]
+		return superseded
+	}
+
+	// the first credentials are issued on demand, and then reused
+	config, err := rotator.Kubeconfig()
+	assert.Nil(t, err)
+	assert.Contains(t, config, "vk-remote-token-1")
+	config, err = rotator.Kubeconfig()
+	assert.Nil(t, err)
+	assert.Contains(t, config, "vk-remote-token-1")
+	assert.Len(t, getSecrets(), 2)
+	assert.Equal(t, "cluster-1", getSecrets()["vk-remote-token-1"].Labels[CredentialsClusterIDLabel])
+	assert.Equal(t, "virtual-kubelet", getSecrets()["vk-remote-token-1"].Labels[CredentialsConsumerLabel])
+
+	// the credentials are not rotated before the period, while the token generated by Kubernetes is superseded
+	assert.Nil(t, rotator.Rotate(now.Add(30*time.Minute)))
+	assert.Empty(t, pushed)
+	assert.True(t, isSuperseded("vk-remote-token-legacy"))
+	assert.False(t, isSuperseded("vk-remote-token-1"))
+	assert.Nil(t, rotator.Rotate(now.Add(40*time.Minute)))
+	assert.NotContains(t, getSecrets(), "vk-remote-token-legacy")
+
+	// the token generated again by Kubernetes has never been granted, and is left untouched
+	_, err = clientset.CoreV1().Secrets("liqo").Create(context.TODO(), legacyToken("vk-remote-token-new", now.Add(time.Hour)), metav1.CreateOptions{})
+	assert.Nil(t, err)
+	assert.Nil(t, rotator.Rotate(now.Add(50*time.Minute)))
+	assert.False(t, isSuperseded("vk-remote-token-new"))
+
+	// the new credentials are not kept if they cannot be pushed
+	pushErr = errors.New("foreign cluster unreachable")
+	now = now.Add(2 * time.Hour)
+	assert.NotNil(t, rotator.Rotate(now))
+	assert.Len(t, pushed, 1)
+	assert.Len(t, getSecrets(), 2)
+
+	// the new credentials are pushed, but they cannot be confirmed: the old ones are still the current ones
+	pushErr = nil
+	updateErr = errors.New("conflict")
+	assert.NotNil(t, rotator.Rotate(now))
+	assert.Len(t, pushed, 2)
+	assert.Contains(t, pushed[1], "vk-remote-token-3")
+	config, err = rotator.Kubeconfig()
+	assert.Nil(t, err)
+	assert.Contains(t, config, "vk-remote-token-1")
+
+	// the interrupted rotation is completed by pushing the same credentials again, and the old ones are superseded
+	updateErr = nil
+	assert.Nil(t, rotator.Rotate(now))
+	assert.Len(t, pushed, 3)
+	assert.Contains(t, pushed[2], "vk-remote-token-3")
+	assert.True(t, isSuperseded("vk-remote-token-1"))
+	assert.True(t, isSuperseded("vk-remote-token-new"))
+	assert.False(t, isSuperseded("vk-remote-token-3"))
+	config, err = rotator.Kubeconfig()
+	assert.Nil(t, err)
+	assert.Contains(t, config, "vk-remote-token-3")
+
+	// the old credentials are revoked after the grace period
+	assert.Nil(t, rotator.Rotate(now.Add(5*time.Minute)))
+	assert.Len(t, getSecrets(), 3)
+	assert.Nil(t, rotator.Rotate(now.Add(10*time.Minute)))
+	assert.Len(t, getSecrets(), 1)
+	assert.Contains(t, getSecrets(), "vk-remote-token-3")
+	assert.Len(t, pushed, 3)
+}
+
+func TestCredentialsRotatorSupersedeRetry(t *testing.T) {
+	createKubeConfigFromSecret = func(_ kubernetes.Interface, serviceAccountName string, secret *corev1.Secret) (string, error) {
+		return serviceAccountName + ":" + string(secret.Data[corev1.ServiceAccountTokenKey]), nil
+	}
+	defer func() { createKubeConfigFromSecret = CreateKubeConfigFromSecret }()
+
+	now := time.Date(2021, time.January, 4, 10, 0, 0, 0, time.UTC)
+	issued := 0
+	clientset := fake.NewSimpleClientset()
+	clientset.PrependReactor("create", "secrets", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		secret := action.(k8stesting.CreateAction).GetObject().(*corev1.Secret)
+		issued++
+		secret.Name = fmt.Sprintf("%s%d", secret.GenerateName, issued)
+		secret.CreationTimestamp = metav1.NewTime(now)
+		secret.Data = map[string][]byte{corev1.ServiceAccountTokenKey: []byte(secret.Name)}
+		return false, secret, nil
+	})
+	// the marking of the replaced credentials fails
+	failSupersede := true
+	clientset.PrependReactor("update", "secrets", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		secret := action.(k8stesting.UpdateAction).GetObject().(*corev1.Secret)
+		if _, ok := secret.Annotations[CredentialsSupersededAnnotation]; ok && failSupersede {
+			return true, nil, errors.New("conflict")
+		}
+		return false, nil, nil
+	})
+
+	rotator := &CredentialsRotator{
+		Clientset:       clientset,
+		Namespace:       "liqo",
+		ServiceAccount:  "vk-remote",
+		RemoteClusterID: "cluster-1",
+		Period:          time.Hour,
+		GracePeriod:     10 * time.Minute,
+		Push:            func(string) error { return nil },
+	}
+	_, err := rotator.Kubeconfig()
+	assert.Nil(t, err)
+
+	now = now.Add(2 * time.Hour)
+	assert.NotNil(t, rotator.Rotate(now))
+
+	// the next rotation marks the replaced credentials, which are then revoked
+	failSupersede = false
+	assert.Nil(t, rotator.Rotate(now))
+	secret, err := clientset.CoreV1().Secrets("liqo").Get(context.TODO(), "vk-remote-token-1", metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Contains(t, secret.Annotations, CredentialsSupersededAnnotation)
+	assert.Nil(t, rotator.Rotate(now.Add(10*time.Minute)))
+	_, err = clientset.CoreV1().Secrets("liqo").Get(context.TODO(), "vk-remote-token-1", metav1.GetOptions{})
+	assert.NotNil(t, err)
+	config, err := rotator.Kubeconfig()
+	assert.Nil(t, err)
+	assert.Contains(t, config, "vk-remote-token-2")
+}
@@ -0,0 +1,179 @@
+package liqonetOperators
+
+import (
+	"context"
+	netv1alpha1 "github.com/liqotech/liqo/apis/net/v1alpha1"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/vishvananda/netlink"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/klog"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+	"time"
+)
+
+const (
+	metricsNamespace = "liqo"
+	metricsSubsystem = "liqonet"
+
+	routeOperatorName  = "route-operator"
+	tunnelOperatorName = "tunnel-operator"
+)
+
+var (
+	tunnelInstalled = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Subsystem: metricsSubsystem,
+		Name:      "tunnel_installed",
+		Help:      "Whether the tunnel towards the remote cluster has been installed on the gateway (1) or not (0).",
+	}, []string{"cluster_id"})
+	tunnelConnected = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Subsystem: metricsSubsystem,
+		Name:      "tunnel_connected",
+		Help:      "Whether the remote gateway answers to the probes sent through the tunnel (1) or not (0).",
+	}, []string{"cluster_id"})
+	tunnelLatency = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Subsystem: metricsSubsystem,
+		Name:      "tunnel_latency_seconds",
+		Help:      "Average round trip time of the last probes sent through the tunnel.",
+	}, []string{"cluster_id"})
+	tunnelPacketLoss = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Subsystem: metricsSubsystem,
+		Name:      "tunnel_packet_loss_ratio",
+		Help:      "Ratio of the last probes sent through the tunnel which did not receive an answer.",
+	}, []string{"cluster_id"})
+	iptablesRules = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Subsystem: metricsSubsystem,
+		Name:      "iptables_rules",
+		Help:      "Number of rules installed in the chains of the remote cluster.",
+	}, []string{"cluster_id", "chain"})
+	reconcileErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: metricsSubsystem,
+		Name:      "reconcile_errors_total",
+		Help:      "Number of reconciliations of the TunnelEndpoints which ended with an error.",
+	}, []string{"operator", "cluster_id"})
+
+	tunnelStatsLabels = []string{"cluster_id", "interface"}
+	tunnelRxBytes     = prometheus.NewDesc(prometheus.BuildFQName(metricsNamespace, metricsSubsystem, "tunnel_receive_bytes_total"),
+		"Bytes received by the network interface of the tunnel.", tunnelStatsLabels, nil)
+	tunnelTxBytes = prometheus.NewDesc(prometheus.BuildFQName(metricsNamespace, metricsSubsystem, "tunnel_transmit_bytes_total"),
+		"Bytes transmitted by the network interface of the tunnel.", tunnelStatsLabels, nil)
+	tunnelRxPackets = prometheus.NewDesc(prometheus.BuildFQName(metricsNamespace, metricsSubsystem, "tunnel_receive_packets_total"),
+		"Packets received by the network interface of the tunnel.", tunnelStatsLabels, nil)
+	tunnelTxPackets = prometheus.NewDesc(prometheus.BuildFQName(metricsNamespace, metricsSubsystem, "tunnel_transmit_packets_total"),
+		"Packets transmitted by the network interface of the tunnel.", tunnelStatsLabels, nil)
+)
+
+func init() {
+	//the metrics are exposed on the endpoint served by the manager
+	metrics.Registry.MustRegister(tunnelInstalled, tunnelConnected, tunnelLatency, tunnelPacketLoss, iptablesRules, reconcileErrors)
+}
+
+//counts the reconciliation errors of the operator for the given remote cluster
+func recordReconcileError(operator, clusterID string, err error) {
+	if err != nil {
+		reconcileErrors.WithLabelValues(operator, clusterID).Inc()
+	}
+}
+
+//records the state of the tunnel towards the remote cluster, as reported in the status of its TunnelEndpoint
+func setTunnelMetrics(clusterID string, status *netv1alpha1.TunnelEndpointStatus) {
+	installed := 0.0
+	if status.TunnelIFaceName != "" {
+		installed = 1
+	}
+	tunnelInstalled.WithLabelValues(clusterID).Set(installed)
+	for i := range status.Conditions {
+		if status.Conditions[i].Type != netv1alpha1.TunnelConnected {
+			continue
+		}
+		connected := 0.0
+		if status.Conditions[i].Status == corev1.ConditionTrue {
+			connected = 1
+		}
+		tunnelConnected.WithLabelValues(clusterID).Set(connected)
+		tunnelPacketLoss.WithLabelValues(clusterID).Set(float64(status.Connection.PacketLoss) / 100)
+		if latency, err := time.ParseDuration(status.Connection.Latency); err == nil {
+			tunnelLatency.WithLabelValues(clusterID).Set(latency.Seconds())
+		} else {
+			tunnelLatency.DeleteLabelValues(clusterID)
+		}
+	}
+}
+
+//removes the metrics of the tunnel towards the remote cluster, once it has been removed
+func deleteTunnelMetrics(clusterID string) {
+	for _, gauge := range []*prometheus.GaugeVec{tunnelInstalled, tunnelConnected, tunnelLatency, tunnelPacketLoss} {
+		gauge.DeleteLabelValues(clusterID)
+	}
+}
+
+//exports the counters of the network interfaces of the tunnels, read from netlink at scrape time.
+//The interfaces shared by the tunnels towards different clusters (e.g. the WireGuard one) are reported for each of them
+type tunnelStatsCollector struct {
+	client client.Reader
+}
+
+func newTunnelStatsCollector(c client.Reader) *tunnelStatsCollector {
+	return &tunnelStatsCollector{client: c}
+}
+
+func (c *tunnelStatsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- tunnelRxBytes
+	ch <- tunnelTxBytes
+	ch <- tunnelRxPackets
+	ch <- tunnelTxPackets
+}
+
+func (c *tunnelStatsCollector) Collect(ch chan<- prometheus.Metric) {
+	var endpoints netv1alpha1.TunnelEndpointList
+	if err := c.client.List(context.Background(), &endpoints); err != nil {
+		klog.Errorf("unable to list resources of type %s: %s", netv1alpha1.GroupResource, err)
+		return
+	}
+	for i := range endpoints.Items {
+		clusterID := endpoints.Items[i].Spec.ClusterID
+		iFaceName := endpoints.Items[i].Status.TunnelIFaceName
+		if iFaceName == "" {
+			continue
+		}
+		link, err := netlink.LinkByName(iFaceName)
+		if err != nil {
+			klog.V(4).Infof("%s -> unable to retrieve tunnel network interface %s: %s", clusterID, iFaceName, err)
+			continue
+		}
+		stats := link.Attrs().Statistics
+		if stats == nil {
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(tunnelRxBytes, prometheus.CounterValue, float64(stats.RxBytes), clusterID, iFaceName)
+		ch <- prometheus.MustNewConstMetric(tunnelTxBytes, prometheus.CounterValue, float64(stats.TxBytes), clusterID, iFaceName)
+		ch <- prometheus.MustNewConstMetric(tunnelRxPackets, prometheus.CounterValue, float64(stats.RxPackets), clusterID, iFaceName)
+		ch <- prometheus.MustNewConstMetric(tunnelTxPackets, prometheus.CounterValue, float64(stats.TxPackets), clusterID, iFaceName)
+	}
+}
+
+//records the number of rules installed in each chain of the remote cluster
+func (r *RouteController) setIPTablesMetrics(tep *netv1alpha1.TunnelEndpoint) {
+	clusterID := tep.Spec.ClusterID
+	for _, chain := range r.GetChainRulespecs(tep) {
+		rules, err := r.ListRulesInChain(chain.table, chain.chainName)
+		if err != nil {
+			klog.Errorf("%s -> unable to list rules for chain %s in table %s: %s", clusterID, chain.chainName, chain.table, err)
+			continue
+		}
+		iptablesRules.WithLabelValues(clusterID, chain.chainName).Set(float64(len(rules)))
+	}
+}
+
+//removes the metrics of the chains of the remote cluster, once they have been removed
+func (r *RouteController) deleteIPTablesMetrics(tep *netv1alpha1.TunnelEndpoint) {
+	for _, chain := range r.GetChainRulespecs(tep) {
+		iptablesRules.DeleteLabelValues(tep.Spec.ClusterID, chain.chainName)
+	}
+}
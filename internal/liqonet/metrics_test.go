@@ -0,0 +1,52 @@
+package liqonetOperators
+
+import (
+	"errors"
+	netv1alpha1 "github.com/liqotech/liqo/apis/net/v1alpha1"
+	liqonetOperator "github.com/liqotech/liqo/pkg/liqonet"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"testing"
+	"time"
+)
+
+func TestSetTunnelMetrics(t *testing.T) {
+	clusterID := "metrics-cluster"
+	status := &netv1alpha1.TunnelEndpointStatus{TunnelIFaceName: "gre-1"}
+	defer deleteTunnelMetrics(clusterID)
+
+	//test1 the tunnel is installed but has not been probed yet
+	setTunnelMetrics(clusterID, status)
+	assert.Equal(t, 1.0, testutil.ToFloat64(tunnelInstalled.WithLabelValues(clusterID)))
+	assert.Equal(t, 0, testutil.CollectAndCount(tunnelConnected))
+
+	//test2 the remote gateway answers to the probes
+	setTunnelHealth(status, &liqonetOperator.ProbeResult{Sent: 5, Received: 4, Latency: 2 * time.Millisecond}, nil, metav1.Now())
+	setTunnelMetrics(clusterID, status)
+	assert.Equal(t, 1.0, testutil.ToFloat64(tunnelConnected.WithLabelValues(clusterID)))
+	assert.Equal(t, 0.002, testutil.ToFloat64(tunnelLatency.WithLabelValues(clusterID)))
+	assert.Equal(t, 0.2, testutil.ToFloat64(tunnelPacketLoss.WithLabelValues(clusterID)))
+
+	//test3 the tunnel goes down and the latency is no longer reported
+	setTunnelHealth(status, &liqonetOperator.ProbeResult{Sent: 5}, nil, metav1.Now())
+	setTunnelMetrics(clusterID, status)
+	assert.Equal(t, 0.0, testutil.ToFloat64(tunnelConnected.WithLabelValues(clusterID)))
+	assert.Equal(t, 1.0, testutil.ToFloat64(tunnelPacketLoss.WithLabelValues(clusterID)))
+	assert.Equal(t, 0, testutil.CollectAndCount(tunnelLatency))
+
+	//test4 the metrics are removed together with the tunnel
+	deleteTunnelMetrics(clusterID)
+	assert.Equal(t, 0, testutil.CollectAndCount(tunnelInstalled))
+	assert.Equal(t, 0, testutil.CollectAndCount(tunnelConnected))
+}
+
+func TestRecordReconcileError(t *testing.T) {
+	clusterID := "metrics-cluster"
+	recordReconcileError(tunnelOperatorName, clusterID, nil)
+	assert.Equal(t, 0.0, testutil.ToFloat64(reconcileErrors.WithLabelValues(tunnelOperatorName, clusterID)))
+	recordReconcileError(tunnelOperatorName, clusterID, errors.New("reconcile failed"))
+	recordReconcileError(tunnelOperatorName, clusterID, errors.New("reconcile failed"))
+	assert.Equal(t, 2.0, testutil.ToFloat64(reconcileErrors.WithLabelValues(tunnelOperatorName, clusterID)))
+	assert.Equal(t, 0.0, testutil.ToFloat64(reconcileErrors.WithLabelValues(routeOperatorName, clusterID)))
+}
@@ -0,0 +1,127 @@
+package liqonetOperators
+
+import (
+	"context"
+	"fmt"
+	discoveryv1alpha1 "github.com/liqotech/liqo/apis/discovery/v1alpha1"
+	netv1alpha1 "github.com/liqotech/liqo/apis/net/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/klog"
+	"net"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"strconv"
+	"strings"
+)
+
+//returns the rules which restrict the traffic coming from the remote cluster to the destinations allowed by the
+//network policy of its ForeignCluster. The traffic is filtered on the gateway node, since all the traffic coming
+//from the remote cluster crosses it. Without network policy the chain has no rules and all the traffic is allowed
+func (r *RouteController) GetPolicyRules(tep *netv1alpha1.TunnelEndpoint) ([]string, error) {
+	if !r.IsGateway {
+		return nil, nil
+	}
+	policy, err := r.getNetworkPolicy(tep.Spec.ClusterID)
+	if err != nil || policy == nil {
+		return nil, err
+	}
+	destinations, err := r.getPolicyDestinations(policy)
+	if err != nil {
+		return nil, err
+	}
+	return getPolicyRules(destinations, policy.AllowedPorts), nil
+}
+
+func (r *RouteController) ensurePolicyRules(tep *netv1alpha1.TunnelEndpoint) error {
+	clusterID := tep.Spec.ClusterID
+	policyChain := strings.Join([]string{LiqonetPolicyClusterChainPrefix, strings.Split(clusterID, "-")[0]}, "")
+	rules, err := r.GetPolicyRules(tep)
+	if err != nil {
+		return err
+	}
+	//list rules in the chain
+	existingRules, err := r.ListRulesInChain(FilterTable, policyChain)
+	if err != nil {
+		klog.Errorf("%s -> unable to list rules for chain %s in table %s: %s", clusterID, policyChain, FilterTable, err)
+		return err
+	}
+	return r.UpdateRulesPerChain(clusterID, policyChain, FilterTable, existingRules, rules)
+}
+
+//returns the network policy set on the ForeignCluster of the remote cluster, nil if not set
+func (r *RouteController) getNetworkPolicy(clusterID string) (*discoveryv1alpha1.PeeringNetworkPolicy, error) {
+	var foreignClusters discoveryv1alpha1.ForeignClusterList
+	if err := r.List(context.Background(), &foreignClusters, client.MatchingLabels{"cluster-id": clusterID}); err != nil {
+		return nil, fmt.Errorf("unable to get the ForeignCluster of cluster %s: %w", clusterID, err)
+	}
+	if len(foreignClusters.Items) == 0 {
+		return nil, nil
+	}
+	return foreignClusters.Items[0].Spec.NetworkPolicy, nil
+}
+
+//returns the destinations the remote cluster can reach: the allowed CIDRs and the IPs of the pods running
+//in the allowed namespaces. An empty list means that any destination can be reached
+func (r *RouteController) getPolicyDestinations(policy *discoveryv1alpha1.PeeringNetworkPolicy) ([]string, error) {
+	var destinations []string
+	for _, cidr := range policy.AllowedCIDRs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CIDR %s in network policy: %w", cidr, err)
+		}
+		//the policy is enforced through iptables, which accepts only IPv4 networks
+		if len(network.Mask) != net.IPv4len {
+			return nil, fmt.Errorf("invalid CIDR %s in network policy: only IPv4 networks are supported", cidr)
+		}
+		destinations = append(destinations, network.String())
+	}
+	for _, namespace := range policy.AllowedNamespaces {
+		var pods corev1.PodList
+		if err := r.List(context.Background(), &pods, client.InNamespace(namespace)); err != nil {
+			return nil, fmt.Errorf("unable to list pods in namespace %s: %w", namespace, err)
+		}
+		for i := range pods.Items {
+			pod := &pods.Items[i]
+			if pod.Spec.HostNetwork || pod.Status.PodIP == "" || net.ParseIP(pod.Status.PodIP).To4() == nil {
+				continue
+			}
+			destinations = append(destinations, pod.Status.PodIP+"/32")
+		}
+	}
+	//a policy listing namespaces without pods allows no destination
+	if len(destinations) == 0 && len(policy.AllowedNamespaces) > 0 {
+		return []string{}, nil
+	}
+	return destinations, nil
+}
+
+//renders the rules of the policy chain: the answers to the connections opened by the local pods are accepted,
+//as well as the traffic towards the allowed destinations and ports, while all the rest is dropped.
+//A nil list of destinations allows all of them, while an empty one allows none
+func getPolicyRules(destinations []string, ports []discoveryv1alpha1.PeeringNetworkPolicyPort) []string {
+	rules := []string{"-m conntrack --ctstate RELATED,ESTABLISHED -j ACCEPT"}
+	var matches []string
+	if destinations == nil {
+		destinations = []string{""}
+	}
+	for _, destination := range destinations {
+		match := ""
+		if destination != "" {
+			match = "-d " + destination + " "
+		}
+		if len(ports) == 0 {
+			matches = append(matches, match)
+			continue
+		}
+		for _, port := range ports {
+			protocol := strings.ToLower(string(port.Protocol))
+			if protocol == "" {
+				protocol = "tcp"
+			}
+			matches = append(matches, strings.Join([]string{match + "-p", protocol, "-m", protocol, "--dport", strconv.Itoa(int(port.Port))}, " ")+" ")
+		}
+	}
+	for _, match := range matches {
+		rules = append(rules, match+"-j ACCEPT")
+	}
+	return append(rules, "-j DROP")
+}
@@ -35,6 +35,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
 	"strconv"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 )
@@ -52,6 +53,7 @@ const (
 	LiqonetPreroutingClusterChainPrefix  = "LIQO-PRRT-CLS-"
 	LiqonetForwardingClusterChainPrefix  = "LIQO-FRWD-CLS-"
 	LiqonetInputClusterChainPrefix       = "LIQO-INPT-CLS-"
+	LiqonetPolicyClusterChainPrefix      = "LIQO-PLCY-CLS-"
 	NatTable                             = "nat"
 	FilterTable                          = "filter"
 )
@@ -83,17 +85,24 @@ type RouteController struct {
 	IPTablesChains         map[string]liqonetOperator.IPTableChain
 	RoutesPerRemoteCluster map[string]netlink.Route
 	RetryTimeout           time.Duration
+	//protects the gateway parameters, which change when another node is elected as gateway
+	Mutex sync.Mutex
 }
 
 // +kubebuilder:rbac:groups=net.liqo.io,resources=tunnelendpoints,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=net.liqo.io,resources=tunnelendpoints/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=core,resources=nodes,verbs=get;list
+// +kubebuilder:rbac:groups=core,resources=pods,verbs=get;list;watch
+// +kubebuilder:rbac:groups=discovery.liqo.io,resources=foreignclusters,verbs=get;list;watch
 
-func (r *RouteController) Reconcile(req ctrl.Request) (ctrl.Result, error) {
+func (r *RouteController) Reconcile(req ctrl.Request) (_ ctrl.Result, err error) {
 	ctx := context.Background()
 	var tep netv1alpha1.TunnelEndpoint
 	//name of our finalizer
 	routeOperatorFinalizer := "routeOperator-" + r.NodeName + "-liqo.io"
+	r.Mutex.Lock()
+	defer r.Mutex.Unlock()
+	defer func() { recordReconcileError(routeOperatorName, tep.Spec.ClusterID, err) }()
 
 	if err := r.Get(ctx, req.NamespacedName, &tep); err != nil {
 		klog.Errorf("unable to fetch resource %s", req.String())
@@ -140,6 +149,7 @@ func (r *RouteController) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 				r.Recorder.Event(&tep, "Warning", "Delete", err.Error())
 				return ctrl.Result{RequeueAfter: r.RetryTimeout}, err
 			}
+			r.deleteIPTablesMetrics(&tep)
 			//remove the finalizer from the list and update it.
 			tep.Finalizers = liqonetOperator.RemoveString(tep.Finalizers, routeOperatorFinalizer)
 			if err := r.Update(ctx, &tep); err != nil {
@@ -159,6 +169,7 @@ func (r *RouteController) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 	} else {
 		r.Recorder.Event(&tep, "Normal", "Processing", "iptables rules ensured")
 	}
+	r.setIPTablesMetrics(&tep)
 	if err := r.ensureRoutesPerCluster(&tep); err != nil {
 		klog.Errorf("%s -> unable to add routes for resource %s: %s", clusterID, req.String(), err)
 		r.Recorder.Event(&tep, "Warning", "Processing", err.Error())
@@ -169,6 +180,18 @@ func (r *RouteController) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 	return result, nil
 }
 
+// SetGateway is called when the node acting as gateway changes: the routes and the iptables rules
+// are updated at the next reconciliation of the tunnelEndpoints
+func (r *RouteController) SetGateway(isGateway bool, gatewayVxlanIP string) {
+	r.Mutex.Lock()
+	defer r.Mutex.Unlock()
+	if r.IsGateway != isGateway {
+		klog.Infof("node %s is the gateway: %t", r.NodeName, isGateway)
+	}
+	r.IsGateway = isGateway
+	r.GatewayVxlanIP = gatewayVxlanIP
+}
+
 func (r *RouteController) GetPodCIDRS(tep *netv1alpha1.TunnelEndpoint) (string, string) {
 	var remotePodCIDR, localRemappedPodCIDR string
 	if tep.Status.RemoteRemappedPodCIDR != "None" {
@@ -247,8 +270,20 @@ func (r *RouteController) UpdateRulesPerChain(clusterID, chain, table string, ex
 			klog.Infof("%s -> removing outdated rule '%s' from chain %s in table %s", clusterID, existingRule, chain, table)
 		}
 	}
-	if err := r.InsertRulesIfNotPresent(clusterID, table, chain, newRules); err != nil {
-		return err
+	//the missing rules are inserted at their position, since the order matters for the non terminating targets
+	//(e.g. the MSS clamping has to precede the ACCEPT rule)
+	for i, rule := range newRules {
+		exists, err := r.IPtables.Exists(table, chain, strings.Split(rule, " ")...)
+		if err != nil {
+			klog.Errorf("%s -> unable to check if rule '%s' exists in chain %s in table %s", clusterID, rule, chain, table)
+			return err
+		}
+		if !exists {
+			if err := r.IPtables.Insert(table, chain, i+1, strings.Split(rule, " ")...); err != nil {
+				return err
+			}
+			klog.Infof("%s -> inserting rule '%s' in chain %s in table %s", clusterID, rule, chain, table)
+		}
 	}
 	return nil
 }
@@ -270,6 +305,14 @@ func (r *RouteController) ListRulesInChain(table, chain string) ([]string, error
 }
 
 func (r *RouteController) ensureIPTablesRulesPerCluster(tep *netv1alpha1.TunnelEndpoint) error {
+	//if supported by the backend, all the chains of the cluster are configured in a single transaction
+	if ipt, ok := r.IPtables.(liqonetOperator.AtomicIPTables); ok {
+		chains, err := r.GetClusterChains(tep)
+		if err != nil {
+			return err
+		}
+		return ipt.EnsureClusterChains(chains)
+	}
 	if err := r.ensureChainRulespecs(tep); err != nil {
 		return err
 	}
@@ -285,6 +328,9 @@ func (r *RouteController) ensureIPTablesRulesPerCluster(tep *netv1alpha1.TunnelE
 	if err := r.ensureInputRules(tep); err != nil {
 		return err
 	}
+	if err := r.ensurePolicyRules(tep); err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -355,6 +401,13 @@ func (r *RouteController) InsertIptablesRulespecIfNotExists(table string, chain
 }
 
 func (r *RouteController) removeIPTablesPerCluster(tep *netv1alpha1.TunnelEndpoint) error {
+	if ipt, ok := r.IPtables.(liqonetOperator.AtomicIPTables); ok {
+		chains, err := r.GetClusterChains(tep)
+		if err != nil {
+			return err
+		}
+		return ipt.RemoveClusterChains(chains)
+	}
 	chains := r.GetChainRulespecs(tep)
 	clusterID := tep.Spec.ClusterID
 	for _, chain := range chains {
@@ -399,6 +452,7 @@ func (r *RouteController) GetChainRulespecs(tep *netv1alpha1.TunnelEndpoint) []s
 	preRoutingChain := strings.Join([]string{LiqonetPreroutingClusterChainPrefix, strings.Split(clusterID, "-")[0]}, "")
 	forwardChain := strings.Join([]string{LiqonetForwardingClusterChainPrefix, strings.Split(clusterID, "-")[0]}, "")
 	inputChain := strings.Join([]string{LiqonetInputClusterChainPrefix, strings.Split(clusterID, "-")[0]}, "")
+	policyChain := strings.Join([]string{LiqonetPolicyClusterChainPrefix, strings.Split(clusterID, "-")[0]}, "")
 	if localRemappedPodCIDR != defaultPodCIDRValue {
 		return []struct {
 			chainName string
@@ -430,6 +484,12 @@ func (r *RouteController) GetChainRulespecs(tep *netv1alpha1.TunnelEndpoint) []s
 				FilterTable,
 				LiqonetInputChain,
 			},
+			{
+				policyChain,
+				strings.Join([]string{"-s", remotePodCIDR, "-j", policyChain}, " "),
+				FilterTable,
+				LiqonetForwardingChain,
+			},
 		}
 	}
 	return []struct {
@@ -456,10 +516,51 @@ func (r *RouteController) GetChainRulespecs(tep *netv1alpha1.TunnelEndpoint) []s
 			FilterTable,
 			LiqonetInputChain,
 		},
+		{
+			policyChain,
+			strings.Join([]string{"-s", remotePodCIDR, "-j", policyChain}, " "),
+			FilterTable,
+			LiqonetForwardingChain,
+		},
 	}
 
 }
 
+//returns the chains of the remote cluster together with their rules, to be configured by the backends
+//which support atomic transactions
+func (r *RouteController) GetClusterChains(tep *netv1alpha1.TunnelEndpoint) ([]liqonetOperator.IPTablesClusterChain, error) {
+	postRoutingRules, err := r.GetPostroutingRules(tep)
+	if err != nil {
+		return nil, err
+	}
+	policyRules, err := r.GetPolicyRules(tep)
+	if err != nil {
+		return nil, err
+	}
+	clusterID := strings.Split(tep.Spec.ClusterID, "-")[0]
+	rulesPerChain := map[string][]string{
+		LiqonetPostroutingClusterChainPrefix + clusterID: postRoutingRules,
+		LiqonetPreroutingClusterChainPrefix + clusterID:  r.GetPreroutingRules(tep),
+		LiqonetForwardingClusterChainPrefix + clusterID:  r.GetForwardRules(tep),
+		LiqonetInputClusterChainPrefix + clusterID:       r.GetInputRules(tep),
+		LiqonetPolicyClusterChainPrefix + clusterID:      policyRules,
+	}
+	var chains []liqonetOperator.IPTablesClusterChain
+	for _, chain := range r.GetChainRulespecs(tep) {
+		clusterChain := liqonetOperator.IPTablesClusterChain{
+			Table:    chain.table,
+			Name:     chain.chainName,
+			Parent:   chain.chain,
+			JumpRule: strings.Split(chain.rulespec, " "),
+		}
+		for _, rule := range rulesPerChain[chain.chainName] {
+			clusterChain.Rules = append(clusterChain.Rules, strings.Split(rule, " "))
+		}
+		chains = append(chains, clusterChain)
+	}
+	return chains, nil
+}
+
 func (r *RouteController) ensureChainRulespecs(tep *netv1alpha1.TunnelEndpoint) error {
 	chains := r.GetChainRulespecs(tep)
 	clusterID := tep.Spec.ClusterID
@@ -509,8 +610,8 @@ func (r *RouteController) ensurePostroutingRules(tep *netv1alpha1.TunnelEndpoint
 	return r.UpdateRulesPerChain(clusterID, postRoutingChain, NatTable, existingRules, rules)
 }
 
-func (r *RouteController) ensurePreroutingRules(tep *netv1alpha1.TunnelEndpoint) error {
-	//if the node is not a gateway node then return
+func (r *RouteController) GetPreroutingRules(tep *netv1alpha1.TunnelEndpoint) []string {
+	//if the node is not a gateway node then there are no rules
 	if !r.IsGateway {
 		return nil
 	}
@@ -519,8 +620,17 @@ func (r *RouteController) ensurePreroutingRules(tep *netv1alpha1.TunnelEndpoint)
 	if localRemappedPodCIDR == defaultPodCIDRValue {
 		return nil
 	}
+	return []string{
+		strings.Join([]string{"-d", localRemappedPodCIDR, "-i", tep.Status.TunnelIFaceName, "-j", "NETMAP", "--to", r.ClusterPodCIDR}, " "),
+	}
+}
+
+func (r *RouteController) ensurePreroutingRules(tep *netv1alpha1.TunnelEndpoint) error {
+	rules := r.GetPreroutingRules(tep)
+	if rules == nil {
+		return nil
+	}
 	clusterID := tep.Spec.ClusterID
-	tunnelIFace := tep.Status.TunnelIFaceName
 	preRoutingChain := strings.Join([]string{LiqonetPreroutingClusterChainPrefix, strings.Split(clusterID, "-")[0]}, "")
 	//list rules in the chain
 	existingRules, err := r.ListRulesInChain(NatTable, preRoutingChain)
@@ -528,14 +638,21 @@ func (r *RouteController) ensurePreroutingRules(tep *netv1alpha1.TunnelEndpoint)
 		klog.Errorf("%s -> unable to list rules for chain %s in table %s: %s", clusterID, preRoutingChain, NatTable, err)
 		return err
 	}
-	rules := []string{
-		strings.Join([]string{"-d", localRemappedPodCIDR, "-i", tunnelIFace, "-j", "NETMAP", "--to", r.ClusterPodCIDR}, " "),
-	}
 	return r.UpdateRulesPerChain(clusterID, preRoutingChain, NatTable, existingRules, rules)
 }
 
-func (r *RouteController) ensureForwardRules(tep *netv1alpha1.TunnelEndpoint) error {
+func (r *RouteController) GetForwardRules(tep *netv1alpha1.TunnelEndpoint) []string {
 	_, remotePodCIDR := r.GetPodCIDRS(tep)
+	var rules []string
+	//the MSS of the TCP connections towards the remote cluster is clamped to the one computed by the tunnel operator,
+	//so that the segments fit in the MTU of the tunnel
+	if tep.Status.TCPMSS > 0 {
+		rules = append(rules, strings.Join([]string{"-p", "tcp", "-m", "tcp", "--tcp-flags", "SYN,RST", "SYN", "-j", "TCPMSS", "--set-mss", strconv.Itoa(tep.Status.TCPMSS)}, " "))
+	}
+	return append(rules, strings.Join([]string{"-d", remotePodCIDR, "-j", "ACCEPT"}, " "))
+}
+
+func (r *RouteController) ensureForwardRules(tep *netv1alpha1.TunnelEndpoint) error {
 	clusterID := tep.Spec.ClusterID
 	forwardChain := strings.Join([]string{LiqonetForwardingClusterChainPrefix, strings.Split(clusterID, "-")[0]}, "")
 
@@ -545,14 +662,17 @@ func (r *RouteController) ensureForwardRules(tep *netv1alpha1.TunnelEndpoint) er
 		klog.Errorf("%s -> unable to list rules for chain %s in table %s: %s", clusterID, forwardChain, NatTable, err)
 		return err
 	}
-	rules := []string{
-		strings.Join([]string{"-d", remotePodCIDR, "-j", "ACCEPT"}, " "),
+	return r.UpdateRulesPerChain(clusterID, forwardChain, FilterTable, existingRules, r.GetForwardRules(tep))
+}
+
+func (r *RouteController) GetInputRules(tep *netv1alpha1.TunnelEndpoint) []string {
+	_, remotePodCIDR := r.GetPodCIDRS(tep)
+	return []string{
+		strings.Join([]string{"-s", r.ClusterPodCIDR, "-d", remotePodCIDR, "-j", "ACCEPT"}, " "),
 	}
-	return r.UpdateRulesPerChain(clusterID, forwardChain, FilterTable, existingRules, rules)
 }
 
 func (r *RouteController) ensureInputRules(tep *netv1alpha1.TunnelEndpoint) error {
-	_, remotePodCIDR := r.GetPodCIDRS(tep)
 	clusterID := tep.Spec.ClusterID
 	inputChain := strings.Join([]string{LiqonetInputClusterChainPrefix, strings.Split(clusterID, "-")[0]}, "")
 
@@ -562,10 +682,7 @@ func (r *RouteController) ensureInputRules(tep *netv1alpha1.TunnelEndpoint) erro
 		klog.Errorf("%s -> unable to list rules for chain %s in table %s: %s", clusterID, inputChain, FilterTable, err)
 		return err
 	}
-	rules := []string{
-		strings.Join([]string{"-s", r.ClusterPodCIDR, "-d", remotePodCIDR, "-j", "ACCEPT"}, " "),
-	}
-	return r.UpdateRulesPerChain(clusterID, inputChain, FilterTable, existingRules, rules)
+	return r.UpdateRulesPerChain(clusterID, inputChain, FilterTable, existingRules, r.GetInputRules(tep))
 }
 
 //this function is called at startup of the operator
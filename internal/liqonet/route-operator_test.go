@@ -1,13 +1,18 @@
 package liqonetOperators
 
 import (
+	discoveryv1alpha1 "github.com/liqotech/liqo/apis/discovery/v1alpha1"
 	netv1alpha1 "github.com/liqotech/liqo/apis/net/v1alpha1"
 	"github.com/liqotech/liqo/pkg/liqonet"
 	"github.com/stretchr/testify/assert"
 	"github.com/vishvananda/netlink"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/kubernetes"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	"reflect"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 	"strings"
 	"testing"
 )
@@ -331,11 +336,11 @@ func TestRouteController_GetChainRulespecs(t *testing.T) {
 		expectedNumberofChains int
 	}{
 		{"10.1.0.0/16",
-			4,
+			5,
 		},
 		{
 			defaultPodCIDRValue,
-			3,
+			4,
 		},
 	}
 	for _, test := range tests {
@@ -344,3 +349,142 @@ func TestRouteController_GetChainRulespecs(t *testing.T) {
 		assert.Equal(t, test.expectedNumberofChains, len(chainRulespecs))
 	}
 }
+
+func TestRouteController_NFTablesPerCluster(t *testing.T) {
+	runner := &liqonet.MockNFTablesRunner{}
+	nft, err := liqonet.NewNFTables(runner)
+	assert.Nil(t, err, "error should be nil")
+	r := getRouteController()
+	r.Client = fake.NewFakeClientWithScheme(getTestScheme())
+	r.IPtables = nft
+	r.IsGateway = true
+	tep := GetTunnelEndpointCR()
+	tep.Status.LocalRemappedPodCIDR = "10.1.0.0/16"
+	assert.Nil(t, r.CreateAndEnsureIPTablesChains(), "error should be nil")
+
+	//all the chains of the cluster are configured in a single transaction
+	transactions := len(runner.Scripts)
+	assert.Nil(t, r.ensureIPTablesRulesPerCluster(tep), "error should be nil")
+	assert.Equal(t, transactions+1, len(runner.Scripts))
+	chains, err := r.GetClusterChains(tep)
+	assert.Nil(t, err, "error should be nil")
+	assert.Equal(t, 5, len(chains))
+	for _, chain := range chains {
+		rules, err := nft.List(chain.Table, chain.Name)
+		assert.Nil(t, err, "error should be nil")
+		assert.Equal(t, len(chain.Rules)+1, len(rules))
+		exists, err := nft.Exists(chain.Table, chain.Parent, chain.JumpRule...)
+		assert.Nil(t, err, "error should be nil")
+		assert.True(t, exists)
+	}
+
+	//and removed in a single transaction
+	assert.Nil(t, r.removeIPTablesPerCluster(tep), "error should be nil")
+	assert.Equal(t, transactions+2, len(runner.Scripts))
+	existingChains, err := nft.ListChains(NatTable)
+	assert.Nil(t, err, "error should be nil")
+	for _, chain := range chains {
+		assert.NotContains(t, existingChains, chain.Name)
+	}
+}
+
+func TestRouteController_GetForwardRules(t *testing.T) {
+	r := getRouteController()
+	tep := GetTunnelEndpointCR()
+
+	//without MSS the traffic towards the remote cluster is only accepted
+	assert.Equal(t, []string{"-d 10.100.0.0/16 -j ACCEPT"}, r.GetForwardRules(tep))
+
+	//the MSS clamping precedes the ACCEPT rule
+	tep.Status.TCPMSS = 1386
+	rules := r.GetForwardRules(tep)
+	assert.Equal(t, []string{
+		"-p tcp -m tcp --tcp-flags SYN,RST SYN -j TCPMSS --set-mss 1386",
+		"-d 10.100.0.0/16 -j ACCEPT",
+	}, rules)
+	assert.Nil(t, r.UpdateRulesPerChain(tep.Spec.ClusterID, "LIQO-FRWD-CLS-test", FilterTable, nil, rules), "error should be nil")
+	assert.Equal(t, 2, len(ip.Rules))
+}
+
+func getTestScheme() *runtime.Scheme {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = discoveryv1alpha1.AddToScheme(scheme)
+	return scheme
+}
+
+func getTestPod(namespace, name, podIP string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
+		Status:     corev1.PodStatus{PodIP: podIP},
+	}
+}
+
+func TestRouteController_GetPolicyRules(t *testing.T) {
+	tep := GetTunnelEndpointCR()
+	foreignCluster := &discoveryv1alpha1.ForeignCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   "cluster-test",
+			Labels: map[string]string{"cluster-id": tep.Spec.ClusterID},
+		},
+		Spec: discoveryv1alpha1.ForeignClusterSpec{
+			ClusterIdentity: discoveryv1alpha1.ClusterIdentity{ClusterID: tep.Spec.ClusterID},
+		},
+	}
+	r := getRouteController()
+	r.IsGateway = true
+	r.Client = fake.NewFakeClientWithScheme(getTestScheme(), foreignCluster,
+		getTestPod("allowed", "pod-1", "10.200.1.5"),
+		getTestPod("allowed", "pod-2", ""),
+		getTestPod("other", "pod-3", "10.200.1.6"))
+
+	//test1 without network policy all the traffic is allowed
+	rules, err := r.GetPolicyRules(tep)
+	assert.Nil(t, err, "error should be nil")
+	assert.Nil(t, rules)
+
+	//test2 only the pods in the allowed namespaces and the allowed CIDRs can be reached, on the allowed ports
+	foreignCluster.Spec.NetworkPolicy = &discoveryv1alpha1.PeeringNetworkPolicy{
+		AllowedNamespaces: []string{"allowed"},
+		AllowedCIDRs:      []string{"10.200.2.1/24"},
+		AllowedPorts: []discoveryv1alpha1.PeeringNetworkPolicyPort{
+			{Port: 80},
+			{Protocol: corev1.ProtocolUDP, Port: 53},
+		},
+	}
+	r.Client = fake.NewFakeClientWithScheme(getTestScheme(), foreignCluster,
+		getTestPod("allowed", "pod-1", "10.200.1.5"),
+		getTestPod("allowed", "pod-2", ""),
+		getTestPod("other", "pod-3", "10.200.1.6"))
+	rules, err = r.GetPolicyRules(tep)
+	assert.Nil(t, err, "error should be nil")
+	assert.Equal(t, []string{
+		"-m conntrack --ctstate RELATED,ESTABLISHED -j ACCEPT",
+		"-d 10.200.2.0/24 -p tcp -m tcp --dport 80 -j ACCEPT",
+		"-d 10.200.2.0/24 -p udp -m udp --dport 53 -j ACCEPT",
+		"-d 10.200.1.5/32 -p tcp -m tcp --dport 80 -j ACCEPT",
+		"-d 10.200.1.5/32 -p udp -m udp --dport 53 -j ACCEPT",
+		"-j DROP",
+	}, rules)
+
+	//test3 a policy without pods in the allowed namespaces drops all the new connections
+	assert.Equal(t, []string{
+		"-m conntrack --ctstate RELATED,ESTABLISHED -j ACCEPT",
+		"-j DROP",
+	}, getPolicyRules([]string{}, nil))
+
+	//test4 IPv6 CIDRs cannot be enforced through iptables and are rejected, as well as the IPv4-mapped ones
+	for _, cidr := range []string{"fd00::/64", "::ffff:10.200.2.0/120"} {
+		foreignCluster.Spec.NetworkPolicy.AllowedCIDRs = []string{cidr}
+		r.Client = fake.NewFakeClientWithScheme(getTestScheme(), foreignCluster)
+		rules, err = r.GetPolicyRules(tep)
+		assert.NotNil(t, err, "error should not be nil")
+		assert.Nil(t, rules)
+	}
+
+	//test5 the traffic is filtered only on the gateway node
+	r.IsGateway = false
+	rules, err = r.GetPolicyRules(tep)
+	assert.Nil(t, err, "error should be nil")
+	assert.Nil(t, rules)
+}
@@ -0,0 +1,59 @@
+package liqonetOperators
+
+import (
+	configv1alpha1 "github.com/liqotech/liqo/apis/config/v1alpha1"
+	"github.com/liqotech/liqo/pkg/clusterConfig"
+	"github.com/liqotech/liqo/pkg/crdClient"
+	liqonetOperator "github.com/liqotech/liqo/pkg/liqonet"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"k8s.io/klog"
+	"os"
+)
+
+func (r *TunnelController) WatchConfiguration(config *rest.Config, gv *schema.GroupVersion) {
+	config.ContentConfig.GroupVersion = gv
+	config.APIPath = "/apis"
+	config.NegotiatedSerializer = scheme.Codecs.WithoutConversion()
+	config.UserAgent = rest.DefaultKubernetesUserAgent()
+	CRDclient, err := crdClient.NewFromConfig(config)
+	if err != nil {
+		klog.Error(err, err.Error())
+		os.Exit(1)
+	}
+	go clusterConfig.WatchConfiguration(func(configuration *configv1alpha1.ClusterConfig) {
+		liqonetConfig := configuration.Spec.LiqonetConfig
+		r.setMTUConfig(liqonetOperator.MTUConfig{
+			PathMTU:            liqonetConfig.PathMTU,
+			PathMTUDiscovery:   liqonetConfig.PathMTUDiscovery,
+			TCPMSS:             liqonetConfig.TCPMSS,
+			DisableMSSClamping: liqonetConfig.DisableMSSClamping,
+		})
+		driverName := liqonetConfig.TunnelDriver
+		if driverName == "" {
+			driverName = liqonetOperator.GreDriverName
+		}
+		//the driver is selected at start-up time, since changing it requires to tear down all the existing tunnels
+		if r.IsConfigured {
+			if driverName != r.DriverName {
+				klog.Warningf("tunnel driver changed from %s to %s: restart the tunnel-operator to apply the change", r.DriverName, driverName)
+			}
+			return
+		}
+		//the operator cannot start without a driver: exit to be restarted instead of waiting forever
+		driver, err := liqonetOperator.NewTunnelDriver(driverName, liqonetConfig.WireGuardPort, r.ClientSet, r.Namespace)
+		if err != nil {
+			klog.Error(err)
+			os.Exit(1)
+		}
+		if err := driver.Init(); err != nil {
+			klog.Errorf("unable to initialize the tunnel driver: %s", err)
+			os.Exit(1)
+		}
+		r.Driver = driver
+		r.DriverName = driverName
+		r.IsConfigured = true
+		r.Configured <- true
+	}, CRDclient, "")
+}
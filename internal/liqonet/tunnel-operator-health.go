@@ -0,0 +1,141 @@
+package liqonetOperators
+
+import (
+	"context"
+	"fmt"
+	netv1alpha1 "github.com/liqotech/liqo/apis/net/v1alpha1"
+	liqonetOperator "github.com/liqotech/liqo/pkg/liqonet"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/util/retry"
+	"k8s.io/klog"
+	"net"
+	"time"
+)
+
+const (
+	// DefaultProbeInterval is the interval between two probing rounds of the tunnels, if not configured.
+	DefaultProbeInterval = 30 * time.Second
+
+	// reasons of the Connected condition
+	tunnelUpReason   = "TunnelUp"
+	tunnelDownReason = "TunnelDown"
+	packetLossReason = "PacketLoss"
+	probeErrorReason = "ProbeError"
+)
+
+// StartTunnelProbing periodically probes the remote gateways through the tunnels, until the stop channel is closed.
+func (r *TunnelController) StartTunnelProbing(stop <-chan struct{}) error {
+	interval := r.ProbeInterval
+	if interval == 0 {
+		interval = DefaultProbeInterval
+	}
+	wait.Until(r.probeTunnels, interval, stop)
+	return nil
+}
+
+//probes all the tunnels which have been installed and records the outcome in the status of the TunnelEndpoints
+func (r *TunnelController) probeTunnels() {
+	var endpoints netv1alpha1.TunnelEndpointList
+	if err := r.List(context.Background(), &endpoints); err != nil {
+		klog.Errorf("unable to list resources of type %s: %s", netv1alpha1.GroupResource, err)
+		return
+	}
+	for i := range endpoints.Items {
+		endpoint := &endpoints.Items[i]
+		if endpoint.Status.TunnelIFaceName == "" || !endpoint.DeletionTimestamp.IsZero() {
+			continue
+		}
+		remoteIP := net.ParseIP(endpoint.Spec.TunnelPublicIP)
+		if remoteIP == nil {
+			continue
+		}
+		result, err := r.Prober.Probe(endpoint.Status.TunnelIFaceName, remoteIP)
+		if err != nil {
+			klog.Errorf("%s -> unable to probe tunnel %s: %s", endpoint.Spec.ClusterID, endpoint.Status.TunnelIFaceName, err)
+		}
+		if err := r.updateTunnelHealth(types.NamespacedName{Namespace: endpoint.Namespace, Name: endpoint.Name}, result, err); err != nil {
+			klog.Errorf("%s -> unable to update status of resource %s: %s", endpoint.Spec.ClusterID, endpoint.Name, err)
+		}
+	}
+}
+
+//records the outcome of a probing round in the status of the TunnelEndpoint and emits an event
+//if the Connected condition changed
+func (r *TunnelController) updateTunnelHealth(name types.NamespacedName, result *liqonetOperator.ProbeResult, probeErr error) error {
+	var endpoint netv1alpha1.TunnelEndpoint
+	var transition *netv1alpha1.TunnelCondition
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		if err := r.Get(context.Background(), name, &endpoint); err != nil {
+			return err
+		}
+		transition = setTunnelHealth(&endpoint.Status, result, probeErr, metav1.Now())
+		return r.Status().Update(context.Background(), &endpoint)
+	})
+	if err != nil {
+		return err
+	}
+	setTunnelMetrics(endpoint.Spec.ClusterID, &endpoint.Status)
+	if transition != nil {
+		eventType := corev1.EventTypeNormal
+		if transition.Status != corev1.ConditionTrue {
+			eventType = corev1.EventTypeWarning
+		}
+		r.Recorder.Event(&endpoint, eventType, transition.Reason, transition.Message)
+		klog.Infof("%s -> tunnel %s: %s", endpoint.Spec.ClusterID, transition.Reason, transition.Message)
+	}
+	return nil
+}
+
+//sets the connection statistics and the Connected condition according to the outcome of a probing round.
+//It returns the condition if its status changed, nil otherwise
+func setTunnelHealth(status *netv1alpha1.TunnelEndpointStatus, result *liqonetOperator.ProbeResult,
+	probeErr error, now metav1.Time) *netv1alpha1.TunnelCondition {
+	condition := netv1alpha1.TunnelCondition{
+		Type:          netv1alpha1.TunnelConnected,
+		LastProbeTime: now,
+	}
+	switch {
+	case probeErr != nil:
+		condition.Status = corev1.ConditionUnknown
+		condition.Reason = probeErrorReason
+		condition.Message = probeErr.Error()
+		status.Connection.Latency = ""
+	case result.Received == 0:
+		condition.Status = corev1.ConditionFalse
+		condition.Reason = tunnelDownReason
+		condition.Message = fmt.Sprintf("no answer received from the remote gateway to %d probes", result.Sent)
+		status.Connection.Latency = ""
+		status.Connection.PacketLoss = result.PacketLoss()
+	default:
+		latency := result.Latency.Round(time.Microsecond).String()
+		condition.Status = corev1.ConditionTrue
+		condition.Reason = tunnelUpReason
+		if result.Received < result.Sent {
+			condition.Reason = packetLossReason
+		}
+		condition.Message = fmt.Sprintf("remote gateway reachable with latency %s and packet loss %d%%", latency, result.PacketLoss())
+		status.Connection.Latency = latency
+		status.Connection.PacketLoss = result.PacketLoss()
+		status.Connection.LastSeen = &now
+	}
+
+	for i := range status.Conditions {
+		if status.Conditions[i].Type != condition.Type {
+			continue
+		}
+		if status.Conditions[i].Status == condition.Status {
+			condition.LastTransitionTime = status.Conditions[i].LastTransitionTime
+			status.Conditions[i] = condition
+			return nil
+		}
+		condition.LastTransitionTime = now
+		status.Conditions[i] = condition
+		return &condition
+	}
+	condition.LastTransitionTime = now
+	status.Conditions = append(status.Conditions, condition)
+	return &condition
+}
@@ -0,0 +1,56 @@
+package liqonetOperators
+
+import (
+	"errors"
+	netv1alpha1 "github.com/liqotech/liqo/apis/net/v1alpha1"
+	liqonetOperator "github.com/liqotech/liqo/pkg/liqonet"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"testing"
+	"time"
+)
+
+func TestSetTunnelHealth(t *testing.T) {
+	status := &netv1alpha1.TunnelEndpointStatus{}
+	first := metav1.NewTime(time.Unix(1000, 0))
+	second := metav1.NewTime(time.Unix(2000, 0))
+	third := metav1.NewTime(time.Unix(3000, 0))
+
+	//test1 the first successful probing round sets the condition and reports the transition
+	transition := setTunnelHealth(status, &liqonetOperator.ProbeResult{Sent: 5, Received: 5, Latency: 1500 * time.Microsecond}, nil, first)
+	assert.NotNil(t, transition)
+	assert.Equal(t, tunnelUpReason, transition.Reason)
+	assert.Equal(t, 1, len(status.Conditions))
+	assert.Equal(t, corev1.ConditionTrue, status.Conditions[0].Status)
+	assert.Equal(t, first, status.Conditions[0].LastTransitionTime)
+	assert.Equal(t, "1.5ms", status.Connection.Latency)
+	assert.Equal(t, 0, status.Connection.PacketLoss)
+	assert.Equal(t, &first, status.Connection.LastSeen)
+
+	//test2 packet loss does not change the status of the condition, hence no transition is reported
+	transition = setTunnelHealth(status, &liqonetOperator.ProbeResult{Sent: 5, Received: 4, Latency: time.Millisecond}, nil, second)
+	assert.Nil(t, transition)
+	assert.Equal(t, 1, len(status.Conditions))
+	assert.Equal(t, packetLossReason, status.Conditions[0].Reason)
+	assert.Equal(t, first, status.Conditions[0].LastTransitionTime)
+	assert.Equal(t, second, status.Conditions[0].LastProbeTime)
+	assert.Equal(t, 20, status.Connection.PacketLoss)
+
+	//test3 the tunnel goes down and the last seen time is preserved
+	transition = setTunnelHealth(status, &liqonetOperator.ProbeResult{Sent: 5}, nil, third)
+	assert.NotNil(t, transition)
+	assert.Equal(t, corev1.ConditionFalse, transition.Status)
+	assert.Equal(t, tunnelDownReason, status.Conditions[0].Reason)
+	assert.Equal(t, third, status.Conditions[0].LastTransitionTime)
+	assert.Equal(t, "", status.Connection.Latency)
+	assert.Equal(t, 100, status.Connection.PacketLoss)
+	assert.Equal(t, &second, status.Connection.LastSeen)
+
+	//test4 a probe error sets the condition to unknown
+	transition = setTunnelHealth(status, nil, errors.New("unable to bind"), third)
+	assert.NotNil(t, transition)
+	assert.Equal(t, corev1.ConditionUnknown, status.Conditions[0].Status)
+	assert.Equal(t, probeErrorReason, status.Conditions[0].Reason)
+	assert.Equal(t, "unable to bind", status.Conditions[0].Message)
+}
@@ -0,0 +1,89 @@
+package liqonetOperators
+
+import (
+	netv1alpha1 "github.com/liqotech/liqo/apis/net/v1alpha1"
+	liqonetOperator "github.com/liqotech/liqo/pkg/liqonet"
+	"k8s.io/klog"
+	"net"
+)
+
+//the MTU parameters of the tunnel towards a remote cluster, exposed in the status of the TunnelEndpoint
+type tunnelMTUParameters struct {
+	pathMTU   int
+	tunnelMTU int
+	tcpMSS    int
+}
+
+func (r *TunnelController) setMTUConfig(config liqonetOperator.MTUConfig) {
+	r.mtuMutex.Lock()
+	defer r.mtuMutex.Unlock()
+	r.mtuConfig = config
+}
+
+func (r *TunnelController) getMTUConfig() liqonetOperator.MTUConfig {
+	r.mtuMutex.Lock()
+	defer r.mtuMutex.Unlock()
+	return r.mtuConfig
+}
+
+//computes the MTU of the tunnel towards the remote cluster, configures it on the tunnel interface and
+//derives the MSS the TCP connections towards the remote cluster have to be clamped to
+func (r *TunnelController) configureTunnelMTU(endpoint *netv1alpha1.TunnelEndpoint, iFaceIndex int) (*tunnelMTUParameters, error) {
+	config := r.getMTUConfig()
+	clusterID := endpoint.Spec.ClusterID
+	remoteIP := net.ParseIP(endpoint.Spec.TunnelPublicIP)
+	pathMTU, err := r.getPathMTU(config, remoteIP)
+	if err != nil {
+		return nil, err
+	}
+	if r.tunnelMTUs == nil {
+		r.tunnelMTUs = make(map[string]int)
+	}
+	r.tunnelMTUs[clusterID] = liqonetOperator.ComputeTunnelMTU(pathMTU, r.DriverName, remoteIP)
+	//the interface may be shared by the tunnels towards different clusters, hence it gets the lowest MTU among them
+	tunnelMTU := r.tunnelMTUs[clusterID]
+	for id, mtu := range r.tunnelMTUs {
+		if r.TunnelIFacesPerRemoteCluster[id] == iFaceIndex && mtu < tunnelMTU {
+			tunnelMTU = mtu
+		}
+	}
+	if err := liqonetOperator.SetIFaceMTU(iFaceIndex, tunnelMTU); err != nil {
+		return nil, err
+	}
+	params := &tunnelMTUParameters{
+		pathMTU:   pathMTU,
+		tunnelMTU: tunnelMTU,
+	}
+	switch {
+	case config.DisableMSSClamping:
+	case config.TCPMSS > 0:
+		params.tcpMSS = config.TCPMSS
+	default:
+		params.tcpMSS = liqonetOperator.ComputeTCPMSS(tunnelMTU)
+	}
+	return params, nil
+}
+
+//returns the MTU of the path towards the remote gateway. The discovered MTUs are cached, since the discovery
+//may take a few seconds
+func (r *TunnelController) getPathMTU(config liqonetOperator.MTUConfig, remoteIP net.IP) (int, error) {
+	discovery := config.PathMTU == 0 && config.PathMTUDiscovery
+	if mtu, ok := r.pathMTUs[remoteIP.String()]; ok && discovery {
+		return mtu, nil
+	}
+	mtu, err := liqonetOperator.GetPathMTU(config, r.MTUProber, remoteIP)
+	if err != nil {
+		if mtu == 0 {
+			return 0, err
+		}
+		//the discovery failed and the MTU of the default interface is used in its place
+		klog.Warning(err)
+	}
+	if discovery {
+		if r.pathMTUs == nil {
+			r.pathMTUs = make(map[string]int)
+		}
+		r.pathMTUs[remoteIP.String()] = mtu
+	}
+	return mtu, nil
+}
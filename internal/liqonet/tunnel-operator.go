@@ -20,14 +20,18 @@ import (
 	liqonetOperator "github.com/liqotech/liqo/pkg/liqonet"
 	"github.com/vishvananda/netlink"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/record"
 	"k8s.io/client-go/util/retry"
 	"k8s.io/klog"
 	"os"
 	"os/signal"
+	"sync"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
 	"time"
 )
@@ -39,14 +43,32 @@ type TunnelController struct {
 	Recorder                     record.EventRecorder
 	TunnelIFacesPerRemoteCluster map[string]int
 	RetryTimeout                 time.Duration
+	ClientSet                    kubernetes.Interface
+	Namespace                    string
+	//the driver used to create the tunnels, selected from the cluster configuration
+	Driver       liqonetOperator.TunnelDriver
+	DriverName   string
+	IsConfigured bool
+	Configured   chan bool
+	//used to periodically check the reachability of the remote gateways through the tunnels
+	Prober        liqonetOperator.TunnelProber
+	ProbeInterval time.Duration
+	//used to discover the MTU of the path towards the remote gateways, if enabled in the cluster configuration
+	MTUProber liqonetOperator.PathMTUProber
+	mtuConfig liqonetOperator.MTUConfig
+	mtuMutex  sync.Mutex
+	//the discovered path MTUs per remote gateway and the MTUs of the tunnels per remote cluster
+	pathMTUs   map[string]int
+	tunnelMTUs map[string]int
 }
 
 // +kubebuilder:rbac:groups=net.liqo.io,resources=tunnelendpoints,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=net.liqo.io,resources=tunnelendpoints/status,verbs=get;update;patch
 
-func (r *TunnelController) Reconcile(req ctrl.Request) (ctrl.Result, error) {
+func (r *TunnelController) Reconcile(req ctrl.Request) (_ ctrl.Result, err error) {
 	ctx := context.Background()
 	var endpoint netv1alpha1.TunnelEndpoint
+	defer func() { recordReconcileError(tunnelOperatorName, endpoint.Spec.ClusterID, err) }()
 	//name of our finalizer
 	tunnelEndpointFinalizer := "tunnelEndpointFinalizer.net.liqo.io"
 	if err := r.Get(ctx, req.NamespacedName, &endpoint); err != nil {
@@ -74,7 +96,7 @@ func (r *TunnelController) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 	} else {
 		//the object is being deleted
 		if liqonetOperator.ContainsString(endpoint.Finalizers, tunnelEndpointFinalizer) {
-			if err := liqonetOperator.RemoveGreTunnel(&endpoint); err != nil {
+			if err := r.Driver.DisconnectFromEndpoint(&endpoint); err != nil {
 				//record an event and return
 				r.Recorder.Event(&endpoint, "Warning", "Processing", err.Error())
 				klog.Errorf("%s -> unable to remove tunnel network interface %s for resource %s: %s", endpoint.Spec.ClusterID, endpoint.Status.TunnelIFaceName, endpoint.Name, err)
@@ -83,6 +105,8 @@ func (r *TunnelController) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 			r.Recorder.Event(&endpoint, "Normal", "Processing", "tunnel network interface removed")
 			//safe to do, even if the key does not exist in the map
 			delete(r.TunnelIFacesPerRemoteCluster, endpoint.Spec.ClusterID)
+			delete(r.tunnelMTUs, endpoint.Spec.ClusterID)
+			deleteTunnelMetrics(endpoint.Spec.ClusterID)
 			klog.Infof("%s -> tunnel network interface %s removed for resource %s", endpoint.Spec.ClusterID, endpoint.Status.TunnelIFaceName, endpoint.Name)
 			retryError := retry.RetryOnConflict(retry.DefaultRetry, func() error {
 				if err := r.Get(ctx, req.NamespacedName, &endpoint); err != nil {
@@ -103,8 +127,8 @@ func (r *TunnelController) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 			return ctrl.Result{RequeueAfter: r.RetryTimeout}, nil
 		}
 	}
-	//try to install the GRE tunnel if it does not exist
-	iFaceIndex, iFaceName, err := liqonetOperator.InstallGreTunnel(&endpoint)
+	//try to install the tunnel if it does not exist
+	iFaceIndex, iFaceName, err := r.Driver.ConnectToEndpoint(&endpoint)
 	if err != nil {
 		klog.Errorf("%s -> unable to create tunnel network interface for resource %s :%s", endpoint.Spec.ClusterID, endpoint.Name, err)
 		r.Recorder.Event(&endpoint, "Warning", "Processing", err.Error())
@@ -114,6 +138,12 @@ func (r *TunnelController) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 	klog.Infof("%s -> tunnel network interface with name %s for resource %s created successfully", endpoint.Spec.ClusterID, iFaceName, endpoint.Name)
 	//save the IFace index in the map
 	r.TunnelIFacesPerRemoteCluster[endpoint.Spec.ClusterID] = iFaceIndex
+	mtu, err := r.configureTunnelMTU(&endpoint, iFaceIndex)
+	if err != nil {
+		klog.Errorf("%s -> unable to configure the MTU of tunnel network interface %s: %s", endpoint.Spec.ClusterID, iFaceName, err)
+		r.Recorder.Event(&endpoint, "Warning", "Processing", err.Error())
+		return ctrl.Result{RequeueAfter: r.RetryTimeout}, err
+	}
 	//update the status of CR if needed
 	//here we recover from conflicting resource versions
 	retryError := retry.RetryOnConflict(retry.DefaultRetry, func() error {
@@ -130,6 +160,12 @@ func (r *TunnelController) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 			endpoint.Status.TunnelIFaceIndex = iFaceIndex
 			toBeUpdated = true
 		}
+		if endpoint.Status.PathMTU != mtu.pathMTU || endpoint.Status.TunnelMTU != mtu.tunnelMTU || endpoint.Status.TCPMSS != mtu.tcpMSS {
+			endpoint.Status.PathMTU = mtu.pathMTU
+			endpoint.Status.TunnelMTU = mtu.tunnelMTU
+			endpoint.Status.TCPMSS = mtu.tcpMSS
+			toBeUpdated = true
+		}
 		if toBeUpdated {
 			err = r.Status().Update(context.Background(), &endpoint)
 			return err
@@ -140,6 +176,7 @@ func (r *TunnelController) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 		klog.Errorf("%s -> unable to update status of resource %s: %s", endpoint.Spec.ClusterID, endpoint.Name, retryError)
 		return ctrl.Result{RequeueAfter: r.RetryTimeout}, retryError
 	}
+	setTunnelMetrics(endpoint.Spec.ClusterID, &endpoint.Status)
 	return ctrl.Result{RequeueAfter: r.RetryTimeout}, nil
 }
 
@@ -147,7 +184,13 @@ func (r *TunnelController) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 //it does not return an error, but just logs them, cause we can not recover from
 //them at exit time
 func (r *TunnelController) RemoveAllTunnels() {
+	//the same interface may be shared by the tunnels towards different clusters
+	removed := make(map[int]bool)
 	for clusterID, ifaceIndex := range r.TunnelIFacesPerRemoteCluster {
+		if removed[ifaceIndex] {
+			continue
+		}
+		removed[ifaceIndex] = true
 		existingIface, err := netlink.LinkByIndex(ifaceIndex)
 		if err == nil {
 			//Remove the existing gre interface
@@ -184,6 +227,21 @@ func (r *TunnelController) SetupWithManager(mgr ctrl.Manager) error {
 			return false
 		},
 	}
+	//answer to the probes of the remote gateways and probe them in turn
+	if err := mgr.Add(manager.RunnableFunc(func(stop <-chan struct{}) error {
+		return liqonetOperator.RunProbeResponder(liqonetOperator.TunnelProbePort, stop)
+	})); err != nil {
+		return err
+	}
+	if r.Prober != nil {
+		if err := mgr.Add(manager.RunnableFunc(r.StartTunnelProbing)); err != nil {
+			return err
+		}
+	}
+	//the counters of the tunnel interfaces are exposed together with the other metrics of the operator
+	if err := metrics.Registry.Register(newTunnelStatsCollector(mgr.GetClient())); err != nil {
+		return err
+	}
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&netv1alpha1.TunnelEndpoint{}).WithEventFilter(resourceToBeProccesedPredicate).
 		Complete(r)
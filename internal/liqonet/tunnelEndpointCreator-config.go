@@ -35,23 +35,77 @@ func (r *TunnelEndpointCreator) GetConfiguration(config *configv1alpha1.ClusterC
 			reservedSubnets[sn.String()] = sn
 		}
 	}
+	//the IPv6 PodCIDR of the local cluster, if any, is always reserved
+	if liqonetConfig.IPv6PodCIDR != "" {
+		_, sn, err := net.ParseCIDR(liqonetConfig.IPv6PodCIDR)
+		if err != nil {
+			klog.Errorf("an error occurred while parsing configuration: %s", err)
+			correctlyParsed = false
+		} else {
+			reservedSubnets[sn.String()] = sn
+		}
+	}
 	if !correctlyParsed {
 		return nil, fmt.Errorf("the reserved subnets list is not in the correct format")
 	}
 	return reservedSubnets, nil
 }
 
+//sets the pools used by the IPAM to remap the PodCIDRs of the peering clusters.
+//The pools are read only at start-up time, hence any change requires a restart.
+func (r *TunnelEndpointCreator) SetIPAMConfiguration(config *configv1alpha1.ClusterConfig) error {
+	liqonetConfig := config.Spec.LiqonetConfig
+	if liqonetConfig.IPv4Pool != "" {
+		_, pool, err := net.ParseCIDR(liqonetConfig.IPv4Pool)
+		if err != nil {
+			return fmt.Errorf("unable to parse the IPv4 pool: %s", err)
+		}
+		if !liqonetOperator.IsIPv4(pool) {
+			return fmt.Errorf("the IPv4 pool %s is not an IPv4 subnet", liqonetConfig.IPv4Pool)
+		}
+		r.IPManager.IPv4Pool = pool
+	}
+	if liqonetConfig.IPv6Pool != "" {
+		_, pool, err := net.ParseCIDR(liqonetConfig.IPv6Pool)
+		if err != nil {
+			return fmt.Errorf("unable to parse the IPv6 pool: %s", err)
+		}
+		if liqonetOperator.IsIPv4(pool) {
+			return fmt.Errorf("the IPv6 pool %s is not an IPv6 subnet", liqonetConfig.IPv6Pool)
+		}
+		r.IPManager.IPv6Pool = pool
+	}
+	r.IPManager.IPv4PrefixLength = liqonetConfig.IPv4PrefixLength
+	r.IPManager.IPv6PrefixLength = liqonetConfig.IPv6PrefixLength
+	return nil
+}
+
 func (r *TunnelEndpointCreator) SetNetParameters(config *configv1alpha1.ClusterConfig) {
 	podCIDR := config.Spec.LiqonetConfig.PodCIDR
+	ipv6PodCIDR := config.Spec.LiqonetConfig.IPv6PodCIDR
 	serviceCIDR := config.Spec.LiqonetConfig.ServiceCIDR
+	tunnelDriver := config.Spec.LiqonetConfig.TunnelDriver
+	wireGuardPort := config.Spec.LiqonetConfig.WireGuardPort
 	if r.PodCIDR != podCIDR {
 		klog.Infof("setting podCIDR to %s", podCIDR)
 		r.PodCIDR = podCIDR
 	}
+	if r.IPv6PodCIDR != ipv6PodCIDR {
+		klog.Infof("setting IPv6 podCIDR to %s", ipv6PodCIDR)
+		r.IPv6PodCIDR = ipv6PodCIDR
+	}
 	if r.ServiceCIDR != serviceCIDR {
 		klog.Infof("setting serviceCIDR to %s", serviceCIDR)
 		r.ServiceCIDR = serviceCIDR
 	}
+	if r.TunnelDriver != tunnelDriver {
+		klog.Infof("setting tunnel driver to %s", tunnelDriver)
+		r.TunnelDriver = tunnelDriver
+	}
+	if r.WireGuardPort != wireGuardPort {
+		klog.Infof("setting WireGuard port to %d", wireGuardPort)
+		r.WireGuardPort = wireGuardPort
+	}
 }
 
 //it returns the subnets used by the foreign clusters
@@ -83,27 +137,35 @@ func (r *TunnelEndpointCreator) GetClustersSubnets() (map[string]*net.IPNet, err
 		return nil, nil
 	}
 	for _, tunEnd := range tunEndList.Items {
-		if tunEnd.Status.LocalRemappedPodCIDR != "" && tunEnd.Status.LocalRemappedPodCIDR != defaultPodCIDRValue {
-			_, sn, err := net.ParseCIDR(tunEnd.Status.LocalRemappedPodCIDR)
-			if err != nil {
-				klog.Errorf("an error occurred while parsing the following cidr %s: %s", tunEnd.Status.LocalRemappedPodCIDR, err)
-				return nil, err
-			}
-			subnets[sn.String()] = sn
-			klog.Infof("subnet %s already reserved for cluster %s", tunEnd.Status.LocalRemappedPodCIDR, tunEnd.Spec.ClusterID)
-		} else if tunEnd.Status.LocalRemappedPodCIDR == defaultPodCIDRValue {
-			_, sn, err := net.ParseCIDR(tunEnd.Spec.PodCIDR)
-			if err != nil {
-				klog.Errorf("an error occurred while parsing the following cidr %s: %s", tunEnd.Spec.PodCIDR, err)
-				return nil, err
-			}
-			subnets[sn.String()] = sn
-			klog.Infof("subnet %s already reserved for cluster %s", tunEnd.Spec.PodCIDR, tunEnd.Spec.ClusterID)
+		if err := addClusterSubnet(subnets, tunEnd.Spec.ClusterID, tunEnd.Status.LocalRemappedPodCIDR, tunEnd.Spec.PodCIDR); err != nil {
+			return nil, err
+		}
+		if err := addClusterSubnet(subnets, tunEnd.Spec.ClusterID, tunEnd.Status.LocalRemappedIPv6PodCIDR, tunEnd.Spec.IPv6PodCIDR); err != nil {
+			return nil, err
 		}
 	}
 	return subnets, nil
 }
 
+//adds to subnets the subnet used by a foreign cluster: the remapped one if the NAT is enabled, the original one otherwise
+func addClusterSubnet(subnets map[string]*net.IPNet, clusterID, remappedPodCIDR, podCIDR string) error {
+	subnet := remappedPodCIDR
+	if remappedPodCIDR == defaultPodCIDRValue {
+		subnet = podCIDR
+	}
+	if subnet == "" {
+		return nil
+	}
+	_, sn, err := net.ParseCIDR(subnet)
+	if err != nil {
+		klog.Errorf("an error occurred while parsing the following cidr %s: %s", subnet, err)
+		return err
+	}
+	subnets[sn.String()] = sn
+	klog.Infof("subnet %s already reserved for cluster %s", subnet, clusterID)
+	return nil
+}
+
 func (r *TunnelEndpointCreator) InitConfiguration(reservedSubnets map[string]*net.IPNet, clusterSubnets map[string]*net.IPNet) error {
 	var isError = false
 	//here we check that there are no conflicts between the configuration and the already used subnets
@@ -242,6 +304,11 @@ func (r *TunnelEndpointCreator) WatchConfiguration(config *rest.Config, gv *sche
 
 		//this section is executed at start-up time
 		if !r.IsConfigured {
+			//get the pools used by the IPAM from the configuration CRD
+			if err := r.SetIPAMConfiguration(configuration); err != nil {
+				klog.Error(err)
+				return
+			}
 			//get the reserved subnets from che configuration CRD
 			reservedSubnets, err := r.GetConfiguration(configuration)
 			if err != nil {
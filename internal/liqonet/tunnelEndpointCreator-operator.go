@@ -29,6 +29,7 @@ import (
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/util/retry"
 	"k8s.io/klog"
@@ -36,6 +37,8 @@ import (
 	"net"
 	"os"
 	"os/signal"
+	"reflect"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -68,12 +71,17 @@ var (
 )
 
 type networkParam struct {
-	remoteClusterID  string
-	remoteGatewayIP  string
-	remotePodCIDR    string
-	remoteNatPodCIDR string
-	localGatewayIP   string
-	localNatPodCIDR  string
+	remoteClusterID      string
+	remoteGatewayIP      string
+	remotePodCIDR        string
+	remoteNatPodCIDR     string
+	remoteIPv6PodCIDR    string
+	remoteNatIPv6PodCIDR string
+	localGatewayIP       string
+	localNatPodCIDR      string
+	localNatIPv6PodCIDR  string
+	remoteBackendType    string
+	remoteBackendConfig  map[string]string
 }
 
 type TunnelEndpointCreator struct {
@@ -84,7 +92,12 @@ type TunnelEndpointCreator struct {
 	DynFactory                 dynamicinformer.DynamicSharedInformerFactory
 	GatewayIP                  string
 	PodCIDR                    string
+	IPv6PodCIDR                string
 	ServiceCIDR                string
+	TunnelDriver               string
+	WireGuardPort              int
+	ClientSet                  kubernetes.Interface
+	Namespace                  string
 	netParamPerCluster         map[string]networkParam
 	ReservedSubnets            map[string]*net.IPNet
 	IPManager                  liqonetOperator.IpManager
@@ -100,6 +113,7 @@ type TunnelEndpointCreator struct {
 //rbac for the net.liqo.io api
 // +kubebuilder:rbac:groups=net.liqo.io,resources=tunnelendpoints,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=net.liqo.io,resources=tunnelendpoints/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=net.liqo.io,resources=ipamstorages,verbs=get;list;watch;create;update
 
 func (r *TunnelEndpointCreator) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 	if !r.IsConfigured {
@@ -145,6 +159,13 @@ func (r *TunnelEndpointCreator) Reconcile(req ctrl.Request) (ctrl.Result, error)
 			klog.Errorf("an error occurred while deleting tunnel endpoint related to %s: %s", netConfig.Name, err)
 			return result, err
 		}
+		//remove the reserved ip for the cluster before removing the finalizer, so that it is retried in case of errors
+		r.Mutex.Lock()
+		err := r.IPManager.RemoveReservedSubnet(netConfig.Spec.ClusterID)
+		r.Mutex.Unlock()
+		if err != nil {
+			return result, err
+		}
 		if liqonetOperator.ContainsString(netConfig.Finalizers, tunnelEndpointCreatorFinalizer) {
 			//remove the finalizer from the list and update it.
 			netConfig.Finalizers = liqonetOperator.RemoveString(netConfig.Finalizers, tunnelEndpointCreatorFinalizer)
@@ -156,8 +177,6 @@ func (r *TunnelEndpointCreator) Reconcile(req ctrl.Request) (ctrl.Result, error)
 				return result, err
 			}
 		}
-		//remove the reserved ip for the cluster
-		r.IPManager.RemoveReservedSubnet(netConfig.Spec.ClusterID)
 		return result, nil
 	}
 
@@ -206,6 +225,15 @@ func (d *TunnelEndpointCreator) Watcher(sharedDynFactory dynamicinformer.Dynamic
 
 func (r *TunnelEndpointCreator) createNetConfig(fc *discoveryv1alpha1.ForeignCluster) error {
 	clusterID := fc.Spec.ClusterIdentity.ClusterID
+	gatewayIP := r.getGatewayIP()
+	if gatewayIP == "" {
+		return fmt.Errorf("the gateway node of the local cluster is not known yet")
+	}
+	backendType, backendConfig, err := r.getBackendConfig()
+	if err != nil {
+		klog.Errorf("an error occurred while getting the configuration of the tunnel driver: %s", err)
+		return err
+	}
 	netConfig := netv1alpha1.NetworkConfig{
 		ObjectMeta: metav1.ObjectMeta{
 			GenerateName: NetConfigNamePrefix,
@@ -226,17 +254,20 @@ func (r *TunnelEndpointCreator) createNetConfig(fc *discoveryv1alpha1.ForeignClu
 		Spec: netv1alpha1.NetworkConfigSpec{
 			ClusterID:      clusterID,
 			PodCIDR:        r.PodCIDR,
-			TunnelPublicIP: r.GatewayIP,
+			IPv6PodCIDR:    r.IPv6PodCIDR,
+			TunnelPublicIP: gatewayIP,
+			BackendType:    backendType,
+			BackendConfig:  backendConfig,
 		},
 		Status: netv1alpha1.NetworkConfigStatus{},
 	}
 	//check if the resource for the remote cluster already exists
-	_, exists, err := r.GetNetworkConfig(clusterID)
+	existing, exists, err := r.GetNetworkConfig(clusterID)
 	if err != nil {
 		return err
 	}
 	if exists {
-		return nil
+		return r.updateNetConfigSpec(existing, gatewayIP, backendType, backendConfig)
 	}
 	err = r.Create(context.TODO(), &netConfig)
 	if err != nil {
@@ -248,6 +279,74 @@ func (r *TunnelEndpointCreator) createNetConfig(fc *discoveryv1alpha1.ForeignClu
 
 }
 
+//returns the tunnel driver used by the local cluster and the parameters the remote clusters need to connect to it
+func (r *TunnelEndpointCreator) getBackendConfig() (string, map[string]string, error) {
+	switch r.TunnelDriver {
+	case "", liqonetOperator.GreDriverName:
+		return liqonetOperator.GreDriverName, nil, nil
+	case liqonetOperator.WireGuardDriverName:
+		backendConfig, err := liqonetOperator.GetWireGuardBackendConfig(r.ClientSet, r.Namespace, r.WireGuardPort)
+		return liqonetOperator.WireGuardDriverName, backendConfig, err
+	default:
+		return "", nil, fmt.Errorf("unknown tunnel driver %s", r.TunnelDriver)
+	}
+}
+
+//updates the gateway IP and the tunnel driver parameters of an existing netConfig, if changed
+func (r *TunnelEndpointCreator) updateNetConfigSpec(netConfig *netv1alpha1.NetworkConfig, gatewayIP, backendType string, backendConfig map[string]string) error {
+	if netConfig.Spec.TunnelPublicIP == gatewayIP && netConfig.Spec.BackendType == backendType &&
+		reflect.DeepEqual(netConfig.Spec.BackendConfig, backendConfig) {
+		return nil
+	}
+	netConfig.Spec.TunnelPublicIP = gatewayIP
+	netConfig.Spec.BackendType = backendType
+	netConfig.Spec.BackendConfig = backendConfig
+	if err := r.Update(context.TODO(), netConfig); err != nil {
+		klog.Errorf("an error occurred while updating the tunnel driver of resource %s: %s", netConfig.Name, err)
+		return err
+	}
+	return nil
+}
+
+func (r *TunnelEndpointCreator) getGatewayIP() string {
+	r.Mutex.Lock()
+	defer r.Mutex.Unlock()
+	return r.GatewayIP
+}
+
+// SetGatewayIP is called when the node acting as gateway changes: the new IP is set in the local netConfigs,
+// so that the remote clusters connect to the new gateway and the LocalTunnelPublicIP of the tunnelEndpoints is updated.
+func (r *TunnelEndpointCreator) SetGatewayIP(gatewayIP string) error {
+	r.Mutex.Lock()
+	r.GatewayIP = gatewayIP
+	r.Mutex.Unlock()
+	netConfigList := &netv1alpha1.NetworkConfigList{}
+	labels := client.MatchingLabels{crdReplicator.LocalLabelSelector: "true"}
+	if err := r.List(context.Background(), netConfigList, labels); err != nil {
+		klog.Errorf("an error occurred while listing resources: %s", err)
+		return err
+	}
+	for i := range netConfigList.Items {
+		netConfig := &netConfigList.Items[i]
+		if netConfig.Spec.TunnelPublicIP == gatewayIP {
+			continue
+		}
+		err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+			if err := r.Get(context.Background(), client.ObjectKey{Name: netConfig.Name}, netConfig); err != nil {
+				return err
+			}
+			netConfig.Spec.TunnelPublicIP = gatewayIP
+			return r.Update(context.Background(), netConfig)
+		})
+		if err != nil {
+			klog.Errorf("an error occurred while updating the gateway IP of resource %s: %s", netConfig.Name, err)
+			return err
+		}
+		klog.Infof("%s -> gateway IP of resource %s updated to %s", netConfig.Spec.ClusterID, netConfig.Name, gatewayIP)
+	}
+	return nil
+}
+
 func (r *TunnelEndpointCreator) deleteNetConfig(fc *discoveryv1alpha1.ForeignCluster) error {
 	clusterID := fc.Spec.ClusterIdentity.ClusterID
 	netConfigList := &netv1alpha1.NetworkConfigList{}
@@ -316,25 +415,21 @@ func (r *TunnelEndpointCreator) processRemoteNetConfig(netConfig *netv1alpha1.Ne
 	defer r.Mutex.Unlock()
 	//networkconfigs resources received from remote clusters contains the clusterID of the destination cluster,
 	//so in order to take the clusterID of the sender we need to retrieve it from the labels.
-	newSubnet, err := r.IPManager.GetNewSubnetPerCluster(clusterSubnet, netConfig.Labels[crdReplicator.RemoteLabelSelector])
+	remoteClusterID := netConfig.Labels[crdReplicator.RemoteLabelSelector]
+	newSubnet, err := r.IPManager.GetNewSubnetPerCluster(clusterSubnet, remoteClusterID)
 	if err != nil {
 		klog.Errorf("an error occurred while getting a new subnet for resource %s: %s", netConfig.Name, err)
 		return err
 	}
+	ipv6PodCIDRNAT, err := r.remapIPv6PodCIDR(netConfig, remoteClusterID)
+	if err != nil {
+		klog.Errorf("an error occurred while getting a new IPv6 subnet for resource %s: %s", netConfig.Name, err)
+		return err
+	}
 
 	//if they are different, the NAT is needed and a new subnet have been reserved for the peering cluster
 	if newSubnet.String() != clusterSubnet.String() {
-		if newSubnet.String() != netConfig.Status.PodCIDRNAT {
-			//update netConfig status
-			netConfig.Status.PodCIDRNAT = newSubnet.String()
-			netConfig.Status.NATEnabled = "true"
-			err := r.Status().Update(context.Background(), netConfig)
-			if err != nil {
-				klog.Errorf("an error occurred while updating the status of resource %s: %s", netConfig.Name, err)
-				return err
-			}
-		}
-		return nil
+		return r.updateNetConfigStatus(netConfig, newSubnet.String(), ipv6PodCIDRNAT)
 	}
 	if owner.GetOwnerByKind(&netConfig.OwnerReferences, "ForeignCluster") == nil {
 		// if it has no owner of kind ForeignCluster, add it
@@ -352,17 +447,45 @@ func (r *TunnelEndpointCreator) processRemoteNetConfig(netConfig *netv1alpha1.Ne
 			}
 		}
 	}
-	if netConfig.Status.PodCIDRNAT != defaultPodCIDRValue {
-		//update netConfig status
-		netConfig.Status.PodCIDRNAT = defaultPodCIDRValue
-		netConfig.Status.NATEnabled = "false"
-		err := r.Status().Update(context.Background(), netConfig)
-		if err != nil {
-			klog.Errorf("an error occurred while updating the status of resource %s: %s", netConfig.Name, err)
-			return err
-		}
+	return r.updateNetConfigStatus(netConfig, defaultPodCIDRValue, ipv6PodCIDRNAT)
+}
+
+//returns the subnet used to remap the IPv6 PodCIDR of the remote cluster, defaultPodCIDRValue if the NAT is not needed
+//and an empty string if either the local or the remote cluster is not dual-stack.
+func (r *TunnelEndpointCreator) remapIPv6PodCIDR(netConfig *netv1alpha1.NetworkConfig, remoteClusterID string) (string, error) {
+	if r.IPv6PodCIDR == "" || netConfig.Spec.IPv6PodCIDR == "" {
+		return "", nil
+	}
+	_, clusterSubnet, err := net.ParseCIDR(netConfig.Spec.IPv6PodCIDR)
+	if err != nil {
+		return "", err
+	}
+	if liqonetOperator.IsIPv4(clusterSubnet) {
+		return "", fmt.Errorf("the IPv6 PodCIDR %s is not an IPv6 subnet", netConfig.Spec.IPv6PodCIDR)
+	}
+	newSubnet, err := r.IPManager.GetNewSubnetPerCluster(clusterSubnet, remoteClusterID)
+	if err != nil {
+		return "", err
+	}
+	if newSubnet.String() == clusterSubnet.String() {
+		return defaultPodCIDRValue, nil
+	}
+	return newSubnet.String(), nil
+}
+
+//sets the subnets used to remap the PodCIDRs of the remote cluster in the status of the netConfig, if changed
+func (r *TunnelEndpointCreator) updateNetConfigStatus(netConfig *netv1alpha1.NetworkConfig, podCIDRNAT, ipv6PodCIDRNAT string) error {
+	if netConfig.Status.PodCIDRNAT == podCIDRNAT && netConfig.Status.IPv6PodCIDRNAT == ipv6PodCIDRNAT {
 		return nil
 	}
+	natEnabled := podCIDRNAT != defaultPodCIDRValue || (ipv6PodCIDRNAT != "" && ipv6PodCIDRNAT != defaultPodCIDRValue)
+	netConfig.Status.PodCIDRNAT = podCIDRNAT
+	netConfig.Status.IPv6PodCIDRNAT = ipv6PodCIDRNAT
+	netConfig.Status.NATEnabled = strconv.FormatBool(natEnabled)
+	if err := r.Status().Update(context.Background(), netConfig); err != nil {
+		klog.Errorf("an error occurred while updating the status of resource %s: %s", netConfig.Name, err)
+		return err
+	}
 	return nil
 }
 
@@ -395,12 +518,17 @@ func (r *TunnelEndpointCreator) processLocalNetConfig(netConfig *netv1alpha1.Net
 	//at this point we have all the necessary parameters to create the tunnelEndpoint resource
 	remoteNetConf := netConfigList.Items[0]
 	netParam := networkParam{
-		remoteClusterID:  netConfig.Spec.ClusterID,
-		remoteGatewayIP:  remoteNetConf.Spec.TunnelPublicIP,
-		remotePodCIDR:    remoteNetConf.Spec.PodCIDR,
-		remoteNatPodCIDR: remoteNetConf.Status.PodCIDRNAT,
-		localNatPodCIDR:  netConfig.Status.PodCIDRNAT,
-		localGatewayIP:   netConfig.Spec.TunnelPublicIP,
+		remoteClusterID:      netConfig.Spec.ClusterID,
+		remoteGatewayIP:      remoteNetConf.Spec.TunnelPublicIP,
+		remotePodCIDR:        remoteNetConf.Spec.PodCIDR,
+		remoteNatPodCIDR:     remoteNetConf.Status.PodCIDRNAT,
+		remoteIPv6PodCIDR:    remoteNetConf.Spec.IPv6PodCIDR,
+		remoteNatIPv6PodCIDR: remoteNetConf.Status.IPv6PodCIDRNAT,
+		localNatPodCIDR:      netConfig.Status.PodCIDRNAT,
+		localNatIPv6PodCIDR:  netConfig.Status.IPv6PodCIDRNAT,
+		localGatewayIP:       netConfig.Spec.TunnelPublicIP,
+		remoteBackendType:    remoteNetConf.Spec.BackendType,
+		remoteBackendConfig:  remoteNetConf.Spec.BackendConfig,
 	}
 	fcOwner := owner.GetOwnerByKind(&netConfig.OwnerReferences, "ForeignCluster")
 	if err := r.ProcessTunnelEndpoint(netParam, fcOwner); err != nil {
@@ -455,6 +583,18 @@ func (r *TunnelEndpointCreator) UpdateSpecTunnelEndpoint(param networkParam) err
 			tep.Spec.PodCIDR = param.remotePodCIDR
 			toBeUpdated = true
 		}
+		if tep.Spec.IPv6PodCIDR != param.remoteIPv6PodCIDR {
+			tep.Spec.IPv6PodCIDR = param.remoteIPv6PodCIDR
+			toBeUpdated = true
+		}
+		if tep.Spec.BackendType != param.remoteBackendType {
+			tep.Spec.BackendType = param.remoteBackendType
+			toBeUpdated = true
+		}
+		if !reflect.DeepEqual(tep.Spec.BackendConfig, param.remoteBackendConfig) {
+			tep.Spec.BackendConfig = param.remoteBackendConfig
+			toBeUpdated = true
+		}
 		if toBeUpdated {
 			err = r.Update(context.Background(), tep)
 			return err
@@ -490,6 +630,14 @@ func (r *TunnelEndpointCreator) UpdateStatusTunnelEndpoint(param networkParam) e
 			tep.Status.RemoteRemappedPodCIDR = param.remoteNatPodCIDR
 			toBeUpdated = true
 		}
+		if tep.Status.LocalRemappedIPv6PodCIDR != param.localNatIPv6PodCIDR {
+			tep.Status.LocalRemappedIPv6PodCIDR = param.localNatIPv6PodCIDR
+			toBeUpdated = true
+		}
+		if tep.Status.RemoteRemappedIPv6PodCIDR != param.remoteNatIPv6PodCIDR {
+			tep.Status.RemoteRemappedIPv6PodCIDR = param.remoteNatIPv6PodCIDR
+			toBeUpdated = true
+		}
 		if tep.Status.LocalTunnelPublicIP != param.localGatewayIP {
 			tep.Status.LocalTunnelPublicIP = param.localGatewayIP
 			toBeUpdated = true
@@ -527,14 +675,19 @@ func (r *TunnelEndpointCreator) CreateTunnelEndpoint(param networkParam, owner *
 		Spec: netv1alpha1.TunnelEndpointSpec{
 			ClusterID:      param.remoteClusterID,
 			PodCIDR:        param.remotePodCIDR,
+			IPv6PodCIDR:    param.remoteIPv6PodCIDR,
 			TunnelPublicIP: param.remoteGatewayIP,
+			BackendType:    param.remoteBackendType,
+			BackendConfig:  param.remoteBackendConfig,
 		},
 		Status: netv1alpha1.TunnelEndpointStatus{
-			Phase:                 "Ready",
-			LocalRemappedPodCIDR:  param.localNatPodCIDR,
-			RemoteRemappedPodCIDR: param.remoteNatPodCIDR,
-			RemoteTunnelPublicIP:  param.remoteGatewayIP,
-			LocalTunnelPublicIP:   param.localGatewayIP,
+			Phase:                     "Ready",
+			LocalRemappedPodCIDR:      param.localNatPodCIDR,
+			RemoteRemappedPodCIDR:     param.remoteNatPodCIDR,
+			LocalRemappedIPv6PodCIDR:  param.localNatIPv6PodCIDR,
+			RemoteRemappedIPv6PodCIDR: param.remoteNatIPv6PodCIDR,
+			RemoteTunnelPublicIP:      param.remoteGatewayIP,
+			LocalTunnelPublicIP:       param.localGatewayIP,
 		},
 	}
 	if owner != nil {
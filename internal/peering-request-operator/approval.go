@@ -0,0 +1,122 @@
+package peering_request_operator
+
+import (
+	"errors"
+	discoveryv1alpha1 "github.com/liqotech/liqo/apis/discovery/v1alpha1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog"
+	"strings"
+)
+
+// GetApproval returns whether the peering has been approved. Without manual approval every accepted
+// PeeringRequest is approved, otherwise it stays pending until an administrator sets the approval annotation.
+func GetApproval(pr *discoveryv1alpha1.PeeringRequest, manualApproval bool) discoveryv1alpha1.PeeringApproval {
+	if !manualApproval {
+		return discoveryv1alpha1.PeeringApprovalApproved
+	}
+	switch value := pr.Annotations[discoveryv1alpha1.PeeringApprovalAnnotation]; {
+	case strings.EqualFold(value, string(discoveryv1alpha1.PeeringApprovalApproved)):
+		return discoveryv1alpha1.PeeringApprovalApproved
+	case strings.EqualFold(value, string(discoveryv1alpha1.PeeringApprovalRejected)):
+		return discoveryv1alpha1.PeeringApprovalRejected
+	default:
+		return discoveryv1alpha1.PeeringApprovalPending
+	}
+}
+
+// returns whether the incoming peerings have to be approved by an administrator. If the operator has no
+// configuration, they are approved automatically
+func (r *PeeringRequestReconciler) isManualApproval() (bool, error) {
+	conf, err := GetConfig(r.crdClient, r.Namespace)
+	if k8serrors.IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return conf.ManualApproval, nil
+}
+
+// returns the ForeignClusters of the cluster which sent the PeeringRequest
+func (r *PeeringRequestReconciler) getForeignClusters(pr *discoveryv1alpha1.PeeringRequest) ([]discoveryv1alpha1.ForeignCluster, error) {
+	tmp, err := r.crdClient.Resource("foreignclusters").List(metav1.ListOptions{
+		LabelSelector: "cluster-id=" + pr.Spec.ClusterIdentity.ClusterID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	fcList, ok := tmp.(*discoveryv1alpha1.ForeignClusterList)
+	if !ok {
+		return nil, errors.New("retrieved object is not a ForeignClusterList")
+	}
+	return fcList.Items, nil
+}
+
+// returns the approval of a PeeringRequest without the approval annotation: the peerings established before the
+// manual approval has been enabled are approved, the ones of a cluster rejected in the past are rejected again,
+// while the others stay pending
+func (r *PeeringRequestReconciler) getImplicitApproval(pr *discoveryv1alpha1.PeeringRequest) (discoveryv1alpha1.PeeringApproval, error) {
+	fcs, err := r.getForeignClusters(pr)
+	if err != nil {
+		return "", err
+	}
+	for i := range fcs {
+		if isRejected(&fcs[i]) {
+			return discoveryv1alpha1.PeeringApprovalRejected, nil
+		}
+	}
+	if pr.Status.BroadcasterRef != nil {
+		return discoveryv1alpha1.PeeringApprovalApproved, nil
+	}
+	for i := range fcs {
+		if fcs[i].Status.Incoming.Joined || fcs[i].Status.Incoming.PeeringRequest != nil {
+			return discoveryv1alpha1.PeeringApprovalApproved, nil
+		}
+	}
+	return discoveryv1alpha1.PeeringApprovalPending, nil
+}
+
+// returns whether the peering of the foreign cluster has been rejected by an administrator
+func isRejected(fc *discoveryv1alpha1.ForeignCluster) bool {
+	return strings.EqualFold(fc.Annotations[discoveryv1alpha1.PeeringApprovalAnnotation], string(discoveryv1alpha1.PeeringApprovalRejected))
+}
+
+// removes a rejected peering: the ForeignCluster is unlinked from the PeeringRequest and marked as rejected, so
+// that the following PeeringRequests of the same cluster are rejected as well (a ForeignCluster is created to
+// this end if the cluster has none). Then the PeeringRequest is deleted, together with the broadcaster it owns
+func (r *PeeringRequestReconciler) rejectPeering(pr *discoveryv1alpha1.PeeringRequest) error {
+	fcs, err := r.getForeignClusters(pr)
+	if err != nil {
+		klog.Error(err, err.Error())
+		return err
+	}
+	if len(fcs) == 0 {
+		fc, err := r.createForeignCluster(pr)
+		if err != nil {
+			return err
+		}
+		fcs = append(fcs, *fc)
+	}
+
+	for i := range fcs {
+		fc := &fcs[i]
+		if isRejected(fc) && fc.Status.Incoming.PeeringRequest == nil {
+			continue
+		}
+		metav1.SetMetaDataAnnotation(&fc.ObjectMeta, discoveryv1alpha1.PeeringApprovalAnnotation, string(discoveryv1alpha1.PeeringApprovalRejected))
+		fc.Status.Incoming.PeeringRequest = nil
+		_, err = r.crdClient.Resource("foreignclusters").Update(fc.Name, fc, metav1.UpdateOptions{})
+		if err != nil && !k8serrors.IsNotFound(err) {
+			klog.Error(err, err.Error())
+			return err
+		}
+	}
+
+	err = r.crdClient.Resource("peeringrequests").Delete(pr.Name, metav1.DeleteOptions{})
+	if err != nil && !k8serrors.IsNotFound(err) {
+		klog.Error(err, err.Error())
+		return err
+	}
+	return nil
+}
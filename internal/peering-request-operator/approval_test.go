@@ -0,0 +1,27 @@
+package peering_request_operator
+
+import (
+	discoveryv1alpha1 "github.com/liqotech/liqo/apis/discovery/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"testing"
+)
+
+func TestGetApproval(t *testing.T) {
+	pr := &discoveryv1alpha1.PeeringRequest{}
+
+	// without manual approval every peering is approved
+	assert.Equal(t, discoveryv1alpha1.PeeringApprovalApproved, GetApproval(pr, false))
+
+	// the peering is pending until the annotation is set
+	assert.Equal(t, discoveryv1alpha1.PeeringApprovalPending, GetApproval(pr, true))
+	metav1.SetMetaDataAnnotation(&pr.ObjectMeta, discoveryv1alpha1.PeeringApprovalAnnotation, "unknown")
+	assert.Equal(t, discoveryv1alpha1.PeeringApprovalPending, GetApproval(pr, true))
+
+	metav1.SetMetaDataAnnotation(&pr.ObjectMeta, discoveryv1alpha1.PeeringApprovalAnnotation, "approved")
+	assert.Equal(t, discoveryv1alpha1.PeeringApprovalApproved, GetApproval(pr, true))
+
+	metav1.SetMetaDataAnnotation(&pr.ObjectMeta, discoveryv1alpha1.PeeringApprovalAnnotation, string(discoveryv1alpha1.PeeringApprovalRejected))
+	assert.Equal(t, discoveryv1alpha1.PeeringApprovalRejected, GetApproval(pr, true))
+	assert.Equal(t, discoveryv1alpha1.PeeringApprovalApproved, GetApproval(pr, false))
+}
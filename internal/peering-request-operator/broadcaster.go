@@ -10,6 +10,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/utils/pointer"
 	"strings"
+	"time"
 )
 
 const (
@@ -31,7 +32,7 @@ func (r *PeeringRequestReconciler) BroadcasterExists(request *discoveryv1alpha1.
 	return true, nil
 }
 
-func GetBroadcasterDeployment(request *discoveryv1alpha1.PeeringRequest, nameSA string, remoteSA string, namespace string, image string, clusterId string) *appsv1.Deployment {
+func GetBroadcasterDeployment(request *discoveryv1alpha1.PeeringRequest, nameSA string, remoteSA string, namespace string, image string, clusterId string, credentialsRotationPeriod time.Duration, credentialsGracePeriod time.Duration) *appsv1.Deployment {
 	args := []string{
 		"--peering-request",
 		request.Name,
@@ -39,6 +40,10 @@ func GetBroadcasterDeployment(request *discoveryv1alpha1.PeeringRequest, nameSA
 		clusterId,
 		"--service-account",
 		remoteSA,
+		"--credentials-rotation-period",
+		credentialsRotationPeriod.String(),
+		"--credentials-grace-period",
+		credentialsGracePeriod.String(),
 	}
 
 	deploy := &appsv1.Deployment{
@@ -10,6 +10,11 @@ import (
 
 type Config struct {
 	AllowAll bool `json:"allowAll"`
+	// the accepted PeeringRequests wait for an administrator to approve them before the resources are granted
+	ManualApproval bool `json:"manualApproval"`
+	// the policy applied to the incoming PeeringRequests when not all of them are allowed.
+	// Without policy all the PeeringRequests are rejected
+	Policy *PeeringPolicy `json:"policy,omitempty"`
 }
 
 func GetConfig(crdClient *crdClient.CRDClient, namespace string) (*Config, error) {
@@ -30,6 +35,13 @@ func GetConfig(crdClient *crdClient.CRDClient, namespace string) (*Config, error
 	}
 
 	conf.AllowAll = config["allowAll"] == "true"
+	conf.ManualApproval = config["manualApproval"] == "true"
+	if policy, ok := config["policy"]; ok && !conf.AllowAll {
+		if conf.Policy, err = ParsePeeringPolicy(policy); err != nil {
+			klog.Error(err, err.Error())
+			return nil, err
+		}
+	}
 
 	return conf, nil
 }
@@ -74,6 +74,9 @@ func (r *PeeringRequestReconciler) createForeignCluster(pr *v1alpha1.PeeringRequ
 	fc := &v1alpha1.ForeignCluster{
 		ObjectMeta: metav1.ObjectMeta{
 			Name: pr.Spec.ClusterIdentity.ClusterID,
+			Labels: map[string]string{
+				"cluster-id": pr.Spec.ClusterIdentity.ClusterID,
+			},
 		},
 		Spec: v1alpha1.ForeignClusterSpec{
 			ClusterIdentity: pr.Spec.ClusterIdentity,
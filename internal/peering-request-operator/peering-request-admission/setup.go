@@ -7,30 +7,29 @@ import (
 	"github.com/liqotech/liqo/pkg/crdClient"
 	"k8s.io/klog"
 	"net/http"
-	"os"
 )
 
-func StartWebhook(certPath string, keyPath string, namespace string, kubeconfigPath string) *WebhookServer {
+func StartWebhook(certPath string, keyPath string, namespace string, kubeconfigPath string) (*WebhookServer, error) {
 	port := 8443
 	return startTls(certPath, keyPath, port, namespace, kubeconfigPath)
 }
 
-func startTls(certPath string, keyPath string, port int, namespace string, kubeconfigPath string) *WebhookServer {
+func startTls(certPath string, keyPath string, port int, namespace string, kubeconfigPath string) (*WebhookServer, error) {
 	pair, err := tls.LoadX509KeyPair(certPath, keyPath)
 	if err != nil {
 		klog.Error(err, err.Error())
-		os.Exit(1)
+		return nil, err
 	}
 
 	config, err := crdClient.NewKubeconfig(kubeconfigPath, &discoveryv1alpha1.GroupVersion)
 	if err != nil {
 		klog.Error(err, "unable to get kube config")
-		os.Exit(1)
+		return nil, err
 	}
 	client, err := crdClient.NewFromConfig(config)
 	if err != nil {
 		klog.Error(err, "unable to create crd client")
-		os.Exit(1)
+		return nil, err
 	}
 
 	whsvr := &WebhookServer{
@@ -57,5 +56,5 @@ func startTls(certPath string, keyPath string, port int, namespace string, kubec
 
 	klog.Info("Server started")
 
-	return whsvr
+	return whsvr, nil
 }
@@ -2,6 +2,7 @@ package peering_request_admission
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	discoveryv1alpha1 "github.com/liqotech/liqo/apis/discovery/v1alpha1"
 	"github.com/liqotech/liqo/internal/peering-request-operator"
@@ -16,7 +17,7 @@ import (
 	"k8s.io/klog"
 	"k8s.io/kubernetes/pkg/apis/core/v1"
 	"net/http"
-	"os"
+	"time"
 )
 
 var (
@@ -25,6 +26,11 @@ var (
 	deserializer  = codecs.UniversalDeserializer()
 )
 
+const (
+	reasonPolicyUnavailable = "PolicyUnavailable"
+	reasonNoPolicy          = "NoPeeringPolicy"
+)
+
 type WebhookServer struct {
 	Server *http.Server
 
@@ -56,7 +62,9 @@ func (whsvr *WebhookServer) validate(ar *v1beta1.AdmissionReview) *v1beta1.Admis
 
 	conf, err := peering_request_operator.GetConfig(whsvr.client, whsvr.Namespace)
 	if err != nil {
-		os.Exit(1)
+		// without a valid configuration no request can be evaluated, hence they are all rejected
+		klog.Info("PeeringRequest " + peerReq.Name + " Denied: unable to load the peering policy")
+		return deny(http.StatusInternalServerError, reasonPolicyUnavailable, "unable to load the peering policy: "+err.Error())
 	}
 
 	if conf.AllowAll {
@@ -66,16 +74,91 @@ func (whsvr *WebhookServer) validate(ar *v1beta1.AdmissionReview) *v1beta1.Admis
 			Allowed: true,
 			Result:  nil,
 		}
-	} else {
-		// TODO: apply policy to accept/reject peering requests
-		klog.Info("PeeringRequest " + peerReq.Name + " Denied")
-		return &v1beta1.AdmissionResponse{
-			Allowed: false,
-			Result: &metav1.Status{
-				Message: "Invalid token",
-			},
+	}
+	if conf.Policy == nil {
+		klog.Info("PeeringRequest " + peerReq.Name + " Denied: no peering policy configured")
+		return deny(http.StatusForbidden, reasonNoPolicy, "incoming peerings are not allowed")
+	}
+
+	candidate, err := whsvr.getPeeringCandidate(&peerReq)
+	if err != nil {
+		klog.Error(err, err.Error())
+		return deny(http.StatusInternalServerError, reasonPolicyUnavailable, "unable to evaluate the peering policy: "+err.Error())
+	}
+	peers, err := whsvr.countPeers(&peerReq)
+	if err != nil {
+		klog.Error(err, err.Error())
+		return deny(http.StatusInternalServerError, reasonPolicyUnavailable, "unable to evaluate the peering policy: "+err.Error())
+	}
+	if rejection := conf.Policy.Evaluate(candidate, peers, time.Now()); rejection != nil {
+		klog.Info("PeeringRequest " + peerReq.Name + " Denied: " + rejection.Error())
+		return deny(http.StatusForbidden, rejection.Reason, rejection.Message)
+	}
+	klog.Info("PeeringRequest " + peerReq.Name + " Allowed by the peering policy")
+	return &v1beta1.AdmissionResponse{
+		Allowed: true,
+		Result:  nil,
+	}
+}
+
+// returns the foreign cluster asking to peer: the discovery type and the trust mode are the ones of its
+// ForeignCluster, if any, otherwise it has been discovered by the incoming peering itself
+func (whsvr *WebhookServer) getPeeringCandidate(peerReq *discoveryv1alpha1.PeeringRequest) (*peering_request_operator.PeeringCandidate, error) {
+	candidate := &peering_request_operator.PeeringCandidate{
+		ClusterID:     peerReq.Spec.ClusterIdentity.ClusterID,
+		ClusterName:   peerReq.Spec.ClusterIdentity.ClusterName,
+		DiscoveryType: discoveryv1alpha1.IncomingPeeringDiscovery,
+		TrustMode:     discoveryv1alpha1.TrustModeUnknown,
+	}
+	tmp, err := whsvr.client.Resource("foreignclusters").List(metav1.ListOptions{
+		LabelSelector: "cluster-id=" + candidate.ClusterID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	fcList, ok := tmp.(*discoveryv1alpha1.ForeignClusterList)
+	if !ok {
+		return nil, errors.New("retrieved object is not a ForeignClusterList")
+	}
+	if len(fcList.Items) > 0 {
+		candidate.DiscoveryType = fcList.Items[0].Spec.DiscoveryType
+		if fcList.Items[0].Status.TrustMode != "" {
+			candidate.TrustMode = fcList.Items[0].Status.TrustMode
 		}
 	}
+	return candidate, nil
+}
+
+// returns the number of foreign clusters, other than the one asking to peer, with an incoming peering
+func (whsvr *WebhookServer) countPeers(peerReq *discoveryv1alpha1.PeeringRequest) (int, error) {
+	tmp, err := whsvr.client.Resource("peeringrequests").List(metav1.ListOptions{})
+	if err != nil {
+		return 0, err
+	}
+	prList, ok := tmp.(*discoveryv1alpha1.PeeringRequestList)
+	if !ok {
+		return 0, errors.New("retrieved object is not a PeeringRequestList")
+	}
+	peers := 0
+	for i := range prList.Items {
+		if prList.Items[i].Spec.ClusterIdentity.ClusterID != peerReq.Spec.ClusterIdentity.ClusterID {
+			peers++
+		}
+	}
+	return peers, nil
+}
+
+// returns a response rejecting the request, reporting the reason in the status
+func deny(code int32, reason, message string) *v1beta1.AdmissionResponse {
+	return &v1beta1.AdmissionResponse{
+		Allowed: false,
+		Result: &metav1.Status{
+			Status:  metav1.StatusFailure,
+			Code:    code,
+			Reason:  metav1.StatusReason(reason),
+			Message: message,
+		},
+	}
 }
 
 func (whsvr *WebhookServer) serve(w http.ResponseWriter, r *http.Request) {
@@ -42,6 +42,9 @@ type PeeringRequestReconciler struct {
 	broadcasterImage          string
 	broadcasterServiceAccount string
 	vkServiceAccount          string
+	// rotation of the credentials granted by the broadcasters to the virtual kubelets of the foreign clusters
+	credentialsRotationPeriod time.Duration
+	credentialsGracePeriod    time.Duration
 	retryTimeout              time.Duration
 
 	// testing
@@ -71,6 +74,43 @@ func (r *PeeringRequestReconciler) Reconcile(req ctrl.Request) (ctrl.Result, err
 		return ctrl.Result{}, nil
 	}
 
+	manualApproval, err := r.isManualApproval()
+	if err != nil {
+		klog.Error(err, err.Error())
+		return ctrl.Result{RequeueAfter: r.retryTimeout}, err
+	}
+	approval := GetApproval(pr, manualApproval)
+	if approval == discoveryv1alpha1.PeeringApprovalPending {
+		if approval, err = r.getImplicitApproval(pr); err != nil {
+			klog.Error(err, err.Error())
+			return ctrl.Result{RequeueAfter: r.retryTimeout}, err
+		}
+		if approval == discoveryv1alpha1.PeeringApprovalApproved {
+			// the peering was established before the manual approval has been enabled: the approval is recorded
+			metav1.SetMetaDataAnnotation(&pr.ObjectMeta, discoveryv1alpha1.PeeringApprovalAnnotation, string(approval))
+			klog.Info("PeeringRequest " + pr.Name + " approved, since the peering is already established")
+		}
+	}
+	if approval == discoveryv1alpha1.PeeringApprovalRejected {
+		if err = r.rejectPeering(pr); err != nil {
+			return ctrl.Result{RequeueAfter: r.retryTimeout}, err
+		}
+		klog.Info("PeeringRequest " + pr.Name + " rejected")
+		return ctrl.Result{}, nil
+	}
+
+	pr.Status.ApprovalStatus = approval
+	if approval == discoveryv1alpha1.PeeringApprovalPending {
+		// nothing is granted until the peering is approved
+		_, err = r.crdClient.Resource("peeringrequests").Update(pr.Name, pr, metav1.UpdateOptions{})
+		if err != nil {
+			klog.Error(err, err.Error())
+			return ctrl.Result{RequeueAfter: r.retryTimeout}, err
+		}
+		klog.Info("PeeringRequest " + pr.Name + " is waiting for approval")
+		return ctrl.Result{RequeueAfter: r.retryTimeout}, nil
+	}
+
 	err = r.UpdateForeignCluster(pr)
 	if err != nil {
 		klog.Error(err, err.Error())
@@ -88,7 +128,7 @@ func (r *PeeringRequestReconciler) Reconcile(req ctrl.Request) (ctrl.Result, err
 	}
 	if !exists {
 		klog.Info("Deploy Broadcaster")
-		deploy := GetBroadcasterDeployment(pr, r.broadcasterServiceAccount, r.vkServiceAccount, r.Namespace, r.broadcasterImage, r.clusterId.GetClusterID())
+		deploy := GetBroadcasterDeployment(pr, r.broadcasterServiceAccount, r.vkServiceAccount, r.Namespace, r.broadcasterImage, r.clusterId.GetClusterID(), r.credentialsRotationPeriod, r.credentialsGracePeriod)
 		deploy, err = r.crdClient.Client().AppsV1().Deployments(r.Namespace).Create(context.TODO(), deploy, metav1.CreateOptions{})
 		if err != nil {
 			klog.Error(err, err.Error())
@@ -0,0 +1,193 @@
+package peering_request_operator
+
+import (
+	"context"
+	discoveryv1alpha1 "github.com/liqotech/liqo/apis/discovery/v1alpha1"
+	"github.com/liqotech/liqo/pkg/clusterID"
+	"github.com/liqotech/liqo/pkg/crdClient"
+	object_references "github.com/liqotech/liqo/pkg/object-references"
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/rest"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/cache"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"testing"
+)
+
+// getTestReconciler returns a reconciler working on fake clients, whose configuration enables the manual approval,
+// and the PeeringRequest of the remote cluster
+func getTestReconciler(t *testing.T) (*PeeringRequestReconciler, *discoveryv1alpha1.PeeringRequest) {
+	crdClient.Fake = true
+	client, err := crdClient.NewFromConfig(&rest.Config{ContentConfig: rest.ContentConfig{GroupVersion: &discoveryv1alpha1.GroupVersion}})
+	assert.Nil(t, err)
+	// the PeeringRequests and the ForeignClusters are kept in the same fake store
+	client.Store, _, err = crdClient.WatchfakeResources("peeringrequests", cache.ResourceEventHandlerFuncs{})
+	assert.Nil(t, err)
+
+	clientset := client.Client().(*fake.Clientset)
+	clientset.PrependReactor("create", "deployments", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		deploy := action.(k8stesting.CreateAction).GetObject().(*appsv1.Deployment)
+		deploy.Name = deploy.GenerateName + "1"
+		return false, deploy, nil
+	})
+	_, err = clientset.CoreV1().ConfigMaps("liqo").Create(context.TODO(), &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "peering-request-operator-cm"},
+		Data: map[string]string{
+			"allowAll":       "true",
+			"manualApproval": "true",
+		},
+	}, metav1.CreateOptions{})
+	assert.Nil(t, err)
+
+	pr := &discoveryv1alpha1.PeeringRequest{
+		ObjectMeta: metav1.ObjectMeta{Name: "pr-remote"},
+		Spec: discoveryv1alpha1.PeeringRequestSpec{
+			ClusterIdentity: discoveryv1alpha1.ClusterIdentity{ClusterID: "remote-cluster"},
+			Namespace:       "liqo",
+			KubeConfigRef:   &corev1.ObjectReference{Namespace: "liqo", Name: "remote-kubeconfig"},
+		},
+	}
+	_, err = client.Resource("peeringrequests").Create(pr, metav1.CreateOptions{})
+	assert.Nil(t, err)
+	// the kubeconfig cannot be loaded, the ForeignConfig is used instead
+	_, err = clientset.CoreV1().Secrets("liqo").Create(context.TODO(), &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "liqo", Name: "remote-kubeconfig"},
+	}, metav1.CreateOptions{})
+	assert.Nil(t, err)
+
+	r := GetPRReconciler(nil, client, "liqo", clusterID.GetNewClusterID("home-cluster", clientset), "broadcaster", "br-sa", "vk-sa", 0, 0)
+	r.ForeignConfig = &rest.Config{Host: "https://remote:6443"}
+	return r, pr
+}
+
+func listForeignClusters(t *testing.T, r *PeeringRequestReconciler) []discoveryv1alpha1.ForeignCluster {
+	tmp, err := r.crdClient.Resource("foreignclusters").List(metav1.ListOptions{})
+	assert.Nil(t, err)
+	return tmp.(*discoveryv1alpha1.ForeignClusterList).Items
+}
+
+func listBroadcasters(t *testing.T, r *PeeringRequestReconciler) []appsv1.Deployment {
+	deploys, err := r.crdClient.Client().AppsV1().Deployments("liqo").List(context.TODO(), metav1.ListOptions{})
+	assert.Nil(t, err)
+	return deploys.Items
+}
+
+func TestReconcileApproval(t *testing.T) {
+	defer func() { crdClient.Fake = false }()
+	req := ctrl.Request{}
+	req.Name = "pr-remote"
+
+	t.Run("pending", func(t *testing.T) {
+		r, pr := getTestReconciler(t)
+
+		_, err := r.Reconcile(req)
+		assert.Nil(t, err)
+		assert.Equal(t, discoveryv1alpha1.PeeringApprovalPending, pr.Status.ApprovalStatus)
+		assert.Nil(t, pr.Status.BroadcasterRef)
+		assert.Empty(t, listBroadcasters(t, r))
+		assert.Empty(t, listForeignClusters(t, r))
+	})
+
+	t.Run("established", func(t *testing.T) {
+		r, pr := getTestReconciler(t)
+		// a broadcaster deployed before the manual approval has been enabled
+		deploy, err := r.crdClient.Client().AppsV1().Deployments("liqo").Create(context.TODO(), &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "liqo", Name: "broadcaster-pr-remote-0"},
+		}, metav1.CreateOptions{})
+		assert.Nil(t, err)
+		pr.Status.BroadcasterRef = &object_references.DeploymentReference{Namespace: deploy.Namespace, Name: deploy.Name}
+
+		_, err = r.Reconcile(req)
+		assert.Nil(t, err)
+		assert.Equal(t, discoveryv1alpha1.PeeringApprovalApproved, pr.Status.ApprovalStatus)
+		assert.Equal(t, string(discoveryv1alpha1.PeeringApprovalApproved), pr.Annotations[discoveryv1alpha1.PeeringApprovalAnnotation])
+		assert.Equal(t, deploy.Name, pr.Status.BroadcasterRef.Name)
+		assert.Len(t, listBroadcasters(t, r), 1)
+	})
+
+	t.Run("approved", func(t *testing.T) {
+		r, pr := getTestReconciler(t)
+		metav1.SetMetaDataAnnotation(&pr.ObjectMeta, discoveryv1alpha1.PeeringApprovalAnnotation, string(discoveryv1alpha1.PeeringApprovalApproved))
+
+		_, err := r.Reconcile(req)
+		assert.Nil(t, err)
+		assert.Equal(t, discoveryv1alpha1.PeeringApprovalApproved, pr.Status.ApprovalStatus)
+		assert.NotNil(t, pr.Status.BroadcasterRef)
+		assert.Len(t, listBroadcasters(t, r), 1)
+		fcs := listForeignClusters(t, r)
+		if assert.Len(t, fcs, 1) {
+			assert.Equal(t, "remote-cluster", fcs[0].Name)
+			assert.Equal(t, discoveryv1alpha1.IncomingPeeringDiscovery, fcs[0].Spec.DiscoveryType)
+		}
+	})
+
+	t.Run("rejected", func(t *testing.T) {
+		r, pr := getTestReconciler(t)
+		// the ForeignCluster created when the peering was pending, before the manual approval has been enabled
+		_, err := r.crdClient.Resource("foreignclusters").Create(&discoveryv1alpha1.ForeignCluster{
+			ObjectMeta: metav1.ObjectMeta{Name: "remote-cluster", Labels: map[string]string{"cluster-id": "remote-cluster"}},
+			Spec:       discoveryv1alpha1.ForeignClusterSpec{DiscoveryType: discoveryv1alpha1.IncomingPeeringDiscovery},
+			Status: discoveryv1alpha1.ForeignClusterStatus{
+				Incoming: discoveryv1alpha1.Incoming{PeeringRequest: &corev1.ObjectReference{Name: pr.Name}},
+			},
+		}, metav1.CreateOptions{})
+		assert.Nil(t, err)
+		metav1.SetMetaDataAnnotation(&pr.ObjectMeta, discoveryv1alpha1.PeeringApprovalAnnotation, string(discoveryv1alpha1.PeeringApprovalRejected))
+
+		_, err = r.Reconcile(req)
+		assert.Nil(t, err)
+		_, err = r.crdClient.Resource("peeringrequests").Get(pr.Name, metav1.GetOptions{})
+		assert.NotNil(t, err)
+		assert.Empty(t, listBroadcasters(t, r))
+		fcs := listForeignClusters(t, r)
+		if assert.Len(t, fcs, 1) {
+			assert.True(t, isRejected(&fcs[0]))
+			assert.Nil(t, fcs[0].Status.Incoming.PeeringRequest)
+		}
+	})
+
+	t.Run("rejected without ForeignCluster", func(t *testing.T) {
+		r, pr := getTestReconciler(t)
+		metav1.SetMetaDataAnnotation(&pr.ObjectMeta, discoveryv1alpha1.PeeringApprovalAnnotation, string(discoveryv1alpha1.PeeringApprovalRejected))
+
+		_, err := r.Reconcile(req)
+		assert.Nil(t, err)
+		_, err = r.crdClient.Resource("peeringrequests").Get(pr.Name, metav1.GetOptions{})
+		assert.NotNil(t, err)
+		fcs := listForeignClusters(t, r)
+		if assert.Len(t, fcs, 1) {
+			assert.Equal(t, "remote-cluster", fcs[0].Name)
+			assert.True(t, isRejected(&fcs[0]))
+			assert.Nil(t, fcs[0].Status.Incoming.PeeringRequest)
+		}
+	})
+
+	t.Run("rejected again", func(t *testing.T) {
+		r, pr := getTestReconciler(t)
+		// the cluster has been rejected in the past, then it has sent a new PeeringRequest
+		_, err := r.crdClient.Resource("foreignclusters").Create(&discoveryv1alpha1.ForeignCluster{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "remote-cluster",
+				Labels:      map[string]string{"cluster-id": "remote-cluster"},
+				Annotations: map[string]string{discoveryv1alpha1.PeeringApprovalAnnotation: string(discoveryv1alpha1.PeeringApprovalRejected)},
+			},
+			Spec: discoveryv1alpha1.ForeignClusterSpec{DiscoveryType: discoveryv1alpha1.IncomingPeeringDiscovery},
+		}, metav1.CreateOptions{})
+		assert.Nil(t, err)
+
+		_, err = r.Reconcile(req)
+		assert.Nil(t, err)
+		_, err = r.crdClient.Resource("peeringrequests").Get(pr.Name, metav1.GetOptions{})
+		assert.NotNil(t, err)
+		assert.Empty(t, listBroadcasters(t, r))
+		fcs := listForeignClusters(t, r)
+		if assert.Len(t, fcs, 1) {
+			assert.True(t, isRejected(&fcs[0]))
+		}
+	})
+}
@@ -0,0 +1,218 @@
+package peering_request_operator
+
+import (
+	"fmt"
+	"github.com/liqotech/liqo/apis/discovery/v1alpha1"
+	"path"
+	"sigs.k8s.io/yaml"
+	"strings"
+	"time"
+)
+
+// reasons reported when a PeeringRequest is rejected by the peering policy
+const (
+	ReasonClusterDenied           = "ClusterDenied"
+	ReasonClusterNotAllowed       = "ClusterNotAllowed"
+	ReasonDiscoveryTypeNotAllowed = "DiscoveryTypeNotAllowed"
+	ReasonUntrustedCluster        = "UntrustedCluster"
+	ReasonTooManyPeers            = "TooManyPeers"
+	ReasonOutsideTimeWindow       = "OutsideTimeWindow"
+)
+
+// PeeringPolicy defines which foreign clusters are allowed to peer with the local one. A PeeringRequest is
+// accepted only if it satisfies all the conditions set.
+type PeeringPolicy struct {
+	// cluster IDs and names (shell patterns are supported) which are always rejected
+	DeniedClusterIDs   []string `json:"deniedClusterIDs,omitempty"`
+	DeniedClusterNames []string `json:"deniedClusterNames,omitempty"`
+	// if set, only the clusters matching at least an ID or a name are accepted
+	AllowedClusterIDs   []string `json:"allowedClusterIDs,omitempty"`
+	AllowedClusterNames []string `json:"allowedClusterNames,omitempty"`
+	// if set, only the clusters discovered in one of these ways are accepted. The clusters without
+	// ForeignCluster are considered discovered through the incoming peering
+	DiscoveryTypes []v1alpha1.DiscoveryType `json:"discoveryTypes,omitempty"`
+	// if set, only the clusters with this trust mode are accepted
+	TrustMode v1alpha1.TrustMode `json:"trustMode,omitempty"`
+	// if greater than zero, the maximum number of foreign clusters peered at the same time
+	MaxPeers int `json:"maxPeers,omitempty"`
+	// if set, the PeeringRequests are accepted only within one of these windows
+	TimeWindows []TimeWindow `json:"timeWindows,omitempty"`
+}
+
+// TimeWindow is a daily time interval, e.g. 08:00-18:00. If the end precedes the start, the window
+// spans across midnight.
+type TimeWindow struct {
+	// days of the week the window applies to (e.g. Mon, Tue), every day if not set
+	Days []string `json:"days,omitempty"`
+	// start and end of the window, in the HH:MM format
+	Start string `json:"start"`
+	End   string `json:"end"`
+	// IANA name of the time zone of the window, UTC if not set
+	TimeZone string `json:"timeZone,omitempty"`
+}
+
+// PeeringCandidate is the foreign cluster asking to peer, as seen by the policy.
+type PeeringCandidate struct {
+	ClusterID     string
+	ClusterName   string
+	DiscoveryType v1alpha1.DiscoveryType
+	TrustMode     v1alpha1.TrustMode
+}
+
+// PolicyRejection describes why a PeeringRequest has been rejected by the policy.
+type PolicyRejection struct {
+	Reason  string
+	Message string
+}
+
+func (r *PolicyRejection) Error() string {
+	return fmt.Sprintf("%s: %s", r.Reason, r.Message)
+}
+
+// ParsePeeringPolicy parses and validates a policy written in YAML or JSON.
+func ParsePeeringPolicy(data string) (*PeeringPolicy, error) {
+	policy := &PeeringPolicy{}
+	if err := yaml.UnmarshalStrict([]byte(data), policy); err != nil {
+		return nil, fmt.Errorf("invalid peering policy: %w", err)
+	}
+	for _, pattern := range append(policy.DeniedClusterNames, policy.AllowedClusterNames...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid cluster name pattern %q in peering policy: %w", pattern, err)
+		}
+	}
+	for i := range policy.TimeWindows {
+		if _, err := policy.TimeWindows[i].contains(time.Now()); err != nil {
+			return nil, fmt.Errorf("invalid time window in peering policy: %w", err)
+		}
+	}
+	if policy.MaxPeers < 0 {
+		return nil, fmt.Errorf("invalid peering policy: maxPeers cannot be negative")
+	}
+	return policy, nil
+}
+
+// Evaluate checks whether the candidate can peer, given the number of foreign clusters already peered.
+// It returns nil if the peering is allowed, the reason of the rejection otherwise.
+func (p *PeeringPolicy) Evaluate(candidate *PeeringCandidate, peers int, now time.Time) *PolicyRejection {
+	if containsString(p.DeniedClusterIDs, candidate.ClusterID) || matchesPattern(p.DeniedClusterNames, candidate.ClusterName) {
+		return &PolicyRejection{
+			Reason:  ReasonClusterDenied,
+			Message: fmt.Sprintf("cluster %s is explicitly denied", candidate.ClusterID),
+		}
+	}
+	if (len(p.AllowedClusterIDs) > 0 || len(p.AllowedClusterNames) > 0) &&
+		!containsString(p.AllowedClusterIDs, candidate.ClusterID) && !matchesPattern(p.AllowedClusterNames, candidate.ClusterName) {
+		return &PolicyRejection{
+			Reason:  ReasonClusterNotAllowed,
+			Message: fmt.Sprintf("cluster %s is not in the list of allowed clusters", candidate.ClusterID),
+		}
+	}
+	if len(p.DiscoveryTypes) > 0 {
+		allowed := false
+		for _, discoveryType := range p.DiscoveryTypes {
+			allowed = allowed || discoveryType == candidate.DiscoveryType
+		}
+		if !allowed {
+			return &PolicyRejection{
+				Reason:  ReasonDiscoveryTypeNotAllowed,
+				Message: fmt.Sprintf("cluster %s has been discovered through %s, while only %v are allowed", candidate.ClusterID, candidate.DiscoveryType, p.DiscoveryTypes),
+			}
+		}
+	}
+	if p.TrustMode != "" && p.TrustMode != candidate.TrustMode {
+		return &PolicyRejection{
+			Reason:  ReasonUntrustedCluster,
+			Message: fmt.Sprintf("cluster %s has trust mode %s, while %s is required", candidate.ClusterID, candidate.TrustMode, p.TrustMode),
+		}
+	}
+	if p.MaxPeers > 0 && peers >= p.MaxPeers {
+		return &PolicyRejection{
+			Reason:  ReasonTooManyPeers,
+			Message: fmt.Sprintf("the maximum number of peered clusters (%d) has been reached", p.MaxPeers),
+		}
+	}
+	if len(p.TimeWindows) > 0 {
+		inWindow := false
+		for i := range p.TimeWindows {
+			contained, err := p.TimeWindows[i].contains(now)
+			inWindow = inWindow || (err == nil && contained)
+		}
+		if !inWindow {
+			return &PolicyRejection{
+				Reason:  ReasonOutsideTimeWindow,
+				Message: "peering requests are not accepted at this time",
+			}
+		}
+	}
+	return nil
+}
+
+// contains returns whether the given instant falls within the window.
+func (w *TimeWindow) contains(now time.Time) (bool, error) {
+	location := time.UTC
+	if w.TimeZone != "" {
+		var err error
+		if location, err = time.LoadLocation(w.TimeZone); err != nil {
+			return false, err
+		}
+	}
+	start, err := time.Parse("15:04", w.Start)
+	if err != nil {
+		return false, err
+	}
+	end, err := time.Parse("15:04", w.End)
+	if err != nil {
+		return false, err
+	}
+	for _, day := range w.Days {
+		if _, err := time.Parse("Mon", day); err != nil {
+			return false, fmt.Errorf("invalid day %q: %w", day, err)
+		}
+	}
+
+	now = now.In(location)
+	minutes := now.Hour()*60 + now.Minute()
+	startMinutes := start.Hour()*60 + start.Minute()
+	endMinutes := end.Hour()*60 + end.Minute()
+	day := now.Weekday()
+	var contained bool
+	if startMinutes <= endMinutes {
+		contained = minutes >= startMinutes && minutes < endMinutes
+	} else {
+		// the window spans across midnight: the early hours belong to the window started the day before
+		contained = minutes >= startMinutes || minutes < endMinutes
+		if minutes < endMinutes {
+			day = (day + 6) % 7
+		}
+	}
+	if !contained || len(w.Days) == 0 {
+		return contained, nil
+	}
+	for _, d := range w.Days {
+		if strings.EqualFold(d, day.String()[:3]) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesPattern(patterns []string, name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, pattern := range patterns {
+		if matched, err := path.Match(pattern, name); err == nil && matched {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,89 @@
+package peering_request_operator
+
+import (
+	"github.com/liqotech/liqo/apis/discovery/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestParsePeeringPolicy(t *testing.T) {
+	policy, err := ParsePeeringPolicy(`
+deniedClusterIDs: ["cluster-3"]
+allowedClusterNames: ["edge-*"]
+discoveryTypes: ["LAN", "Manual"]
+trustMode: Trusted
+maxPeers: 2
+timeWindows:
+  - days: ["Mon", "Tue"]
+    start: "08:00"
+    end: "18:00"
+    timeZone: Europe/Rome
+`)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"edge-*"}, policy.AllowedClusterNames)
+	assert.Equal(t, []v1alpha1.DiscoveryType{v1alpha1.LanDiscovery, v1alpha1.ManualDiscovery}, policy.DiscoveryTypes)
+	assert.Equal(t, v1alpha1.TrustModeTrusted, policy.TrustMode)
+	assert.Equal(t, 2, policy.MaxPeers)
+	assert.Equal(t, "Europe/Rome", policy.TimeWindows[0].TimeZone)
+
+	invalidPolicies := []string{
+		"unknownField: true",
+		"allowedClusterNames: [\"edge-[\"]",
+		"maxPeers: -1",
+		"timeWindows: [{start: \"8\", end: \"18:00\"}]",
+		"timeWindows: [{start: \"08:00\", end: \"18:00\", days: [\"Someday\"]}]",
+		"timeWindows: [{start: \"08:00\", end: \"18:00\", timeZone: \"Nowhere/Nowhere\"}]",
+	}
+	for _, invalid := range invalidPolicies {
+		_, err := ParsePeeringPolicy(invalid)
+		assert.NotNil(t, err, invalid)
+	}
+}
+
+func TestPeeringPolicy_Evaluate(t *testing.T) {
+	// Monday 10:00 UTC
+	now := time.Date(2021, time.January, 4, 10, 0, 0, 0, time.UTC)
+	candidate := &PeeringCandidate{
+		ClusterID:     "cluster-1",
+		ClusterName:   "edge-1",
+		DiscoveryType: v1alpha1.LanDiscovery,
+		TrustMode:     v1alpha1.TrustModeTrusted,
+	}
+
+	testCases := []struct {
+		name   string
+		policy PeeringPolicy
+		peers  int
+		reason string
+	}{
+		{name: "empty policy", policy: PeeringPolicy{}},
+		{name: "denied ID", policy: PeeringPolicy{DeniedClusterIDs: []string{"cluster-1"}}, reason: ReasonClusterDenied},
+		{name: "denied name", policy: PeeringPolicy{DeniedClusterNames: []string{"edge-*"}}, reason: ReasonClusterDenied},
+		{name: "denied wins over allowed", policy: PeeringPolicy{DeniedClusterIDs: []string{"cluster-1"}, AllowedClusterIDs: []string{"cluster-1"}}, reason: ReasonClusterDenied},
+		{name: "allowed ID", policy: PeeringPolicy{AllowedClusterIDs: []string{"cluster-1"}}},
+		{name: "allowed name", policy: PeeringPolicy{AllowedClusterIDs: []string{"cluster-2"}, AllowedClusterNames: []string{"edge-?"}}},
+		{name: "not allowed", policy: PeeringPolicy{AllowedClusterIDs: []string{"cluster-2"}}, reason: ReasonClusterNotAllowed},
+		{name: "discovery type", policy: PeeringPolicy{DiscoveryTypes: []v1alpha1.DiscoveryType{v1alpha1.LanDiscovery}}},
+		{name: "wrong discovery type", policy: PeeringPolicy{DiscoveryTypes: []v1alpha1.DiscoveryType{v1alpha1.ManualDiscovery}}, reason: ReasonDiscoveryTypeNotAllowed},
+		{name: "untrusted", policy: PeeringPolicy{TrustMode: v1alpha1.TrustModeUntrusted}, reason: ReasonUntrustedCluster},
+		{name: "below max peers", policy: PeeringPolicy{MaxPeers: 2}, peers: 1},
+		{name: "max peers reached", policy: PeeringPolicy{MaxPeers: 2}, peers: 2, reason: ReasonTooManyPeers},
+		{name: "within time window", policy: PeeringPolicy{TimeWindows: []TimeWindow{{Days: []string{"Mon"}, Start: "08:00", End: "18:00"}}}},
+		{name: "wrong day", policy: PeeringPolicy{TimeWindows: []TimeWindow{{Days: []string{"Tue"}, Start: "08:00", End: "18:00"}}}, reason: ReasonOutsideTimeWindow},
+		{name: "outside time window", policy: PeeringPolicy{TimeWindows: []TimeWindow{{Start: "12:00", End: "18:00"}}}, reason: ReasonOutsideTimeWindow},
+		{name: "second time window", policy: PeeringPolicy{TimeWindows: []TimeWindow{{Start: "12:00", End: "18:00"}, {Start: "09:30", End: "10:30"}}}},
+		{name: "time zone", policy: PeeringPolicy{TimeWindows: []TimeWindow{{Start: "08:00", End: "10:00", TimeZone: "Europe/Rome"}}}, reason: ReasonOutsideTimeWindow},
+		// the early hours of Monday belong to the window started on Sunday
+		{name: "window across midnight", policy: PeeringPolicy{TimeWindows: []TimeWindow{{Days: []string{"Sun"}, Start: "22:00", End: "11:00"}}}},
+	}
+
+	for _, tc := range testCases {
+		rejection := tc.policy.Evaluate(candidate, tc.peers, now)
+		if tc.reason == "" {
+			assert.Nil(t, rejection, tc.name)
+		} else if assert.NotNil(t, rejection, tc.name) {
+			assert.Equal(t, tc.reason, rejection.Reason, tc.name)
+		}
+	}
+}
@@ -22,7 +22,7 @@ func init() {
 	// +kubebuilder:scaffold:scheme
 }
 
-func StartOperator(namespace string, broadcasterImage string, broadcasterServiceAccount string, vkServiceAccount string, kubeconfigPath string) {
+func StartOperator(namespace string, broadcasterImage string, broadcasterServiceAccount string, vkServiceAccount string, credentialsRotationPeriod time.Duration, credentialsGracePeriod time.Duration, kubeconfigPath string) {
 	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
 		Scheme:           scheme,
 		Port:             9443,
@@ -59,6 +59,8 @@ func StartOperator(namespace string, broadcasterImage string, broadcasterService
 		broadcasterImage,
 		broadcasterServiceAccount,
 		vkServiceAccount,
+		credentialsRotationPeriod,
+		credentialsGracePeriod,
 	)).SetupWithManager(mgr); err != nil {
 		klog.Error(err, "unable to create controller")
 		os.Exit(1)
@@ -71,7 +73,7 @@ func StartOperator(namespace string, broadcasterImage string, broadcasterService
 	}
 }
 
-func GetPRReconciler(scheme *runtime.Scheme, crdClient *crdClient.CRDClient, namespace string, clusterId *clusterID.ClusterID, broadcasterImage string, broadcasterServiceAccount string, vkServiceAccount string) *PeeringRequestReconciler {
+func GetPRReconciler(scheme *runtime.Scheme, crdClient *crdClient.CRDClient, namespace string, clusterId *clusterID.ClusterID, broadcasterImage string, broadcasterServiceAccount string, vkServiceAccount string, credentialsRotationPeriod time.Duration, credentialsGracePeriod time.Duration) *PeeringRequestReconciler {
 	return &PeeringRequestReconciler{
 		Scheme:                    scheme,
 		crdClient:                 crdClient,
@@ -80,6 +82,8 @@ func GetPRReconciler(scheme *runtime.Scheme, crdClient *crdClient.CRDClient, nam
 		broadcasterImage:          broadcasterImage,
 		broadcasterServiceAccount: broadcasterServiceAccount,
 		vkServiceAccount:          vkServiceAccount,
+		credentialsRotationPeriod: credentialsRotationPeriod,
+		credentialsGracePeriod:    credentialsGracePeriod,
 		retryTimeout:              1 * time.Minute,
 		ForeignConfig:             nil,
 	}
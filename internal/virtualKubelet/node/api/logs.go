@@ -18,6 +18,7 @@ import (
 	"context"
 	"io"
 	"net/http"
+	"net/url"
 	"strconv"
 	"time"
 
@@ -33,10 +34,14 @@ type ContainerLogsHandlerFunc func(ctx context.Context, namespace, podName, cont
 // ContainerLogOpts are used to pass along options to be set on the container
 // log stream.
 type ContainerLogOpts struct {
-	Tail       int
-	Since      time.Duration
-	LimitBytes int
-	Timestamps bool
+	// Tail is nil when the number of lines is not limited, since zero lines is a legal value.
+	Tail         *int
+	LimitBytes   int
+	Timestamps   bool
+	Follow       bool
+	Previous     bool
+	SinceSeconds int
+	SinceTime    time.Time
 }
 
 // HandleContainerLogs creates an http handler function from a provider to serve logs from a pod
@@ -55,22 +60,10 @@ func HandleContainerLogs(h ContainerLogsHandlerFunc) http.HandlerFunc {
 		namespace := vars["namespace"]
 		pod := vars["pod"]
 		container := vars["container"]
-		tail := 10
-		q := req.URL.Query()
 
-		if queryTail := q.Get("tailLines"); queryTail != "" {
-			t, err := strconv.Atoi(queryTail)
-			if err != nil {
-				return errdefs.AsInvalidInput(errors.Wrap(err, "could not parse \"tailLines\""))
-			}
-			tail = t
-		}
-
-		// TODO(@cpuguy83): support v1.PodLogOptions
-		// The kubelet decoding here is not straight forward, so this needs to be disected
-
-		opts := ContainerLogOpts{
-			Tail: tail,
+		opts, err := parseLogOptions(req.URL.Query())
+		if err != nil {
+			return err
 		}
 
 		logs, err := h(ctx, namespace, pod, container, opts)
@@ -92,3 +85,64 @@ func HandleContainerLogs(h ContainerLogsHandlerFunc) http.HandlerFunc {
 		return nil
 	})
 }
+
+// parseLogOptions parses the query parameters of a container logs request, which follow the v1.PodLogOptions format.
+func parseLogOptions(q url.Values) (opts ContainerLogOpts, err error) {
+	if tailLines := q.Get("tailLines"); tailLines != "" {
+		var tail int
+		tail, err = strconv.Atoi(tailLines)
+		if err != nil {
+			return opts, errdefs.AsInvalidInput(errors.Wrap(err, "could not parse \"tailLines\""))
+		}
+		if tail < 0 {
+			return opts, errdefs.InvalidInputf("\"tailLines\" is %d", tail)
+		}
+		opts.Tail = &tail
+	}
+	if follow := q.Get("follow"); follow != "" {
+		opts.Follow, err = strconv.ParseBool(follow)
+		if err != nil {
+			return opts, errdefs.AsInvalidInput(errors.Wrap(err, "could not parse \"follow\""))
+		}
+	}
+	if limitBytes := q.Get("limitBytes"); limitBytes != "" {
+		opts.LimitBytes, err = strconv.Atoi(limitBytes)
+		if err != nil {
+			return opts, errdefs.AsInvalidInput(errors.Wrap(err, "could not parse \"limitBytes\""))
+		}
+		if opts.LimitBytes < 1 {
+			return opts, errdefs.InvalidInputf("\"limitBytes\" is %d", opts.LimitBytes)
+		}
+	}
+	if previous := q.Get("previous"); previous != "" {
+		opts.Previous, err = strconv.ParseBool(previous)
+		if err != nil {
+			return opts, errdefs.AsInvalidInput(errors.Wrap(err, "could not parse \"previous\""))
+		}
+	}
+	if sinceSeconds := q.Get("sinceSeconds"); sinceSeconds != "" {
+		opts.SinceSeconds, err = strconv.Atoi(sinceSeconds)
+		if err != nil {
+			return opts, errdefs.AsInvalidInput(errors.Wrap(err, "could not parse \"sinceSeconds\""))
+		}
+		if opts.SinceSeconds < 1 {
+			return opts, errdefs.InvalidInputf("\"sinceSeconds\" is %d", opts.SinceSeconds)
+		}
+	}
+	if sinceTime := q.Get("sinceTime"); sinceTime != "" {
+		opts.SinceTime, err = time.Parse(time.RFC3339, sinceTime)
+		if err != nil {
+			return opts, errdefs.AsInvalidInput(errors.Wrap(err, "could not parse \"sinceTime\""))
+		}
+		if opts.SinceSeconds > 0 {
+			return opts, errdefs.InvalidInput("both \"sinceSeconds\" and \"sinceTime\" are set")
+		}
+	}
+	if timestamps := q.Get("timestamps"); timestamps != "" {
+		opts.Timestamps, err = strconv.ParseBool(timestamps)
+		if err != nil {
+			return opts, errdefs.AsInvalidInput(errors.Wrap(err, "could not parse \"timestamps\""))
+		}
+	}
+	return opts, nil
+}
@@ -0,0 +1,76 @@
+// Copyright © 2017 The virtual-kubelet authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/liqotech/liqo/internal/utils/errdefs"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/kubernetes/pkg/kubelet/server/portforward"
+)
+
+// PortForwardHandlerFunc defines the handler function used to forward a port of a pod,
+// passing through the data stream of the client connection.
+type PortForwardHandlerFunc func(ctx context.Context, namespace, podName string, port int32, stream io.ReadWriteCloser) error
+
+// HandlePortForward makes an http handler func from a Provider which forwards the ports of a pod
+// Note that this handler currently depends on gorrilla/mux to get url parts as variables.
+func HandlePortForward(h PortForwardHandlerFunc) http.HandlerFunc {
+	if h == nil {
+		return NotImplemented
+	}
+	return handleError(func(w http.ResponseWriter, req *http.Request) error {
+		vars := mux.Vars(req)
+
+		namespace := vars["namespace"]
+		pod := vars["pod"]
+
+		supportedStreamProtocols := strings.Split(req.Header.Get("X-Stream-Protocol-Version"), ",")
+
+		portForwardOpts, err := portforward.NewV4Options(req)
+		if err != nil {
+			return errdefs.AsInvalidInput(err)
+		}
+
+		idleTimeout := time.Second * 30
+		streamCreationTimeout := time.Second * 30
+
+		ctx, cancel := context.WithCancel(context.TODO())
+		defer cancel()
+
+		forwarder := &portForwardContext{ctx: ctx, h: h, pod: pod, namespace: namespace}
+		portforward.ServePortForward(w, req, forwarder, pod, "", portForwardOpts, idleTimeout, streamCreationTimeout, supportedStreamProtocols)
+
+		return nil
+	})
+}
+
+type portForwardContext struct {
+	h              PortForwardHandlerFunc
+	namespace, pod string
+	ctx            context.Context
+}
+
+// PortForward Implements portforward.PortForwarder
+// This is called by portforward.ServePortForward
+func (c *portForwardContext) PortForward(name string, uid types.UID, port int32, stream io.ReadWriteCloser) error {
+	return c.h(c.ctx, c.namespace, c.pod, port, stream)
+}
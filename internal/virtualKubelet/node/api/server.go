@@ -33,9 +33,11 @@ type ServeMux interface {
 }
 
 type PodHandlerConfig struct {
-	RunInContainer   ContainerExecHandlerFunc
-	GetContainerLogs ContainerLogsHandlerFunc
-	GetPods          PodListerFunc
+	RunInContainer    ContainerExecHandlerFunc
+	AttachToContainer ContainerAttachHandlerFunc
+	PortForward       PortForwardHandlerFunc
+	GetContainerLogs  ContainerLogsHandlerFunc
+	GetPods           PodListerFunc
 }
 
 // PodHandler creates an http handler for interacting with pods/containers.
@@ -49,6 +51,8 @@ func PodHandler(p PodHandlerConfig, debug bool) http.Handler {
 	}
 	r.HandleFunc("/containerLogs/{namespace}/{pod}/{container}", HandleContainerLogs(p.GetContainerLogs)).Methods("GET")
 	r.HandleFunc("/exec/{namespace}/{pod}/{container}", HandleContainerExec(p.RunInContainer)).Methods("POST")
+	r.HandleFunc("/attach/{namespace}/{pod}/{container}", HandleContainerAttach(p.AttachToContainer)).Methods("POST", "GET")
+	r.HandleFunc("/portForward/{namespace}/{pod}", HandlePortForward(p.PortForward)).Methods("POST", "GET")
 	r.NotFoundHandler = http.HandlerFunc(NotFound)
 	return r
 }
@@ -12,10 +12,13 @@ import (
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/utils/pointer"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sort"
+	"strings"
 )
 
 // create deployment for a virtual-kubelet
-func CreateVkDeployment(adv *advtypes.Advertisement, vkName, vkNamespace, vkImage, initVKImage, nodeName, homeClusterId string) *appsv1.Deployment {
+func CreateVkDeployment(adv *advtypes.Advertisement, vkName, vkNamespace, vkImage, initVKImage, nodeName, homeClusterId string,
+	storageClassMapping map[string]string) *appsv1.Deployment {
 
 	command := []string{
 		"/usr/bin/virtual-kubelet",
@@ -31,10 +34,19 @@ func CreateVkDeployment(adv *advtypes.Advertisement, vkName, vkNamespace, vkImag
 		"--kubelet-namespace",
 		vkNamespace,
 		"--foreign-kubeconfig",
-		"/app/kubeconfig/remote",
+		"/app/kubeconfig/remote/kubeconfig",
 		"--home-cluster-id",
 		homeClusterId,
 	}
+	if len(storageClassMapping) > 0 {
+		// the pairs are sorted, so that the deployment is not updated if the mapping does not change
+		pairs := make([]string, 0, len(storageClassMapping))
+		for home, foreign := range storageClassMapping {
+			pairs = append(pairs, strings.Join([]string{home, foreign}, "="))
+		}
+		sort.Strings(pairs)
+		args = append(args, "--storage-class-mapping", strings.Join(pairs, ","))
+	}
 
 	volumes := []v1.Volume{
 		{
@@ -55,9 +67,9 @@ func CreateVkDeployment(adv *advtypes.Advertisement, vkName, vkNamespace, vkImag
 
 	volumeMounts := []v1.VolumeMount{
 		{
+			// the whole secret is mounted, since a subPath would not receive the updates of the rotated credentials
 			Name:      "remote-kubeconfig",
 			MountPath: "/app/kubeconfig/remote",
-			SubPath:   "kubeconfig",
 		},
 		{
 			Name:      "virtual-kubelet-crt",
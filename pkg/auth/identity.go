@@ -0,0 +1,213 @@
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"github.com/liqotech/liqo/pkg/csrApprover"
+	certificatesv1beta1 "k8s.io/api/certificates/v1beta1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	"k8s.io/klog"
+	"time"
+)
+
+const (
+	// interval between two checks of the expiration of the identity
+	renewalCheckPeriod = time.Minute
+	// maximum time waited for a CSR to be approved and signed
+	certificateIssueTimeout = 30 * time.Second
+)
+
+// ErrIdentityDenied is returned when the CSR requesting the identity of a cluster is denied.
+var ErrIdentityDenied = errors.New("identity denied")
+
+var (
+	// create the client authenticated by the current identity, and encode the kubeconfig, replaceable in tests
+	newIdentityClient = newClientFromKubeconfig
+	writeKubeconfig   = clientcmd.Write
+)
+
+func newClientFromKubeconfig(config *clientcmdapi.Config) (kubernetes.Interface, error) {
+	restConfig, err := clientcmd.NewDefaultClientConfig(*config, &clientcmd.ConfigOverrides{}).ClientConfig()
+	if err != nil {
+		return nil, err
+	}
+	return kubernetes.NewForConfig(restConfig)
+}
+
+// GenerateIdentityRequest generates the private key of the identity of the given cluster, and the PEM encoded
+// certificate request to be sent to the auth service of the remote cluster in the RoleRequest.
+func GenerateIdentityRequest(clusterID string) (key []byte, request []byte, err error) {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	der, err := x509.MarshalECPrivateKey(privateKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	csr, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject: pkix.Name{CommonName: csrApprover.IdentityUserName(clusterID)},
+	}, privateKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: csr}), nil
+}
+
+// IdentityRenewer renews the client certificate granted by a remote cluster before it expires. The new certificate
+// is requested through the CSR API of the remote cluster, authenticated by the current one, and is approved by the
+// Liqo CSR approver only if its subject is the same.
+type IdentityRenewer struct {
+	// ID of the local cluster, i.e. the subject of the certificate
+	ClusterID string
+	// time before the expiration of the certificate when it is renewed, a third of its lifetime if zero
+	RenewBefore time.Duration
+	// returns the kubeconfig with the current certificate and private key
+	Load func() ([]byte, error)
+	// stores the kubeconfig with the renewed certificate and private key
+	Store func(kubeconfig []byte) error
+}
+
+// Renew replaces the certificate of the kubeconfig if it expires within the renewal period.
+func (r *IdentityRenewer) Renew(now time.Time) error {
+	kubeconfig, err := r.Load()
+	if err != nil {
+		return err
+	}
+	config, err := clientcmd.Load(kubeconfig)
+	if err != nil {
+		return err
+	}
+	authInfo, err := getAuthInfo(config)
+	if err != nil {
+		return err
+	}
+	cert, err := parseCertificate(authInfo.ClientCertificateData)
+	if err != nil {
+		return err
+	}
+	renewBefore := r.RenewBefore
+	if renewBefore <= 0 {
+		renewBefore = cert.NotAfter.Sub(cert.NotBefore) / 3
+	}
+	if cert.NotAfter.Sub(now) > renewBefore {
+		return nil
+	}
+
+	clientset, err := newIdentityClient(config)
+	if err != nil {
+		return err
+	}
+	key, request, err := GenerateIdentityRequest(r.ClusterID)
+	if err != nil {
+		return err
+	}
+	csr, err := RequestIdentityCertificate(clientset, r.ClusterID, request)
+	if err != nil {
+		return err
+	}
+
+	authInfo.ClientCertificateData = csr.Status.Certificate
+	authInfo.ClientKeyData = key
+	if kubeconfig, err = writeKubeconfig(*config); err != nil {
+		return err
+	}
+	if err = r.Store(kubeconfig); err != nil {
+		return err
+	}
+	klog.Infof("identity of cluster %s renewed, previous certificate expiring at %v", r.ClusterID, cert.NotAfter)
+	return nil
+}
+
+// Run renews the certificate until the stop channel is closed.
+func (r *IdentityRenewer) Run(stop <-chan struct{}) {
+	wait.Until(func() {
+		if err := r.Renew(time.Now()); err != nil {
+			klog.Errorf("unable to renew the identity of cluster %s: %v", r.ClusterID, err)
+		}
+	}, renewalCheckPeriod, stop)
+}
+
+// RequestIdentityCertificate submits the certificate request of the identity of the given cluster to the CSR API,
+// and returns the CSR once it has been approved by the Liqo CSR approver and signed. The CSR is named after the
+// cluster, hence the one of a previous request is replaced.
+func RequestIdentityCertificate(clientset kubernetes.Interface, clusterID string, request []byte) (*certificatesv1beta1.CertificateSigningRequest, error) {
+	signerName := csrApprover.IdentitySignerName
+	csr := &certificatesv1beta1.CertificateSigningRequest{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: csrApprover.IdentityCSRName(clusterID),
+			Labels: map[string]string{
+				"liqo.io/csr":                      "true",
+				csrApprover.IdentityClusterIDLabel: clusterID,
+			},
+		},
+		Spec: certificatesv1beta1.CertificateSigningRequestSpec{
+			Request:    request,
+			SignerName: &signerName,
+			Usages: []certificatesv1beta1.KeyUsage{
+				certificatesv1beta1.UsageDigitalSignature,
+				certificatesv1beta1.UsageKeyEncipherment,
+				certificatesv1beta1.UsageClientAuth,
+			},
+		},
+	}
+	err := clientset.CertificatesV1beta1().CertificateSigningRequests().Delete(context.TODO(), csr.Name, metav1.DeleteOptions{})
+	if err != nil && !kerrors.IsNotFound(err) {
+		return nil, err
+	}
+	csr, err = clientset.CertificatesV1beta1().CertificateSigningRequests().Create(context.TODO(), csr, metav1.CreateOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	name := csr.Name
+	err = wait.PollImmediate(500*time.Millisecond, certificateIssueTimeout, func() (bool, error) {
+		csr, err = clientset.CertificatesV1beta1().CertificateSigningRequests().Get(context.TODO(), name, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+		for _, condition := range csr.Status.Conditions {
+			if condition.Type == certificatesv1beta1.CertificateDenied {
+				return false, fmt.Errorf("%w: CSR %s denied: %s", ErrIdentityDenied, name, condition.Message)
+			}
+		}
+		return len(csr.Status.Certificate) > 0, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return csr, nil
+}
+
+func getAuthInfo(config *clientcmdapi.Config) (*clientcmdapi.AuthInfo, error) {
+	kubeContext, ok := config.Contexts[config.CurrentContext]
+	if !ok {
+		return nil, fmt.Errorf("context %s not found", config.CurrentContext)
+	}
+	authInfo, ok := config.AuthInfos[kubeContext.AuthInfo]
+	if !ok || len(authInfo.ClientCertificateData) == 0 {
+		return nil, errors.New("the kubeconfig contains no client certificate")
+	}
+	return authInfo, nil
+}
+
+func parseCertificate(certificate []byte) (*x509.Certificate, error) {
+	block, _ := pem.Decode(certificate)
+	if block == nil {
+		return nil, errors.New("the client certificate is not PEM encoded")
+	}
+	return x509.ParseCertificate(block.Bytes)
+}
@@ -0,0 +1,127 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	certificatesv1beta1 "k8s.io/api/certificates/v1beta1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	"math/big"
+	"testing"
+	"time"
+)
+
+// signs a certificate for the public key with the given validity
+func signCertificate(t *testing.T, template *x509.Certificate, publicKey interface{}) []byte {
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+	template.SerialNumber = big.NewInt(1)
+	cert, err := x509.CreateCertificate(rand.Reader, template, template, publicKey, caKey)
+	assert.Nil(t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert})
+}
+
+func getKubeconfig(certificate []byte) []byte {
+	return []byte(fmt.Sprintf(`apiVersion: v1
+kind: Config
+clusters:
+- name: remote
+  cluster:
+    server: https://remote:6443
+users:
+- name: identity
+  user:
+    client-certificate-data: %s
+contexts:
+- name: remote
+  context:
+    cluster: remote
+    user: identity
+current-context: remote
+`, base64.StdEncoding.EncodeToString(certificate)))
+}
+
+func TestIdentityRenewer(t *testing.T) {
+	now := time.Now()
+	clientset := fake.NewSimpleClientset()
+	var created []*certificatesv1beta1.CertificateSigningRequest
+	deny := false
+	// act as the Liqo CSR approver and the signer of the remote cluster
+	clientset.PrependReactor("create", "certificatesigningrequests", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		csr := action.(k8stesting.CreateAction).GetObject().(*certificatesv1beta1.CertificateSigningRequest)
+		created = append(created, csr)
+		if deny {
+			csr.Status.Conditions = append(csr.Status.Conditions, certificatesv1beta1.CertificateSigningRequestCondition{
+				Type: certificatesv1beta1.CertificateDenied,
+			})
+			return false, csr, nil
+		}
+		block, _ := pem.Decode(csr.Spec.Request)
+		request, err := x509.ParseCertificateRequest(block.Bytes)
+		assert.Nil(t, err)
+		csr.Status.Certificate = signCertificate(t, &x509.Certificate{Subject: request.Subject, NotBefore: now, NotAfter: now.Add(30 * time.Hour)}, request.PublicKey)
+		return false, csr, nil
+	})
+	newIdentityClient = func(*clientcmdapi.Config) (kubernetes.Interface, error) { return clientset, nil }
+	var stored *clientcmdapi.Config
+	writeKubeconfig = func(config clientcmdapi.Config) ([]byte, error) {
+		stored = &config
+		return getKubeconfig(config.AuthInfos["identity"].ClientCertificateData), nil
+	}
+	defer func() {
+		newIdentityClient = newClientFromKubeconfig
+		writeKubeconfig = clientcmd.Write
+	}()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+	kubeconfig := getKubeconfig(signCertificate(t, &x509.Certificate{NotBefore: now.Add(-20 * time.Hour), NotAfter: now.Add(10 * time.Hour)}, key.Public()))
+	renewer := &IdentityRenewer{
+		ClusterID:   "cluster-1",
+		RenewBefore: 5 * time.Hour,
+		Load:        func() ([]byte, error) { return kubeconfig, nil },
+		Store: func(config []byte) error {
+			kubeconfig = config
+			return nil
+		},
+	}
+
+	// the certificate is not renewed before the renewal period
+	assert.Nil(t, renewer.Renew(now))
+	assert.Empty(t, created)
+
+	// the renewal is retried if the CSR is denied
+	deny = true
+	assert.NotNil(t, renewer.Renew(now.Add(6*time.Hour)))
+	assert.Len(t, created, 1)
+	assert.Nil(t, stored)
+
+	// the certificate is replaced with a new one, with a new private key
+	deny = false
+	assert.Nil(t, renewer.Renew(now.Add(6*time.Hour)))
+	assert.Len(t, created, 2)
+	assert.Equal(t, "cluster-1", created[1].Labels["liqo.io/remote-cluster-id"])
+	assert.Equal(t, "liqo-identity-cluster-1", created[1].Name)
+	assert.NotNil(t, stored)
+	assert.NotEmpty(t, stored.AuthInfos["identity"].ClientKeyData)
+	cert, err := parseCertificate(stored.AuthInfos["identity"].ClientCertificateData)
+	assert.Nil(t, err)
+	assert.Equal(t, "liqo-cluster:cluster-1", cert.Subject.CommonName)
+
+	// the renewed certificate is kept, without a renewal period a third of its lifetime is left before renewing it
+	renewer.RenewBefore = 0
+	assert.Nil(t, renewer.Renew(now.Add(15*time.Hour)))
+	assert.Len(t, created, 2)
+	assert.Nil(t, renewer.Renew(now.Add(21*time.Hour)))
+	assert.Len(t, created, 3)
+}
@@ -3,4 +3,7 @@ package auth
 type RoleRequest struct {
 	ClusterID string `json:"clusterID"`
 	Token     string `json:"token"`
+	// PEM encoded certificate request, generated by GenerateIdentityRequest. If set, the identity returned to the
+	// remote cluster is a client certificate instead of a ServiceAccount token, renewed by an IdentityRenewer
+	CertificateSigningRequest []byte `json:"certificateSigningRequest,omitempty"`
 }
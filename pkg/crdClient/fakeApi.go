@@ -2,10 +2,13 @@ package crdClient
 
 import (
 	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/client-go/rest"
+	"reflect"
 )
 
 type FakeClient struct {
@@ -33,8 +36,29 @@ func (c *FakeClient) Get(name string, _ metav1.GetOptions) (runtime.Object, erro
 	return result.(runtime.Object), err
 }
 
-func (c *FakeClient) List(_ metav1.ListOptions) (runtime.Object, error) {
-	panic("to implement")
+func (c *FakeClient) List(opts metav1.ListOptions) (runtime.Object, error) {
+	selector, err := labels.Parse(opts.LabelSelector)
+	if err != nil {
+		return nil, err
+	}
+
+	// the storage can be shared by several resources, only the ones of the requested type are returned
+	list := reflect.New(c.resource.PluralType)
+	items := list.Elem().FieldByName("Items")
+	for _, obj := range c.storage.List() {
+		if reflect.TypeOf(obj) != reflect.PtrTo(c.resource.SingularType) {
+			continue
+		}
+		accessor, err := meta.Accessor(obj)
+		if err != nil {
+			return nil, err
+		}
+		if selector.Matches(labels.Set(accessor.GetLabels())) {
+			items.Set(reflect.Append(items, reflect.ValueOf(obj).Elem()))
+		}
+	}
+
+	return list.Interface().(runtime.Object), nil
 }
 
 func (c *FakeClient) Watch(_ metav1.ListOptions) (watch.Interface, error) {
@@ -1,6 +1,7 @@
 package crdClient
 
 import (
+	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/client-go/tools/cache"
@@ -12,6 +13,9 @@ import (
 func NewFakeCustomInformer(handlers cache.ResourceEventHandlerFuncs,
 	keyer KeyerFunc,
 	groupResource schema.GroupResource) (cache.Store, chan struct{}) {
+	if keyer == nil {
+		keyer = nameKeyer
+	}
 	i := &fakeInformer{
 		FakeCustomStore: cache.FakeCustomStore{},
 		funcs:           handlers,
@@ -33,3 +37,12 @@ func NewFakeCustomInformer(handlers cache.ResourceEventHandlerFuncs,
 	i.Watch()
 	return i, make(chan struct{}, 1)
 }
+
+// nameKeyer is the default keyer of the fake informers, which indexes the objects by name
+func nameKeyer(obj runtime.Object) (string, error) {
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return "", err
+	}
+	return accessor.GetName(), nil
+}
@@ -0,0 +1,101 @@
+package crdClient
+
+import (
+	"errors"
+	"io/ioutil"
+	utilnet "k8s.io/apimachinery/pkg/util/net"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/klog"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ReloadableToken is a bearer token which can be replaced while the clients using it are running, so that the
+// credentials granted by a remote cluster can be rotated without restarting the component.
+type ReloadableToken struct {
+	mutex sync.RWMutex
+	token string
+}
+
+// NewReloadableToken moves the bearer token of the config to a ReloadableToken, which authenticates the requests of
+// every client created from the config from then on. The config is left untouched if it has no bearer token.
+func NewReloadableToken(config *rest.Config) *ReloadableToken {
+	t := &ReloadableToken{token: config.BearerToken}
+	if config.BearerToken == "" {
+		return t
+	}
+	config.BearerToken = ""
+	config.BearerTokenFile = ""
+	wrapTransport := config.WrapTransport
+	config.WrapTransport = func(rt http.RoundTripper) http.RoundTripper {
+		if wrapTransport != nil {
+			rt = wrapTransport(rt)
+		}
+		return &tokenRoundTripper{token: t, rt: rt}
+	}
+	return t
+}
+
+func (t *ReloadableToken) Get() string {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+	return t.token
+}
+
+// Set replaces the token, and returns whether it has changed.
+func (t *ReloadableToken) Set(token string) bool {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	changed := t.token != token
+	t.token = token
+	return changed
+}
+
+// SetFromKubeconfig replaces the token with the one of the given kubeconfig, and returns whether it has changed.
+// The other parameters of the kubeconfig, e.g. the address of the API server, are expected not to change.
+func (t *ReloadableToken) SetFromKubeconfig(kubeconfig []byte) (bool, error) {
+	config, err := clientcmd.RESTConfigFromKubeConfig(kubeconfig)
+	if err != nil {
+		return false, err
+	}
+	if config.BearerToken == "" {
+		return false, errors.New("the kubeconfig contains no bearer token")
+	}
+	return t.Set(config.BearerToken), nil
+}
+
+// WatchKubeconfigFile reloads the token from the kubeconfig file every period, until the stop channel is closed.
+func (t *ReloadableToken) WatchKubeconfigFile(path string, period time.Duration, stop <-chan struct{}) {
+	wait.Until(func() {
+		kubeconfig, err := ioutil.ReadFile(path)
+		if err != nil {
+			klog.Error(err)
+			return
+		}
+		if changed, err := t.SetFromKubeconfig(kubeconfig); err != nil {
+			klog.Errorf("unable to reload the credentials from %s: %v", path, err)
+		} else if changed {
+			klog.Infof("credentials reloaded from %s", path)
+		}
+	}, period, stop)
+}
+
+type tokenRoundTripper struct {
+	token *ReloadableToken
+	rt    http.RoundTripper
+}
+
+func (rt *tokenRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	token := rt.token.Get()
+	if token == "" || req.Header.Get("Authorization") != "" {
+		return rt.rt.RoundTrip(req)
+	}
+	req = utilnet.CloneRequest(req)
+	req.Header.Set("Authorization", "Bearer "+token)
+	return rt.rt.RoundTrip(req)
+}
+
+func (rt *tokenRoundTripper) WrappedRoundTripper() http.RoundTripper { return rt.rt }
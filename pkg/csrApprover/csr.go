@@ -2,26 +2,41 @@ package csrApprover
 
 import (
 	"context"
+	"fmt"
 	certificatesv1beta1 "k8s.io/api/certificates/v1beta1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/informers"
 	k8s "k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/klog"
+	"strings"
 	"time"
 )
 
-func approveCSR(clientSet k8s.Interface, csr *certificatesv1beta1.CertificateSigningRequest) error {
+// approveCSR approves the CSRs watched by Liqo. The CSRs issuing the identity of a remote cluster, i.e. signed by the
+// signer of the client certificates, labelled with the ID of the cluster or created by the identityRequester or by a
+// remote cluster, are denied if not valid. The other CSRs are approved only if created by a virtual kubelet, i.e. by
+// a user whose name starts with kubeletRequesterPrefix.
+func approveCSR(clientSet k8s.Interface, csr *certificatesv1beta1.CertificateSigningRequest, identityRequester, kubeletRequesterPrefix string) error {
 	// certificate already added to CSR
 	if csr.Status.Certificate != nil {
 		return nil
 	}
-	// Check if the certificate is already approved but the certificate is still not available
+	// Check if the certificate is already approved or denied but the certificate is still not available
 	for _, b := range csr.Status.Conditions {
-		if b.Type == "Approved" {
+		if b.Type == certificatesv1beta1.CertificateApproved || b.Type == certificatesv1beta1.CertificateDenied {
 			return nil
 		}
 	}
+	// the identity of a remote cluster is approved only if the subject matches the cluster which requested it
+	switch {
+	case isIdentityCSR(csr, identityRequester):
+		if err := ValidateIdentityCSR(csr, identityRequester); err != nil {
+			return denyCSR(clientSet, csr, err)
+		}
+	case kubeletRequesterPrefix == "" || !strings.HasPrefix(csr.Spec.Username, kubeletRequesterPrefix):
+		return denyCSR(clientSet, csr, fmt.Errorf("user %q is not allowed to request certificates", csr.Spec.Username))
+	}
 	// Approve
 	csr.Status.Conditions = append(csr.Status.Conditions, certificatesv1beta1.CertificateSigningRequestCondition{
 		Type:           certificatesv1beta1.CertificateApproved,
@@ -36,7 +51,35 @@ func approveCSR(clientSet k8s.Interface, csr *certificatesv1beta1.CertificateSig
 	return nil
 }
 
-func WatchCSR(clientset k8s.Interface, label string, resyncPeriod time.Duration) {
+// isIdentityCSR returns whether the CSR asks for, or has been created by, the identity of a remote cluster
+func isIdentityCSR(csr *certificatesv1beta1.CertificateSigningRequest, identityRequester string) bool {
+	if _, labelled := csr.Labels[IdentityClusterIDLabel]; labelled {
+		return true
+	}
+	if csr.Spec.SignerName != nil && *csr.Spec.SignerName == IdentitySignerName {
+		return true
+	}
+	return strings.HasPrefix(csr.Spec.Username, IdentityUserPrefix) || (identityRequester != "" && csr.Spec.Username == identityRequester)
+}
+
+// denies the CSR, reporting the reason in its conditions
+func denyCSR(clientSet k8s.Interface, csr *certificatesv1beta1.CertificateSigningRequest, reason error) error {
+	csr.Status.Conditions = append(csr.Status.Conditions, certificatesv1beta1.CertificateSigningRequestCondition{
+		Type:           certificatesv1beta1.CertificateDenied,
+		Reason:         "LiqoDenial",
+		Message:        reason.Error(),
+		LastUpdateTime: metav1.Now(),
+	})
+	if _, err := clientSet.CertificatesV1beta1().CertificateSigningRequests().UpdateApproval(context.TODO(), csr, metav1.UpdateOptions{}); err != nil {
+		return err
+	}
+	return fmt.Errorf("CSR %v denied: %w", csr.Name, reason)
+}
+
+// WatchCSR approves the CSRs matching the label selector. identityRequester is the user, i.e. the ServiceAccount of
+// the auth service, allowed to request the identities of the remote clusters, while kubeletRequesterPrefix is the
+// prefix of the users, i.e. the ServiceAccounts of the virtual kubelets, allowed to request their certificates.
+func WatchCSR(clientset k8s.Interface, label string, resyncPeriod time.Duration, identityRequester, kubeletRequesterPrefix string) {
 
 	stop := make(chan struct{})
 	lo := func(options *metav1.ListOptions) {
@@ -54,7 +97,7 @@ func WatchCSR(clientset k8s.Interface, label string, resyncPeriod time.Duration)
 				klog.Error("Unable to cast object")
 				return
 			}
-			err := approveCSR(clientset, csr)
+			err := approveCSR(clientset, csr, identityRequester, kubeletRequesterPrefix)
 			if err != nil {
 				klog.Error(err)
 			} else {
@@ -2,6 +2,12 @@ package csrApprover
 
 import (
 	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
 	"github.com/stretchr/testify/assert"
 	certificatesv1beta1 "k8s.io/api/certificates/v1beta1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -19,7 +25,9 @@ func TestNewNamespaceWithSuffix(t *testing.T) {
 				"liqo.io/csr": "true",
 			},
 		},
-		Spec:   certificatesv1beta1.CertificateSigningRequestSpec{},
+		Spec: certificatesv1beta1.CertificateSigningRequestSpec{
+			Username: "system:serviceaccount:liqo:virtual-kubelet-cluster-1",
+		},
 		Status: certificatesv1beta1.CertificateSigningRequestStatus{},
 	}
 
@@ -28,7 +36,7 @@ func TestNewNamespaceWithSuffix(t *testing.T) {
 	if err != nil {
 		t.Fail()
 	}
-	err = approveCSR(c, &certificateToValidate)
+	err = approveCSR(c, &certificateToValidate, "", "system:serviceaccount:liqo:virtual-kubelet-")
 	if err != nil {
 		t.Fail()
 	}
@@ -43,3 +51,109 @@ func TestNewNamespaceWithSuffix(t *testing.T) {
 	assert.Equal(t, conditions[0].Reason, "LiqoApproval")
 	assert.Equal(t, conditions[0].Message, "This CSR was approved by Liqo Advertisement Operator")
 }
+
+func getIdentityRequest(t *testing.T, template *x509.CertificateRequest) []byte {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+	request, err := x509.CreateCertificateRequest(rand.Reader, template, key)
+	assert.Nil(t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: request})
+}
+
+func TestApproveIdentityCSR(t *testing.T) {
+	const requester = "system:serviceaccount:liqo:auth-service"
+	signerName := IdentitySignerName
+	identity := func(commonName string) *x509.CertificateRequest {
+		return &x509.CertificateRequest{Subject: pkix.Name{CommonName: commonName}}
+	}
+	testCases := []struct {
+		name     string
+		username string
+		label    string
+		template *x509.CertificateRequest
+		approved bool
+	}{
+		{name: "issued by the auth service", username: requester, label: "cluster-1", template: identity("liqo-cluster:cluster-1"), approved: true},
+		{name: "renewed by the cluster", username: "liqo-cluster:cluster-1", template: identity("liqo-cluster:cluster-1"), approved: true},
+		{name: "renewed with its label", username: "liqo-cluster:cluster-1", label: "cluster-1", template: identity("liqo-cluster:cluster-1"), approved: true},
+		{name: "not namespaced", username: requester, label: "cluster-1", template: identity("cluster-1")},
+		{name: "other cluster", username: requester, label: "cluster-1", template: identity("liqo-cluster:cluster-2")},
+		{name: "system user", username: requester, label: "system:kube-scheduler", template: identity("system:kube-scheduler")},
+		{name: "invalid cluster ID", username: requester, label: "Cluster_1", template: identity("liqo-cluster:Cluster_1")},
+		{name: "renewal of another cluster", username: "liqo-cluster:cluster-2", template: identity("liqo-cluster:cluster-1")},
+		{name: "renewal with another label", username: "liqo-cluster:cluster-2", label: "cluster-1", template: identity("liqo-cluster:cluster-2")},
+		{name: "untrusted requester", username: "mallory", label: "cluster-1", template: identity("liqo-cluster:cluster-1")},
+		{name: "groups", username: requester, label: "cluster-1",
+			template: &x509.CertificateRequest{Subject: pkix.Name{CommonName: "liqo-cluster:cluster-1", Organization: []string{"system:masters"}}}},
+		{name: "alternative names", username: requester, label: "cluster-1",
+			template: &x509.CertificateRequest{Subject: pkix.Name{CommonName: "liqo-cluster:cluster-1"}, DNSNames: []string{"cluster-1"}}},
+	}
+
+	for _, tc := range testCases {
+		labels := map[string]string{"liqo.io/csr": "true"}
+		if tc.label != "" {
+			labels[IdentityClusterIDLabel] = tc.label
+		}
+		csr := &certificatesv1beta1.CertificateSigningRequest{
+			ObjectMeta: v1.ObjectMeta{
+				Name:   "identity",
+				Labels: labels,
+			},
+			Spec: certificatesv1beta1.CertificateSigningRequestSpec{
+				Request:    getIdentityRequest(t, tc.template),
+				SignerName: &signerName,
+				Username:   tc.username,
+			},
+		}
+		c := testclient.NewSimpleClientset(csr)
+		err := approveCSR(c, csr.DeepCopy(), requester, "system:serviceaccount:liqo:virtual-kubelet-")
+		assert.Equal(t, tc.approved, err == nil, tc.name)
+
+		cert, err := c.CertificatesV1beta1().CertificateSigningRequests().Get(context.TODO(), "identity", v1.GetOptions{})
+		assert.Nil(t, err)
+		assert.Len(t, cert.Status.Conditions, 1, tc.name)
+		if tc.approved {
+			assert.Equal(t, certificatesv1beta1.CertificateApproved, cert.Status.Conditions[0].Type, tc.name)
+		} else {
+			assert.Equal(t, certificatesv1beta1.CertificateDenied, cert.Status.Conditions[0].Type, tc.name)
+		}
+	}
+}
+
+func TestDenyUnauthorizedCSR(t *testing.T) {
+	const requester = "system:serviceaccount:liqo:auth-service"
+	kubeletSigner := "kubernetes.io/kubelet-serving"
+	node := &x509.CertificateRequest{Subject: pkix.Name{CommonName: "system:node:x", Organization: []string{"system:nodes"}}}
+	testCases := []struct {
+		name     string
+		username string
+		signer   *string
+	}{
+		{name: "node identity requested by a remote cluster", username: "liqo-cluster:cluster-1", signer: &kubeletSigner},
+		{name: "node identity without signer requested by a remote cluster", username: "liqo-cluster:cluster-1"},
+		{name: "node identity requested by the auth service", username: requester},
+		{name: "node identity requested by another user", username: "mallory", signer: &kubeletSigner},
+		{name: "node identity requested by another service account", username: "system:serviceaccount:default:virtual-kubelet-x"},
+	}
+
+	for _, tc := range testCases {
+		csr := &certificatesv1beta1.CertificateSigningRequest{
+			ObjectMeta: v1.ObjectMeta{
+				Name:   "node",
+				Labels: map[string]string{"liqo.io/csr": "true"},
+			},
+			Spec: certificatesv1beta1.CertificateSigningRequestSpec{
+				Request:    getIdentityRequest(t, node),
+				SignerName: tc.signer,
+				Username:   tc.username,
+			},
+		}
+		c := testclient.NewSimpleClientset(csr)
+		assert.NotNil(t, approveCSR(c, csr.DeepCopy(), requester, "system:serviceaccount:liqo:virtual-kubelet-"), tc.name)
+
+		cert, err := c.CertificatesV1beta1().CertificateSigningRequests().Get(context.TODO(), "node", v1.GetOptions{})
+		assert.Nil(t, err)
+		assert.Len(t, cert.Status.Conditions, 1, tc.name)
+		assert.Equal(t, certificatesv1beta1.CertificateDenied, cert.Status.Conditions[0].Type, tc.name)
+	}
+}
@@ -0,0 +1,105 @@
+package csrApprover
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	certificatesv1beta1 "k8s.io/api/certificates/v1beta1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"strings"
+)
+
+const (
+	// label set on the CSRs issuing the identity of a remote cluster, whose value is the ID of that cluster
+	IdentityClusterIDLabel = "liqo.io/remote-cluster-id"
+	// signer of the client certificates used by the remote clusters to authenticate to the API server
+	IdentitySignerName = "kubernetes.io/kube-apiserver-client"
+	// prefix of the user names of the remote clusters, which keeps them apart from the users of the local cluster
+	IdentityUserPrefix = "liqo-cluster:"
+)
+
+// IdentityCSRName returns the name of the CSR issuing the identity of a remote cluster, which is fixed so that the
+// cluster can be granted access to its own CSR only.
+func IdentityCSRName(clusterID string) string {
+	return "liqo-identity-" + clusterID
+}
+
+// IdentityUserName returns the user name authenticated by the client certificate of a remote cluster.
+func IdentityUserName(clusterID string) string {
+	return IdentityUserPrefix + clusterID
+}
+
+// ValidateClusterID checks that the cluster ID can be used in the names of the resources granted to a remote cluster.
+func ValidateClusterID(clusterID string) error {
+	if errs := validation.IsDNS1123Label(clusterID); len(errs) > 0 {
+		return fmt.Errorf("invalid cluster ID %q: %s", clusterID, strings.Join(errs, ", "))
+	}
+	return nil
+}
+
+// ParseIdentityRequest decodes a PEM encoded certificate request, and checks that it has been signed with the
+// private key matching its public key.
+func ParseIdentityRequest(request []byte) (*x509.CertificateRequest, error) {
+	block, _ := pem.Decode(request)
+	if block == nil || block.Type != "CERTIFICATE REQUEST" {
+		return nil, errors.New("PEM block of type CERTIFICATE REQUEST expected")
+	}
+	csr, err := x509.ParseCertificateRequest(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	if err = csr.CheckSignature(); err != nil {
+		return nil, err
+	}
+	return csr, nil
+}
+
+// ValidateIdentityRequest checks that the certificate request only asks for the identity of the given cluster:
+// the common name has to be the user name of the cluster, with no groups nor alternative names.
+func ValidateIdentityRequest(csr *x509.CertificateRequest, clusterID string) error {
+	if err := ValidateClusterID(clusterID); err != nil {
+		return err
+	}
+	if strings.HasPrefix(csr.Subject.CommonName, "system:") {
+		return fmt.Errorf("common name %q is reserved", csr.Subject.CommonName)
+	}
+	if csr.Subject.CommonName != IdentityUserName(clusterID) {
+		return fmt.Errorf("common name %q does not match the identity %q", csr.Subject.CommonName, IdentityUserName(clusterID))
+	}
+	if len(csr.Subject.Organization) > 0 {
+		return fmt.Errorf("groups %v not allowed", csr.Subject.Organization)
+	}
+	if len(csr.DNSNames) > 0 || len(csr.IPAddresses) > 0 || len(csr.EmailAddresses) > 0 || len(csr.URIs) > 0 {
+		return errors.New("subject alternative names not allowed")
+	}
+	return nil
+}
+
+// ValidateIdentityCSR checks that the CSR issues a client certificate whose subject is a remote cluster, and that
+// it has been created by someone entitled to: the identityRequester, i.e. the auth service, can request the identity
+// of the cluster set in the IdentityClusterIDLabel, while a remote cluster can only renew its own identity.
+func ValidateIdentityCSR(csr *certificatesv1beta1.CertificateSigningRequest, identityRequester string) error {
+	if csr.Spec.SignerName == nil || *csr.Spec.SignerName != IdentitySignerName {
+		return fmt.Errorf("signer %s expected", IdentitySignerName)
+	}
+
+	var clusterID string
+	switch {
+	case identityRequester != "" && csr.Spec.Username == identityRequester:
+		clusterID = csr.Labels[IdentityClusterIDLabel]
+	case strings.HasPrefix(csr.Spec.Username, IdentityUserPrefix):
+		clusterID = strings.TrimPrefix(csr.Spec.Username, IdentityUserPrefix)
+		if label, ok := csr.Labels[IdentityClusterIDLabel]; ok && label != clusterID {
+			return fmt.Errorf("user %s cannot request the identity of cluster %s", csr.Spec.Username, label)
+		}
+	default:
+		return fmt.Errorf("user %q is not allowed to request the identity of a remote cluster", csr.Spec.Username)
+	}
+
+	request, err := ParseIdentityRequest(csr.Spec.Request)
+	if err != nil {
+		return err
+	}
+	return ValidateIdentityRequest(request, clusterID)
+}
@@ -0,0 +1,118 @@
+package liqonet
+
+import (
+	"context"
+	"fmt"
+	"github.com/liqotech/liqo/internal/utils/errdefs"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+	"k8s.io/klog"
+	"time"
+)
+
+const (
+	// GatewayLabelSelector selects the nodes which are candidates to be the gateway of the cluster.
+	GatewayLabelSelector = "net.liqo.io/gateway == true"
+	// GatewayLeaseName is the name of the Lease used to elect the gateway among the candidate nodes.
+	// Its holder is the name of the node currently acting as gateway.
+	GatewayLeaseName = "liqo-gateway"
+	// GatewayCheckPeriod is the period used by the components to check if the gateway node changed.
+	GatewayCheckPeriod = 5 * time.Second
+
+	gatewayLeaseDuration = 15 * time.Second
+	gatewayRenewDeadline = 10 * time.Second
+	gatewayRetryPeriod   = 2 * time.Second
+)
+
+// RunGatewayLeaderElection runs the election of the gateway among the candidate nodes, using the name of the
+// local node as identity. It blocks until the context is cancelled or the leadership is lost.
+func RunGatewayLeaderElection(ctx context.Context, clientset kubernetes.Interface, namespace, nodeName string,
+	callbacks leaderelection.LeaderCallbacks) error {
+	lock := &resourcelock.LeaseLock{
+		LeaseMeta: metav1.ObjectMeta{
+			Name:      GatewayLeaseName,
+			Namespace: namespace,
+		},
+		Client: clientset.CoordinationV1(),
+		LockConfig: resourcelock.ResourceLockConfig{
+			Identity: nodeName,
+		},
+	}
+	elector, err := leaderelection.NewLeaderElector(leaderelection.LeaderElectionConfig{
+		Lock:            lock,
+		LeaseDuration:   gatewayLeaseDuration,
+		RenewDeadline:   gatewayRenewDeadline,
+		RetryPeriod:     gatewayRetryPeriod,
+		Callbacks:       callbacks,
+		ReleaseOnCancel: true,
+		Name:            GatewayLeaseName,
+	})
+	if err != nil {
+		return fmt.Errorf("unable to configure the gateway leader election: %w", err)
+	}
+	elector.Run(ctx)
+	return nil
+}
+
+// GetGatewayNode returns the node currently acting as gateway, i.e. the holder of the gateway Lease.
+// If the Lease does not exist, the gateway is the only node labelled as candidate.
+func GetGatewayNode(clientset kubernetes.Interface, namespace string) (*corev1.Node, error) {
+	lease, err := clientset.CoordinationV1().Leases(namespace).Get(context.TODO(), GatewayLeaseName, metav1.GetOptions{})
+	if err != nil && !k8serrors.IsNotFound(err) {
+		return nil, fmt.Errorf("unable to get the gateway lease: %w", err)
+	}
+	if err == nil && lease.Spec.HolderIdentity != nil && *lease.Spec.HolderIdentity != "" {
+		node, err := clientset.CoreV1().Nodes().Get(context.TODO(), *lease.Spec.HolderIdentity, metav1.GetOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("unable to get the gateway node %s: %w", *lease.Spec.HolderIdentity, err)
+		}
+		return node, nil
+	}
+	nodesList, err := clientset.CoreV1().Nodes().List(context.TODO(), metav1.ListOptions{LabelSelector: GatewayLabelSelector})
+	if err != nil {
+		return nil, fmt.Errorf("unable to list nodes with label 'net.liqo.io/gateway=true': %w", err)
+	}
+	if len(nodesList.Items) != 1 {
+		klog.V(4).Infof("gateway lease not found and number of gateway nodes found: %d", len(nodesList.Items))
+		return nil, errdefs.NotFound("no gateway node has been found")
+	}
+	return &nodesList.Items[0], nil
+}
+
+// GetGatewayIP returns the IP address of the node currently acting as gateway.
+func GetGatewayIP(clientset kubernetes.Interface, namespace string) (string, error) {
+	node, err := GetGatewayNode(clientset, namespace)
+	if err != nil {
+		return "", err
+	}
+	internalIP, err := getInternalIPOfNode(*node)
+	if err != nil {
+		return "", fmt.Errorf("unable to get internal ip of the gateway node: %v", err)
+	}
+	return internalIP, nil
+}
+
+// WatchGateway periodically checks the node acting as gateway, calling the handler with its IP address
+// at start-up and each time it changes, until the stop channel is closed.
+func WatchGateway(clientset kubernetes.Interface, namespace string, period time.Duration, stop <-chan struct{},
+	handler func(gatewayIP string)) {
+	var current string
+	wait.Until(func() {
+		gatewayIP, err := GetGatewayIP(clientset, namespace)
+		if err != nil {
+			klog.Errorf("unable to get the gateway node: %s", err)
+			return
+		}
+		if gatewayIP == current {
+			return
+		}
+		klog.Infof("gateway node changed: the new gateway IP is %s", gatewayIP)
+		current = gatewayIP
+		handler(gatewayIP)
+	}, period, stop)
+}
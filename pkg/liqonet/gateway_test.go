@@ -0,0 +1,99 @@
+package liqonet
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"os"
+	"testing"
+)
+
+func getTestNode(name, internalIP string, candidate bool) *corev1.Node {
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   name,
+			Labels: map[string]string{},
+		},
+		Status: corev1.NodeStatus{
+			Addresses: []corev1.NodeAddress{
+				{Type: corev1.NodeHostName, Address: name},
+				{Type: corev1.NodeInternalIP, Address: internalIP},
+			},
+		},
+	}
+	if candidate {
+		node.Labels["net.liqo.io/gateway"] = "true"
+	}
+	return node
+}
+
+func getTestGatewayLease(namespace, holder string) *coordinationv1.Lease {
+	return &coordinationv1.Lease{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      GatewayLeaseName,
+			Namespace: namespace,
+		},
+		Spec: coordinationv1.LeaseSpec{
+			HolderIdentity: &holder,
+		},
+	}
+}
+
+func TestGetGatewayNode(t *testing.T) {
+	namespace := "liqo"
+	clientset := fake.NewSimpleClientset(
+		getTestNode("node-1", "10.0.0.1", true),
+		getTestNode("node-2", "10.0.0.2", false),
+	)
+
+	//test1 without lease the gateway is the only candidate node
+	ip, err := GetGatewayIP(clientset, namespace)
+	assert.Nil(t, err, "error should be nil")
+	assert.Equal(t, "10.0.0.1", ip)
+
+	//test2 without lease and with multiple candidate nodes the gateway can not be found
+	_, err = clientset.CoreV1().Nodes().Create(context.TODO(), getTestNode("node-3", "10.0.0.3", true), metav1.CreateOptions{})
+	assert.Nil(t, err, "error should be nil")
+	_, err = GetGatewayNode(clientset, namespace)
+	assert.NotNil(t, err, "error should be not nil")
+
+	//test3 the gateway is the holder of the lease
+	_, err = clientset.CoordinationV1().Leases(namespace).Create(context.TODO(), getTestGatewayLease(namespace, "node-3"), metav1.CreateOptions{})
+	assert.Nil(t, err, "error should be nil")
+	node, err := GetGatewayNode(clientset, namespace)
+	assert.Nil(t, err, "error should be nil")
+	assert.Equal(t, "node-3", node.Name)
+
+	//test4 the holder of the lease does not exist
+	_, err = clientset.CoordinationV1().Leases(namespace).Update(context.TODO(), getTestGatewayLease(namespace, "node-4"), metav1.UpdateOptions{})
+	assert.Nil(t, err, "error should be nil")
+	_, err = GetGatewayNode(clientset, namespace)
+	assert.NotNil(t, err, "error should be not nil")
+}
+
+func TestIsGatewayNode(t *testing.T) {
+	namespace := "liqo"
+	clientset := fake.NewSimpleClientset(
+		getTestNode("node-1", "10.0.0.1", true),
+		getTestNode("node-2", "10.0.0.2", true),
+		getTestGatewayLease(namespace, "node-2"),
+	)
+	defer os.Unsetenv("POD_IP")
+
+	assert.Nil(t, os.Setenv("POD_IP", "10.0.0.1"))
+	isGateway, err := IsGatewayNode(clientset, namespace)
+	assert.Nil(t, err, "error should be nil")
+	assert.False(t, isGateway)
+
+	assert.Nil(t, os.Setenv("POD_IP", "10.0.0.2"))
+	isGateway, err = IsGatewayNode(clientset, namespace)
+	assert.Nil(t, err, "error should be nil")
+	assert.True(t, isGateway)
+
+	vxlanIP, err := GetGatewayVxlanIP(clientset, namespace, VxlanNetConfig{Network: "192.168.200.0/24"})
+	assert.Nil(t, err, "error should be nil")
+	assert.Equal(t, "192.168.200.2", vxlanIP)
+}
@@ -1,8 +1,10 @@
 package liqonet
 
 import (
+	"bytes"
 	"fmt"
 	"github.com/apparentlymart/go-cidr/cidr"
+	netv1alpha1 "github.com/liqotech/liqo/apis/net/v1alpha1"
 	"k8s.io/klog"
 	"net"
 )
@@ -10,31 +12,114 @@ import (
 type Ipam interface {
 	Init() error
 	GetNewSubnetPerCluster(network *net.IPNet, clusterID string) (*net.IPNet, error)
-	RemoveReservedSubnet(clusterID string)
+	RemoveReservedSubnet(clusterID string) error
 }
 
+const (
+	// DefaultIPv4Pool is the pool used to remap the IPv4 POD CIDRs of the peering clusters, if not configured.
+	DefaultIPv4Pool = "10.0.0.0/8"
+	// DefaultIPv4PrefixLength is the prefix length of the subnets allocated from the IPv4 pool, if not configured.
+	DefaultIPv4PrefixLength = 16
+	// DefaultIPv6PrefixLength is the prefix length of the subnets allocated from the IPv6 pool, if not configured.
+	DefaultIPv6PrefixLength = 64
+	// maxPoolSubnetsBits limits the number of subnets a pool is split into to 2^maxPoolSubnetsBits.
+	maxPoolSubnetsBits = 16
+)
+
 type IpManager struct {
-	UsedSubnets        map[string]*net.IPNet
-	FreeSubnets        map[string]*net.IPNet
-	ConflictingSubnets map[string]*net.IPNet
-	SubnetPerCluster   map[string]*net.IPNet
+	UsedSubnets          map[string]*net.IPNet
+	FreeSubnets          map[string]*net.IPNet
+	ConflictingSubnets   map[string]*net.IPNet
+	SubnetPerCluster     map[string]*net.IPNet
+	IPv6SubnetPerCluster map[string]*net.IPNet
+
+	//the pools used to remap the POD CIDRs of the peering clusters, split in subnets with the given prefix lengths.
+	//The IPv4 pool defaults to DefaultIPv4Pool, while the IPv6 one is optional.
+	IPv4Pool         *net.IPNet
+	IPv4PrefixLength int
+	IPv6Pool         *net.IPNet
+	IPv6PrefixLength int
+
+	//the storage where the subnets allocated to the peering clusters are persisted, if any.
+	//Each allocation is stored before being used, and the stored ones are restored by Init.
+	Storage IpamStorage
 }
 
 func (ip IpManager) Init() error {
-	//TODO: remove the hardcoded value of the CIDRBlock
-	CIDRBlock := "10.0.0.0/16"
-	//the first /16 subnet in 10/8 cidr block
-	_, subnet, err := net.ParseCIDR("10.0.0.0/16")
+	ipv4Pool := ip.IPv4Pool
+	if ipv4Pool == nil {
+		_, ipv4Pool, _ = net.ParseCIDR(DefaultIPv4Pool)
+	}
+	ipv4PrefixLength := ip.IPv4PrefixLength
+	if ipv4PrefixLength == 0 {
+		ipv4PrefixLength = DefaultIPv4PrefixLength
+	}
+	if err := ip.addPool(ipv4Pool, ipv4PrefixLength); err != nil {
+		klog.Errorf("unable to initialize the IPv4 pool: %s", err)
+		return err
+	}
+
+	if ip.IPv6Pool != nil {
+		ipv6PrefixLength := ip.IPv6PrefixLength
+		if ipv6PrefixLength == 0 {
+			ipv6PrefixLength = DefaultIPv6PrefixLength
+		}
+		if err := ip.addPool(ip.IPv6Pool, ipv6PrefixLength); err != nil {
+			klog.Errorf("unable to initialize the IPv6 pool: %s", err)
+			return err
+		}
+	}
+
+	if err := ip.loadClusterSubnets(); err != nil {
+		klog.Errorf("unable to restore the subnets allocated to the peering clusters: %s", err)
+		return err
+	}
+	return nil
+}
+
+//restores the subnets allocated to the peering clusters from the storage,
+//so that each cluster keeps the same subnets across restarts
+func (ip IpManager) loadClusterSubnets() error {
+	if ip.Storage == nil {
+		return nil
+	}
+	clusterSubnets, err := ip.Storage.GetClusterSubnets()
 	if err != nil {
-		klog.Errorf("unable to parse the first subnet %s: %s", CIDRBlock, err)
 		return err
 	}
-	//The first subnet /16 is added to the FreeSubnets
-	ip.FreeSubnets[subnet.String()] = subnet
-	//here we divide the CIDRBlock 10.0.0.0/8 in 256 /16 subnets
-	for i := 0; i < 255; i++ {
-		subnet, _ = cidr.NextSubnet(subnet, 16)
+	for clusterID, subnets := range clusterSubnets {
+		for _, subnet := range []string{subnets.PodCIDR, subnets.IPv6PodCIDR} {
+			if subnet == "" {
+				continue
+			}
+			_, sn, err := net.ParseCIDR(subnet)
+			if err != nil {
+				return fmt.Errorf("invalid subnet %s stored for cluster %s: %w", subnet, clusterID, err)
+			}
+			ip.UsedSubnets[sn.String()] = sn
+			ip.subnetPerCluster(sn)[clusterID] = sn
+			klog.Infof("%s -> subnet %s restored from the storage", clusterID, sn.String())
+		}
+	}
+	ip.removeConflictingSubnets()
+	return nil
+}
+
+//splits the pool in subnets with the given prefix length and adds them to the FreeSubnets
+func (ip IpManager) addPool(pool *net.IPNet, prefixLength int) error {
+	ones, bits := pool.Mask.Size()
+	if prefixLength < ones || prefixLength > bits {
+		return fmt.Errorf("prefix length %d is not valid for pool %s", prefixLength, pool.String())
+	}
+	if prefixLength-ones > maxPoolSubnetsBits {
+		return fmt.Errorf("pool %s is too large to be split in /%d subnets (at most %d subnets are supported)",
+			pool.String(), prefixLength, 1<<maxPoolSubnetsBits)
+	}
+
+	subnet := &net.IPNet{IP: pool.IP.Mask(pool.Mask), Mask: net.CIDRMask(prefixLength, bits)}
+	for i := 0; i < 1<<(prefixLength-ones); i++ {
 		ip.FreeSubnets[subnet.String()] = subnet
+		subnet, _ = cidr.NextSubnet(subnet, prefixLength)
 	}
 	return nil
 }
@@ -43,62 +128,134 @@ func (ip IpManager) Init() error {
 //a new subnet if the original pod Cidr of the cluster has conflicts
 //the existing subnet allocated to the cluster if already called this function
 //original network if no conflicts are present.
+//IPv4 and IPv6 networks are handled independently, hence a dual-stack cluster gets a subnet per family.
 func (ip IpManager) GetNewSubnetPerCluster(network *net.IPNet, clusterID string) (*net.IPNet, error) {
-	//first check if we already have assigned a subnet to the cluster
-	if _, ok := ip.SubnetPerCluster[clusterID]; ok {
-		return ip.SubnetPerCluster[clusterID], nil
+	//first check if we already have assigned a subnet of the same family to the cluster
+	if subnet, ok := ip.subnetPerCluster(network)[clusterID]; ok {
+		return subnet, nil
 	}
 	//check if the given network has conflicts with any of the used subnets
+	subnet := network
 	if flag := VerifyNoOverlap(ip.UsedSubnets, network); flag {
-		//if there are conflicts then get a free subnet from the pool and return it
-		//return also a "true" value for the bool
-		if subnet, err := ip.getNextSubnet(); err != nil {
+		//if there are conflicts then get a free subnet of the same family from the pool
+		var err error
+		if subnet, err = ip.getNextSubnet(IsIPv4(network)); err != nil {
 			return nil, err
-		} else {
-			ip.reserveSubnet(subnet, clusterID)
-			klog.Infof("%s -> NAT enabled, remapping original subnet %s to new subnet %s", clusterID, network.String(), subnet.String())
-			return subnet, nil
 		}
 	}
-	ip.reserveSubnet(network, clusterID)
-	klog.Infof("%s -> NAT not needed, using original subnet %s", clusterID, network.String())
-
-	return network, nil
+	//the allocation is persisted before being used, hence it is not lost if the operator restarts
+	if err := ip.storeSubnetPerCluster(subnet, clusterID); err != nil {
+		klog.Errorf("unable to store the subnet %s allocated to cluster %s: %s", subnet.String(), clusterID, err)
+		return nil, err
+	}
+	ip.reserveSubnet(subnet, clusterID)
+	if subnet != network {
+		klog.Infof("%s -> NAT enabled, remapping original subnet %s to new subnet %s", clusterID, network.String(), subnet.String())
+	} else {
+		klog.Infof("%s -> NAT not needed, using original subnet %s", clusterID, network.String())
+	}
+	return subnet, nil
 }
 
-func (ip *IpManager) getNextSubnet() (*net.IPNet, error) {
-	if len(ip.FreeSubnets) == 0 {
+//returns the free subnet of the given family with the lowest address, so that the allocations are deterministic
+func (ip *IpManager) getNextSubnet(ipv4 bool) (*net.IPNet, error) {
+	var next *net.IPNet
+	for _, subnet := range ip.FreeSubnets {
+		if IsIPv4(subnet) != ipv4 {
+			continue
+		}
+		if next == nil || bytes.Compare(subnet.IP.To16(), next.IP.To16()) < 0 {
+			next = subnet
+		}
+	}
+	if next == nil {
 		return nil, fmt.Errorf("no more available subnets to allocate")
 	}
-	var availableSubnet *net.IPNet
-	for _, subnet := range ip.FreeSubnets {
-		availableSubnet = subnet
-		break
+	return next, nil
+}
+
+//returns the subnets allocated to the peering clusters, in the format used by the storage
+func (ip IpManager) clusterSubnets() map[string]netv1alpha1.ClusterSubnets {
+	clusterSubnets := make(map[string]netv1alpha1.ClusterSubnets)
+	for clusterID, subnet := range ip.SubnetPerCluster {
+		subnets := clusterSubnets[clusterID]
+		subnets.PodCIDR = subnet.String()
+		clusterSubnets[clusterID] = subnets
+	}
+	for clusterID, subnet := range ip.IPv6SubnetPerCluster {
+		subnets := clusterSubnets[clusterID]
+		subnets.IPv6PodCIDR = subnet.String()
+		clusterSubnets[clusterID] = subnets
+	}
+	return clusterSubnets
+}
+
+//persists the subnet allocated to the cluster, together with the already allocated ones
+func (ip IpManager) storeSubnetPerCluster(subnet *net.IPNet, clusterID string) error {
+	if ip.Storage == nil {
+		return nil
 	}
-	return availableSubnet, nil
+	clusterSubnets := ip.clusterSubnets()
+	subnets := clusterSubnets[clusterID]
+	if IsIPv4(subnet) {
+		subnets.PodCIDR = subnet.String()
+	} else {
+		subnets.IPv6PodCIDR = subnet.String()
+	}
+	clusterSubnets[clusterID] = subnets
+	return ip.Storage.UpdateClusterSubnets(clusterSubnets)
+}
+
+//returns the map of the subnets allocated to the clusters with the same family of the given network
+func (ip IpManager) subnetPerCluster(network *net.IPNet) map[string]*net.IPNet {
+	if IsIPv4(network) {
+		return ip.SubnetPerCluster
+	}
+	return ip.IPv6SubnetPerCluster
 }
 
 //add the network to the UsedSubnets and remove the subnets in free subnets that overlap with the network
 func (ip IpManager) reserveSubnet(network *net.IPNet, clusterID string) {
 	ip.UsedSubnets[network.String()] = network
+	ip.removeConflictingSubnets()
+	//add the very same subnet to the ones allocated to the cluster
+	ip.subnetPerCluster(network)[clusterID] = network
+}
+
+//moves the free subnets that overlap with the used ones to the conflicting subnets
+func (ip IpManager) removeConflictingSubnets() {
 	for _, net := range ip.FreeSubnets {
 		if bool := VerifyNoOverlap(ip.UsedSubnets, net); bool {
 			ip.ConflictingSubnets[net.String()] = net
 			delete(ip.FreeSubnets, net.String())
 		}
 	}
-	//add the very same subnet to the
-	ip.SubnetPerCluster[clusterID] = network
 }
 
-func (ip IpManager) RemoveReservedSubnet(clusterID string) {
-	subnet, ok := ip.SubnetPerCluster[clusterID]
-	if !ok {
-		return
+func (ip IpManager) RemoveReservedSubnet(clusterID string) error {
+	//the release is persisted first, hence in case of errors the subnets are still allocated to the cluster
+	if ip.Storage != nil {
+		clusterSubnets := ip.clusterSubnets()
+		if _, ok := clusterSubnets[clusterID]; ok {
+			delete(clusterSubnets, clusterID)
+			if err := ip.Storage.UpdateClusterSubnets(clusterSubnets); err != nil {
+				klog.Errorf("unable to release the subnets allocated to cluster %s: %s", clusterID, err)
+				return err
+			}
+		}
+	}
+	removed := false
+	for _, subnetPerCluster := range []map[string]*net.IPNet{ip.SubnetPerCluster, ip.IPv6SubnetPerCluster} {
+		if subnet, ok := subnetPerCluster[clusterID]; ok {
+			//remove the subnet from the used ones
+			delete(ip.UsedSubnets, subnet.String())
+			delete(subnetPerCluster, clusterID)
+			removed = true
+		}
+	}
+	if !removed {
+		return nil
 	}
-	//remove the subnet from the used ones
-	delete(ip.UsedSubnets, subnet.String())
-	delete(ip.SubnetPerCluster, clusterID)
 	//check if there are subnets in the conflicting map that can be made available in to the free pool
 	for _, net := range ip.ConflictingSubnets {
 		if overlap := VerifyNoOverlap(ip.UsedSubnets, net); !overlap {
@@ -106,4 +263,5 @@ func (ip IpManager) RemoveReservedSubnet(clusterID string) {
 			ip.FreeSubnets[net.String()] = net
 		}
 	}
+	return nil
 }
@@ -0,0 +1,103 @@
+package liqonet
+
+import (
+	"context"
+	netv1alpha1 "github.com/liqotech/liqo/apis/net/v1alpha1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/util/retry"
+	"k8s.io/klog"
+)
+
+// IpamStorageName is the name of the IpamStorage resource where the state of the IPAM is persisted.
+const IpamStorageName = "liqonet-ipam"
+
+// IpamStorage persists the subnets allocated by the IPAM to the peering clusters,
+// so that the allocations survive the restarts of the operator.
+type IpamStorage interface {
+	GetClusterSubnets() (map[string]netv1alpha1.ClusterSubnets, error)
+	UpdateClusterSubnets(clusterSubnets map[string]netv1alpha1.ClusterSubnets) error
+}
+
+// IpamCRDStorage is the IpamStorage backed by the IpamStorage custom resource.
+type IpamCRDStorage struct {
+	client dynamic.ResourceInterface
+}
+
+// NewIpamCRDStorage returns an IpamCRDStorage using the given dynamic client.
+func NewIpamCRDStorage(dynClient dynamic.Interface) *IpamCRDStorage {
+	return &IpamCRDStorage{
+		client: dynClient.Resource(netv1alpha1.IpamStorageGroupVersionResource),
+	}
+}
+
+// GetClusterSubnets returns the subnets allocated to the peering clusters, indexed by cluster ID.
+func (s *IpamCRDStorage) GetClusterSubnets() (map[string]netv1alpha1.ClusterSubnets, error) {
+	storage, err := s.getStorage()
+	if err != nil {
+		return nil, err
+	}
+	return storage.Spec.ClusterSubnets, nil
+}
+
+// UpdateClusterSubnets replaces the subnets allocated to the peering clusters with the given ones.
+func (s *IpamCRDStorage) UpdateClusterSubnets(clusterSubnets map[string]netv1alpha1.ClusterSubnets) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		storage, err := s.getStorage()
+		if err != nil {
+			return err
+		}
+		storage.Spec.ClusterSubnets = clusterSubnets
+		obj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(storage)
+		if err != nil {
+			return err
+		}
+		_, err = s.client.Update(context.TODO(), &unstructured.Unstructured{Object: obj}, metav1.UpdateOptions{})
+		return err
+	})
+}
+
+//returns the IpamStorage resource, creating it if it does not exist yet
+func (s *IpamCRDStorage) getStorage() (*netv1alpha1.IpamStorage, error) {
+	obj, err := s.client.Get(context.TODO(), IpamStorageName, metav1.GetOptions{})
+	if k8serrors.IsNotFound(err) {
+		obj, err = s.createStorage()
+	}
+	if err != nil {
+		klog.Errorf("unable to get resource %s of type %s: %s", IpamStorageName, netv1alpha1.IpamStorageGroupVersionResource, err)
+		return nil, err
+	}
+	storage := &netv1alpha1.IpamStorage{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, storage); err != nil {
+		return nil, err
+	}
+	return storage, nil
+}
+
+func (s *IpamCRDStorage) createStorage() (*unstructured.Unstructured, error) {
+	storage := &netv1alpha1.IpamStorage{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "IpamStorage",
+			APIVersion: netv1alpha1.GroupVersion.String(),
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name: IpamStorageName,
+		},
+	}
+	obj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(storage)
+	if err != nil {
+		return nil, err
+	}
+	created, err := s.client.Create(context.TODO(), &unstructured.Unstructured{Object: obj}, metav1.CreateOptions{})
+	if k8serrors.IsAlreadyExists(err) {
+		//the resource has been created in the meantime
+		return s.client.Get(context.TODO(), IpamStorageName, metav1.GetOptions{})
+	}
+	if err == nil {
+		klog.Infof("resource %s of type %s created", IpamStorageName, netv1alpha1.IpamStorageGroupVersionResource)
+	}
+	return created, err
+}
@@ -1,7 +1,11 @@
 package liqonet
 
 import (
+	"fmt"
+	netv1alpha1 "github.com/liqotech/liqo/apis/net/v1alpha1"
 	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/dynamic/fake"
 	"net"
 	"testing"
 )
@@ -9,10 +13,11 @@ import (
 func TestIpManager_GetNewSubnetPerCluster(t *testing.T) {
 	//init ipam
 	ipam := IpManager{
-		UsedSubnets:        make(map[string]*net.IPNet),
-		FreeSubnets:        make(map[string]*net.IPNet),
-		ConflictingSubnets: make(map[string]*net.IPNet),
-		SubnetPerCluster:   make(map[string]*net.IPNet),
+		UsedSubnets:          make(map[string]*net.IPNet),
+		FreeSubnets:          make(map[string]*net.IPNet),
+		ConflictingSubnets:   make(map[string]*net.IPNet),
+		SubnetPerCluster:     make(map[string]*net.IPNet),
+		IPv6SubnetPerCluster: make(map[string]*net.IPNet),
 	}
 	err := ipam.Init()
 	assert.Nil(t, err, "should be nil")
@@ -99,10 +104,11 @@ func TestIpManager_GetNewSubnetPerCluster(t *testing.T) {
 func TestIpManager_RemoveReservedSubnet(t *testing.T) {
 	//init ipam
 	ipam := IpManager{
-		UsedSubnets:        make(map[string]*net.IPNet),
-		FreeSubnets:        make(map[string]*net.IPNet),
-		ConflictingSubnets: make(map[string]*net.IPNet),
-		SubnetPerCluster:   make(map[string]*net.IPNet),
+		UsedSubnets:          make(map[string]*net.IPNet),
+		FreeSubnets:          make(map[string]*net.IPNet),
+		ConflictingSubnets:   make(map[string]*net.IPNet),
+		SubnetPerCluster:     make(map[string]*net.IPNet),
+		IPv6SubnetPerCluster: make(map[string]*net.IPNet),
 	}
 	err := ipam.Init()
 	assert.Nil(t, err, "should be nil")
@@ -114,7 +120,7 @@ func TestIpManager_RemoveReservedSubnet(t *testing.T) {
 	assert.Nil(t, err, "error should be nil")
 	newSubnet, err := ipam.GetNewSubnetPerCluster(clusterSubnet, clusterID)
 	assert.Nil(t, err, "error should be nil")
-	ipam.RemoveReservedSubnet(clusterID)
+	assert.Nil(t, ipam.RemoveReservedSubnet(clusterID), "error should be nil")
 	_, exists := ipam.UsedSubnets[newSubnet.String()]
 	assert.False(t, exists)
 	_, exists = ipam.SubnetPerCluster[clusterID]
@@ -122,9 +128,272 @@ func TestIpManager_RemoveReservedSubnet(t *testing.T) {
 
 	//test2 we try to free a reserved subnet for a cluster that we did not processed
 	clusterID = "test2"
-	ipam.RemoveReservedSubnet(clusterID)
+	assert.Nil(t, ipam.RemoveReservedSubnet(clusterID), "error should be nil")
 	_, exists = ipam.UsedSubnets[newSubnet.String()]
 	assert.False(t, exists)
 	_, exists = ipam.SubnetPerCluster[clusterID]
 	assert.False(t, exists)
 }
+
+func newTestIpManager() IpManager {
+	return IpManager{
+		UsedSubnets:          make(map[string]*net.IPNet),
+		FreeSubnets:          make(map[string]*net.IPNet),
+		ConflictingSubnets:   make(map[string]*net.IPNet),
+		SubnetPerCluster:     make(map[string]*net.IPNet),
+		IPv6SubnetPerCluster: make(map[string]*net.IPNet),
+	}
+}
+
+func TestIpManager_InitPools(t *testing.T) {
+	//test1 default pool: 10.0.0.0/8 split in /16 subnets
+	ipam := newTestIpManager()
+	assert.Nil(t, ipam.Init(), "should be nil")
+	assert.Equal(t, 256, len(ipam.FreeSubnets))
+	_, exists := ipam.FreeSubnets["10.255.0.0/16"]
+	assert.True(t, exists)
+
+	//test2 configured IPv4 and IPv6 pools
+	ipam = newTestIpManager()
+	_, ipam.IPv4Pool, _ = net.ParseCIDR("192.168.0.0/20")
+	ipam.IPv4PrefixLength = 24
+	_, ipam.IPv6Pool, _ = net.ParseCIDR("fd00:0:0:ff00::/56")
+	assert.Nil(t, ipam.Init(), "should be nil")
+	assert.Equal(t, 16+256, len(ipam.FreeSubnets))
+	_, exists = ipam.FreeSubnets["192.168.15.0/24"]
+	assert.True(t, exists)
+	_, exists = ipam.FreeSubnets["fd00:0:0:ffff::/64"]
+	assert.True(t, exists)
+
+	//test3 prefix length shorter than the pool one
+	ipam = newTestIpManager()
+	_, ipam.IPv4Pool, _ = net.ParseCIDR("192.168.0.0/16")
+	ipam.IPv4PrefixLength = 8
+	assert.NotNil(t, ipam.Init(), "should be not nil")
+
+	//test4 pool too large to be split
+	ipam = newTestIpManager()
+	_, ipam.IPv6Pool, _ = net.ParseCIDR("fd00::/32")
+	assert.NotNil(t, ipam.Init(), "should be not nil")
+}
+
+func TestIpManager_DualStack(t *testing.T) {
+	ipam := newTestIpManager()
+	_, ipam.IPv6Pool, _ = net.ParseCIDR("fd00:0:0:ff00::/56")
+	assert.Nil(t, ipam.Init(), "should be nil")
+
+	//the local pod CIDRs are reserved
+	for _, subnet := range []string{"10.1.0.0/16", "fd00:0:0:1::/64"} {
+		_, sn, _ := net.ParseCIDR(subnet)
+		ipam.UsedSubnets[sn.String()] = sn
+	}
+
+	//test1 both the subnets of the peering cluster conflict with the local ones
+	//expecting a new subnet of the same family to be allocated for each of them
+	clusterID := "test1"
+	_, ipv4Subnet, _ := net.ParseCIDR("10.1.0.0/16")
+	_, ipv6Subnet, _ := net.ParseCIDR("fd00:0:0:1::/64")
+	newIPv4Subnet, err := ipam.GetNewSubnetPerCluster(ipv4Subnet, clusterID)
+	assert.Nil(t, err, "error should be nil")
+	assert.True(t, IsIPv4(newIPv4Subnet))
+	assert.NotEqual(t, ipv4Subnet.String(), newIPv4Subnet.String())
+	newIPv6Subnet, err := ipam.GetNewSubnetPerCluster(ipv6Subnet, clusterID)
+	assert.Nil(t, err, "error should be nil")
+	assert.False(t, IsIPv4(newIPv6Subnet))
+	assert.NotEqual(t, ipv6Subnet.String(), newIPv6Subnet.String())
+	assert.Equal(t, newIPv4Subnet, ipam.SubnetPerCluster[clusterID])
+	assert.Equal(t, newIPv6Subnet, ipam.IPv6SubnetPerCluster[clusterID])
+
+	//test2 asking again returns the already allocated subnets
+	subnet, err := ipam.GetNewSubnetPerCluster(ipv6Subnet, clusterID)
+	assert.Nil(t, err, "error should be nil")
+	assert.Equal(t, newIPv6Subnet.String(), subnet.String())
+
+	//test3 the IPv6 subnet of a peering cluster without conflicts is used as is
+	_, ipv6Subnet, _ = net.ParseCIDR("fd00:0:0:2::/64")
+	subnet, err = ipam.GetNewSubnetPerCluster(ipv6Subnet, "test3")
+	assert.Nil(t, err, "error should be nil")
+	assert.Equal(t, ipv6Subnet.String(), subnet.String())
+
+	//test4 removing the cluster releases the subnets of both families
+	assert.Nil(t, ipam.RemoveReservedSubnet(clusterID), "error should be nil")
+	_, exists := ipam.UsedSubnets[newIPv4Subnet.String()]
+	assert.False(t, exists)
+	_, exists = ipam.UsedSubnets[newIPv6Subnet.String()]
+	assert.False(t, exists)
+	_, exists = ipam.IPv6SubnetPerCluster[clusterID]
+	assert.False(t, exists)
+}
+
+func TestIpManager_NoIPv6Pool(t *testing.T) {
+	ipam := newTestIpManager()
+	assert.Nil(t, ipam.Init(), "should be nil")
+	_, sn, _ := net.ParseCIDR("fd00:0:0:1::/64")
+	ipam.UsedSubnets[sn.String()] = sn
+
+	//a conflicting IPv6 subnet cannot be remapped without an IPv6 pool
+	_, err := ipam.GetNewSubnetPerCluster(sn, "test1")
+	assert.NotNil(t, err, "should be not nil")
+}
+
+func TestVerifyNoOverlap(t *testing.T) {
+	subnets := make(map[string]*net.IPNet)
+	for _, subnet := range []string{"10.0.0.0/16", "192.168.1.0/24", "fd00::/64"} {
+		_, sn, _ := net.ParseCIDR(subnet)
+		subnets[sn.String()] = sn
+	}
+
+	testCases := []struct {
+		subnet   string
+		overlaps bool
+	}{
+		{subnet: "10.0.0.0/8", overlaps: true},
+		{subnet: "10.0.128.0/24", overlaps: true},
+		{subnet: "10.1.0.0/16", overlaps: false},
+		{subnet: "192.168.0.0/24", overlaps: false},
+		{subnet: "fd00::/48", overlaps: true},
+		{subnet: "fd00::1:0/112", overlaps: true},
+		{subnet: "fd00:0:0:1::/64", overlaps: false},
+		{subnet: "::/0", overlaps: true},
+	}
+
+	for _, tc := range testCases {
+		_, sn, err := net.ParseCIDR(tc.subnet)
+		assert.Nil(t, err, "error should be nil")
+		assert.Equal(t, tc.overlaps, VerifyNoOverlap(subnets, sn), tc.subnet)
+	}
+}
+
+type failingIpamStorage struct {
+	clusterSubnets map[string]netv1alpha1.ClusterSubnets
+}
+
+func (s *failingIpamStorage) GetClusterSubnets() (map[string]netv1alpha1.ClusterSubnets, error) {
+	return s.clusterSubnets, nil
+}
+
+func (s *failingIpamStorage) UpdateClusterSubnets(map[string]netv1alpha1.ClusterSubnets) error {
+	return fmt.Errorf("storage not available")
+}
+
+func TestIpManager_LowestFreeSubnet(t *testing.T) {
+	ipam := newTestIpManager()
+	assert.Nil(t, ipam.Init(), "should be nil")
+	_, localSubnet, _ := net.ParseCIDR("10.1.0.0/16")
+	ipam.UsedSubnets[localSubnet.String()] = localSubnet
+	ipam.removeConflictingSubnets()
+
+	//test1 the conflicting subnets are remapped to the free subnets with the lowest addresses
+	expected := []string{"10.0.0.0/16", "10.2.0.0/16", "10.3.0.0/16"}
+	for i, subnet := range expected {
+		newSubnet, err := ipam.GetNewSubnetPerCluster(localSubnet, fmt.Sprintf("test%d", i))
+		assert.Nil(t, err, "error should be nil")
+		assert.Equal(t, subnet, newSubnet.String())
+	}
+
+	//test2 a released subnet is the first to be allocated again
+	assert.Nil(t, ipam.RemoveReservedSubnet("test1"), "error should be nil")
+	newSubnet, err := ipam.GetNewSubnetPerCluster(localSubnet, "test4")
+	assert.Nil(t, err, "error should be nil")
+	assert.Equal(t, "10.2.0.0/16", newSubnet.String())
+}
+
+func TestIpManager_Storage(t *testing.T) {
+	storage := NewIpamCRDStorage(fake.NewSimpleDynamicClient(runtime.NewScheme()))
+	_, localSubnet, _ := net.ParseCIDR("10.1.0.0/16")
+	_, localIPv6Subnet, _ := net.ParseCIDR("fd00:0:0:1::/64")
+	_, remoteSubnet, _ := net.ParseCIDR("192.168.0.0/16")
+	newIpManager := func() IpManager {
+		ipam := newTestIpManager()
+		_, ipam.IPv6Pool, _ = net.ParseCIDR("fd00:0:0:ff00::/56")
+		ipam.Storage = storage
+		assert.Nil(t, ipam.Init(), "should be nil")
+		//the PodCIDRs of the local cluster are always reserved
+		ipam.UsedSubnets[localSubnet.String()] = localSubnet
+		ipam.UsedSubnets[localIPv6Subnet.String()] = localIPv6Subnet
+		ipam.removeConflictingSubnets()
+		return ipam
+	}
+
+	//test1 the allocations are persisted in the storage
+	ipam := newIpManager()
+	allocated := make(map[string][]*net.IPNet)
+	for _, clusterID := range []string{"test1", "test2", "test3"} {
+		for _, clusterSubnet := range []*net.IPNet{localSubnet, localIPv6Subnet} {
+			subnet, err := ipam.GetNewSubnetPerCluster(clusterSubnet, clusterID)
+			assert.Nil(t, err, "error should be nil")
+			allocated[clusterID] = append(allocated[clusterID], subnet)
+		}
+	}
+	subnet, err := ipam.GetNewSubnetPerCluster(remoteSubnet, "test4")
+	assert.Nil(t, err, "error should be nil")
+	assert.Equal(t, remoteSubnet.String(), subnet.String())
+	assert.Nil(t, ipam.RemoveReservedSubnet("test2"), "error should be nil")
+	delete(allocated, "test2")
+
+	clusterSubnets, err := storage.GetClusterSubnets()
+	assert.Nil(t, err, "error should be nil")
+	assert.Equal(t, map[string]netv1alpha1.ClusterSubnets{
+		"test1": {PodCIDR: allocated["test1"][0].String(), IPv6PodCIDR: allocated["test1"][1].String()},
+		"test3": {PodCIDR: allocated["test3"][0].String(), IPv6PodCIDR: allocated["test3"][1].String()},
+		"test4": {PodCIDR: remoteSubnet.String()},
+	}, clusterSubnets)
+
+	//test2 after a restart each cluster keeps the very same subnets
+	//and the subnets allocated before are not allocated to new clusters
+	ipam = newIpManager()
+	for clusterID, subnets := range allocated {
+		for i, clusterSubnet := range []*net.IPNet{localSubnet, localIPv6Subnet} {
+			subnet, err := ipam.GetNewSubnetPerCluster(clusterSubnet, clusterID)
+			assert.Nil(t, err, "error should be nil")
+			assert.Equal(t, subnets[i].String(), subnet.String())
+		}
+	}
+	subnet, err = ipam.GetNewSubnetPerCluster(remoteSubnet, "test4")
+	assert.Nil(t, err, "error should be nil")
+	assert.Equal(t, remoteSubnet.String(), subnet.String())
+	for _, clusterSubnet := range []*net.IPNet{localSubnet, localIPv6Subnet} {
+		subnet, err = ipam.GetNewSubnetPerCluster(clusterSubnet, "test5")
+		assert.Nil(t, err, "error should be nil")
+		for _, subnets := range allocated {
+			for _, allocatedSubnet := range subnets {
+				assert.NotEqual(t, allocatedSubnet.String(), subnet.String())
+			}
+		}
+	}
+
+	//test3 the allocations are deterministic, hence the released subnets are allocated again
+	_, released, _ := net.ParseCIDR("10.2.0.0/16")
+	assert.Equal(t, released.String(), ipam.SubnetPerCluster["test5"].String())
+}
+
+func TestIpManager_StorageErrors(t *testing.T) {
+	storage := &failingIpamStorage{clusterSubnets: map[string]netv1alpha1.ClusterSubnets{
+		"test1": {PodCIDR: "10.0.0.0/16"},
+	}}
+	ipam := newTestIpManager()
+	ipam.Storage = storage
+	assert.Nil(t, ipam.Init(), "should be nil")
+	_, localSubnet, _ := net.ParseCIDR("10.1.0.0/16")
+	ipam.UsedSubnets[localSubnet.String()] = localSubnet
+
+	//test1 a subnet which cannot be persisted is not allocated
+	_, err := ipam.GetNewSubnetPerCluster(localSubnet, "test2")
+	assert.NotNil(t, err, "should be not nil")
+	_, exists := ipam.SubnetPerCluster["test2"]
+	assert.False(t, exists)
+	_, exists = ipam.UsedSubnets["10.2.0.0/16"]
+	assert.False(t, exists)
+
+	//test2 a subnet whose release cannot be persisted is still allocated
+	assert.NotNil(t, ipam.RemoveReservedSubnet("test1"), "should be not nil")
+	assert.Equal(t, "10.0.0.0/16", ipam.SubnetPerCluster["test1"].String())
+	_, exists = ipam.UsedSubnets["10.0.0.0/16"]
+	assert.True(t, exists)
+
+	//test3 invalid subnets in the storage make the initialization fail
+	storage.clusterSubnets["test3"] = netv1alpha1.ClusterSubnets{PodCIDR: "10.3.0.0"}
+	ipam = newTestIpManager()
+	ipam.Storage = storage
+	assert.NotNil(t, ipam.Init(), "should be not nil")
+}
@@ -1,5 +1,7 @@
 package liqonet
 
+import "github.com/coreos/go-iptables/iptables"
+
 type IPtableRule struct {
 	Table    string
 	Chain    string
@@ -22,3 +24,11 @@ type IPTables interface {
 	ClearChain(table, chain string) error
 	DeleteChain(table, chain string) error
 }
+
+func newIPTablesBackend() (IPTables, error) {
+	ipt, err := iptables.New()
+	if err != nil {
+		return nil, err
+	}
+	return ipt, nil
+}
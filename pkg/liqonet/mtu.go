@@ -0,0 +1,103 @@
+package liqonet
+
+import (
+	"fmt"
+	"github.com/vishvananda/netlink"
+	"net"
+)
+
+const (
+	//the overhead of the outer IP header (IPv4 or IPv6) and of the GRE header
+	greOverhead     = 20 + 4
+	greIPv6Overhead = 40 + 4
+	//the overhead of the outer IP header, of the UDP header and of the WireGuard header and authentication tag
+	wireGuardOverhead     = 20 + 8 + 32
+	wireGuardIPv6Overhead = 40 + 8 + 32
+	//the overhead of the IPv4 and TCP headers, without options
+	tcpOverhead = 20 + 20
+
+	//the minimum MTU every IPv4 host has to accept
+	minPathMTU = 576
+	//the minimum MSS, corresponding to the minimum IPv4 MTU
+	minTCPMSS = minPathMTU - tcpOverhead
+)
+
+// MTUConfig contains the parameters used to compute the MTU of the tunnels and the MSS the TCP connections are clamped to.
+type MTUConfig struct {
+	//the MTU of the path between the gateways. If zero, it is probed when the discovery is enabled,
+	//otherwise the MTU of the default interface is used
+	PathMTU          int
+	PathMTUDiscovery bool
+	//the MSS the TCP connections are clamped to. If zero, it is derived from the MTU of the tunnel
+	TCPMSS             int
+	DisableMSSClamping bool
+}
+
+// GetTunnelOverhead returns the bytes added to each packet by the encapsulation of the given tunnel driver,
+// depending on the family of the address of the remote gateway.
+func GetTunnelOverhead(driverName string, remoteIP net.IP) int {
+	ipv6 := remoteIP != nil && remoteIP.To4() == nil
+	switch driverName {
+	case WireGuardDriverName:
+		if ipv6 {
+			return wireGuardIPv6Overhead
+		}
+		return wireGuardOverhead
+	default:
+		if ipv6 {
+			return greIPv6Overhead
+		}
+		return greOverhead
+	}
+}
+
+// ComputeTunnelMTU returns the MTU of the tunnel interface, given the MTU of the path towards the remote gateway.
+func ComputeTunnelMTU(pathMTU int, driverName string, remoteIP net.IP) int {
+	return pathMTU - GetTunnelOverhead(driverName, remoteIP)
+}
+
+// ComputeTCPMSS returns the MSS of the TCP connections towards a remote cluster, given the MTU of the tunnel.
+// The packets exchanged by the pods are carried by both the tunnel and the VXLAN overlay of the clusters,
+// hence the overhead of both the encapsulations is taken into account.
+func ComputeTCPMSS(tunnelMTU int) int {
+	mss := tunnelMTU - vxlanOverhead - tcpOverhead
+	if mss < minTCPMSS {
+		return minTCPMSS
+	}
+	return mss
+}
+
+// GetPathMTU returns the MTU of the path towards the remote gateway: the configured one if set, otherwise the one
+// discovered by the prober, if enabled, falling back to the MTU of the default interface of the host.
+func GetPathMTU(config MTUConfig, prober PathMTUProber, remoteIP net.IP) (int, error) {
+	if config.PathMTU > 0 {
+		return config.PathMTU, nil
+	}
+	ifaceMTU, err := getDefaultIfaceMTU()
+	if err != nil {
+		return 0, err
+	}
+	if !config.PathMTUDiscovery || prober == nil {
+		return ifaceMTU, nil
+	}
+	pathMTU, err := prober.ProbePathMTU(remoteIP, ifaceMTU)
+	if err != nil {
+		return ifaceMTU, fmt.Errorf("unable to discover the path MTU towards %s, using the MTU of the default interface: %w", remoteIP, err)
+	}
+	return pathMTU, nil
+}
+
+// SetIFaceMTU sets the MTU of the network interface with the given index, if different from the current one.
+func SetIFaceMTU(iFaceIndex int, mtu int) error {
+	link, err := netlink.LinkByIndex(iFaceIndex)
+	if err != nil {
+		return fmt.Errorf("unable to retrieve link with index %d: %w", iFaceIndex, err)
+	}
+	if link.Attrs().MTU == mtu {
+		return nil
+	}
+	if err := netlink.LinkSetMTU(link, mtu); err != nil {
+		return fmt.Errorf("unable to set the MTU of interface %s to %d: %w", link.Attrs().Name, mtu, err)
+	}
+	return nil
+}
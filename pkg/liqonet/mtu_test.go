@@ -0,0 +1,31 @@
+package liqonet
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net"
+	"testing"
+)
+
+func TestComputeTunnelMTU(t *testing.T) {
+	ipv4 := net.ParseIP("192.168.5.1")
+	ipv6 := net.ParseIP("fd00::1")
+	assert.Equal(t, 1476, ComputeTunnelMTU(1500, GreDriverName, ipv4))
+	assert.Equal(t, 1456, ComputeTunnelMTU(1500, GreDriverName, ipv6))
+	assert.Equal(t, 1440, ComputeTunnelMTU(1500, WireGuardDriverName, ipv4))
+	assert.Equal(t, 1420, ComputeTunnelMTU(1500, WireGuardDriverName, ipv6))
+}
+
+func TestComputeTCPMSS(t *testing.T) {
+	//both the VXLAN and the TCP/IP overheads are subtracted from the MTU of the tunnel
+	assert.Equal(t, 1386, ComputeTCPMSS(1476))
+	assert.Equal(t, 1350, ComputeTCPMSS(1440))
+	//the MSS is never lower than the minimum one
+	assert.Equal(t, minTCPMSS, ComputeTCPMSS(400))
+}
+
+func TestGetPathMTU(t *testing.T) {
+	//the configured MTU takes precedence over the discovery
+	mtu, err := GetPathMTU(MTUConfig{PathMTU: 1400, PathMTUDiscovery: true}, nil, net.ParseIP("192.168.5.1"))
+	assert.Nil(t, err, "error should be nil")
+	assert.Equal(t, 1400, mtu)
+}
@@ -0,0 +1,684 @@
+package liqonet
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"os/exec"
+	"strings"
+	"sync"
+)
+
+const (
+	// IPTablesBackend configures the firewall rules through the iptables binaries.
+	IPTablesBackend = "iptables"
+	// NFTablesBackend configures the firewall rules through nftables, for the nodes without the iptables support.
+	NFTablesBackend = "nftables"
+
+	//the nftables tables used by liqo are named after the iptables ones, with this prefix
+	nftTablePrefix = "liqo-"
+	//the liqo tables handle both the IPv4 and the IPv6 traffic
+	nftFamily = "inet"
+)
+
+//the base chains are created on demand, hooked as the iptables built-in chains with the same name.
+//The nat chains precede the ones of the other tables hooked at the same point (e.g. the iptables ones),
+//so that the NAT bindings set up by liqo are not overridden by them
+var nftBaseChains = map[string]map[string]string{
+	"nat": {
+		"PREROUTING":  "type nat hook prerouting priority -101; policy accept;",
+		"INPUT":       "type nat hook input priority 99; policy accept;",
+		"OUTPUT":      "type nat hook output priority -101; policy accept;",
+		"POSTROUTING": "type nat hook postrouting priority 99; policy accept;",
+	},
+	"filter": {
+		"INPUT":   "type filter hook input priority 0; policy accept;",
+		"FORWARD": "type filter hook forward priority 0; policy accept;",
+		"OUTPUT":  "type filter hook output priority 0; policy accept;",
+	},
+}
+
+// IPTablesClusterChain is a chain dedicated to a remote cluster: it contains the rules for the cluster
+// and it is referenced by a single rule in its parent chain.
+type IPTablesClusterChain struct {
+	Table    string
+	Name     string
+	Parent   string
+	JumpRule []string
+	Rules    [][]string
+}
+
+// AtomicIPTables is implemented by the backends able to apply all the chains of a remote cluster
+// in a single transaction, so that the NAT and the filtering rules are never partially configured.
+type AtomicIPTables interface {
+	IPTables
+	EnsureClusterChains(chains []IPTablesClusterChain) error
+	RemoveClusterChains(chains []IPTablesClusterChain) error
+}
+
+// NFTablesRunner applies an nftables script in a single transaction.
+type NFTablesRunner interface {
+	Run(script string) error
+}
+
+type nftRunner struct{}
+
+func (nftRunner) Run(script string) error {
+	cmd := exec.Command("nft", "-f", "-")
+	cmd.Stdin = strings.NewReader(script)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("unable to apply nftables rules: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+type nftTable struct {
+	chains []string
+	rules  map[string][][]string
+}
+
+// NFTables implements the IPTables interface on top of nftables. The rules are expressed with the
+// iptables syntax used by the operators and translated to nftables ones. Liqo owns its tables, hence
+// the rule-set is kept in memory and each change replaces the liqo tables in a single transaction.
+// Since the liqo tables are separated from the other ones, an accept verdict does not prevent the
+// packets from being dropped by the chains of other tables hooked at the same point. In the nat table
+// an accept is translated to a NAT binding which leaves the packets unchanged: as the first NAT binding
+// of a connection is the only one applied, the packets are not translated by the other tables, e.g. by
+// the masquerading rules of the CNI or of kube-proxy. The tables belong to the inet family, which
+// supports NAT since Linux 5.2.
+type NFTables struct {
+	mutex  sync.Mutex
+	runner NFTablesRunner
+	tables map[string]*nftTable
+}
+
+// NewIPTables returns the implementation of the IPTables interface for the given backend.
+func NewIPTables(backend string) (IPTables, error) {
+	switch backend {
+	case "", IPTablesBackend:
+		return newIPTablesBackend()
+	case NFTablesBackend:
+		return NewNFTables(nftRunner{})
+	default:
+		return nil, fmt.Errorf("unknown firewall backend %q", backend)
+	}
+}
+
+// NewNFTables returns an NFTables backend which applies the rules through the given runner.
+// The liqo tables left over by a previous run are removed.
+func NewNFTables(runner NFTablesRunner) (*NFTables, error) {
+	n := &NFTables{
+		runner: runner,
+		tables: make(map[string]*nftTable),
+	}
+	var script strings.Builder
+	for table := range nftBaseChains {
+		//the tables of the ip family were used by the previous versions
+		for _, family := range []string{"ip", nftFamily} {
+			fmt.Fprintf(&script, "table %s %s%s {}\ndelete table %s %s%s\n", family, nftTablePrefix, table, family, nftTablePrefix, table)
+		}
+	}
+	if err := runner.Run(script.String()); err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+func (n *NFTables) Insert(table string, chain string, pos int, rulespec ...string) error {
+	return n.transaction(func(tables map[string]*nftTable) error {
+		t, err := getChain(tables, table, chain)
+		if err != nil {
+			return err
+		}
+		rules := t.rules[chain]
+		if pos < 1 || pos > len(rules)+1 {
+			return fmt.Errorf("invalid position %d for a rule in chain %s", pos, chain)
+		}
+		t.rules[chain] = append(rules[:pos-1], append([][]string{rulespec}, rules[pos-1:]...)...)
+		return nil
+	})
+}
+
+func (n *NFTables) Delete(table string, chain string, rulespec ...string) error {
+	return n.transaction(func(tables map[string]*nftTable) error {
+		t, ok := tables[table]
+		if !ok {
+			return nil
+		}
+		if i := ruleIndex(t.rules[chain], rulespec); i != -1 {
+			t.rules[chain] = append(t.rules[chain][:i], t.rules[chain][i+1:]...)
+		}
+		return nil
+	})
+}
+
+func (n *NFTables) Exists(table string, chain string, rulespec ...string) (bool, error) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	t, ok := n.tables[table]
+	if !ok {
+		return false, nil
+	}
+	return ruleIndex(t.rules[chain], rulespec) != -1, nil
+}
+
+func (n *NFTables) ListChains(table string) ([]string, error) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	t, ok := n.tables[table]
+	if !ok {
+		return nil, nil
+	}
+	return append([]string(nil), t.chains...), nil
+}
+
+func (n *NFTables) NewChain(table string, chain string) error {
+	return n.transaction(func(tables map[string]*nftTable) error {
+		if err := checkTable(table); err != nil {
+			return err
+		}
+		if t, ok := tables[table]; ok && containsChain(t, chain) {
+			return fmt.Errorf("chain %s already exists in table %s", chain, table)
+		}
+		addChain(tables, table, chain)
+		return nil
+	})
+}
+
+// List returns the rules of the chain in the format of iptables-save, as the iptables backend does.
+func (n *NFTables) List(table, chain string) ([]string, error) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	t, ok := n.tables[table]
+	if !ok || !containsChain(t, chain) {
+		if _, builtin := nftBaseChains[table][chain]; builtin {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("chain %s does not exist in table %s", chain, table)
+	}
+	var rules []string
+	if _, builtin := nftBaseChains[table][chain]; !builtin {
+		rules = append(rules, strings.Join([]string{"-N", chain}, " "))
+	}
+	for _, rule := range t.rules[chain] {
+		rules = append(rules, strings.Join(append([]string{"-A", chain}, rule...), " "))
+	}
+	return rules, nil
+}
+
+func (n *NFTables) AppendUnique(table string, chain string, rulespec ...string) error {
+	return n.transaction(func(tables map[string]*nftTable) error {
+		t, err := getChain(tables, table, chain)
+		if err != nil {
+			return err
+		}
+		if ruleIndex(t.rules[chain], rulespec) == -1 {
+			t.rules[chain] = append(t.rules[chain], rulespec)
+		}
+		return nil
+	})
+}
+
+// ClearChain removes all the rules from the chain, creating it if it does not exist as the iptables backend does.
+func (n *NFTables) ClearChain(table, chain string) error {
+	return n.transaction(func(tables map[string]*nftTable) error {
+		if err := checkTable(table); err != nil {
+			return err
+		}
+		addChain(tables, table, chain)
+		tables[table].rules[chain] = nil
+		return nil
+	})
+}
+
+func (n *NFTables) DeleteChain(table, chain string) error {
+	return n.transaction(func(tables map[string]*nftTable) error {
+		return deleteChain(tables, table, chain)
+	})
+}
+
+// EnsureClusterChains creates or updates the given chains, together with the rules referencing them
+// in their parent chains, in a single transaction.
+func (n *NFTables) EnsureClusterChains(chains []IPTablesClusterChain) error {
+	return n.transaction(func(tables map[string]*nftTable) error {
+		for _, chain := range chains {
+			t, err := getChain(tables, chain.Table, chain.Parent)
+			if err != nil {
+				return err
+			}
+			addChain(tables, chain.Table, chain.Name)
+			t.rules[chain.Parent] = removeJumps(t.rules[chain.Parent], chain.Name, chain.JumpRule)
+			if ruleIndex(t.rules[chain.Parent], chain.JumpRule) == -1 {
+				t.rules[chain.Parent] = append(t.rules[chain.Parent], chain.JumpRule)
+			}
+			t.rules[chain.Name] = append([][]string(nil), chain.Rules...)
+		}
+		return nil
+	})
+}
+
+// RemoveClusterChains removes the given chains, together with the rules referencing them in their
+// parent chains, in a single transaction.
+func (n *NFTables) RemoveClusterChains(chains []IPTablesClusterChain) error {
+	return n.transaction(func(tables map[string]*nftTable) error {
+		for _, chain := range chains {
+			t, ok := tables[chain.Table]
+			if !ok || !containsChain(t, chain.Name) {
+				continue
+			}
+			t.rules[chain.Parent] = removeJumps(t.rules[chain.Parent], chain.Name, nil)
+			t.rules[chain.Name] = nil
+			if err := deleteChain(tables, chain.Table, chain.Name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+//applies the changes performed by the given function on a copy of the rule-set, replacing the liqo
+//tables in a single transaction. The in-memory rule-set is updated only if the transaction succeeds
+func (n *NFTables) transaction(change func(tables map[string]*nftTable) error) error {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	tables := copyTables(n.tables)
+	if err := change(tables); err != nil {
+		return err
+	}
+	before, err := renderTables(n.tables)
+	if err != nil {
+		return err
+	}
+	after, err := renderTables(tables)
+	if err != nil {
+		return err
+	}
+	if before == after {
+		n.tables = tables
+		return nil
+	}
+	var script strings.Builder
+	for _, table := range tableNames(n.tables, tables) {
+		fmt.Fprintf(&script, "table %s %s%s {}\ndelete table %s %s%s\n", nftFamily, nftTablePrefix, table, nftFamily, nftTablePrefix, table)
+	}
+	script.WriteString(after)
+	if err := n.runner.Run(script.String()); err != nil {
+		return err
+	}
+	n.tables = tables
+	return nil
+}
+
+func renderTables(tables map[string]*nftTable) (string, error) {
+	var script strings.Builder
+	for _, name := range tableNames(tables) {
+		t := tables[name]
+		if len(t.chains) == 0 {
+			continue
+		}
+		fmt.Fprintf(&script, "table %s %s%s {\n", nftFamily, nftTablePrefix, name)
+		for _, chain := range t.chains {
+			fmt.Fprintf(&script, "\tchain %s {\n", chain)
+			if hook, builtin := nftBaseChains[name][chain]; builtin {
+				fmt.Fprintf(&script, "\t\t%s\n", hook)
+			}
+			for _, rule := range t.rules[chain] {
+				translated, err := nftRule(tables, name, chain, rule)
+				if err != nil {
+					return "", err
+				}
+				fmt.Fprintf(&script, "\t\t%s\n", translated)
+			}
+			script.WriteString("\t}\n")
+		}
+		script.WriteString("}\n")
+	}
+	return script.String(), nil
+}
+
+//translates an iptables rulespec to the nftables syntax. Only the matches and targets used by the
+//operators are supported
+func nftRule(tables map[string]*nftTable, table, chain string, rulespec []string) (string, error) {
+	var statements []string
+	var protocol string
+	negate := false
+	family, err := nftRuleFamily(rulespec)
+	if err != nil {
+		return "", err
+	}
+	next := func(i int) (string, error) {
+		if i+1 >= len(rulespec) {
+			return "", fmt.Errorf("missing value for %s in rule '%s'", rulespec[i], strings.Join(rulespec, " "))
+		}
+		return rulespec[i+1], nil
+	}
+	operator := func() string {
+		if negate {
+			negate = false
+			return "!= "
+		}
+		return ""
+	}
+	for i := 0; i < len(rulespec); i++ {
+		token := rulespec[i]
+		if token == "!" {
+			negate = true
+			continue
+		}
+		value, err := next(i)
+		if err != nil {
+			return "", err
+		}
+		i++
+		switch token {
+		case "-s", "--source":
+			statements = append(statements, fmt.Sprintf("%s saddr %s%s", nftAddressFamily(value), operator(), value))
+		case "-d", "--destination":
+			statements = append(statements, fmt.Sprintf("%s daddr %s%s", nftAddressFamily(value), operator(), value))
+		case "-i", "--in-interface":
+			statements = append(statements, fmt.Sprintf("iifname %s%q", operator(), value))
+		case "-o", "--out-interface":
+			statements = append(statements, fmt.Sprintf("oifname %s%q", operator(), value))
+		case "-p", "--protocol":
+			protocol = value
+			statements = append(statements, fmt.Sprintf("meta l4proto %s%s", operator(), value))
+		case "-m", "--match":
+			//the matches are implied by the nftables expressions
+		case "--dport", "--sport":
+			if protocol == "" {
+				return "", fmt.Errorf("%s requires a protocol in rule '%s'", token, strings.Join(rulespec, " "))
+			}
+			statements = append(statements, fmt.Sprintf("%s %s %s%s", protocol, strings.TrimPrefix(token, "--"), operator(), value))
+		case "--ctstate":
+			statements = append(statements, fmt.Sprintf("ct state %s%s", operator(), strings.ToLower(value)))
+		case "--tcp-flags":
+			//the flags to be examined are followed by the ones which have to be set
+			set, err := next(i)
+			if err != nil {
+				return "", err
+			}
+			i++
+			op := operator()
+			if op == "" {
+				op = "== "
+			}
+			statements = append(statements, fmt.Sprintf("tcp flags & (%s) %s%s", nftTCPFlags(value), op, nftTCPFlags(set)))
+		case "-j", "--jump":
+			verdict, err := nftVerdict(tables, table, chain, family, value, rulespec[i+1:])
+			if err != nil {
+				return "", err
+			}
+			return strings.Join(append(statements, verdict), " "), nil
+		default:
+			return "", fmt.Errorf("unsupported option %s in rule '%s'", token, strings.Join(rulespec, " "))
+		}
+	}
+	return "", fmt.Errorf("missing target in rule '%s'", strings.Join(rulespec, " "))
+}
+
+//translates an iptables target to the nftables verdict. The family of the addresses matched by the rule
+//is required by the NAT statements, since the liqo tables handle both the IPv4 and the IPv6 traffic
+func nftVerdict(tables map[string]*nftTable, table, chain, family, target string, options []string) (string, error) {
+	option := func(name string) (string, error) {
+		if len(options) != 2 || options[0] != name {
+			return "", fmt.Errorf("target %s requires the %s option", target, name)
+		}
+		return options[1], nil
+	}
+	natFamily := func() (string, error) {
+		if family == "" {
+			return "", fmt.Errorf("target %s requires the source or the destination address to be matched", target)
+		}
+		return family, nil
+	}
+	switch target {
+	case "ACCEPT":
+		if table != "nat" {
+			return "accept", nil
+		}
+		natFamily, err := natFamily()
+		if err != nil {
+			return "", err
+		}
+		//the packets are bound to their own addresses, so that they are not translated by the other tables
+		switch hookOf(tables, table, chain, 0) {
+		case "PREROUTING", "OUTPUT":
+			return fmt.Sprintf("dnat %s to %s daddr", natFamily, natFamily), nil
+		case "POSTROUTING", "INPUT":
+			return fmt.Sprintf("snat %s to %s saddr", natFamily, natFamily), nil
+		default:
+			return "", fmt.Errorf("unable to find the hook of chain %s in table %s", chain, table)
+		}
+	case "DROP", "RETURN":
+		return strings.ToLower(target), nil
+	case "SNAT":
+		address, err := option("--to-source")
+		if err != nil {
+			return "", err
+		}
+		natFamily, err := natFamily()
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("snat %s to %s", natFamily, address), nil
+	case "DNAT":
+		address, err := option("--to-destination")
+		if err != nil {
+			return "", err
+		}
+		natFamily, err := natFamily()
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("dnat %s to %s", natFamily, address), nil
+	case "TCPMSS":
+		mss, err := option("--set-mss")
+		if err != nil {
+			return "", err
+		}
+		return "tcp option maxseg size set " + mss, nil
+	case "NETMAP":
+		cidr, err := option("--to")
+		if err != nil {
+			return "", err
+		}
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return "", err
+		}
+		hostMask := make(net.IP, len(network.Mask))
+		for i := range network.Mask {
+			hostMask[i] = ^network.Mask[i]
+		}
+		natFamily := nftAddressFamily(cidr)
+		//as in iptables, the destination is mapped in the prerouting and output hooks, the source otherwise
+		switch hookOf(tables, table, chain, 0) {
+		case "PREROUTING", "OUTPUT":
+			return fmt.Sprintf("dnat %s to %s daddr & %s | %s", natFamily, natFamily, hostMask, network.IP), nil
+		case "POSTROUTING", "INPUT":
+			return fmt.Sprintf("snat %s to %s saddr & %s | %s", natFamily, natFamily, hostMask, network.IP), nil
+		default:
+			return "", fmt.Errorf("unable to find the hook of chain %s in table %s", chain, table)
+		}
+	default:
+		if len(options) != 0 {
+			return "", fmt.Errorf("unsupported target %s", target)
+		}
+		return "jump " + target, nil
+	}
+}
+
+//returns the nftables family of the given address or network: ip6 for the IPv6 ones, ip otherwise
+func nftAddressFamily(address string) string {
+	if strings.Contains(address, ":") {
+		return "ip6"
+	}
+	return "ip"
+}
+
+//returns the nftables family of the addresses of the rule, empty if the rule does not contain any address
+func nftRuleFamily(rulespec []string) (string, error) {
+	family := ""
+	for i := 0; i+1 < len(rulespec); i++ {
+		switch rulespec[i] {
+		case "-s", "--source", "-d", "--destination", "--to-source", "--to-destination", "--to":
+			addressFamily := nftAddressFamily(rulespec[i+1])
+			if family != "" && family != addressFamily {
+				return "", fmt.Errorf("rule '%s' mixes IPv4 and IPv6 addresses", strings.Join(rulespec, " "))
+			}
+			family = addressFamily
+		}
+	}
+	return family, nil
+}
+
+//translates a comma separated list of iptables tcp flags to the nftables syntax
+func nftTCPFlags(flags string) string {
+	return strings.ReplaceAll(strings.ToLower(flags), ",", "|")
+}
+
+//returns the built-in chain from which the given chain is reached
+func hookOf(tables map[string]*nftTable, table, chain string, depth int) string {
+	if _, builtin := nftBaseChains[table][chain]; builtin {
+		return chain
+	}
+	t, ok := tables[table]
+	if !ok || depth > len(t.chains) {
+		return ""
+	}
+	for _, parent := range t.chains {
+		for _, rule := range t.rules[parent] {
+			if jumpsTo(rule, chain) {
+				return hookOf(tables, table, parent, depth+1)
+			}
+		}
+	}
+	return ""
+}
+
+func checkTable(table string) error {
+	if _, ok := nftBaseChains[table]; !ok {
+		return fmt.Errorf("table %s is not supported by the nftables backend", table)
+	}
+	return nil
+}
+
+func getChain(tables map[string]*nftTable, table, chain string) (*nftTable, error) {
+	if err := checkTable(table); err != nil {
+		return nil, err
+	}
+	if _, builtin := nftBaseChains[table][chain]; builtin {
+		addChain(tables, table, chain)
+	}
+	t, ok := tables[table]
+	if !ok || !containsChain(t, chain) {
+		return nil, fmt.Errorf("chain %s does not exist in table %s", chain, table)
+	}
+	return t, nil
+}
+
+func addChain(tables map[string]*nftTable, table, chain string) {
+	t, ok := tables[table]
+	if !ok {
+		t = &nftTable{rules: make(map[string][][]string)}
+		tables[table] = t
+	}
+	if !containsChain(t, chain) {
+		t.chains = append(t.chains, chain)
+	}
+}
+
+func deleteChain(tables map[string]*nftTable, table, chain string) error {
+	t, ok := tables[table]
+	if !ok || !containsChain(t, chain) {
+		return fmt.Errorf("chain %s does not exist in table %s", chain, table)
+	}
+	if len(t.rules[chain]) != 0 {
+		return fmt.Errorf("chain %s in table %s is not empty", chain, table)
+	}
+	for _, rules := range t.rules {
+		for _, rule := range rules {
+			if jumpsTo(rule, chain) {
+				return fmt.Errorf("chain %s in table %s is still referenced", chain, table)
+			}
+		}
+	}
+	for i := range t.chains {
+		if t.chains[i] == chain {
+			t.chains = append(t.chains[:i], t.chains[i+1:]...)
+			break
+		}
+	}
+	delete(t.rules, chain)
+	return nil
+}
+
+func containsChain(t *nftTable, chain string) bool {
+	for _, c := range t.chains {
+		if c == chain {
+			return true
+		}
+	}
+	return false
+}
+
+func ruleIndex(rules [][]string, rulespec []string) int {
+	for i, rule := range rules {
+		if strings.Join(rule, " ") == strings.Join(rulespec, " ") {
+			return i
+		}
+	}
+	return -1
+}
+
+func jumpsTo(rule []string, chain string) bool {
+	for i := 0; i+1 < len(rule); i++ {
+		if (rule[i] == "-j" || rule[i] == "--jump") && rule[i+1] == chain {
+			return true
+		}
+	}
+	return false
+}
+
+//removes the rules jumping to the given chain, except the one equal to keep
+func removeJumps(rules [][]string, chain string, keep []string) [][]string {
+	var result [][]string
+	for _, rule := range rules {
+		if jumpsTo(rule, chain) && (keep == nil || strings.Join(rule, " ") != strings.Join(keep, " ")) {
+			continue
+		}
+		result = append(result, rule)
+	}
+	return result
+}
+
+func copyTables(tables map[string]*nftTable) map[string]*nftTable {
+	result := make(map[string]*nftTable, len(tables))
+	for name, t := range tables {
+		c := &nftTable{
+			chains: append([]string(nil), t.chains...),
+			rules:  make(map[string][][]string, len(t.rules)),
+		}
+		for chain, rules := range t.rules {
+			c.rules[chain] = append([][]string(nil), rules...)
+		}
+		result[name] = c
+	}
+	return result
+}
+
+//returns the sorted names of the tables in the given rule-sets, without duplicates
+func tableNames(ruleSets ...map[string]*nftTable) []string {
+	var names []string
+	for _, table := range []string{"filter", "nat"} {
+		for _, tables := range ruleSets {
+			if _, ok := tables[table]; ok {
+				names = append(names, table)
+				break
+			}
+		}
+	}
+	return names
+}
@@ -0,0 +1,16 @@
+package liqonet
+
+// MockNFTablesRunner records the nftables scripts instead of applying them.
+// If Err is set, the scripts are rejected as nft would do with an invalid transaction.
+type MockNFTablesRunner struct {
+	Scripts []string
+	Err     error
+}
+
+func (m *MockNFTablesRunner) Run(script string) error {
+	if m.Err != nil {
+		return m.Err
+	}
+	m.Scripts = append(m.Scripts, script)
+	return nil
+}
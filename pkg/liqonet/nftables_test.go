@@ -0,0 +1,231 @@
+package liqonet
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func newTestNFTables(t *testing.T) (*NFTables, *MockNFTablesRunner) {
+	runner := &MockNFTablesRunner{}
+	n, err := NewNFTables(runner)
+	assert.Nil(t, err, "error should be nil")
+	//the tables left over by the previous runs are removed, including the ones of the ip family
+	assert.Equal(t, 1, len(runner.Scripts))
+	assert.Contains(t, runner.Scripts[0], "delete table ip liqo-nat")
+	assert.Contains(t, runner.Scripts[0], "delete table inet liqo-nat")
+	runner.Scripts = nil
+	return n, runner
+}
+
+func getTestClusterChains() []IPTablesClusterChain {
+	return []IPTablesClusterChain{
+		{
+			Table:    "nat",
+			Name:     "LIQO-PSTRT-CLS-test",
+			Parent:   "POSTROUTING",
+			JumpRule: []string{"-d", "10.100.0.0/16", "-j", "LIQO-PSTRT-CLS-test"},
+			Rules: [][]string{
+				{"-s", "10.200.0.0/16", "-d", "10.100.0.0/16", "-j", "NETMAP", "--to", "10.50.0.0/16"},
+				{"!", "-s", "10.200.0.0/16", "-d", "10.100.0.0/16", "-j", "SNAT", "--to-source", "10.50.0.0"},
+			},
+		},
+		{
+			Table:    "nat",
+			Name:     "LIQO-PRRT-CLS-test",
+			Parent:   "PREROUTING",
+			JumpRule: []string{"-d", "10.50.0.0/16", "-j", "LIQO-PRRT-CLS-test"},
+			Rules: [][]string{
+				{"-d", "10.50.0.0/16", "-i", "liqo-test", "-j", "NETMAP", "--to", "10.200.0.0/16"},
+			},
+		},
+		{
+			Table:    "filter",
+			Name:     "LIQO-FRWD-CLS-test",
+			Parent:   "FORWARD",
+			JumpRule: []string{"-d", "10.100.0.0/16", "-j", "LIQO-FRWD-CLS-test"},
+			Rules: [][]string{
+				{"-d", "10.100.0.0/16", "-j", "ACCEPT"},
+			},
+		},
+	}
+}
+
+func TestNFTables_EnsureClusterChains(t *testing.T) {
+	n, runner := newTestNFTables(t)
+
+	//test1 all the chains are applied in a single transaction
+	assert.Nil(t, n.EnsureClusterChains(getTestClusterChains()), "error should be nil")
+	assert.Equal(t, 1, len(runner.Scripts))
+	script := runner.Scripts[0]
+	for _, expected := range []string{
+		"table inet liqo-nat {",
+		"type nat hook postrouting priority 99; policy accept;",
+		"ip daddr 10.100.0.0/16 jump LIQO-PSTRT-CLS-test",
+		"ip saddr 10.200.0.0/16 ip daddr 10.100.0.0/16 snat ip to ip saddr & 0.0.255.255 | 10.50.0.0",
+		"ip saddr != 10.200.0.0/16 ip daddr 10.100.0.0/16 snat ip to 10.50.0.0",
+		"ip daddr 10.50.0.0/16 iifname \"liqo-test\" dnat ip to ip daddr & 0.0.255.255 | 10.200.0.0",
+		"table inet liqo-filter {",
+		"ip daddr 10.100.0.0/16 accept",
+	} {
+		assert.Contains(t, script, expected)
+	}
+	rules, err := n.List("nat", "LIQO-PSTRT-CLS-test")
+	assert.Nil(t, err, "error should be nil")
+	assert.Equal(t, []string{
+		"-N LIQO-PSTRT-CLS-test",
+		"-A LIQO-PSTRT-CLS-test -s 10.200.0.0/16 -d 10.100.0.0/16 -j NETMAP --to 10.50.0.0/16",
+		"-A LIQO-PSTRT-CLS-test ! -s 10.200.0.0/16 -d 10.100.0.0/16 -j SNAT --to-source 10.50.0.0",
+	}, rules)
+
+	//test2 ensuring the same chains again does not apply any transaction
+	assert.Nil(t, n.EnsureClusterChains(getTestClusterChains()), "error should be nil")
+	assert.Equal(t, 1, len(runner.Scripts))
+
+	//test3 the outdated jump rules are replaced
+	chains := getTestClusterChains()
+	chains[2].JumpRule = []string{"-d", "10.101.0.0/16", "-j", "LIQO-FRWD-CLS-test"}
+	assert.Nil(t, n.EnsureClusterChains(chains), "error should be nil")
+	rules, err = n.List("filter", "FORWARD")
+	assert.Nil(t, err, "error should be nil")
+	assert.Equal(t, []string{"-A FORWARD -d 10.101.0.0/16 -j LIQO-FRWD-CLS-test"}, rules)
+
+	//test4 the chains are removed in a single transaction
+	runner.Scripts = nil
+	assert.Nil(t, n.RemoveClusterChains(chains), "error should be nil")
+	assert.Equal(t, 1, len(runner.Scripts))
+	assert.NotContains(t, runner.Scripts[0], "LIQO-PSTRT-CLS-test")
+	chainList, err := n.ListChains("nat")
+	assert.Nil(t, err, "error should be nil")
+	assert.Equal(t, []string{"POSTROUTING", "PREROUTING"}, chainList)
+}
+
+func TestNFTables_FailedTransaction(t *testing.T) {
+	n, runner := newTestNFTables(t)
+	runner.Err = errors.New("transaction rejected")
+
+	//nothing is changed if the transaction fails
+	assert.NotNil(t, n.EnsureClusterChains(getTestClusterChains()), "error should be not nil")
+	chains, err := n.ListChains("nat")
+	assert.Nil(t, err, "error should be nil")
+	assert.Equal(t, 0, len(chains))
+
+	//the invalid rules are rejected before applying the transaction
+	runner.Err = nil
+	chains2 := getTestClusterChains()
+	chains2[2].Rules = [][]string{{"-d", "10.100.0.0/16", "-m", "limit", "--limit", "5/min", "-j", "ACCEPT"}}
+	assert.NotNil(t, n.EnsureClusterChains(chains2), "error should be not nil")
+	assert.Equal(t, 0, len(runner.Scripts))
+}
+
+func TestNFTables_IPTablesInterface(t *testing.T) {
+	n, runner := newTestNFTables(t)
+
+	assert.Nil(t, n.NewChain("filter", "LIQO-INPUT"), "error should be nil")
+	assert.NotNil(t, n.NewChain("filter", "LIQO-INPUT"), "error should be not nil")
+	assert.NotNil(t, n.NewChain("mangle", "LIQO-INPUT"), "error should be not nil")
+	assert.Nil(t, n.Insert("filter", "INPUT", 1, "-p", "udp", "-m", "udp", "-j", "LIQO-INPUT"), "error should be nil")
+	assert.Nil(t, n.AppendUnique("filter", "LIQO-INPUT", "-p", "udp", "-m", "udp", "--dport", "4789", "-j", "ACCEPT"), "error should be nil")
+	assert.Nil(t, n.AppendUnique("filter", "LIQO-INPUT", "-p", "udp", "-m", "udp", "--dport", "4789", "-j", "ACCEPT"), "error should be nil")
+	assert.NotNil(t, n.AppendUnique("filter", "LIQO-MISSING", "-j", "ACCEPT"), "error should be not nil")
+
+	exists, err := n.Exists("filter", "LIQO-INPUT", "-p", "udp", "-m", "udp", "--dport", "4789", "-j", "ACCEPT")
+	assert.Nil(t, err, "error should be nil")
+	assert.True(t, exists)
+	rules, err := n.List("filter", "LIQO-INPUT")
+	assert.Nil(t, err, "error should be nil")
+	assert.Equal(t, 2, len(rules))
+	last := runner.Scripts[len(runner.Scripts)-1]
+	assert.Contains(t, last, "meta l4proto udp jump LIQO-INPUT")
+	assert.Contains(t, last, "meta l4proto udp udp dport 4789 accept")
+
+	//a chain can be deleted only when it is empty and not referenced
+	assert.NotNil(t, n.DeleteChain("filter", "LIQO-INPUT"), "error should be not nil")
+	assert.Nil(t, n.ClearChain("filter", "LIQO-INPUT"), "error should be nil")
+	assert.NotNil(t, n.DeleteChain("filter", "LIQO-INPUT"), "error should be not nil")
+	assert.Nil(t, n.Delete("filter", "INPUT", "-p", "udp", "-m", "udp", "-j", "LIQO-INPUT"), "error should be nil")
+	assert.Nil(t, n.DeleteChain("filter", "LIQO-INPUT"), "error should be nil")
+	last = runner.Scripts[len(runner.Scripts)-1]
+	assert.False(t, strings.Contains(last, "LIQO-INPUT"))
+}
+
+func TestNFTables_MSSClamping(t *testing.T) {
+	n, runner := newTestNFTables(t)
+
+	chains := getTestClusterChains()
+	chains[2].Rules = append([][]string{
+		{"-p", "tcp", "-m", "tcp", "--tcp-flags", "SYN,RST", "SYN", "-j", "TCPMSS", "--set-mss", "1386"},
+	}, chains[2].Rules...)
+	assert.Nil(t, n.EnsureClusterChains(chains), "error should be nil")
+	assert.Equal(t, 1, len(runner.Scripts))
+	assert.Contains(t, runner.Scripts[0], "meta l4proto tcp tcp flags & (syn|rst) == syn tcp option maxseg size set 1386")
+
+	//the connection tracking states are translated as well
+	chains[2].Rules[0] = []string{"-m", "conntrack", "--ctstate", "RELATED,ESTABLISHED", "-j", "ACCEPT"}
+	assert.Nil(t, n.EnsureClusterChains(chains), "error should be nil")
+	assert.Contains(t, runner.Scripts[1], "ct state related,established accept")
+
+	//the target requires the MSS
+	chains[2].Rules[0] = []string{"-p", "tcp", "-m", "tcp", "--tcp-flags", "SYN,RST", "SYN", "-j", "TCPMSS"}
+	assert.NotNil(t, n.EnsureClusterChains(chains), "error should be not nil")
+}
+
+func TestNFTables_NATExemption(t *testing.T) {
+	n, runner := newTestNFTables(t)
+
+	//the accepted packets are bound to their own addresses, so that they are not masqueraded by the other tables
+	chains := getTestClusterChains()
+	chains[0].Rules = [][]string{
+		{"!", "-s", "10.200.0.0/16", "-d", "10.100.0.0/16", "-j", "SNAT", "--to-source", "10.200.0.1"},
+		{"-s", "10.200.0.0/16", "-d", "10.100.0.0/16", "-j", "ACCEPT"},
+	}
+	chains[1].Rules = [][]string{{"-d", "10.50.0.0/16", "-j", "ACCEPT"}}
+	assert.Nil(t, n.EnsureClusterChains(chains), "error should be nil")
+	assert.Equal(t, 1, len(runner.Scripts))
+	assert.Contains(t, runner.Scripts[0], "ip saddr 10.200.0.0/16 ip daddr 10.100.0.0/16 snat ip to ip saddr")
+	assert.Contains(t, runner.Scripts[0], "ip daddr 10.50.0.0/16 dnat ip to ip daddr")
+	assert.Contains(t, runner.Scripts[0], "ip daddr 10.100.0.0/16 accept")
+
+	//the family of the binding cannot be inferred without addresses
+	chains[0].Rules = [][]string{{"-p", "udp", "-j", "ACCEPT"}}
+	assert.NotNil(t, n.EnsureClusterChains(chains), "error should be not nil")
+}
+
+func TestNFTables_IPv6(t *testing.T) {
+	n, runner := newTestNFTables(t)
+
+	chains := []IPTablesClusterChain{
+		{
+			Table:    "nat",
+			Name:     "LIQO-PSTRT-CLS-test",
+			Parent:   "POSTROUTING",
+			JumpRule: []string{"-d", "fd00:100::/64", "-j", "LIQO-PSTRT-CLS-test"},
+			Rules: [][]string{
+				{"-s", "fd00:200::/64", "-d", "fd00:100::/64", "-j", "NETMAP", "--to", "fd00:50::/64"},
+				{"!", "-s", "fd00:200::/64", "-d", "fd00:100::/64", "-j", "SNAT", "--to-source", "fd00:50::"},
+			},
+		},
+		{
+			Table:    "filter",
+			Name:     "LIQO-FRWD-CLS-test",
+			Parent:   "FORWARD",
+			JumpRule: []string{"-d", "fd00:100::/64", "-j", "LIQO-FRWD-CLS-test"},
+			Rules:    [][]string{{"-d", "fd00:100::/64", "-j", "ACCEPT"}},
+		},
+	}
+	assert.Nil(t, n.EnsureClusterChains(chains), "error should be nil")
+	assert.Equal(t, 1, len(runner.Scripts))
+	for _, expected := range []string{
+		"ip6 daddr fd00:100::/64 jump LIQO-PSTRT-CLS-test",
+		"ip6 saddr fd00:200::/64 ip6 daddr fd00:100::/64 snat ip6 to ip6 saddr & ::ffff:ffff:ffff:ffff | fd00:50::",
+		"ip6 saddr != fd00:200::/64 ip6 daddr fd00:100::/64 snat ip6 to fd00:50::",
+		"ip6 daddr fd00:100::/64 accept",
+	} {
+		assert.Contains(t, runner.Scripts[0], expected)
+	}
+
+	//the rules mixing IPv4 and IPv6 addresses are rejected
+	chains[1].Rules = [][]string{{"-s", "10.200.0.0/16", "-d", "fd00:100::/64", "-j", "ACCEPT"}}
+	assert.NotNil(t, n.EnsureClusterChains(chains), "error should be not nil")
+}
@@ -0,0 +1,80 @@
+package liqonet
+
+import (
+	"fmt"
+	netv1alpha1 "github.com/liqotech/liqo/apis/net/v1alpha1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// GreDriverName is the name of the driver which creates unencrypted GRE tunnels, used if none is configured.
+	GreDriverName = "gre"
+	// WireGuardDriverName is the name of the driver which creates encrypted WireGuard tunnels.
+	WireGuardDriverName = "wireguard"
+)
+
+// TunnelDriver is implemented by the backends which create the tunnels towards the peering clusters.
+type TunnelDriver interface {
+	// Init prepares the driver to create the tunnels, e.g. generating its keys.
+	Init() error
+	// ConnectToEndpoint creates the tunnel towards the cluster described by the TunnelEndpoint, if it does not exist yet,
+	// returning the index and the name of the network interface used to reach the cluster.
+	ConnectToEndpoint(endpoint *netv1alpha1.TunnelEndpoint) (int, string, error)
+	// DisconnectFromEndpoint removes the tunnel towards the cluster described by the TunnelEndpoint. It has to be idempotent.
+	DisconnectFromEndpoint(endpoint *netv1alpha1.TunnelEndpoint) error
+}
+
+// NewTunnelDriver returns the driver with the given name. The clientset and the namespace are used
+// to store the keys of the drivers which need them, while the port is the one the WireGuard driver listens on.
+func NewTunnelDriver(name string, wireGuardPort int, clientset kubernetes.Interface, namespace string) (TunnelDriver, error) {
+	switch name {
+	case "", GreDriverName:
+		return &greDriver{}, nil
+	case WireGuardDriverName:
+		if wireGuardPort == 0 {
+			wireGuardPort = DefaultWireGuardPort
+		}
+		return &wireGuardDriver{
+			port:      wireGuardPort,
+			clientset: clientset,
+			namespace: namespace,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown tunnel driver %s", name)
+	}
+}
+
+// GetBackendType returns the driver used by the remote cluster described by the TunnelEndpoint.
+// Endpoints without a backend type come from clusters supporting only the GRE tunnels.
+func GetBackendType(endpoint *netv1alpha1.TunnelEndpoint) string {
+	if endpoint.Spec.BackendType == "" {
+		return GreDriverName
+	}
+	return endpoint.Spec.BackendType
+}
+
+//returns an error if the remote cluster uses a driver different from the local one, since the tunnel could not be established
+func checkBackendType(endpoint *netv1alpha1.TunnelEndpoint, driverName string) error {
+	if backendType := GetBackendType(endpoint); backendType != driverName {
+		return fmt.Errorf("the remote cluster %s uses the %s tunnel driver, while the local one is %s",
+			endpoint.Spec.ClusterID, backendType, driverName)
+	}
+	return nil
+}
+
+type greDriver struct{}
+
+func (d *greDriver) Init() error {
+	return nil
+}
+
+func (d *greDriver) ConnectToEndpoint(endpoint *netv1alpha1.TunnelEndpoint) (int, string, error) {
+	if err := checkBackendType(endpoint, GreDriverName); err != nil {
+		return 0, "", err
+	}
+	return InstallGreTunnel(endpoint)
+}
+
+func (d *greDriver) DisconnectFromEndpoint(endpoint *netv1alpha1.TunnelEndpoint) error {
+	return RemoveGreTunnel(endpoint)
+}
@@ -0,0 +1,349 @@
+package liqonet
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+	"golang.org/x/sys/unix"
+	"k8s.io/klog"
+	"net"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const (
+	// TunnelProbePort is the UDP port where the gateways answer to the probes sent through the tunnels.
+	TunnelProbePort = 5871
+	// DefaultProbeCount is the number of probes sent to the remote gateway at each probing round, if not configured.
+	DefaultProbeCount = 5
+	// DefaultProbeTimeout is the time waited for the answer to a probe, if not configured.
+	DefaultProbeTimeout = time.Second
+
+	//a probe is made of the magic, the sequence number and the time it has been sent.
+	//The probes used to discover the path MTU are padded, and the answer contains only their first bytes
+	probeSize = 16
+	//the number of probes of each size sent to discover the path MTU, before considering the size too big
+	pathMTUProbeAttempts = 2
+	//the size of the IP (IPv4 or IPv6) and UDP headers of the probes
+	udpOverhead     = 20 + 8
+	udpIPv6Overhead = 40 + 8
+	//the minimum MTU every IPv6 host has to accept
+	minIPv6PathMTU = 1280
+)
+
+var probeMagic = []byte("LIQO")
+
+// ProbeResult contains the outcome of a probing round towards a remote gateway.
+type ProbeResult struct {
+	Sent     int
+	Received int
+	//the average round trip time of the probes which received an answer
+	Latency time.Duration
+}
+
+// PacketLoss returns the percentage of the probes which did not receive an answer.
+func (r *ProbeResult) PacketLoss() int {
+	if r.Sent == 0 {
+		return 0
+	}
+	return (r.Sent - r.Received) * 100 / r.Sent
+}
+
+// TunnelProber measures the reachability of a remote gateway through a tunnel.
+type TunnelProber interface {
+	//sends the probes to the remote address through the given network interface
+	Probe(iFaceName string, remoteIP net.IP) (*ProbeResult, error)
+}
+
+// PathMTUProber discovers the MTU of the path towards a remote gateway.
+type PathMTUProber interface {
+	//returns the biggest MTU, not greater than maxMTU, of the packets which reach the remote address without being fragmented
+	ProbePathMTU(remoteIP net.IP, maxMTU int) (int, error)
+}
+
+// UDPTunnelProber sends UDP echo probes to the responder running on the remote gateway.
+// The socket is bound to the tunnel interface, so that the probes are forced through the tunnel
+// instead of following the default route towards the public IP of the remote gateway.
+type UDPTunnelProber struct {
+	Port    int
+	Count   int
+	Timeout time.Duration
+}
+
+// NewUDPTunnelProber returns a UDPTunnelProber with the default parameters.
+func NewUDPTunnelProber() *UDPTunnelProber {
+	return &UDPTunnelProber{
+		Port:    TunnelProbePort,
+		Count:   DefaultProbeCount,
+		Timeout: DefaultProbeTimeout,
+	}
+}
+
+// Probe sends Count probes to the remote IP, one at a time, and returns the statistics of the answers.
+// If iFaceName is empty the socket is not bound to any interface.
+func (p *UDPTunnelProber) Probe(iFaceName string, remoteIP net.IP) (*ProbeResult, error) {
+	dialer := net.Dialer{}
+	if iFaceName != "" {
+		dialer.Control = bindToDevice(iFaceName)
+	}
+	conn, err := dialer.Dial("udp", net.JoinHostPort(remoteIP.String(), strconv.Itoa(p.Port)))
+	if err != nil {
+		return nil, fmt.Errorf("unable to open the probe socket towards %s: %w", remoteIP, err)
+	}
+	defer conn.Close()
+
+	result := &ProbeResult{}
+	var rtt time.Duration
+	request := make([]byte, probeSize)
+	for seq := 0; seq < p.Count; seq++ {
+		result.Sent++
+		elapsed, received, err := p.exchange(conn, request, seq)
+		if err != nil {
+			return nil, err
+		}
+		if received {
+			result.Received++
+			rtt += elapsed
+		}
+	}
+	if result.Received > 0 {
+		result.Latency = rtt / time.Duration(result.Received)
+	}
+	return result, nil
+}
+
+// ProbePathMTU looks for the path MTU towards the remote IP by means of a binary search, sending probes of different
+// sizes with the don't fragment flag set to the responder running on the remote gateway.
+func (p *UDPTunnelProber) ProbePathMTU(remoteIP net.IP, maxMTU int) (int, error) {
+	ipv6 := remoteIP.To4() == nil
+	headers, minMTU := udpOverhead, minPathMTU
+	if ipv6 {
+		headers, minMTU = udpIPv6Overhead, minIPv6PathMTU
+	}
+	if maxMTU < minMTU {
+		return 0, fmt.Errorf("the maximum MTU %d is lower than the minimum one %d", maxMTU, minMTU)
+	}
+	dialer := net.Dialer{Control: setDontFragment(ipv6)}
+	conn, err := dialer.Dial("udp", net.JoinHostPort(remoteIP.String(), strconv.Itoa(p.Port)))
+	if err != nil {
+		return 0, fmt.Errorf("unable to open the probe socket towards %s: %w", remoteIP, err)
+	}
+	defer conn.Close()
+
+	seq := 0
+	fits := func(mtu int) (bool, error) {
+		request := make([]byte, mtu-headers)
+		for attempt := 0; attempt < pathMTUProbeAttempts; attempt++ {
+			seq++
+			_, received, err := p.exchange(conn, request, seq)
+			if err != nil || received {
+				return received, err
+			}
+		}
+		return false, nil
+	}
+	//the minimum MTU is expected to always fit, otherwise the remote gateway is not answering at all
+	if ok, err := fits(minMTU); err != nil || !ok {
+		if err == nil {
+			err = fmt.Errorf("no answer received from %s", remoteIP)
+		}
+		return 0, err
+	}
+	low, high := minMTU, maxMTU
+	for low < high {
+		mtu := (low + high + 1) / 2
+		ok, err := fits(mtu)
+		if err != nil {
+			return 0, err
+		}
+		if ok {
+			low = mtu
+		} else {
+			high = mtu - 1
+		}
+	}
+	klog.V(4).Infof("path MTU towards %s: %d", remoteIP, low)
+	return low, nil
+}
+
+//sends the probe with the given sequence number, padded to the size of the request, and waits for its answer.
+//It returns the round trip time and whether the answer has been received
+func (p *UDPTunnelProber) exchange(conn net.Conn, request []byte, seq int) (time.Duration, bool, error) {
+	sent := time.Now()
+	copy(request, probeMagic)
+	binary.BigEndian.PutUint32(request[4:8], uint32(seq))
+	binary.BigEndian.PutUint64(request[8:16], uint64(sent.UnixNano()))
+	if _, err := conn.Write(request); err != nil {
+		//probes bigger than the MTU of the local interface are refused by the kernel
+		klog.V(4).Infof("unable to send probe %d of %d bytes to %s: %s", seq, len(request), conn.RemoteAddr(), err)
+		return 0, false, nil
+	}
+	if err := conn.SetReadDeadline(sent.Add(p.Timeout)); err != nil {
+		return 0, false, err
+	}
+	//discard the late answers to the previous probes
+	reply := make([]byte, probeSize)
+	for {
+		n, err := conn.Read(reply)
+		if err != nil {
+			return 0, false, nil
+		}
+		if n == probeSize && bytes.Equal(reply[:8], request[:8]) {
+			return time.Since(sent), true, nil
+		}
+	}
+}
+
+// RunProbeResponder answers to the probes received on the given UDP port, until the stop channel is closed.
+// The answers are sent back through the interface the probes have been received from, i.e. the tunnel: the probes
+// are addressed to the public IP of the gateway, whose default route does not go through the tunnel, and the
+// probers only accept the answers received from the tunnel.
+func RunProbeResponder(port int, stop <-chan struct{}) error {
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{Port: port})
+	if err != nil {
+		return fmt.Errorf("unable to listen for the tunnel probes on port %d: %w", port, err)
+	}
+	conns := []probeConn{&ipv4ProbeConn{ipv4.NewPacketConn(conn)}}
+	//the gateways without IPv6 only answer to the IPv4 probes
+	if conn, err := net.ListenUDP("udp6", &net.UDPAddr{Port: port}); err != nil {
+		klog.Warningf("unable to listen for the IPv6 tunnel probes on port %d: %s", port, err)
+	} else {
+		conns = append(conns, &ipv6ProbeConn{ipv6.NewPacketConn(conn)})
+	}
+	closeAll := func() {
+		for _, conn := range conns {
+			conn.Close()
+		}
+	}
+	for _, conn := range conns {
+		if err := conn.setControlMessage(); err != nil {
+			closeAll()
+			return fmt.Errorf("unable to listen for the tunnel probes on port %d: %w", port, err)
+		}
+	}
+	go func() {
+		<-stop
+		closeAll()
+	}()
+
+	var wg sync.WaitGroup
+	for _, conn := range conns {
+		wg.Add(1)
+		go func(conn probeConn) {
+			defer wg.Done()
+			answerProbes(conn, stop)
+		}(conn)
+	}
+	wg.Wait()
+	return nil
+}
+
+//answers to the probes received from the connection until the stop channel is closed
+func answerProbes(conn probeConn, stop <-chan struct{}) {
+	buffer := make([]byte, 65535)
+	for {
+		n, addr, dst, ifIndex, err := conn.readProbe(buffer)
+		if err != nil {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			klog.Errorf("unable to read tunnel probe: %s", err)
+			continue
+		}
+		if n < probeSize || !bytes.Equal(buffer[:4], probeMagic) {
+			continue
+		}
+		//the answer comes from the address the probe has been sent to, as expected by the prober
+		if err := conn.writeAnswer(buffer[:probeSize], addr, dst, ifIndex); err != nil {
+			klog.V(4).Infof("unable to answer tunnel probe from %s: %s", addr, err)
+		}
+	}
+}
+
+//probeConn receives the probes together with the address they have been sent to and the interface they have been
+//received from, and sends the answers from that address through the same interface
+type probeConn interface {
+	setControlMessage() error
+	readProbe(b []byte) (n int, src net.Addr, dst net.IP, ifIndex int, err error)
+	writeAnswer(b []byte, dst net.Addr, src net.IP, ifIndex int) error
+	Close() error
+}
+
+type ipv4ProbeConn struct {
+	*ipv4.PacketConn
+}
+
+func (c *ipv4ProbeConn) setControlMessage() error {
+	return c.SetControlMessage(ipv4.FlagDst|ipv4.FlagInterface, true)
+}
+
+func (c *ipv4ProbeConn) readProbe(b []byte) (int, net.Addr, net.IP, int, error) {
+	n, cm, src, err := c.ReadFrom(b)
+	if err != nil || cm == nil {
+		return n, src, nil, 0, err
+	}
+	return n, src, cm.Dst, cm.IfIndex, nil
+}
+
+func (c *ipv4ProbeConn) writeAnswer(b []byte, dst net.Addr, src net.IP, ifIndex int) error {
+	_, err := c.WriteTo(b, &ipv4.ControlMessage{Src: src, IfIndex: ifIndex}, dst)
+	return err
+}
+
+type ipv6ProbeConn struct {
+	*ipv6.PacketConn
+}
+
+func (c *ipv6ProbeConn) setControlMessage() error {
+	return c.SetControlMessage(ipv6.FlagDst|ipv6.FlagInterface, true)
+}
+
+func (c *ipv6ProbeConn) readProbe(b []byte) (int, net.Addr, net.IP, int, error) {
+	n, cm, src, err := c.ReadFrom(b)
+	if err != nil || cm == nil {
+		return n, src, nil, 0, err
+	}
+	return n, src, cm.Dst, cm.IfIndex, nil
+}
+
+func (c *ipv6ProbeConn) writeAnswer(b []byte, dst net.Addr, src net.IP, ifIndex int) error {
+	_, err := c.WriteTo(b, &ipv6.ControlMessage{Src: src, IfIndex: ifIndex}, dst)
+	return err
+}
+
+func bindToDevice(iFaceName string) func(network, address string, c syscall.RawConn) error {
+	return func(network, address string, c syscall.RawConn) error {
+		var bindErr error
+		err := c.Control(func(fd uintptr) {
+			bindErr = unix.BindToDevice(int(fd), iFaceName)
+		})
+		if err != nil {
+			return err
+		}
+		return bindErr
+	}
+}
+
+//sets the don't fragment flag on the probes, ignoring the path MTU cached by the kernel
+func setDontFragment(ipv6 bool) func(network, address string, c syscall.RawConn) error {
+	return func(network, address string, c syscall.RawConn) error {
+		var optErr error
+		err := c.Control(func(fd uintptr) {
+			if ipv6 {
+				optErr = unix.SetsockoptInt(int(fd), unix.IPPROTO_IPV6, unix.IPV6_MTU_DISCOVER, unix.IPV6_PMTUDISC_PROBE)
+			} else {
+				optErr = unix.SetsockoptInt(int(fd), unix.IPPROTO_IP, unix.IP_MTU_DISCOVER, unix.IP_PMTUDISC_PROBE)
+			}
+		})
+		if err != nil {
+			return err
+		}
+		return optErr
+	}
+}
@@ -0,0 +1,188 @@
+package liqonet
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/vishvananda/netlink"
+	"github.com/vishvananda/netns"
+	"net"
+	"runtime"
+	"testing"
+	"time"
+)
+
+const testProbePort = 15871
+
+func TestProbeResult_PacketLoss(t *testing.T) {
+	assert.Equal(t, 0, (&ProbeResult{}).PacketLoss())
+	assert.Equal(t, 0, (&ProbeResult{Sent: 5, Received: 5}).PacketLoss())
+	assert.Equal(t, 40, (&ProbeResult{Sent: 5, Received: 3}).PacketLoss())
+	assert.Equal(t, 100, (&ProbeResult{Sent: 5}).PacketLoss())
+}
+
+func TestUDPTunnelProber_Probe(t *testing.T) {
+	stop := make(chan struct{})
+	done := make(chan error)
+	go func() {
+		done <- RunProbeResponder(testProbePort, stop)
+	}()
+	prober := &UDPTunnelProber{Port: testProbePort, Count: 3, Timeout: 200 * time.Millisecond}
+
+	//test1 all the probes are answered by the responder
+	var result *ProbeResult
+	var err error
+	//wait for the responder to be listening
+	for i := 0; i < 10; i++ {
+		if result, err = prober.Probe("", net.ParseIP("127.0.0.1")); err == nil && result.Received == 3 {
+			break
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	assert.Nil(t, err, "error should be nil")
+	assert.Equal(t, 3, result.Sent)
+	assert.Equal(t, 3, result.Received)
+	assert.Equal(t, 0, result.PacketLoss())
+	assert.True(t, result.Latency > 0)
+
+	//test2 no probe is answered once the responder is stopped
+	close(stop)
+	assert.Nil(t, <-done, "error should be nil")
+	result, err = prober.Probe("", net.ParseIP("127.0.0.1"))
+	assert.Nil(t, err, "error should be nil")
+	assert.Equal(t, 3, result.Sent)
+	assert.Equal(t, 0, result.Received)
+	assert.Equal(t, 100, result.PacketLoss())
+	assert.Equal(t, time.Duration(0), result.Latency)
+}
+
+func TestUDPTunnelProber_ProbePathMTU(t *testing.T) {
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		_ = RunProbeResponder(testProbePort+1, stop)
+	}()
+	prober := &UDPTunnelProber{Port: testProbePort + 1, Timeout: 200 * time.Millisecond}
+
+	//test1 the loopback interface delivers the probes of all sizes, hence the maximum MTU is returned
+	var mtu int
+	var err error
+	//wait for the responder to be listening
+	for i := 0; i < 10; i++ {
+		if mtu, err = prober.ProbePathMTU(net.ParseIP("127.0.0.1"), 1500); err == nil {
+			break
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	assert.Nil(t, err, "error should be nil")
+	assert.Equal(t, 1500, mtu)
+
+	//test2 the maximum MTU can not be lower than the minimum one
+	_, err = prober.ProbePathMTU(net.ParseIP("127.0.0.1"), 500)
+	assert.NotNil(t, err, "error should be not nil")
+
+	//test3 no answer is received from an address without responder
+	prober.Port = testProbePort + 2
+	_, err = prober.ProbePathMTU(net.ParseIP("127.0.0.1"), 1500)
+	assert.NotNil(t, err, "error should be not nil")
+}
+
+// creates a veth pair whose ends are configured with the given addresses, the second one in the given namespace
+func addTestVeth(t *testing.T, name, peerName string, addr, peerAddr *netlink.Addr, peerNs netns.NsHandle) {
+	veth := &netlink.Veth{LinkAttrs: netlink.LinkAttrs{Name: name}, PeerName: peerName}
+	assert.Nil(t, netlink.LinkAdd(veth))
+	peer, err := netlink.LinkByName(peerName)
+	assert.Nil(t, err)
+	assert.Nil(t, netlink.LinkSetNsFd(peer, int(peerNs)))
+	assert.Nil(t, netlink.AddrAdd(veth, addr))
+	assert.Nil(t, netlink.LinkSetUp(veth))
+
+	handle, err := netlink.NewHandleAt(peerNs)
+	assert.Nil(t, err)
+	defer handle.Delete()
+	peer, err = handle.LinkByName(peerName)
+	assert.Nil(t, err)
+	assert.Nil(t, handle.AddrAdd(peer, peerAddr))
+	assert.Nil(t, handle.LinkSetUp(peer))
+}
+
+func TestUDPTunnelProber_ProbeThroughTunnel(t *testing.T) {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	origin, err := netns.Get()
+	if err != nil {
+		t.Skipf("network namespaces not available: %s", err)
+	}
+	defer origin.Close()
+	defer netns.Set(origin)
+	//the local gateway runs in the namespace of the test, the remote one in the other namespace
+	local, err := netns.New()
+	if err != nil {
+		t.Skipf("unable to create network namespace: %s", err)
+	}
+	defer local.Close()
+	remote, err := netns.New()
+	if err != nil {
+		t.Skipf("unable to create network namespace: %s", err)
+	}
+	defer remote.Close()
+	assert.Nil(t, netns.Set(local))
+
+	//the public network, where the gateways reach each other through the default route, and the tunnel,
+	//with no address nor route towards the public IP of the remote gateway
+	localPublic, remotePublic := net.ParseIP("172.31.0.1"), net.ParseIP("172.31.0.2")
+	localTunnel := net.ParseIP("10.99.0.1")
+	addTestVeth(t, "public0", "public1",
+		&netlink.Addr{IPNet: &net.IPNet{IP: localPublic, Mask: net.CIDRMask(24, 32)}},
+		&netlink.Addr{IPNet: &net.IPNet{IP: remotePublic, Mask: net.CIDRMask(24, 32)}}, remote)
+	addTestVeth(t, "tunnel0", "tunnel1",
+		&netlink.Addr{IPNet: &net.IPNet{IP: localTunnel, Mask: net.CIDRMask(32, 32)}},
+		&netlink.Addr{IPNet: &net.IPNet{IP: net.ParseIP("10.99.0.2"), Mask: net.CIDRMask(32, 32)}}, remote)
+	//the remote gateway reaches the tunnel address of the local one through the public network
+	handle, err := netlink.NewHandleAt(remote)
+	assert.Nil(t, err)
+	defer handle.Delete()
+	public1, err := handle.LinkByName("public1")
+	assert.Nil(t, err)
+	assert.Nil(t, handle.RouteAdd(&netlink.Route{
+		LinkIndex: public1.Attrs().Index,
+		Dst:       &net.IPNet{IP: localTunnel, Mask: net.CIDRMask(32, 32)},
+		Gw:        localPublic,
+	}))
+
+	stop := make(chan struct{})
+	done := make(chan error)
+	go func() {
+		//the thread is left in the remote namespace, and terminated when the goroutine exits
+		runtime.LockOSThread()
+		if err := netns.Set(remote); err != nil {
+			done <- err
+			return
+		}
+		done <- RunProbeResponder(testProbePort+3, stop)
+	}()
+	prober := &UDPTunnelProber{Port: testProbePort + 3, Count: 3, Timeout: 200 * time.Millisecond}
+
+	//test1 the answers come back through the tunnel, where the probes have been sent
+	var result *ProbeResult
+	//wait for the responder to be listening
+	for i := 0; i < 10; i++ {
+		if result, err = prober.Probe("tunnel0", remotePublic); err == nil && result.Received == 3 {
+			break
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	if assert.Nil(t, err, "error should be nil") {
+		assert.Equal(t, 3, result.Received)
+	}
+
+	//test2 no answer is received if the tunnel is down, even though the remote gateway is reachable
+	tunnel1, err := handle.LinkByName("tunnel1")
+	assert.Nil(t, err)
+	assert.Nil(t, handle.LinkSetDown(tunnel1))
+	result, err = prober.Probe("tunnel0", remotePublic)
+	if assert.Nil(t, err, "error should be nil") {
+		assert.Equal(t, 0, result.Received)
+	}
+
+	close(stop)
+	assert.Nil(t, <-done, "error should be nil")
+}
@@ -1,10 +1,8 @@
 package liqonet
 
 import (
-	"bytes"
 	"context"
 	"fmt"
-	"github.com/apparentlymart/go-cidr/cidr"
 	"github.com/liqotech/liqo/internal/utils/errdefs"
 	"golang.org/x/tools/go/ssa/interp/testdata/src/errors"
 	corev1 "k8s.io/api/core/v1"
@@ -63,60 +61,41 @@ func getInternalIPOfNode(node corev1.Node) (string, error) {
 	return internalIp, nil
 }
 
-func IsGatewayNode(clientset *kubernetes.Clientset) (bool, error) {
-	isGatewayNode := false
-	//retrieve the node which is labeled as the gateway
-	nodesList, err := clientset.CoreV1().Nodes().List(context.TODO(), metav1.ListOptions{LabelSelector: "net.liqo.io/gateway == true"})
+// IsGatewayNode returns true if the local node is the one currently acting as gateway.
+func IsGatewayNode(clientset kubernetes.Interface, namespace string) (bool, error) {
+	gatewayIP, err := GetGatewayIP(clientset, namespace)
 	if err != nil {
-		logger.Error(err, "Unable to list nodes with labbel 'net.liqo.io/gateway=true'")
-		return isGatewayNode, fmt.Errorf(" Unable to list nodes with label 'net.liqo.io/gateway=true': %v", err)
-	}
-	if len(nodesList.Items) != 1 {
-		klog.V(4).Infof("number of gateway nodes found: %d", len(nodesList.Items))
-		return isGatewayNode, errdefs.NotFound("no gateway node has been found")
+		return false, err
 	}
 	//check if my ip node is the same as the internal ip of the gateway node
 	podIP, err := getPodIP()
 	if err != nil {
-		return isGatewayNode, err
-	}
-	internalIP, err := getInternalIPOfNode(nodesList.Items[0])
-	if err != nil {
-		return isGatewayNode, fmt.Errorf("unable to get internal ip of the gateway node: %v", err)
-	}
-	if podIP.String() == internalIP {
-		isGatewayNode = true
-		return isGatewayNode, nil
-	} else {
-		return isGatewayNode, nil
+		return false, err
 	}
+	return podIP.String() == gatewayIP, nil
 }
-func GetGatewayVxlanIP(clientset *kubernetes.Clientset, vxlanConfig VxlanNetConfig) (string, error) {
-	var gatewayVxlanIP string
-	//retrieve the node which is labeled as the gateway
-	nodesList, err := clientset.CoreV1().Nodes().List(context.TODO(), metav1.ListOptions{LabelSelector: "net.liqo.io/gateway == true"})
-	if err != nil {
-		logger.Error(err, "Unable to list nodes with label 'net.liqo.io/gateway=true'")
-		return gatewayVxlanIP, fmt.Errorf(" Unable to list nodes with label 'net.liqo.io/gateway=true': %v", err)
-	}
-	if len(nodesList.Items) != 1 {
-		klog.V(4).Infof("number of gateway nodes found: %d", len(nodesList.Items))
-		return gatewayVxlanIP, errdefs.NotFound("no gateway node has been found")
-	}
-	internalIP, err := getInternalIPOfNode(nodesList.Items[0])
+
+// GetGatewayVxlanIP returns the IP address of the vxlan interface of the node currently acting as gateway.
+func GetGatewayVxlanIP(clientset kubernetes.Interface, namespace string, vxlanConfig VxlanNetConfig) (string, error) {
+	gatewayIP, err := GetGatewayIP(clientset, namespace)
 	if err != nil {
-		return gatewayVxlanIP, fmt.Errorf("unable to get internal ip of the gateway node: %v", err)
+		return "", err
 	}
+	return GetVxlanIP(vxlanConfig, gatewayIP), nil
+}
+
+// GetVxlanIP returns the IP address of the vxlan interface of the node with the given IP address.
+func GetVxlanIP(vxlanConfig VxlanNetConfig, nodeIP string) string {
 	token := strings.Split(vxlanConfig.Network, "/")
 	vxlanNet := token[0]
 	//derive IP for the vxlan device
 	//take the last octet of the podIP
 	//TODO: use & and | operators with masks
-	temp := strings.Split(internalIP, ".")
+	temp := strings.Split(nodeIP, ".")
 	temp1 := strings.Split(vxlanNet, ".")
-	gatewayVxlanIP = temp1[0] + "." + temp1[1] + "." + temp1[2] + "." + temp[3]
-	return gatewayVxlanIP, nil
+	return temp1[0] + "." + temp1[1] + "." + temp1[2] + "." + temp[3]
 }
+
 func getRemoteVTEPS(clientset *kubernetes.Clientset) ([]string, error) {
 	var remoteVTEP []string
 	nodesList, err := clientset.CoreV1().Nodes().List(context.TODO(), metav1.ListOptions{LabelSelector: "type != virtual-node"})
@@ -164,29 +143,25 @@ func RemoveString(slice []string, s string) (result []string) {
 	return
 }
 
+//returns true if the newNet overlaps with any of the given subnets of the same family
 func VerifyNoOverlap(subnets map[string]*net.IPNet, newNet *net.IPNet) bool {
-	firstLastIP := make([][]net.IP, 1)
-
 	for _, value := range subnets {
-		if bytes.Compare(value.Mask, newNet.Mask) <= 0 {
-			first, last := cidr.AddressRange(newNet)
-			firstLastIP[0] = []net.IP{first, last}
-			if value.Contains(firstLastIP[0][0]) || value.Contains(firstLastIP[0][1]) {
-				klog.Infof("the subnets %s and %s overlaps", value.String(), newNet.String())
-				return true
-			}
-		} else {
-			first, last := cidr.AddressRange(value)
-			firstLastIP[0] = []net.IP{first, last}
-			if newNet.Contains(firstLastIP[0][0]) || newNet.Contains(firstLastIP[0][1]) {
-				klog.Infof("the subnets %s and %s overlaps", value.String(), newNet.String())
-				return true
-			}
+		if IsIPv4(value) != IsIPv4(newNet) {
+			continue
+		}
+		if value.Contains(newNet.IP.Mask(newNet.Mask)) || newNet.Contains(value.IP.Mask(value.Mask)) {
+			klog.Infof("the subnets %s and %s overlaps", value.String(), newNet.String())
+			return true
 		}
 	}
 	return false
 }
 
+//returns true if the network is an IPv4 one
+func IsIPv4(network *net.IPNet) bool {
+	return network.IP.To4() != nil
+}
+
 func SetLabelHandler(labelKey, labelValue string, mapToUpdate map[string]string) map[string]string {
 	if mapToUpdate == nil {
 		mapToUpdate = make(map[string]string)
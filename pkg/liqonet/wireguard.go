@@ -0,0 +1,266 @@
+package liqonet
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	netv1alpha1 "github.com/liqotech/liqo/apis/net/v1alpha1"
+	"github.com/vishvananda/netlink"
+	"golang.org/x/crypto/curve25519"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog"
+	"net"
+	"os/exec"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+const (
+	// DefaultWireGuardPort is the UDP port where the gateway listens for the WireGuard tunnels, if not configured.
+	DefaultWireGuardPort = 51820
+	// WireGuardSecretName is the name of the Secret where the key pair of the WireGuard driver is stored.
+	WireGuardSecretName = "liqo-wireguard-keys"
+	// WireGuardIFaceName is the name of the network interface used for all the WireGuard tunnels.
+	WireGuardIFaceName = "liqo-wg"
+	// WireGuardPublicKey is the key of the public key in the Secret and in the BackendConfig of the endpoints.
+	WireGuardPublicKey = "publicKey"
+	// WireGuardPrivateKey is the key of the private key in the Secret.
+	WireGuardPrivateKey = "privateKey"
+	// WireGuardEndpointPort is the key of the listening port in the BackendConfig of the endpoints.
+	WireGuardEndpointPort = "port"
+
+	wireGuardKeepalive = 25
+)
+
+// WireGuardKeys contains the base64 encoded key pair used by the WireGuard driver.
+type WireGuardKeys struct {
+	PrivateKey string
+	PublicKey  string
+}
+
+// NewWireGuardKeys generates a new WireGuard key pair.
+func NewWireGuardKeys() (*WireGuardKeys, error) {
+	var privateKey [32]byte
+	if _, err := rand.Read(privateKey[:]); err != nil {
+		return nil, fmt.Errorf("unable to generate the WireGuard private key: %w", err)
+	}
+	//clamp the private key as required by curve25519
+	privateKey[0] &= 248
+	privateKey[31] = (privateKey[31] & 127) | 64
+	publicKey, err := curve25519.X25519(privateKey[:], curve25519.Basepoint)
+	if err != nil {
+		return nil, fmt.Errorf("unable to derive the WireGuard public key: %w", err)
+	}
+	return &WireGuardKeys{
+		PrivateKey: base64.StdEncoding.EncodeToString(privateKey[:]),
+		PublicKey:  base64.StdEncoding.EncodeToString(publicKey),
+	}, nil
+}
+
+// GetOrCreateWireGuardKeys returns the WireGuard key pair stored in the Secret in the given namespace,
+// generating and storing a new one if it does not exist yet.
+func GetOrCreateWireGuardKeys(clientset kubernetes.Interface, namespace string) (*WireGuardKeys, error) {
+	secrets := clientset.CoreV1().Secrets(namespace)
+	secret, err := secrets.Get(context.TODO(), WireGuardSecretName, metav1.GetOptions{})
+	if k8serrors.IsNotFound(err) {
+		var keys *WireGuardKeys
+		if keys, err = NewWireGuardKeys(); err != nil {
+			return nil, err
+		}
+		secret = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      WireGuardSecretName,
+				Namespace: namespace,
+			},
+			Type: corev1.SecretTypeOpaque,
+			Data: map[string][]byte{
+				WireGuardPrivateKey: []byte(keys.PrivateKey),
+				WireGuardPublicKey:  []byte(keys.PublicKey),
+			},
+		}
+		secret, err = secrets.Create(context.TODO(), secret, metav1.CreateOptions{})
+		if k8serrors.IsAlreadyExists(err) {
+			//the keys have been generated in the meantime by another operator
+			secret, err = secrets.Get(context.TODO(), WireGuardSecretName, metav1.GetOptions{})
+		} else if err == nil {
+			klog.Infof("WireGuard keys generated and stored in secret %s/%s", namespace, WireGuardSecretName)
+		}
+	}
+	if err != nil {
+		klog.Errorf("unable to get the WireGuard keys from secret %s/%s: %s", namespace, WireGuardSecretName, err)
+		return nil, err
+	}
+
+	keys := &WireGuardKeys{
+		PrivateKey: string(secret.Data[WireGuardPrivateKey]),
+		PublicKey:  string(secret.Data[WireGuardPublicKey]),
+	}
+	if keys.PrivateKey == "" || keys.PublicKey == "" {
+		return nil, fmt.Errorf("secret %s/%s does not contain a valid WireGuard key pair", namespace, WireGuardSecretName)
+	}
+	return keys, nil
+}
+
+// GetWireGuardBackendConfig returns the parameters the remote clusters need to connect to the local WireGuard driver.
+func GetWireGuardBackendConfig(clientset kubernetes.Interface, namespace string, port int) (map[string]string, error) {
+	keys, err := GetOrCreateWireGuardKeys(clientset, namespace)
+	if err != nil {
+		return nil, err
+	}
+	if port == 0 {
+		port = DefaultWireGuardPort
+	}
+	return map[string]string{
+		WireGuardPublicKey:    keys.PublicKey,
+		WireGuardEndpointPort: strconv.Itoa(port),
+	}, nil
+}
+
+type wireGuardDriver struct {
+	port      int
+	clientset kubernetes.Interface
+	namespace string
+	keys      *WireGuardKeys
+}
+
+//creates the WireGuard interface, if it does not exist, and configures it with the private key and the listening port
+func (d *wireGuardDriver) Init() error {
+	keys, err := GetOrCreateWireGuardKeys(d.clientset, d.namespace)
+	if err != nil {
+		return err
+	}
+	d.keys = keys
+	link, err := d.getLink()
+	if err != nil {
+		return err
+	}
+	if err := runWg(keys.PrivateKey, "set", WireGuardIFaceName, "listen-port", strconv.Itoa(d.port), "private-key", "/dev/stdin"); err != nil {
+		return err
+	}
+	if err := netlink.LinkSetUp(link); err != nil {
+		return fmt.Errorf("unable to bring up the interface %s: %w", WireGuardIFaceName, err)
+	}
+	klog.Infof("WireGuard interface %s listening on port %d", WireGuardIFaceName, d.port)
+	return nil
+}
+
+func (d *wireGuardDriver) ConnectToEndpoint(endpoint *netv1alpha1.TunnelEndpoint) (int, string, error) {
+	if err := checkBackendType(endpoint, WireGuardDriverName); err != nil {
+		return 0, "", err
+	}
+	link, err := d.getLink()
+	if err != nil {
+		return 0, "", err
+	}
+	args, err := wireGuardPeerArgs(endpoint)
+	if err != nil {
+		return 0, "", err
+	}
+	if err := runWg("", args...); err != nil {
+		return 0, "", err
+	}
+	return link.Attrs().Index, link.Attrs().Name, nil
+}
+
+func (d *wireGuardDriver) DisconnectFromEndpoint(endpoint *netv1alpha1.TunnelEndpoint) error {
+	publicKey := endpoint.Spec.BackendConfig[WireGuardPublicKey]
+	if publicKey == "" {
+		klog.Infof("%s -> no WireGuard peer configured. Do nothing", endpoint.Spec.ClusterID)
+		return nil
+	}
+	if _, err := netlink.LinkByName(WireGuardIFaceName); err != nil {
+		klog.Infof("%s -> WireGuard interface %s not found. Do nothing", endpoint.Spec.ClusterID, WireGuardIFaceName)
+		return nil
+	}
+	return runWg("", "set", WireGuardIFaceName, "peer", publicKey, "remove")
+}
+
+//returns the WireGuard interface, creating it if it does not exist
+func (d *wireGuardDriver) getLink() (netlink.Link, error) {
+	link := &netlink.GenericLink{
+		LinkAttrs: netlink.LinkAttrs{Name: WireGuardIFaceName},
+		LinkType:  WireGuardDriverName,
+	}
+	if err := netlink.LinkAdd(link); err != nil && err != syscall.EEXIST {
+		return nil, fmt.Errorf("unable to create the WireGuard interface %s (is the wireguard kernel module loaded?): %w", WireGuardIFaceName, err)
+	}
+	existing, err := netlink.LinkByName(WireGuardIFaceName)
+	if err != nil {
+		return nil, fmt.Errorf("unable to retrieve the WireGuard interface %s: %w", WireGuardIFaceName, err)
+	}
+	if existing.Type() != WireGuardDriverName {
+		return nil, fmt.Errorf("existing iface named %s with index number %d is not of type wireguard", WireGuardIFaceName, existing.Attrs().Index)
+	}
+	return existing, nil
+}
+
+//returns the arguments of the wg command which configures the peer of the remote cluster described by the TunnelEndpoint.
+//Only the traffic coming from the PodCIDRs of the remote cluster, as seen by the local one, is accepted from the peer.
+func wireGuardPeerArgs(endpoint *netv1alpha1.TunnelEndpoint) ([]string, error) {
+	publicKey := endpoint.Spec.BackendConfig[WireGuardPublicKey]
+	if publicKey == "" {
+		return nil, fmt.Errorf("the WireGuard public key of cluster %s is not set", endpoint.Spec.ClusterID)
+	}
+	port := endpoint.Spec.BackendConfig[WireGuardEndpointPort]
+	if port == "" {
+		port = strconv.Itoa(DefaultWireGuardPort)
+	}
+	publicIP := net.ParseIP(endpoint.Spec.TunnelPublicIP)
+	if publicIP == nil {
+		return nil, fmt.Errorf("invalid tunnel public IP %q for cluster %s", endpoint.Spec.TunnelPublicIP, endpoint.Spec.ClusterID)
+	}
+	allowedIPs := GetRemotePodCIDRs(endpoint)
+	if len(allowedIPs) == 0 {
+		return nil, fmt.Errorf("the PodCIDR of cluster %s is not set", endpoint.Spec.ClusterID)
+	}
+	//the public IP of the remote gateway is allowed as well, since it is the destination of the tunnel probes.
+	//No route is configured for it, hence only the probes bound to the WireGuard interface go through the tunnel
+	hostBits := 32
+	if publicIP.To4() == nil {
+		hostBits = 128
+	}
+	allowedIPs = append(allowedIPs, (&net.IPNet{IP: publicIP, Mask: net.CIDRMask(hostBits, hostBits)}).String())
+	return []string{
+		"set", WireGuardIFaceName,
+		"peer", publicKey,
+		"endpoint", net.JoinHostPort(endpoint.Spec.TunnelPublicIP, port),
+		"allowed-ips", strings.Join(allowedIPs, ","),
+		"persistent-keepalive", strconv.Itoa(wireGuardKeepalive),
+	}, nil
+}
+
+// GetRemotePodCIDRs returns the PodCIDRs of the remote cluster as seen by the local one,
+// i.e. the remapped ones if the NAT is enabled and the original ones otherwise.
+func GetRemotePodCIDRs(endpoint *netv1alpha1.TunnelEndpoint) []string {
+	var podCIDRs []string
+	for _, cidrs := range [][2]string{
+		{endpoint.Status.RemoteRemappedPodCIDR, endpoint.Spec.PodCIDR},
+		{endpoint.Status.RemoteRemappedIPv6PodCIDR, endpoint.Spec.IPv6PodCIDR},
+	} {
+		remapped, original := cidrs[0], cidrs[1]
+		if remapped != "" && remapped != "None" {
+			podCIDRs = append(podCIDRs, remapped)
+		} else if original != "" {
+			podCIDRs = append(podCIDRs, original)
+		}
+	}
+	return podCIDRs
+}
+
+//runs the wg command with the given arguments, writing the input to its standard input
+func runWg(input string, args ...string) error {
+	cmd := exec.Command("wg", args...)
+	cmd.Stdin = strings.NewReader(input)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("wg %s failed: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+package liqonet
+
+import (
+	"context"
+	"encoding/base64"
+	netv1alpha1 "github.com/liqotech/liqo/apis/net/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/curve25519"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"testing"
+)
+
+func TestNewWireGuardKeys(t *testing.T) {
+	keys, err := NewWireGuardKeys()
+	assert.Nil(t, err, "error should be nil")
+	privateKey, err := base64.StdEncoding.DecodeString(keys.PrivateKey)
+	assert.Nil(t, err, "error should be nil")
+	assert.Equal(t, 32, len(privateKey))
+	publicKey, err := curve25519.X25519(privateKey, curve25519.Basepoint)
+	assert.Nil(t, err, "error should be nil")
+	assert.Equal(t, base64.StdEncoding.EncodeToString(publicKey), keys.PublicKey)
+
+	otherKeys, err := NewWireGuardKeys()
+	assert.Nil(t, err, "error should be nil")
+	assert.NotEqual(t, keys.PrivateKey, otherKeys.PrivateKey)
+}
+
+func TestGetOrCreateWireGuardKeys(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+
+	//test1 the keys are generated and stored in the secret if it does not exist
+	keys, err := GetOrCreateWireGuardKeys(clientset, "liqo")
+	assert.Nil(t, err, "error should be nil")
+	secret, err := clientset.CoreV1().Secrets("liqo").Get(context.TODO(), WireGuardSecretName, metav1.GetOptions{})
+	assert.Nil(t, err, "error should be nil")
+	assert.Equal(t, keys.PrivateKey, string(secret.Data[WireGuardPrivateKey]))
+	assert.Equal(t, keys.PublicKey, string(secret.Data[WireGuardPublicKey]))
+
+	//test2 the stored keys are returned once generated
+	storedKeys, err := GetOrCreateWireGuardKeys(clientset, "liqo")
+	assert.Nil(t, err, "error should be nil")
+	assert.Equal(t, keys, storedKeys)
+
+	//test3 the backend config exposes only the public key and the port
+	backendConfig, err := GetWireGuardBackendConfig(clientset, "liqo", 0)
+	assert.Nil(t, err, "error should be nil")
+	assert.Equal(t, map[string]string{WireGuardPublicKey: keys.PublicKey, WireGuardEndpointPort: "51820"}, backendConfig)
+
+	//test4 a secret without the keys is not valid
+	_, err = clientset.CoreV1().Secrets("other").Create(context.TODO(), &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: WireGuardSecretName, Namespace: "other"},
+	}, metav1.CreateOptions{})
+	assert.Nil(t, err, "error should be nil")
+	_, err = GetOrCreateWireGuardKeys(clientset, "other")
+	assert.NotNil(t, err, "should be not nil")
+}
+
+func getWireGuardEndpoint() *netv1alpha1.TunnelEndpoint {
+	return &netv1alpha1.TunnelEndpoint{
+		Spec: netv1alpha1.TunnelEndpointSpec{
+			ClusterID:      "remote",
+			PodCIDR:        "10.1.0.0/16",
+			TunnelPublicIP: "192.168.1.1",
+			BackendType:    WireGuardDriverName,
+			BackendConfig:  map[string]string{WireGuardPublicKey: "key", WireGuardEndpointPort: "51821"},
+		},
+		Status: netv1alpha1.TunnelEndpointStatus{
+			RemoteRemappedPodCIDR: "None",
+		},
+	}
+}
+
+func TestWireGuardPeerArgs(t *testing.T) {
+	//test1 the remote PodCIDR is used if the NAT is not enabled
+	endpoint := getWireGuardEndpoint()
+	args, err := wireGuardPeerArgs(endpoint)
+	assert.Nil(t, err, "error should be nil")
+	assert.Equal(t, []string{"set", WireGuardIFaceName, "peer", "key", "endpoint", "192.168.1.1:51821",
+		"allowed-ips", "10.1.0.0/16,192.168.1.1/32", "persistent-keepalive", "25"}, args)
+
+	//test2 the remapped PodCIDRs of both families are used if the NAT is enabled
+	endpoint = getWireGuardEndpoint()
+	endpoint.Spec.TunnelPublicIP = "fd00::1"
+	endpoint.Spec.IPv6PodCIDR = "fd00:0:0:1::/64"
+	endpoint.Spec.BackendConfig = map[string]string{WireGuardPublicKey: "key"}
+	endpoint.Status.RemoteRemappedPodCIDR = "10.2.0.0/16"
+	endpoint.Status.RemoteRemappedIPv6PodCIDR = "fd00:0:0:ff00::/64"
+	args, err = wireGuardPeerArgs(endpoint)
+	assert.Nil(t, err, "error should be nil")
+	assert.Equal(t, []string{"set", WireGuardIFaceName, "peer", "key", "endpoint", "[fd00::1]:51820",
+		"allowed-ips", "10.2.0.0/16,fd00:0:0:ff00::/64,fd00::1/128", "persistent-keepalive", "25"}, args)
+
+	//test3 the public key of the remote cluster is required
+	endpoint = getWireGuardEndpoint()
+	endpoint.Spec.BackendConfig = nil
+	_, err = wireGuardPeerArgs(endpoint)
+	assert.NotNil(t, err, "should be not nil")
+
+	//test4 the public IP of the remote cluster has to be valid
+	endpoint = getWireGuardEndpoint()
+	endpoint.Spec.TunnelPublicIP = ""
+	_, err = wireGuardPeerArgs(endpoint)
+	assert.NotNil(t, err, "should be not nil")
+}
+
+func TestNewTunnelDriver(t *testing.T) {
+	driver, err := NewTunnelDriver("", 0, nil, "")
+	assert.Nil(t, err, "error should be nil")
+	assert.IsType(t, &greDriver{}, driver)
+
+	driver, err = NewTunnelDriver(WireGuardDriverName, 0, nil, "liqo")
+	assert.Nil(t, err, "error should be nil")
+	assert.Equal(t, DefaultWireGuardPort, driver.(*wireGuardDriver).port)
+
+	_, err = NewTunnelDriver("ipsec", 0, nil, "")
+	assert.NotNil(t, err, "should be not nil")
+
+	//the tunnel cannot be created if the remote cluster uses a different driver
+	endpoint := getWireGuardEndpoint()
+	_, _, err = (&greDriver{}).ConnectToEndpoint(endpoint)
+	assert.NotNil(t, err, "should be not nil")
+	endpoint.Spec.BackendType = ""
+	assert.Equal(t, GreDriverName, GetBackendType(endpoint))
+	assert.NotNil(t, checkBackendType(endpoint, WireGuardDriverName), "should be not nil")
+}
@@ -2,10 +2,13 @@ package controller
 
 import (
 	"errors"
+	configv1alpha1 "github.com/liqotech/liqo/apis/config/v1alpha1"
 	"github.com/liqotech/liqo/pkg/virtualKubelet/apiReflection"
+	"github.com/liqotech/liqo/pkg/virtualKubelet/apiReflection/reflectors/outgoing"
 	"github.com/liqotech/liqo/pkg/virtualKubelet/namespacesMapping"
 	"github.com/liqotech/liqo/pkg/virtualKubelet/options"
 	"github.com/liqotech/liqo/pkg/virtualKubelet/storage"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/klog"
 	"sync"
@@ -34,7 +37,7 @@ type Controller struct {
 	stopController chan struct{}
 }
 
-func NewApiController(homeClient, foreignClient kubernetes.Interface, mapper namespacesMapping.MapperController, opts map[options.OptionKey]options.Option) *Controller {
+func NewApiController(homeClient, foreignClient kubernetes.Interface, homeDynClient, foreignDynClient dynamic.Interface, mapper namespacesMapping.MapperController, opts map[options.OptionKey]options.Option) *Controller {
 	klog.V(2).Infof("starting reflection manager")
 
 	outgoingReflectionInforming := make(chan apiReflection.ApiEvent)
@@ -43,7 +46,7 @@ func NewApiController(homeClient, foreignClient kubernetes.Interface, mapper nam
 
 	c := &Controller{
 		mapper:                       mapper,
-		outgoingReflectorsController: NewOutgoingReflectorsController(homeClient, foreignClient, cacheManager, outgoingReflectionInforming, mapper, opts),
+		outgoingReflectorsController: NewOutgoingReflectorsController(homeClient, foreignClient, homeDynClient, foreignDynClient, cacheManager, outgoingReflectionInforming, mapper, opts),
 		incomingReflectorsController: NewIncomingReflectorsController(homeClient, foreignClient, cacheManager, incomingReflectionInforming, mapper, opts),
 		outgoingReflectionGroup:      &sync.WaitGroup{},
 		incomingReflectionGroup:      &sync.WaitGroup{},
@@ -101,6 +104,16 @@ func (c *Controller) SetInformingFunc(api apiReflection.ApiType, handler func(in
 	c.incomingReflectorsController.SetInforming(api, handler)
 }
 
+// SetReflectedResources configures the resources reflected by the generic reflector, in addition to the built-in ones.
+func (c *Controller) SetReflectedResources(resources []configv1alpha1.ReflectedResource) {
+	c.outgoingReflectorsController.SetReflectedResources(resources)
+}
+
+// SetReflectionBlacklist configures the objects not to be reflected by the built-in reflectors.
+func (c *Controller) SetReflectionBlacklist(resources []configv1alpha1.BlacklistedResource) {
+	outgoing.SetBlacklist(resources)
+}
+
 func (c *Controller) CacheManager() *storage.Manager {
 	return c.cacheManager
 }
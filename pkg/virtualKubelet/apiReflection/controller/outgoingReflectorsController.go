@@ -1,13 +1,16 @@
 package controller
 
 import (
+	configv1alpha1 "github.com/liqotech/liqo/apis/config/v1alpha1"
 	apimgmt "github.com/liqotech/liqo/pkg/virtualKubelet/apiReflection"
 	"github.com/liqotech/liqo/pkg/virtualKubelet/apiReflection/reflectors"
+	"github.com/liqotech/liqo/pkg/virtualKubelet/apiReflection/reflectors/generic"
 	"github.com/liqotech/liqo/pkg/virtualKubelet/apiReflection/reflectors/outgoing"
 	ri "github.com/liqotech/liqo/pkg/virtualKubelet/apiReflection/reflectors/reflectorsInterfaces"
 	"github.com/liqotech/liqo/pkg/virtualKubelet/namespacesMapping"
 	"github.com/liqotech/liqo/pkg/virtualKubelet/options"
 	"github.com/liqotech/liqo/pkg/virtualKubelet/storage"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/klog"
 	"sync"
@@ -15,9 +18,13 @@ import (
 
 type OutgoingReflectorsController struct {
 	*ReflectorsController
+
+	// genericManager reflects the resources configured in the ClusterConfig, in addition to the built-in ones.
+	genericManager *generic.Manager
 }
 
-func NewOutgoingReflectorsController(homeClient, foreignClient kubernetes.Interface, cacheManager *storage.Manager,
+func NewOutgoingReflectorsController(homeClient, foreignClient kubernetes.Interface,
+	homeDynClient, foreignDynClient dynamic.Interface, cacheManager *storage.Manager,
 	outputChan chan apimgmt.ApiEvent,
 	namespaceNatting namespacesMapping.MapperController,
 	opts map[options.OptionKey]options.Option) OutGoingAPIReflectorsController {
@@ -33,6 +40,7 @@ func NewOutgoingReflectorsController(homeClient, foreignClient kubernetes.Interf
 			reflectionGroup:  &sync.WaitGroup{},
 			cacheManager:     cacheManager,
 		},
+		generic.NewManager(homeDynClient, foreignDynClient, namespaceNatting),
 	}
 
 	for api := range outgoing.ReflectorBuilders {
@@ -62,10 +70,12 @@ func (c *OutgoingReflectorsController) Start() {
 		select {
 		case ns := <-c.namespaceNatting.PollStartOutgoingReflection():
 			c.startNamespaceReflection(ns)
+			c.genericManager.StartNamespace(ns)
 			klog.V(2).Infof("outgoing reflection for namespace %v started", ns)
 		case ns := <-c.namespaceNatting.PollStopOutgoingReflection():
 			c.stopNamespaceReflection(ns)
-			klog.V(2).Infof("incoming reflection for namespace %v started", ns)
+			c.genericManager.StopNamespace(ns)
+			klog.V(2).Infof("outgoing reflection for namespace %v stopped", ns)
 		}
 	}
 }
@@ -73,3 +83,12 @@ func (c *OutgoingReflectorsController) Start() {
 func (c *OutgoingReflectorsController) stopNamespaceReflection(namespace string) {
 	close(c.namespacedStops[namespace])
 }
+
+func (c *OutgoingReflectorsController) Stop() {
+	c.ReflectorsController.Stop()
+	c.genericManager.Stop()
+}
+
+func (c *OutgoingReflectorsController) SetReflectedResources(resources []configv1alpha1.ReflectedResource) {
+	c.genericManager.SetResources(resources)
+}
@@ -1,6 +1,7 @@
 package controller
 
 import (
+	configv1alpha1 "github.com/liqotech/liqo/apis/config/v1alpha1"
 	apimgmt "github.com/liqotech/liqo/pkg/virtualKubelet/apiReflection"
 	"github.com/liqotech/liqo/pkg/virtualKubelet/apiReflection/reflectors/incoming"
 	"github.com/liqotech/liqo/pkg/virtualKubelet/apiReflection/reflectors/outgoing"
@@ -27,6 +28,7 @@ type OutGoingAPIReflectorsController interface {
 	SpecializedAPIReflectorsController
 
 	buildOutgoingReflector(api apimgmt.ApiType, opts map[options.OptionKey]options.Option) ri.OutgoingAPIReflector
+	SetReflectedResources(resources []configv1alpha1.ReflectedResource)
 }
 
 type IncomingAPIReflectorsController interface {
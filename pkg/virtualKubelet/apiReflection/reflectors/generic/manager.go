@@ -0,0 +1,149 @@
+package generic
+
+import (
+	configv1alpha1 "github.com/liqotech/liqo/apis/config/v1alpha1"
+	"github.com/liqotech/liqo/pkg/virtualKubelet/namespacesMapping"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/klog"
+	"reflect"
+	"sync"
+)
+
+// builtinResources are reflected by the specialized outgoing reflectors, hence they are ignored if configured.
+var builtinResources = map[schema.GroupResource]bool{
+	{Group: "", Resource: "configmaps"}:                     true,
+	{Group: "", Resource: "persistentvolumeclaims"}:         true,
+	{Group: "", Resource: "secrets"}:                        true,
+	{Group: "", Resource: "services"}:                       true,
+	{Group: "discovery.k8s.io", Resource: "endpointslices"}: true,
+}
+
+// Manager handles the generic reflectors of the resources configured in the ClusterConfig,
+// running a dynamic informer factory for each namespace whose outgoing reflection is active.
+type Manager struct {
+	mutex            sync.Mutex
+	homeClient       dynamic.Interface
+	foreignClient    dynamic.Interface
+	namespaceNatting namespacesMapping.NamespaceNatter
+
+	resources  []configv1alpha1.ReflectedResource
+	reflectors map[schema.GroupVersionResource]*Reflector
+	namespaces map[string]chan struct{}
+}
+
+// NewManager returns a Manager without any configured resource.
+func NewManager(homeClient, foreignClient dynamic.Interface, namespaceNatting namespacesMapping.NamespaceNatter) *Manager {
+	return &Manager{
+		homeClient:       homeClient,
+		foreignClient:    foreignClient,
+		namespaceNatting: namespaceNatting,
+		reflectors:       make(map[schema.GroupVersionResource]*Reflector),
+		namespaces:       make(map[string]chan struct{}),
+	}
+}
+
+// SetResources configures the resources to be reflected: the reflected objects of the resources no longer
+// configured are deleted from the foreign cluster, and the reflection of the active namespaces is restarted.
+func (m *Manager) SetResources(resources []configv1alpha1.ReflectedResource) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if reflect.DeepEqual(m.resources, resources) {
+		return
+	}
+
+	reflectors := make(map[schema.GroupVersionResource]*Reflector)
+	for _, resource := range resources {
+		gvr := GroupVersionResource(resource)
+		if builtinResources[gvr.GroupResource()] {
+			klog.Warningf("resource %v is already reflected by the virtual kubelet, ignoring it", gvr)
+			continue
+		}
+		if gvr.Version == "" || gvr.Resource == "" {
+			klog.Warningf("invalid resource %v to be reflected, ignoring it", gvr)
+			continue
+		}
+		reflectors[gvr] = NewReflector(resource, m.homeClient, m.foreignClient, m.namespaceNatting)
+	}
+
+	for namespace := range m.namespaces {
+		m.stopInformers(namespace)
+		for gvr, reflector := range m.reflectors {
+			if _, ok := reflectors[gvr]; !ok {
+				reflector.CleanupNamespace(namespace)
+			}
+		}
+	}
+
+	m.resources = resources
+	m.reflectors = reflectors
+	for namespace := range m.namespaces {
+		m.startInformers(namespace)
+	}
+	klog.V(2).Infof("generic reflection configured for %d resources", len(reflectors))
+}
+
+// StartNamespace starts the reflection of the configured resources in the given home namespace.
+func (m *Manager) StartNamespace(namespace string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if _, ok := m.namespaces[namespace]; ok {
+		return
+	}
+	m.startInformers(namespace)
+}
+
+// StopNamespace stops the reflection in the given home namespace, deleting the reflected objects.
+func (m *Manager) StopNamespace(namespace string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.stopNamespace(namespace)
+}
+
+// Stop stops the reflection in all the namespaces.
+func (m *Manager) Stop() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	for namespace := range m.namespaces {
+		m.stopNamespace(namespace)
+	}
+}
+
+func (m *Manager) stopNamespace(namespace string) {
+	if _, ok := m.namespaces[namespace]; !ok {
+		return
+	}
+	m.stopInformers(namespace)
+	delete(m.namespaces, namespace)
+
+	for _, reflector := range m.reflectors {
+		reflector.CleanupNamespace(namespace)
+	}
+}
+
+func (m *Manager) startInformers(namespace string) {
+	stop := make(chan struct{})
+	m.namespaces[namespace] = stop
+	if len(m.reflectors) == 0 {
+		return
+	}
+
+	factory := dynamicinformer.NewFilteredDynamicSharedInformerFactory(m.homeClient, 0, namespace, nil)
+	for gvr, reflector := range m.reflectors {
+		factory.ForResource(gvr).Informer().AddEventHandler(reflector.EventHandlers())
+	}
+	factory.Start(stop)
+}
+
+func (m *Manager) stopInformers(namespace string) {
+	select {
+	case <-m.namespaces[namespace]:
+	default:
+		close(m.namespaces[namespace])
+	}
+}
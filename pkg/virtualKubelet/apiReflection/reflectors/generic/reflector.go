@@ -0,0 +1,240 @@
+package generic
+
+import (
+	"context"
+	"fmt"
+	configv1alpha1 "github.com/liqotech/liqo/apis/config/v1alpha1"
+	apimgmt "github.com/liqotech/liqo/pkg/virtualKubelet/apiReflection"
+	"github.com/liqotech/liqo/pkg/virtualKubelet/namespacesMapping"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/retry"
+	"k8s.io/klog"
+	"strings"
+)
+
+// defaultStrippedFields are the fields removed from every reflected object, since they are either
+// populated by the API server or meaningful only in the home cluster.
+var defaultStrippedFields = [][]string{
+	{"metadata", "uid"},
+	{"metadata", "resourceVersion"},
+	{"metadata", "generation"},
+	{"metadata", "creationTimestamp"},
+	{"metadata", "deletionTimestamp"},
+	{"metadata", "deletionGracePeriodSeconds"},
+	{"metadata", "selfLink"},
+	{"metadata", "managedFields"},
+	{"metadata", "ownerReferences"},
+	{"metadata", "finalizers"},
+	{"status"},
+}
+
+// Reflector reflects the objects of a namespaced resource from the home cluster to the foreign one,
+// by means of dynamic clients, so that any resource kind can be reflected without specialized code.
+type Reflector struct {
+	gvr              schema.GroupVersionResource
+	homeClient       dynamic.Interface
+	foreignClient    dynamic.Interface
+	namespaceNatting namespacesMapping.NamespaceNatter
+	strippedFields   [][]string
+	blacklist        map[string]bool
+}
+
+// NewReflector returns the Reflector for the given resource, configured with its stripping rules and blacklist.
+func NewReflector(resource configv1alpha1.ReflectedResource, homeClient, foreignClient dynamic.Interface,
+	namespaceNatting namespacesMapping.NamespaceNatter) *Reflector {
+	r := &Reflector{
+		gvr:              GroupVersionResource(resource),
+		homeClient:       homeClient,
+		foreignClient:    foreignClient,
+		namespaceNatting: namespaceNatting,
+		strippedFields:   defaultStrippedFields,
+		blacklist:        make(map[string]bool),
+	}
+
+	for _, field := range resource.StrippedFields {
+		if field = strings.Trim(field, "."); field != "" {
+			r.strippedFields = append(r.strippedFields, strings.Split(field, "."))
+		}
+	}
+	for _, key := range resource.Blacklist {
+		r.blacklist[key] = true
+	}
+
+	return r
+}
+
+// GroupVersionResource returns the GroupVersionResource of a reflected resource.
+func GroupVersionResource(resource configv1alpha1.ReflectedResource) schema.GroupVersionResource {
+	return schema.GroupVersionResource{
+		Group:    resource.Group,
+		Version:  resource.Version,
+		Resource: resource.Resource.Resource,
+	}
+}
+
+// GVR returns the GroupVersionResource reflected by this reflector.
+func (r *Reflector) GVR() schema.GroupVersionResource {
+	return r.gvr
+}
+
+// IsBlacklisted returns true if the object with the given namespace and name must not be reflected.
+func (r *Reflector) IsBlacklisted(namespace, name string) bool {
+	return r.blacklist[strings.Join([]string{namespace, name}, "/")]
+}
+
+// EventHandlers returns the handlers to be registered on the home informer of the reflected resource.
+func (r *Reflector) EventHandlers() cache.ResourceEventHandlerFuncs {
+	return cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			r.handle(obj, r.Reflect)
+		},
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			oldU, okOld := oldObj.(*unstructured.Unstructured)
+			newU, okNew := newObj.(*unstructured.Unstructured)
+			if okOld && okNew && oldU.GetResourceVersion() == newU.GetResourceVersion() {
+				return
+			}
+			r.handle(newObj, r.Reflect)
+		},
+		DeleteFunc: func(obj interface{}) {
+			if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			r.handle(obj, r.Delete)
+		},
+	}
+}
+
+func (r *Reflector) handle(obj interface{}, handler func(*unstructured.Unstructured) error) {
+	local, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		klog.Errorf("OUTGOING REFLECTION: cannot cast object to unstructured for resource %v", r.gvr)
+		return
+	}
+	if err := handler(local); err != nil {
+		klog.Errorf("OUTGOING REFLECTION: error while reflecting %v %v/%v - ERR: %v", r.gvr.Resource, local.GetNamespace(), local.GetName(), err)
+	}
+}
+
+// Reflect creates or updates the foreign copy of the given home object.
+// Foreign objects with the same name which have not been created by the reflection are left untouched.
+func (r *Reflector) Reflect(local *unstructured.Unstructured) error {
+	if r.IsBlacklisted(local.GetNamespace(), local.GetName()) {
+		klog.V(4).Infof("OUTGOING REFLECTION: %v %v/%v is blacklisted", r.gvr.Resource, local.GetNamespace(), local.GetName())
+		return nil
+	}
+
+	nattedNs, err := r.namespaceNatting.NatNamespace(local.GetNamespace(), false)
+	if err != nil {
+		return err
+	}
+	foreign := r.ForgeForeignObject(local, nattedNs)
+	client := r.foreignClient.Resource(r.gvr).Namespace(nattedNs)
+
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		remote, err := client.Get(context.TODO(), foreign.GetName(), metav1.GetOptions{})
+		if kerrors.IsNotFound(err) {
+			if _, err = client.Create(context.TODO(), foreign, metav1.CreateOptions{}); err != nil {
+				return err
+			}
+			klog.V(3).Infof("OUTGOING REFLECTION: remote %v %v/%v correctly created", r.gvr.Resource, nattedNs, foreign.GetName())
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if remote.GetLabels()[apimgmt.LiqoLabelKey] != apimgmt.LiqoLabelValue {
+			klog.Warningf("OUTGOING REFLECTION: remote %v %v/%v has not been created by the reflection, skipping",
+				r.gvr.Resource, nattedNs, foreign.GetName())
+			return nil
+		}
+
+		foreign.SetResourceVersion(remote.GetResourceVersion())
+		if _, err = client.Update(context.TODO(), foreign, metav1.UpdateOptions{}); err != nil {
+			return err
+		}
+		klog.V(3).Infof("OUTGOING REFLECTION: remote %v %v/%v correctly updated", r.gvr.Resource, nattedNs, foreign.GetName())
+		return nil
+	})
+}
+
+// Delete deletes the foreign copy of the given home object, if it has been created by the reflection.
+func (r *Reflector) Delete(local *unstructured.Unstructured) error {
+	if r.IsBlacklisted(local.GetNamespace(), local.GetName()) {
+		return nil
+	}
+
+	nattedNs, err := r.namespaceNatting.NatNamespace(local.GetNamespace(), false)
+	if err != nil {
+		return err
+	}
+
+	client := r.foreignClient.Resource(r.gvr).Namespace(nattedNs)
+	remote, err := client.Get(context.TODO(), local.GetName(), metav1.GetOptions{})
+	if kerrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if remote.GetLabels()[apimgmt.LiqoLabelKey] != apimgmt.LiqoLabelValue {
+		return nil
+	}
+
+	err = client.Delete(context.TODO(), local.GetName(), metav1.DeleteOptions{})
+	if err != nil && !kerrors.IsNotFound(err) {
+		return err
+	}
+	klog.V(3).Infof("OUTGOING REFLECTION: remote %v %v/%v correctly deleted", r.gvr.Resource, nattedNs, local.GetName())
+	return nil
+}
+
+// CleanupNamespace deletes all the reflected objects from the foreign namespace corresponding to the given home one.
+func (r *Reflector) CleanupNamespace(localNamespace string) {
+	foreignNamespace, err := r.namespaceNatting.NatNamespace(localNamespace, false)
+	if err != nil {
+		klog.Error(err)
+		return
+	}
+
+	client := r.foreignClient.Resource(r.gvr).Namespace(foreignNamespace)
+	objects, err := client.List(context.TODO(), metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("%v=%v", apimgmt.LiqoLabelKey, apimgmt.LiqoLabelValue),
+	})
+	if err != nil {
+		klog.Errorf("error while listing remote %v in namespace %v - ERR: %v", r.gvr.Resource, foreignNamespace, err)
+		return
+	}
+
+	for i := range objects.Items {
+		name := objects.Items[i].GetName()
+		if err := client.Delete(context.TODO(), name, metav1.DeleteOptions{}); err != nil && !kerrors.IsNotFound(err) {
+			klog.Errorf("Error while deleting remote %v %v/%v - ERR: %v", r.gvr.Resource, foreignNamespace, name, err)
+		}
+	}
+}
+
+// ForgeForeignObject returns the foreign copy of the given home object, in the natted namespace,
+// without the stripped fields and labelled as reflected.
+func (r *Reflector) ForgeForeignObject(local *unstructured.Unstructured, nattedNs string) *unstructured.Unstructured {
+	foreign := local.DeepCopy()
+	for _, field := range r.strippedFields {
+		unstructured.RemoveNestedField(foreign.Object, field...)
+	}
+
+	foreign.SetNamespace(nattedNs)
+	labels := foreign.GetLabels()
+	if labels == nil {
+		labels = make(map[string]string)
+	}
+	labels[apimgmt.LiqoLabelKey] = apimgmt.LiqoLabelValue
+	foreign.SetLabels(labels)
+
+	return foreign
+}
@@ -8,8 +8,9 @@ import (
 )
 
 var ReflectorBuilder = map[apimgmt.ApiType]func(reflector ri.APIReflector, opts map[options.OptionKey]options.Option) ri.IncomingAPIReflector{
-	apimgmt.Pods:        podsReflectorBuilder,
-	apimgmt.ReplicaSets: replicaSetsReflectorBuilder,
+	apimgmt.Pods:                   podsReflectorBuilder,
+	apimgmt.ReplicaSets:            replicaSetsReflectorBuilder,
+	apimgmt.PersistentVolumeClaims: persistentVolumeClaimsReflectorBuilder,
 }
 
 func podsReflectorBuilder(reflector ri.APIReflector, opts map[options.OptionKey]options.Option) ri.IncomingAPIReflector {
@@ -23,3 +24,9 @@ func replicaSetsReflectorBuilder(reflector ri.APIReflector, _ map[options.Option
 		APIReflector: reflector,
 	}
 }
+
+func persistentVolumeClaimsReflectorBuilder(reflector ri.APIReflector, _ map[options.OptionKey]options.Option) ri.IncomingAPIReflector {
+	return &PersistentVolumeClaimsIncomingReflector{
+		APIReflector: reflector,
+	}
+}
@@ -0,0 +1,101 @@
+package incoming
+
+import (
+	"context"
+	"github.com/liqotech/liqo/pkg/virtualKubelet"
+	apimgmt "github.com/liqotech/liqo/pkg/virtualKubelet/apiReflection"
+	ri "github.com/liqotech/liqo/pkg/virtualKubelet/apiReflection/reflectors/reflectorsInterfaces"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/klog"
+)
+
+// PersistentVolumeClaimsIncomingReflector is in charge of reflecting the binding status of the remote
+// persistentVolumeClaims in the home cluster. The phase is reported in an annotation, since the status of the
+// home persistentVolumeClaims, which are not bound to any home volume, is owned by the home PV controller.
+type PersistentVolumeClaimsIncomingReflector struct {
+	ri.APIReflector
+}
+
+func (r *PersistentVolumeClaimsIncomingReflector) SetSpecializedPreProcessingHandlers() {
+	r.SetPreProcessingHandlers(ri.PreProcessingHandlers{
+		AddFunc:    r.preAdd,
+		UpdateFunc: r.preUpdate,
+		DeleteFunc: r.preDelete,
+	})
+}
+
+// HandleEvent receives the home persistentVolumeClaim annotated with the status of its
+// remote counterpart, and updates it in the home cluster
+func (r *PersistentVolumeClaimsIncomingReflector) HandleEvent(obj interface{}) {
+	event, ok := obj.(watch.Event)
+	if !ok {
+		klog.Error("cannot cast object to event")
+		return
+	}
+
+	pvc, ok := event.Object.(*corev1.PersistentVolumeClaim)
+	if !ok {
+		klog.Error("INCOMING REFLECTION: wrong type, cannot cast object to persistentVolumeClaim")
+		return
+	}
+
+	klog.V(3).Infof("INCOMING REFLECTION: received %v for persistentVolumeClaim %v/%v", event.Type, pvc.Namespace, pvc.Name)
+
+	switch event.Type {
+	case watch.Added, watch.Modified:
+		if _, err := r.GetHomeClient().CoreV1().PersistentVolumeClaims(pvc.Namespace).Update(context.TODO(), pvc, metav1.UpdateOptions{}); err != nil {
+			klog.Errorf("INCOMING REFLECTION: error while updating home persistentVolumeClaim %v/%v - ERR: %v", pvc.Namespace, pvc.Name, err)
+		} else {
+			klog.V(3).Infof("INCOMING REFLECTION: home persistentVolumeClaim %v/%v correctly updated", pvc.Namespace, pvc.Name)
+		}
+	case watch.Deleted:
+		klog.V(4).Infof("INCOMING REFLECTION: event %v for object %v/%v ignored", event.Type, pvc.Namespace, pvc.Name)
+	}
+}
+
+func (r *PersistentVolumeClaimsIncomingReflector) preAdd(obj interface{}) interface{} {
+	return r.forgeAnnotatedHomePvc(obj)
+}
+
+func (r *PersistentVolumeClaimsIncomingReflector) preUpdate(newObj, _ interface{}) interface{} {
+	return r.forgeAnnotatedHomePvc(newObj)
+}
+
+// preDelete returns always nil because the remote persistentVolumeClaims are deleted only
+// as a consequence of the deletion of the home ones
+func (r *PersistentVolumeClaimsIncomingReflector) preDelete(_ interface{}) interface{} {
+	return nil
+}
+
+// forgeAnnotatedHomePvc returns the home persistentVolumeClaim corresponding to the remote one, annotated with
+// the phase of the latter. If the annotation is already up to date, nil is returned.
+func (r *PersistentVolumeClaimsIncomingReflector) forgeAnnotatedHomePvc(obj interface{}) interface{} {
+	foreignPvc := obj.(*corev1.PersistentVolumeClaim)
+
+	homeNamespace, err := r.NattingTable().DeNatNamespace(foreignPvc.Namespace)
+	if err != nil {
+		klog.Error(err)
+		return nil
+	}
+
+	homeObj, err := r.GetCacheManager().GetHomeNamespacedObject(apimgmt.PersistentVolumeClaims, homeNamespace, foreignPvc.Name)
+	if err != nil {
+		klog.Error(err)
+		return nil
+	}
+	homePvc := homeObj.(*corev1.PersistentVolumeClaim)
+
+	if homePvc.Annotations[virtualKubelet.RemotePvcPhaseAnnotation] == string(foreignPvc.Status.Phase) {
+		return nil
+	}
+
+	homePvc = homePvc.DeepCopy()
+	metav1.SetMetaDataAnnotation(&homePvc.ObjectMeta, virtualKubelet.RemotePvcPhaseAnnotation, string(foreignPvc.Status.Phase))
+	return homePvc
+}
+
+// CleanupNamespace does nothing because the remote persistentVolumeClaims are deleted by
+// the persistentVolumeClaims outgoing reflector with its CleanupNamespace implementation.
+func (r *PersistentVolumeClaimsIncomingReflector) CleanupNamespace(_ string) {}
@@ -8,10 +8,11 @@ import (
 )
 
 var ReflectorBuilders = map[apimgmt.ApiType]func(reflector ri.APIReflector, opts map[options.OptionKey]options.Option) ri.OutgoingAPIReflector{
-	apimgmt.Configmaps:     configmapsReflectorBuilder,
-	apimgmt.EndpointSlices: endpointslicesReflectorBuilder,
-	apimgmt.Secrets:        secretsReflectorBuilder,
-	apimgmt.Services:       servicesReflectorBuilder,
+	apimgmt.Configmaps:             configmapsReflectorBuilder,
+	apimgmt.EndpointSlices:         endpointslicesReflectorBuilder,
+	apimgmt.Secrets:                secretsReflectorBuilder,
+	apimgmt.Services:               servicesReflectorBuilder,
+	apimgmt.PersistentVolumeClaims: persistentVolumeClaimsReflectorBuilder,
 }
 
 func configmapsReflectorBuilder(reflector ri.APIReflector, _ map[options.OptionKey]options.Option) ri.OutgoingAPIReflector {
@@ -33,3 +34,11 @@ func secretsReflectorBuilder(reflector ri.APIReflector, _ map[options.OptionKey]
 func servicesReflectorBuilder(reflector ri.APIReflector, _ map[options.OptionKey]options.Option) ri.OutgoingAPIReflector {
 	return &ServicesReflector{APIReflector: reflector}
 }
+
+func persistentVolumeClaimsReflectorBuilder(reflector ri.APIReflector, opts map[options.OptionKey]options.Option) ri.OutgoingAPIReflector {
+	return &PersistentVolumeClaimsReflector{
+		APIReflector:        reflector,
+		StorageClassMapping: opts[types.StorageClassMapping],
+		NodeName:            opts[types.NodeName],
+	}
+}
@@ -1,12 +1,16 @@
 package outgoing
 
 import (
+	configv1alpha1 "github.com/liqotech/liqo/apis/config/v1alpha1"
 	apimgmt "github.com/liqotech/liqo/pkg/virtualKubelet/apiReflection"
+	"k8s.io/klog"
+	"sync"
 )
 
 type blackList map[string]bool
 
-var blacklist = map[apimgmt.ApiType]blackList{
+// defaultBlacklist contains the objects which are never reflected, regardless of the configuration.
+var defaultBlacklist = map[apimgmt.ApiType]blackList{
 	apimgmt.EndpointSlices: {
 		"default/kubernetes": true,
 	},
@@ -14,3 +18,59 @@ var blacklist = map[apimgmt.ApiType]blackList{
 		"default/kubernetes": true,
 	},
 }
+
+var (
+	blacklistMutex sync.RWMutex
+	// configuredBlacklist contains the objects not to be reflected set in the ClusterConfig.
+	configuredBlacklist = map[apimgmt.ApiType]blackList{}
+	// runtimeBlacklist contains the objects excluded from the reflection because they cannot be reflected.
+	runtimeBlacklist = map[apimgmt.ApiType]blackList{}
+)
+
+// SetBlacklist configures the objects not to be reflected by the built-in reflectors, in addition to the default ones.
+func SetBlacklist(resources []configv1alpha1.BlacklistedResource) {
+	blacklist := make(map[apimgmt.ApiType]blackList)
+	for _, resource := range resources {
+		api, ok := apiTypeByName(resource.Resource)
+		if !ok {
+			klog.Warningf("resource %v is not reflected by the built-in reflectors, ignoring its blacklist", resource.Resource)
+			continue
+		}
+		if blacklist[api] == nil {
+			blacklist[api] = make(blackList)
+		}
+		for _, key := range resource.Objects {
+			blacklist[api][key] = true
+		}
+	}
+
+	blacklistMutex.Lock()
+	defer blacklistMutex.Unlock()
+	configuredBlacklist = blacklist
+}
+
+// isBlacklisted returns true if the object with the given key must not be reflected.
+func isBlacklisted(api apimgmt.ApiType, key string) bool {
+	blacklistMutex.RLock()
+	defer blacklistMutex.RUnlock()
+	return defaultBlacklist[api][key] || configuredBlacklist[api][key] || runtimeBlacklist[api][key]
+}
+
+// addToBlacklist excludes the object with the given key from the reflection.
+func addToBlacklist(api apimgmt.ApiType, key string) {
+	blacklistMutex.Lock()
+	defer blacklistMutex.Unlock()
+	if runtimeBlacklist[api] == nil {
+		runtimeBlacklist[api] = make(blackList)
+	}
+	runtimeBlacklist[api][key] = true
+}
+
+func apiTypeByName(name string) (apimgmt.ApiType, bool) {
+	for api := range ReflectorBuilders {
+		if apimgmt.ApiNames[api] == name {
+			return api, true
+		}
+	}
+	return 0, false
+}
@@ -19,6 +19,7 @@ type ConfigmapsReflector struct {
 
 func (r *ConfigmapsReflector) SetSpecializedPreProcessingHandlers() {
 	r.SetPreProcessingHandlers(ri.PreProcessingHandlers{
+		IsAllowed:  r.isAllowed,
 		AddFunc:    r.PreAdd,
 		UpdateFunc: r.PreUpdate,
 		DeleteFunc: r.PreDelete})
@@ -181,3 +182,17 @@ func (r *ConfigmapsReflector) CleanupNamespace(localNamespace string) {
 		}
 	}
 }
+
+func (r *ConfigmapsReflector) isAllowed(obj interface{}) bool {
+	cm, ok := obj.(*corev1.ConfigMap)
+	if !ok {
+		klog.Error("cannot convert obj to configmap")
+		return false
+	}
+	key := r.Keyer(cm.Namespace, cm.Name)
+	blacklisted := isBlacklisted(apimgmt.Configmaps, key)
+	if blacklisted {
+		klog.V(4).Infof("configmap %v blacklisted", key)
+	}
+	return !blacklisted
+}
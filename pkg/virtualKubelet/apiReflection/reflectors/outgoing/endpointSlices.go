@@ -98,7 +98,7 @@ func (r *EndpointSlicesReflector) PreAdd(obj interface{}) interface{} {
 	}
 	if err = retry.OnError(retry.DefaultBackoff, retriable, fn); err != nil {
 		klog.Errorf("error while retrieving service %v in endppointslices reflector - ERR: %v", key, err)
-		blacklist[apimgmt.EndpointSlices][key] = true
+		addToBlacklist(apimgmt.EndpointSlices, key)
 		return nil
 	}
 
@@ -223,9 +223,9 @@ func (r *EndpointSlicesReflector) isAllowed(obj interface{}) bool {
 		return false
 	}
 	key := r.Keyer(eps.Namespace, eps.Name)
-	_, ok = blacklist[apimgmt.EndpointSlices][key]
-	if ok {
+	blacklisted := isBlacklisted(apimgmt.EndpointSlices, key)
+	if blacklisted {
 		klog.V(4).Infof("endpointslice %v blacklisted", key)
 	}
-	return !ok
+	return !blacklisted
 }
@@ -0,0 +1,281 @@
+package outgoing
+
+import (
+	"context"
+	apimgmt "github.com/liqotech/liqo/pkg/virtualKubelet/apiReflection"
+	ri "github.com/liqotech/liqo/pkg/virtualKubelet/apiReflection/reflectors/reflectorsInterfaces"
+	"github.com/liqotech/liqo/pkg/virtualKubelet/options"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/util/retry"
+	"k8s.io/klog"
+	"strings"
+)
+
+type PersistentVolumeClaimsReflector struct {
+	ri.APIReflector
+
+	StorageClassMapping options.ReadOnlyOption
+	NodeName            options.ReadOnlyOption
+}
+
+func (r *PersistentVolumeClaimsReflector) SetSpecializedPreProcessingHandlers() {
+	r.SetPreProcessingHandlers(ri.PreProcessingHandlers{
+		IsAllowed:  r.isAllowed,
+		AddFunc:    r.PreAdd,
+		UpdateFunc: r.PreUpdate,
+		DeleteFunc: r.PreDelete})
+}
+
+func (r *PersistentVolumeClaimsReflector) HandleEvent(e interface{}) {
+	var err error
+
+	event := e.(watch.Event)
+	pvc, ok := event.Object.(*corev1.PersistentVolumeClaim)
+	if !ok {
+		klog.Error("OUTGOING REFLECTION: cannot cast object to persistentVolumeClaim")
+		return
+	}
+	klog.V(3).Infof("OUTGOING REFLECTION: received %v for persistentVolumeClaim %v/%v", event.Type, pvc.Namespace, pvc.Name)
+
+	switch event.Type {
+	case watch.Added:
+		_, err := r.GetForeignClient().CoreV1().PersistentVolumeClaims(pvc.Namespace).Create(context.TODO(), pvc, metav1.CreateOptions{})
+		if kerrors.IsAlreadyExists(err) {
+			klog.V(3).Infof("OUTGOING REFLECTION: The remote persistentVolumeClaim %v/%v has not been created: %v", pvc.Namespace, pvc.Name, err)
+			break
+		}
+
+		if err != nil {
+			klog.Errorf("OUTGOING REFLECTION: Error while creating the remote persistentVolumeClaim %v/%v - ERR: %v", pvc.Namespace, pvc.Name, err)
+		} else {
+			klog.V(3).Infof("OUTGOING REFLECTION: remote persistentVolumeClaim %v/%v correctly created", pvc.Namespace, pvc.Name)
+		}
+
+	case watch.Modified:
+		if _, err = r.GetForeignClient().CoreV1().PersistentVolumeClaims(pvc.Namespace).Update(context.TODO(), pvc, metav1.UpdateOptions{}); err != nil {
+			klog.Errorf("OUTGOING REFLECTION: Error while updating the remote persistentVolumeClaim %v/%v - ERR: %v", pvc.Namespace, pvc.Name, err)
+		} else {
+			klog.V(3).Infof("OUTGOING REFLECTION: remote persistentVolumeClaim %v/%v correctly updated", pvc.Namespace, pvc.Name)
+		}
+
+	case watch.Deleted:
+		if err := r.GetForeignClient().CoreV1().PersistentVolumeClaims(pvc.Namespace).Delete(context.TODO(), pvc.Name, metav1.DeleteOptions{}); err != nil {
+			klog.Errorf("OUTGOING REFLECTION: Error while deleting the remote persistentVolumeClaim %v/%v - ERR: %v", pvc.Namespace, pvc.Name, err)
+		} else {
+			klog.V(3).Infof("OUTGOING REFLECTION: remote persistentVolumeClaim %v/%v correctly deleted", pvc.Namespace, pvc.Name)
+		}
+	}
+}
+
+// PreAdd forges the remote persistentVolumeClaim, through ForgeForeignPersistentVolumeClaim.
+func (r *PersistentVolumeClaimsReflector) PreAdd(obj interface{}) interface{} {
+	pvcLocal := obj.(*corev1.PersistentVolumeClaim)
+	klog.V(3).Infof("PreAdd routine started for persistentVolumeClaim %v/%v", pvcLocal.Namespace, pvcLocal.Name)
+
+	nattedNs, err := r.NattingTable().NatNamespace(pvcLocal.Namespace, false)
+	if err != nil {
+		klog.Error(err)
+		return nil
+	}
+
+	pvcRemote := ForgeForeignPersistentVolumeClaim(pvcLocal, nattedNs, r.storageClassMapping())
+
+	klog.V(3).Infof("PreAdd routine completed for persistentVolumeClaim %v/%v", pvcLocal.Namespace, pvcLocal.Name)
+	return pvcRemote
+}
+
+// PreUpdate propagates the changes of labels and requested resources (i.e., volume expansion),
+// since the other fields of the persistentVolumeClaim spec are immutable.
+func (r *PersistentVolumeClaimsReflector) PreUpdate(newObj, _ interface{}) interface{} {
+	newPvc := newObj.(*corev1.PersistentVolumeClaim)
+
+	klog.V(3).Infof("PreUpdate routine started for persistentVolumeClaim %v/%v", newPvc.Namespace, newPvc.Name)
+
+	nattedNs, err := r.NattingTable().NatNamespace(newPvc.Namespace, false)
+	if err != nil {
+		klog.Error(err)
+		return nil
+	}
+
+	oldRemoteObj, err := r.GetCacheManager().GetForeignNamespacedObject(apimgmt.PersistentVolumeClaims, nattedNs, newPvc.Name)
+	if err != nil {
+		err = errors.Wrapf(err, "persistentVolumeClaim %v/%v", nattedNs, newPvc.Name)
+		klog.Error(err)
+		return nil
+	}
+	remotePvc := oldRemoteObj.(*corev1.PersistentVolumeClaim).DeepCopy()
+
+	if remotePvc.Labels == nil {
+		remotePvc.Labels = make(map[string]string)
+	}
+	for k, v := range newPvc.Labels {
+		remotePvc.Labels[k] = v
+	}
+	remotePvc.Labels[apimgmt.LiqoLabelKey] = apimgmt.LiqoLabelValue
+	remotePvc.Spec.Resources = newPvc.Spec.Resources
+
+	klog.V(3).Infof("PreUpdate routine completed for persistentVolumeClaim %v/%v", newPvc.Namespace, newPvc.Name)
+	return remotePvc
+}
+
+func (r *PersistentVolumeClaimsReflector) PreDelete(obj interface{}) interface{} {
+	pvcLocal := obj.(*corev1.PersistentVolumeClaim).DeepCopy()
+	klog.V(3).Infof("PreDelete routine started for persistentVolumeClaim %v/%v", pvcLocal.Namespace, pvcLocal.Name)
+
+	nattedNs, err := r.NattingTable().NatNamespace(pvcLocal.Namespace, false)
+	if err != nil {
+		klog.Error(err)
+		return nil
+	}
+	pvcLocal.Namespace = nattedNs
+
+	klog.V(3).Infof("PreDelete routine completed for persistentVolumeClaim %v/%v", pvcLocal.Namespace, pvcLocal.Name)
+	return pvcLocal
+}
+
+func (r *PersistentVolumeClaimsReflector) CleanupNamespace(localNamespace string) {
+	foreignNamespace, err := r.NattingTable().NatNamespace(localNamespace, false)
+	if err != nil {
+		klog.Error(err)
+		return
+	}
+
+	objects, err := r.GetCacheManager().ResyncListForeignNamespacedObject(apimgmt.PersistentVolumeClaims, foreignNamespace)
+	if err != nil {
+		klog.Error(err)
+		return
+	}
+
+	retriable := func(err error) bool {
+		switch kerrors.ReasonForError(err) {
+		case metav1.StatusReasonNotFound:
+			return false
+		default:
+			klog.Warningf("retrying while deleting persistentVolumeClaim because of- ERR; %v", err)
+			return true
+		}
+	}
+	for _, obj := range objects {
+		pvc := obj.(*corev1.PersistentVolumeClaim)
+		if err := retry.OnError(retry.DefaultBackoff, retriable, func() error {
+			return r.GetForeignClient().CoreV1().PersistentVolumeClaims(foreignNamespace).Delete(context.TODO(), pvc.Name, metav1.DeleteOptions{})
+		}); err != nil {
+			klog.Errorf("Error while deleting remote persistentVolumeClaim %v/%v", pvc.Namespace, pvc.Name)
+		}
+	}
+}
+
+// isAllowed reflects only the persistentVolumeClaims used by the pods offloaded through this virtual node,
+// and the ones already reflected, so that their updates and deletion are propagated to the foreign cluster.
+func (r *PersistentVolumeClaimsReflector) isAllowed(obj interface{}) bool {
+	pvc, ok := obj.(*corev1.PersistentVolumeClaim)
+	if !ok {
+		klog.Error("cannot convert obj to persistentVolumeClaim")
+		return false
+	}
+
+	return r.isUsedByOffloadedPods(pvc) || r.isReflected(pvc)
+}
+
+func (r *PersistentVolumeClaimsReflector) isUsedByOffloadedPods(pvc *corev1.PersistentVolumeClaim) bool {
+	if r.NodeName == nil {
+		return false
+	}
+	nodeName := string(r.NodeName.Value())
+
+	pods, err := r.GetCacheManager().ListHomeNamespacedObject(apimgmt.Pods, pvc.Namespace)
+	if err != nil {
+		klog.Error(err)
+		return false
+	}
+
+	for _, obj := range pods {
+		pod := obj.(*corev1.Pod)
+		if pod.Spec.NodeName != nodeName {
+			continue
+		}
+		for i := range pod.Spec.Volumes {
+			claim := pod.Spec.Volumes[i].PersistentVolumeClaim
+			if claim != nil && claim.ClaimName == pvc.Name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (r *PersistentVolumeClaimsReflector) isReflected(pvc *corev1.PersistentVolumeClaim) bool {
+	nattedNs, err := r.NattingTable().NatNamespace(pvc.Namespace, false)
+	if err != nil {
+		klog.Error(err)
+		return false
+	}
+
+	pvcs, err := r.GetCacheManager().ListForeignNamespacedObject(apimgmt.PersistentVolumeClaims, nattedNs)
+	if err != nil {
+		klog.Error(err)
+		return false
+	}
+
+	for _, obj := range pvcs {
+		if obj.(*corev1.PersistentVolumeClaim).Name == pvc.Name {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *PersistentVolumeClaimsReflector) storageClassMapping() string {
+	if r.StorageClassMapping == nil {
+		return ""
+	}
+	return r.StorageClassMapping.Value().ToString()
+}
+
+// ForgeForeignPersistentVolumeClaim forges the foreign counterpart of a home persistentVolumeClaim, keeping only
+// the fields that do not refer to home cluster resources (i.e., the bound volume, the volume selector and the
+// data source) and mapping the storage class according to storageClassMapping.
+func ForgeForeignPersistentVolumeClaim(homePvc *corev1.PersistentVolumeClaim, foreignNamespace, storageClassMapping string) *corev1.PersistentVolumeClaim {
+	foreignPvc := &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        homePvc.Name,
+			Namespace:   foreignNamespace,
+			Labels:      make(map[string]string),
+			Annotations: make(map[string]string),
+		},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			AccessModes:      homePvc.Spec.AccessModes,
+			Resources:        homePvc.Spec.Resources,
+			VolumeMode:       homePvc.Spec.VolumeMode,
+			StorageClassName: MapStorageClass(storageClassMapping, homePvc.Spec.StorageClassName),
+		},
+	}
+	for k, v := range homePvc.Labels {
+		foreignPvc.Labels[k] = v
+	}
+	foreignPvc.Labels[apimgmt.LiqoLabelKey] = apimgmt.LiqoLabelValue
+
+	return foreignPvc
+}
+
+// MapStorageClass returns the foreign storage class corresponding to the home one, according to
+// mapping (a comma separated list of home=foreign pairs). If the home storage class is not mapped,
+// nil is returned, so that the default storage class of the foreign cluster is used.
+func MapStorageClass(mapping string, storageClass *string) *string {
+	if storageClass == nil {
+		return nil
+	}
+
+	for _, pair := range strings.Split(mapping, ",") {
+		classes := strings.SplitN(strings.TrimSpace(pair), "=", 2)
+		if len(classes) == 2 && classes[0] == *storageClass {
+			foreignClass := classes[1]
+			return &foreignClass
+		}
+	}
+	return nil
+}
@@ -213,6 +213,11 @@ func (r *SecretsReflector) isAllowed(obj interface{}) bool {
 		klog.Error("cannot convert obj to secret")
 		return false
 	}
+	key := r.Keyer(sec.Namespace, sec.Name)
+	if isBlacklisted(apimgmt.Secrets, key) {
+		klog.V(4).Infof("secret %v blacklisted", key)
+		return false
+	}
 	// if this annotation is set, this secret will not be reflected to the remote cluster
 	val, ok := sec.Annotations["liqo.io/not-reflect"]
 	return !ok || val != "true"
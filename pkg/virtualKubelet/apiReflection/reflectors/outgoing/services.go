@@ -189,9 +189,9 @@ func (r *ServicesReflector) isAllowed(obj interface{}) bool {
 		return false
 	}
 	key := r.Keyer(svc.Namespace, svc.Name)
-	_, ok = blacklist[apimgmt.Services][key]
-	if ok {
+	blacklisted := isBlacklisted(apimgmt.Services, key)
+	if blacklisted {
 		klog.V(4).Infof("service %v blacklisted", key)
 	}
-	return !ok
+	return !blacklisted
 }
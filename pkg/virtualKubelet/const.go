@@ -6,4 +6,7 @@ const (
 	VirtualKubeletSecPrefix = "vk-kubeconfig-secret-"
 	AdvertisementPrefix     = "advertisement-"
 	ReflectedpodKey         = "virtualkubelet.liqo.io/source-pod"
+
+	// RemotePvcPhaseAnnotation is set on the home PersistentVolumeClaims to report the phase of their remote counterpart
+	RemotePvcPhaseAnnotation = "virtualkubelet.liqo.io/remote-pvc-phase"
 )
@@ -5,6 +5,7 @@ import (
 	"errors"
 	nattingv1 "github.com/liqotech/liqo/apis/virtualKubelet/v1alpha1"
 	"github.com/liqotech/liqo/pkg/crdClient"
+	"github.com/liqotech/liqo/pkg/virtualKubelet/translation"
 	v1 "k8s.io/api/core/v1"
 	kerror "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -12,12 +13,20 @@ import (
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/util/retry"
 	"k8s.io/klog"
+	"reflect"
 	"strings"
+	"sync"
 	"time"
 )
 
 var cacheResyncPeriod = 10 * time.Second
 
+const (
+	corednsNamespace = "kube-system"
+	corednsConfigMap = "coredns"
+	corefileKey      = "Corefile"
+)
+
 type namespaceNTCache struct {
 	Store            cache.Store
 	Controller       chan struct{}
@@ -32,6 +41,16 @@ type NamespaceMapper struct {
 	foreignClusterId string
 	homeClusterId    string
 
+	// the domain of the cluster, used to rewrite the names of the home services in the foreign cluster DNS.
+	// The rewriting is disabled if empty
+	clusterDomain string
+	// protects the state of the DNS rewriting, which is configured by the natting table cache and removed when unpeering
+	dnsMutex sync.Mutex
+	// the natting table the rewrite rules have been last configured for
+	dnsNattingTable map[string]string
+	// true if the rewrite rules have been removed, or the DNS of the foreign cluster cannot be configured
+	dnsRewritingStopped bool
+
 	startOutgoingReflection chan string
 	startIncomingReflection chan string
 	stopOutgoingReflection  chan string
@@ -251,4 +270,75 @@ func (m *NamespaceMapper) manageReflections(oldObj interface{}, newObj interface
 			m.stopIncomingReflection <- localNs
 		}
 	}
+
+	m.ensureDNSRewriting(newNattingTable)
+}
+
+// ensureDNSRewriting configures the DNS of the foreign cluster to resolve the names of the home services
+// to the reflected ones in the natted namespaces. If the DNS of the foreign cluster cannot be configured,
+// e.g. because it is managed by the cloud provider, the rewriting is reported and no longer attempted.
+func (m *NamespaceMapper) ensureDNSRewriting(nattingTable map[string]string) {
+	m.dnsMutex.Lock()
+	defer m.dnsMutex.Unlock()
+
+	if m.clusterDomain == "" || m.dnsRewritingStopped ||
+		(m.dnsNattingTable != nil && reflect.DeepEqual(m.dnsNattingTable, nattingTable)) {
+		return
+	}
+
+	err := m.updateCorefile(nattingTable)
+	if kerror.IsNotFound(err) || errors.Is(err, translation.ErrCorefileNotSupported) {
+		klog.Warningf("the DNS of the foreign cluster cannot be configured, the names of the home services will not be resolved by the offloaded pods - ERR: %v", err)
+		m.dnsRewritingStopped = true
+		return
+	}
+	if err != nil {
+		klog.Errorf("cannot configure the DNS rewriting of the natted namespaces in the foreign cluster - ERR: %v", err)
+		return
+	}
+
+	m.dnsNattingTable = make(map[string]string, len(nattingTable))
+	for k, v := range nattingTable {
+		m.dnsNattingTable[k] = v
+	}
+	klog.V(3).Infof("DNS rewriting of the natted namespaces configured in the foreign cluster")
+}
+
+// removeDNSRewriting removes the rewrite rules of the home cluster from the DNS of the foreign cluster,
+// which are no longer configured afterwards.
+func (m *NamespaceMapper) removeDNSRewriting() error {
+	m.dnsMutex.Lock()
+	defer m.dnsMutex.Unlock()
+
+	if m.clusterDomain == "" {
+		return nil
+	}
+	m.dnsRewritingStopped = true
+	if err := m.updateCorefile(nil); err != nil && !kerror.IsNotFound(err) {
+		return err
+	}
+	m.dnsNattingTable = nil
+	klog.V(3).Infof("DNS rewriting of the natted namespaces removed from the foreign cluster")
+	return nil
+}
+
+// updateCorefile replaces the rewrite rules of the home cluster in the Corefile of the foreign cluster
+// with the ones of the given natting table.
+func (m *NamespaceMapper) updateCorefile(nattingTable map[string]string) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		cm, err := m.foreignClient.CoreV1().ConfigMaps(corednsNamespace).Get(context.TODO(), corednsConfigMap, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		corefile, err := translation.UpdateCorefile(cm.Data[corefileKey], m.homeClusterId, m.clusterDomain, nattingTable)
+		if err != nil {
+			return err
+		}
+		if corefile == cm.Data[corefileKey] {
+			return nil
+		}
+		cm.Data[corefileKey] = corefile
+		_, err = m.foreignClient.CoreV1().ConfigMaps(corednsNamespace).Update(context.TODO(), cm, metav1.UpdateOptions{})
+		return err
+	})
 }
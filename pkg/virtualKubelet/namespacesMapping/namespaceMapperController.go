@@ -35,7 +35,10 @@ type NamespaceMapperController struct {
 	mapper *NamespaceMapper
 }
 
-func NewNamespaceMapperController(client crdClient.NamespacedCRDClientInterface, foreignClient kubernetes.Interface, homeClusterId, foreignClusterId string) (*NamespaceMapperController, error) {
+// NewNamespaceMapperController creates the controller managing the natting of the namespaces of the home cluster
+// towards the foreign one. If clusterDomain is not empty, the DNS of the foreign cluster is configured to resolve
+// the names of the home services to the reflected ones in the natted namespaces.
+func NewNamespaceMapperController(client crdClient.NamespacedCRDClientInterface, foreignClient kubernetes.Interface, homeClusterId, foreignClusterId, clusterDomain string) (*NamespaceMapperController, error) {
 	controller := &NamespaceMapperController{
 		mapper: &NamespaceMapper{
 			homeClient: client,
@@ -45,6 +48,7 @@ func NewNamespaceMapperController(client crdClient.NamespacedCRDClientInterface,
 			foreignClient:           foreignClient,
 			homeClusterId:           homeClusterId,
 			foreignClusterId:        foreignClusterId,
+			clusterDomain:           clusterDomain,
 			startOutgoingReflection: make(chan string, 100),
 			startIncomingReflection: make(chan string, 100),
 			stopIncomingReflection:  make(chan string, 100),
@@ -100,6 +104,12 @@ func (c *NamespaceMapperController) DeNatNamespace(namespace string) (string, er
 	return c.mapper.DeNatNamespace(namespace)
 }
 
+// RemoveDNSRewriting removes the configuration of the foreign cluster DNS resolving the names of the home services,
+// which is no longer updated afterwards.
+func (c *NamespaceMapperController) RemoveDNSRewriting() error {
+	return c.mapper.removeDNSRewriting()
+}
+
 func (c *NamespaceMapperController) WaitForSync() {
 	c.mapper.cache.WaitNamespaceNattingTableSync()
 }
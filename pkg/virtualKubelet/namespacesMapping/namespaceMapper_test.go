@@ -0,0 +1,67 @@
+package namespacesMapping
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"testing"
+)
+
+const testCorefile = `.:53 {
+    errors
+    kubernetes foreign.local in-addr.arpa ip6.arpa {
+       fallthrough in-addr.arpa ip6.arpa
+    }
+    forward . /etc/resolv.conf
+}`
+
+func getCorefile(t *testing.T, client *fake.Clientset) string {
+	cm, err := client.CoreV1().ConfigMaps(corednsNamespace).Get(context.TODO(), corednsConfigMap, metav1.GetOptions{})
+	assert.Nil(t, err)
+	return cm.Data[corefileKey]
+}
+
+func TestDNSRewriting(t *testing.T) {
+	client := fake.NewSimpleClientset(&v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: corednsNamespace, Name: corednsConfigMap},
+		Data:       map[string]string{corefileKey: testCorefile},
+	})
+	m := &NamespaceMapper{foreignClient: client, homeClusterId: "home", clusterDomain: "cluster.local"}
+
+	// the rules translate the home names to the foreign cluster domain
+	m.ensureDNSRewriting(map[string]string{"default": "default-home"})
+	corefile := getCorefile(t, client)
+	assert.Contains(t, corefile, "{1}.default-home.svc.foreign.local")
+	assert.Contains(t, corefile, "{1}.default.svc.cluster.local.home.liqo.local")
+
+	// the rules are removed when unpeering, and no longer configured
+	assert.Nil(t, m.removeDNSRewriting())
+	assert.Equal(t, testCorefile, getCorefile(t, client))
+	m.ensureDNSRewriting(map[string]string{"default": "default-home", "app": "app-home"})
+	assert.Equal(t, testCorefile, getCorefile(t, client))
+}
+
+func TestDNSRewritingNotSupported(t *testing.T) {
+	managed := ".:53 {\n    forward . /etc/resolv.conf\n}"
+	client := fake.NewSimpleClientset(&v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: corednsNamespace, Name: corednsConfigMap},
+		Data:       map[string]string{corefileKey: managed},
+	})
+	m := &NamespaceMapper{foreignClient: client, homeClusterId: "home", clusterDomain: "cluster.local"}
+
+	// the Corefile is left untouched, and the rewriting is no longer attempted
+	m.ensureDNSRewriting(map[string]string{"default": "default-home"})
+	assert.True(t, m.dnsRewritingStopped)
+	client.ClearActions()
+	m.ensureDNSRewriting(map[string]string{"default": "default-home", "app": "app-home"})
+	assert.Empty(t, client.Actions())
+	assert.Equal(t, managed, getCorefile(t, client))
+
+	// the same holds if the foreign cluster has no coredns ConfigMap
+	m = &NamespaceMapper{foreignClient: fake.NewSimpleClientset(), homeClusterId: "home", clusterDomain: "cluster.local"}
+	m.ensureDNSRewriting(map[string]string{"default": "default-home"})
+	assert.True(t, m.dnsRewritingStopped)
+	assert.Nil(t, m.removeDNSRewriting())
+}
@@ -0,0 +1,48 @@
+package types
+
+import (
+	"github.com/liqotech/liqo/pkg/virtualKubelet/options"
+	"sync"
+)
+
+type StorageKey string
+type StorageValue string
+
+const (
+	// StorageClassMapping is a comma separated list of home=foreign storage class pairs,
+	// used to select the storage class of the reflected PersistentVolumeClaims.
+	StorageClassMapping = "storageClassMapping"
+)
+
+func NewStorageOption(key StorageKey, value StorageValue) *StorageOption {
+	return &StorageOption{
+		key:   key,
+		value: value,
+		lock:  sync.RWMutex{},
+	}
+}
+
+type StorageOption struct {
+	key   StorageKey
+	value StorageValue
+
+	lock sync.RWMutex
+}
+
+func (o *StorageOption) Key() options.OptionKey {
+	return options.OptionKey(o.key)
+}
+
+func (o *StorageOption) Value() options.OptionValue {
+	o.lock.RLock()
+	defer o.lock.RUnlock()
+
+	return options.OptionValue(o.value)
+}
+
+func (o *StorageOption) SetValue(v options.OptionValue) {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	o.value = StorageValue(v)
+}
@@ -0,0 +1,197 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	discoveryv1alpha1 "github.com/liqotech/liqo/apis/discovery/v1alpha1"
+	v1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/retry"
+	"k8s.io/klog"
+	"strings"
+	"time"
+)
+
+// EvictionPolicy defines what happens to the pods offloaded to a foreign cluster which is no longer available.
+type EvictionPolicy string
+
+const (
+	// EvictionPolicyNone leaves the offloaded pods untouched.
+	EvictionPolicyNone EvictionPolicy = "None"
+	// EvictionPolicyFail marks the offloaded pods as Failed, so that their controllers replace them.
+	EvictionPolicyFail EvictionPolicy = "Fail"
+	// EvictionPolicyDelete deletes the offloaded pods forcefully, so that their controllers recreate them
+	// elsewhere even though the remote pods cannot be removed.
+	EvictionPolicyDelete EvictionPolicy = "Delete"
+)
+
+// Reasons of the events and of the status of the evicted pods.
+const (
+	ReasonForeignClusterUnreachable = "ForeignClusterUnreachable"
+	ReasonAdvertisementDeleted      = "AdvertisementDeleted"
+	ReasonPodsEvicted               = "PodsEvicted"
+	ReasonPodEvictionFailed         = "PodEvictionFailed"
+)
+
+// ParseEvictionPolicy converts a string to the corresponding EvictionPolicy, ignoring the case.
+func ParseEvictionPolicy(policy string) (EvictionPolicy, error) {
+	for _, p := range []EvictionPolicy{EvictionPolicyNone, EvictionPolicyFail, EvictionPolicyDelete} {
+		if strings.EqualFold(policy, string(p)) {
+			return p, nil
+		}
+	}
+	return "", fmt.Errorf("unknown eviction policy %q (allowed values: %v, %v, %v)",
+		policy, EvictionPolicyNone, EvictionPolicyFail, EvictionPolicyDelete)
+}
+
+// checkForeignUnreachability evicts the offloaded pods once the foreign API server has been unreachable
+// for longer than the eviction grace period. The pods are evicted only once per outage.
+func (p *KubernetesProvider) checkForeignUnreachability(apiServerErr error, now time.Time) {
+	if apiServerErr == nil {
+		p.foreignUnreachableSince = time.Time{}
+		p.podsEvicted = false
+		return
+	}
+
+	if p.foreignUnreachableSince.IsZero() {
+		p.foreignUnreachableSince = now
+	}
+	if p.podsEvicted || now.Sub(p.foreignUnreachableSince) < p.evictionGracePeriod {
+		return
+	}
+
+	p.podsEvicted = true
+	message := fmt.Sprintf("the foreign cluster %v has been unreachable for more than %v", p.foreignClusterId, p.evictionGracePeriod)
+	p.evictOffloadedPods(ReasonForeignClusterUnreachable, message)
+}
+
+// evictOffloadedPods applies the eviction policy to the pods scheduled on the virtual node, recording
+// the outcome on the pods and on the ForeignCluster they were offloaded to.
+func (p *KubernetesProvider) evictOffloadedPods(reason, message string) {
+	if p.evictionPolicy == EvictionPolicyNone {
+		klog.Infof("%v: eviction policy is %v, offloaded pods left untouched", message, EvictionPolicyNone)
+		return
+	}
+
+	evicted, err := evictPods(context.TODO(), p.homeClient.Client(), p.nodeName.Value().ToString(), p.evictionPolicy, reason, message)
+	for _, pod := range evicted {
+		p.recorder.Eventf(pod, v1.EventTypeWarning, reason, "%v: pod evicted (policy %v)", message, p.evictionPolicy)
+	}
+
+	eventReason := ReasonPodsEvicted
+	eventMessage := fmt.Sprintf("%v: %d offloaded pods evicted (policy %v)", message, len(evicted), p.evictionPolicy)
+	if err != nil {
+		klog.Errorf("error while evicting the pods offloaded to foreign cluster %v - ERR: %v", p.foreignClusterId, err)
+		eventReason = ReasonPodEvictionFailed
+		eventMessage = fmt.Sprintf("%v, %v", eventMessage, err)
+	}
+	klog.Info(eventMessage)
+
+	fc, err := p.getForeignCluster()
+	if err != nil {
+		klog.Errorf("unable to record eviction event - ERR: %v", err)
+		return
+	}
+	p.recorder.Event(fc, v1.EventTypeWarning, eventReason, eventMessage)
+}
+
+// getForeignCluster returns the ForeignCluster resource related to the foreign cluster of this virtual node.
+func (p *KubernetesProvider) getForeignCluster() (*discoveryv1alpha1.ForeignCluster, error) {
+	tmp, err := p.discoveryClient.Resource("foreignclusters").List(metav1.ListOptions{
+		LabelSelector: strings.Join([]string{"cluster-id", p.foreignClusterId}, "="),
+	})
+	if err != nil {
+		return nil, err
+	}
+	fcList, ok := tmp.(*discoveryv1alpha1.ForeignClusterList)
+	if !ok {
+		return nil, errors.New("retrieved object is not a ForeignClusterList")
+	}
+	if len(fcList.Items) == 0 {
+		return nil, fmt.Errorf("ForeignCluster not found for cluster id %v", p.foreignClusterId)
+	}
+	return &fcList.Items[0], nil
+}
+
+// evictPods applies the eviction policy to the pods scheduled on the given node, returning the evicted ones.
+// DaemonSet pods are skipped, since they are never offloaded, and so are the terminated pods with the Fail policy.
+func evictPods(ctx context.Context, client kubernetes.Interface, nodeName string, policy EvictionPolicy, reason, message string) ([]*v1.Pod, error) {
+	pods, err := client.CoreV1().Pods(v1.NamespaceAll).List(ctx, metav1.ListOptions{
+		FieldSelector: fields.OneTermEqualSelector("spec.nodeName", nodeName).String(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var evicted []*v1.Pod
+	var errs []string
+	var gracePeriod int64
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if isDaemonSetPod(pod) {
+			continue
+		}
+
+		switch policy {
+		case EvictionPolicyDelete:
+			// a forced deletion, since the virtual kubelet cannot remove the remote pod while the foreign cluster
+			// is unavailable, and a graceful one would leave the home pod terminating until it comes back
+			err = client.CoreV1().Pods(pod.Namespace).Delete(ctx, pod.Name, metav1.DeleteOptions{GracePeriodSeconds: &gracePeriod})
+		case EvictionPolicyFail:
+			if pod.Status.Phase == v1.PodFailed || pod.Status.Phase == v1.PodSucceeded {
+				continue
+			}
+			err = failPod(ctx, client, pod, reason, message)
+		default:
+			return nil, fmt.Errorf("unsupported eviction policy %q", policy)
+		}
+
+		if err != nil && !kerrors.IsNotFound(err) {
+			errs = append(errs, fmt.Sprintf("pod %v/%v: %v", pod.Namespace, pod.Name, err))
+			continue
+		}
+		evicted = append(evicted, pod)
+	}
+
+	if len(errs) > 0 {
+		return evicted, fmt.Errorf("unable to evict %d pods: %v", len(errs), strings.Join(errs, "; "))
+	}
+	return evicted, nil
+}
+
+// failPod sets the phase of the pod to Failed, with the given reason and message.
+func failPod(ctx context.Context, client kubernetes.Interface, pod *v1.Pod, reason, message string) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		current, err := client.CoreV1().Pods(pod.Namespace).Get(ctx, pod.Name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+
+		current.Status.Phase = v1.PodFailed
+		current.Status.Reason = reason
+		current.Status.Message = message
+		for i := range current.Status.Conditions {
+			if current.Status.Conditions[i].Type == v1.PodReady || current.Status.Conditions[i].Type == v1.ContainersReady {
+				current.Status.Conditions[i].Status = v1.ConditionFalse
+				current.Status.Conditions[i].Reason = reason
+				current.Status.Conditions[i].LastTransitionTime = metav1.Now()
+			}
+		}
+
+		_, err = client.CoreV1().Pods(pod.Namespace).UpdateStatus(ctx, current, metav1.UpdateOptions{})
+		return err
+	})
+}
+
+func isDaemonSetPod(pod *v1.Pod) bool {
+	for _, owner := range pod.OwnerReferences {
+		if owner.Kind == "DaemonSet" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,166 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	advtypes "github.com/liqotech/liqo/apis/sharing/v1alpha1"
+	advertisementOperator "github.com/liqotech/liqo/internal/advertisement-operator"
+	"github.com/liqotech/liqo/pkg/crdClient"
+	optTypes "github.com/liqotech/liqo/pkg/virtualKubelet/options/types"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/rest"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+	"testing"
+	"time"
+)
+
+func forgeHomePod(name string, phase v1.PodPhase, owner string) *v1.Pod {
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: homeNamespace},
+		Spec:       v1.PodSpec{NodeName: "virtual-node"},
+		Status: v1.PodStatus{
+			Phase:      phase,
+			Conditions: []v1.PodCondition{{Type: v1.PodReady, Status: v1.ConditionTrue}},
+		},
+	}
+	if owner != "" {
+		pod.OwnerReferences = []metav1.OwnerReference{{Kind: owner, Name: "owner"}}
+	}
+	return pod
+}
+
+// newEvictionClient returns a fake clientset with some pods on the virtual node
+// (the fake clientset ignores field selectors, hence no pods on other nodes are added).
+func newEvictionClient() *fake.Clientset {
+	return fake.NewSimpleClientset(
+		forgeHomePod("running", v1.PodRunning, "ReplicaSet"),
+		forgeHomePod("succeeded", v1.PodSucceeded, ""),
+		forgeHomePod("daemonset", v1.PodRunning, "DaemonSet"),
+	)
+}
+
+func TestParseEvictionPolicy(t *testing.T) {
+	for _, policy := range []string{"None", "fail", "DELETE"} {
+		_, err := ParseEvictionPolicy(policy)
+		assert.NoError(t, err)
+	}
+	_, err := ParseEvictionPolicy("Reschedule")
+	assert.Error(t, err)
+}
+
+func TestEvictPodsDelete(t *testing.T) {
+	client := newEvictionClient()
+
+	evicted, err := evictPods(context.TODO(), client, "virtual-node", EvictionPolicyDelete, ReasonForeignClusterUnreachable, "unreachable")
+	assert.NoError(t, err)
+	assert.Len(t, evicted, 2)
+
+	pods, err := client.CoreV1().Pods(homeNamespace).List(context.TODO(), metav1.ListOptions{})
+	assert.NoError(t, err)
+	assert.Len(t, pods.Items, 1)
+	assert.Equal(t, "daemonset", pods.Items[0].Name)
+}
+
+func TestEvictPodsFail(t *testing.T) {
+	client := newEvictionClient()
+
+	evicted, err := evictPods(context.TODO(), client, "virtual-node", EvictionPolicyFail, ReasonForeignClusterUnreachable, "unreachable")
+	assert.NoError(t, err)
+	assert.Len(t, evicted, 1)
+
+	pod, err := client.CoreV1().Pods(homeNamespace).Get(context.TODO(), "running", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, v1.PodFailed, pod.Status.Phase)
+	assert.Equal(t, ReasonForeignClusterUnreachable, pod.Status.Reason)
+	assert.Equal(t, v1.ConditionFalse, pod.Status.Conditions[0].Status)
+
+	pod, err = client.CoreV1().Pods(homeNamespace).Get(context.TODO(), "succeeded", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, v1.PodSucceeded, pod.Status.Phase)
+}
+
+func TestEvictPodsError(t *testing.T) {
+	client := newEvictionClient()
+	client.PrependReactor("delete", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, kerrors.NewInternalError(errors.New("etcd unavailable"))
+	})
+
+	evicted, err := evictPods(context.TODO(), client, "virtual-node", EvictionPolicyDelete, ReasonForeignClusterUnreachable, "unreachable")
+	assert.Error(t, err)
+	assert.Empty(t, evicted)
+}
+
+func TestCheckForeignUnreachability(t *testing.T) {
+	p := &KubernetesProvider{evictionPolicy: EvictionPolicyNone, evictionGracePeriod: time.Minute}
+	start := time.Now()
+	unreachable := errors.New("connection refused")
+
+	p.checkForeignUnreachability(unreachable, start)
+	assert.Equal(t, start, p.foreignUnreachableSince)
+	assert.False(t, p.podsEvicted)
+
+	p.checkForeignUnreachability(unreachable, start.Add(30*time.Second))
+	assert.False(t, p.podsEvicted)
+
+	p.checkForeignUnreachability(unreachable, start.Add(time.Minute))
+	assert.True(t, p.podsEvicted)
+
+	p.checkForeignUnreachability(nil, start.Add(2*time.Minute))
+	assert.True(t, p.foreignUnreachableSince.IsZero())
+	assert.False(t, p.podsEvicted)
+}
+
+func TestReleaseAdvertisement(t *testing.T) {
+	crdClient.Fake = true
+	defer func() { crdClient.Fake = false }()
+	crdClient.AddToRegistry("advertisements", &advtypes.Advertisement{}, &advtypes.AdvertisementList{}, advtypes.Keyer, advtypes.GroupResource)
+
+	newClient := func(resource string) *crdClient.CRDClient {
+		client, err := crdClient.NewFromConfig(&rest.Config{ContentConfig: rest.ContentConfig{GroupVersion: &advtypes.GroupVersion}})
+		assert.NoError(t, err)
+		client.Store, _, err = crdClient.WatchfakeResources(resource, cache.ResourceEventHandlerFuncs{})
+		assert.NoError(t, err)
+		return client
+	}
+
+	homeClient := newClient("advertisements")
+	for _, pod := range []*v1.Pod{forgeHomePod("running", v1.PodRunning, "ReplicaSet"), forgeHomePod("daemonset", v1.PodRunning, "DaemonSet")} {
+		_, err := homeClient.Client().CoreV1().Pods(homeNamespace).Create(context.TODO(), pod, metav1.CreateOptions{})
+		assert.NoError(t, err)
+	}
+	advClient := newClient("advertisements")
+	adv := &advtypes.Advertisement{ObjectMeta: metav1.ObjectMeta{
+		Name:       "advertisement-foreign-cluster",
+		Finalizers: []string{advertisementOperator.FinalizerString},
+	}}
+	_, err := advClient.Resource("advertisements").Create(adv, metav1.CreateOptions{})
+	assert.NoError(t, err)
+
+	p := &KubernetesProvider{
+		homeClient:       homeClient,
+		advClient:        advClient,
+		discoveryClient:  newClient("foreignclusters"),
+		recorder:         record.NewFakeRecorder(10),
+		nodeName:         optTypes.NewNetworkingOption(optTypes.NodeName, "virtual-node"),
+		foreignClusterId: "foreign-cluster",
+		evictionPolicy:   EvictionPolicyDelete,
+	}
+
+	// the offloaded pods are evicted when the advertisement is deleted, before the virtual kubelet goes away
+	assert.NoError(t, p.releaseAdvertisement(adv.DeepCopy()))
+	pods, err := homeClient.Client().CoreV1().Pods(homeNamespace).List(context.TODO(), metav1.ListOptions{})
+	assert.NoError(t, err)
+	assert.Len(t, pods.Items, 1)
+	assert.Equal(t, "daemonset", pods.Items[0].Name)
+
+	obj, err := advClient.Resource("advertisements").Get(adv.Name, metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Empty(t, obj.(*advtypes.Advertisement).Finalizers)
+}
@@ -0,0 +1,237 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	nettypes "github.com/liqotech/liqo/apis/net/v1alpha1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog"
+	"time"
+)
+
+const (
+	// foreignHealthCheckPeriod is the period between two consecutive checks of the foreign cluster health.
+	foreignHealthCheckPeriod = 10 * time.Second
+	// virtualNodeSelector excludes the virtual nodes of the foreign cluster from the aggregated conditions.
+	virtualNodeSelector = "type!=virtual-node"
+)
+
+// Reasons of the virtual node conditions.
+const (
+	ReasonForeignClusterReady         = "ForeignClusterReady"
+	ReasonForeignAPIServerUnreachable = "ForeignAPIServerUnreachable"
+	ReasonNoReadyForeignNodes         = "NoReadyForeignNodes"
+	ReasonResourcesNotAdvertised      = "ResourcesNotAdvertised"
+	ReasonTunnelEndpointMissing       = "TunnelEndpointMissing"
+	ReasonTunnelNotReady              = "TunnelNotReady"
+	ReasonTunnelDisconnected          = "TunnelDisconnected"
+	ReasonTunnelReady                 = "TunnelReady"
+	ReasonForeignNodesUnderPressure   = "ForeignNodesUnderPressure"
+	ReasonForeignNodesNoPressure      = "ForeignNodesNotUnderPressure"
+)
+
+// foreignClusterStatus summarizes the health of the foreign cluster, from which the virtual node conditions are derived.
+type foreignClusterStatus struct {
+	// apiServerErr is the error returned while contacting the foreign API server, nil if it is reachable.
+	apiServerErr error
+	// nodes are the physical nodes of the foreign cluster, as seen by the last successful check.
+	nodes []v1.Node
+	// tunnelEndpoint is the TunnelEndpoint towards the foreign cluster, nil if it does not exist.
+	tunnelEndpoint *nettypes.TunnelEndpoint
+	// podCIDR is the (possibly remapped) pod CIDR of the foreign cluster.
+	podCIDR string
+	// resourcesAdvertised is true if the node resources have been set from the advertisement.
+	resourcesAdvertised bool
+}
+
+// checkForeignCluster verifies that the foreign API server is reachable and retrieves its physical nodes,
+// storing the results to be used for the next update of the node conditions.
+func (p *KubernetesProvider) checkForeignCluster() {
+	client := p.foreignClient.Client()
+
+	var nodes []v1.Node
+	_, err := client.Discovery().ServerVersion()
+	if err == nil {
+		var nodeList *v1.NodeList
+		if nodeList, err = client.CoreV1().Nodes().List(context.TODO(), metav1.ListOptions{LabelSelector: virtualNodeSelector}); err == nil {
+			nodes = nodeList.Items
+		}
+	}
+	if err != nil {
+		klog.Warningf("unable to check the health of foreign cluster %v - ERR: %v", p.foreignClusterId, err)
+	}
+
+	p.foreignStatusMutex.Lock()
+	p.foreignAPIServerErr = err
+	if err == nil {
+		p.foreignNodes = nodes
+	}
+	p.foreignStatusMutex.Unlock()
+
+	p.checkForeignUnreachability(err, time.Now())
+}
+
+// setTunnelEndpoint stores the TunnelEndpoint towards the foreign cluster, nil if it has been deleted.
+func (p *KubernetesProvider) setTunnelEndpoint(tep *nettypes.TunnelEndpoint) {
+	p.foreignStatusMutex.Lock()
+	defer p.foreignStatusMutex.Unlock()
+	p.tunnelEndpoint = tep
+}
+
+// getForeignClusterStatus returns a snapshot of the foreign cluster status.
+func (p *KubernetesProvider) getForeignClusterStatus(resourcesAdvertised bool) *foreignClusterStatus {
+	p.foreignStatusMutex.Lock()
+	defer p.foreignStatusMutex.Unlock()
+
+	return &foreignClusterStatus{
+		apiServerErr:        p.foreignAPIServerErr,
+		nodes:               p.foreignNodes,
+		tunnelEndpoint:      p.tunnelEndpoint,
+		podCIDR:             p.RemoteRemappedPodCidr.Value().ToString(),
+		resourcesAdvertised: resourcesAdvertised,
+	}
+}
+
+// forgeNodeConditions derives the virtual node conditions from the status of the foreign cluster.
+// The last transition time of the conditions whose status did not change is preserved from the previous ones.
+func forgeNodeConditions(status *foreignClusterStatus, previous []v1.NodeCondition, now metav1.Time) []v1.NodeCondition {
+	conditions := []v1.NodeCondition{
+		readyCondition(status),
+		pressureCondition(status, v1.NodeMemoryPressure),
+		pressureCondition(status, v1.NodeDiskPressure),
+		pressureCondition(status, v1.NodePIDPressure),
+		networkUnavailableCondition(status),
+	}
+
+	for i := range conditions {
+		conditions[i].LastHeartbeatTime = now
+		conditions[i].LastTransitionTime = now
+		for j := range previous {
+			if previous[j].Type == conditions[i].Type && previous[j].Status == conditions[i].Status &&
+				!previous[j].LastTransitionTime.IsZero() {
+				conditions[i].LastTransitionTime = previous[j].LastTransitionTime
+			}
+		}
+	}
+	return conditions
+}
+
+func readyCondition(status *foreignClusterStatus) v1.NodeCondition {
+	condition := v1.NodeCondition{Type: v1.NodeReady, Status: v1.ConditionFalse}
+
+	readyNodes := countNodesWithCondition(status.nodes, v1.NodeReady)
+	tunnelReady, tunnelReason, tunnelMessage := tunnelStatus(status)
+	switch {
+	case status.apiServerErr != nil:
+		condition.Reason = ReasonForeignAPIServerUnreachable
+		condition.Message = fmt.Sprintf("the foreign API server is not reachable: %v", status.apiServerErr)
+	case !tunnelReady:
+		condition.Reason = tunnelReason
+		condition.Message = tunnelMessage
+	case !status.resourcesAdvertised:
+		condition.Reason = ReasonResourcesNotAdvertised
+		condition.Message = "the resources of the foreign cluster have not been advertised yet"
+	case readyNodes == 0:
+		condition.Reason = ReasonNoReadyForeignNodes
+		condition.Message = "no node of the foreign cluster is ready"
+	default:
+		condition.Status = v1.ConditionTrue
+		condition.Reason = ReasonForeignClusterReady
+		condition.Message = fmt.Sprintf("%d/%d foreign nodes are ready", readyNodes, len(status.nodes))
+	}
+	return condition
+}
+
+// pressureCondition aggregates a pressure condition of the foreign nodes: the virtual node is considered
+// under pressure only if all the ready foreign nodes are, since pods can still be scheduled on the others.
+func pressureCondition(status *foreignClusterStatus, conditionType v1.NodeConditionType) v1.NodeCondition {
+	condition := v1.NodeCondition{Type: conditionType}
+
+	if status.apiServerErr != nil {
+		condition.Status = v1.ConditionUnknown
+		condition.Reason = ReasonForeignAPIServerUnreachable
+		condition.Message = "the foreign API server is not reachable"
+		return condition
+	}
+
+	var readyNodes []v1.Node
+	for i := range status.nodes {
+		if nodeConditionIsTrue(&status.nodes[i], v1.NodeReady) {
+			readyNodes = append(readyNodes, status.nodes[i])
+		}
+	}
+	underPressure := countNodesWithCondition(readyNodes, conditionType)
+
+	condition.Message = fmt.Sprintf("%d/%d ready foreign nodes report %v", underPressure, len(readyNodes), conditionType)
+	if len(readyNodes) > 0 && underPressure == len(readyNodes) {
+		condition.Status = v1.ConditionTrue
+		condition.Reason = ReasonForeignNodesUnderPressure
+	} else {
+		condition.Status = v1.ConditionFalse
+		condition.Reason = ReasonForeignNodesNoPressure
+	}
+	return condition
+}
+
+func networkUnavailableCondition(status *foreignClusterStatus) v1.NodeCondition {
+	condition := v1.NodeCondition{Type: v1.NodeNetworkUnavailable, Status: v1.ConditionTrue}
+
+	ready, reason, message := tunnelStatus(status)
+	if ready {
+		condition.Status = v1.ConditionFalse
+	}
+	condition.Reason = reason
+	condition.Message = message
+	return condition
+}
+
+// tunnelStatus returns whether the tunnel towards the foreign cluster is ready, along with the reason and a message.
+// The tunnel is ready once its interface has been installed and the remote gateway answers to the probes sent through it.
+func tunnelStatus(status *foreignClusterStatus) (ready bool, reason, message string) {
+	if status.tunnelEndpoint == nil {
+		return false, ReasonTunnelEndpointMissing, "the tunnelEndpoint towards the foreign cluster does not exist"
+	}
+	if status.tunnelEndpoint.Status.TunnelIFaceName == "" {
+		return false, ReasonTunnelNotReady, "the tunnel towards the foreign cluster has not been installed yet"
+	}
+	connected := getTunnelCondition(status.tunnelEndpoint, nettypes.TunnelConnected)
+	switch {
+	case connected == nil:
+		return false, ReasonTunnelNotReady, "the tunnel towards the foreign cluster has not been probed yet"
+	case connected.Status != v1.ConditionTrue:
+		return false, ReasonTunnelDisconnected, fmt.Sprintf("the tunnel towards the foreign cluster is not connected: %s", connected.Message)
+	case status.podCIDR == "":
+		return false, ReasonTunnelNotReady, "the pod CIDR of the foreign cluster has not been set yet"
+	default:
+		return true, ReasonTunnelReady, "the tunnel towards the foreign cluster is ready"
+	}
+}
+
+func getTunnelCondition(tep *nettypes.TunnelEndpoint, conditionType nettypes.TunnelConditionType) *nettypes.TunnelCondition {
+	for i := range tep.Status.Conditions {
+		if tep.Status.Conditions[i].Type == conditionType {
+			return &tep.Status.Conditions[i]
+		}
+	}
+	return nil
+}
+
+func countNodesWithCondition(nodes []v1.Node, conditionType v1.NodeConditionType) int {
+	count := 0
+	for i := range nodes {
+		if nodeConditionIsTrue(&nodes[i], conditionType) {
+			count++
+		}
+	}
+	return count
+}
+
+func nodeConditionIsTrue(node *v1.Node, conditionType v1.NodeConditionType) bool {
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == conditionType {
+			return condition.Status == v1.ConditionTrue
+		}
+	}
+	return false
+}
@@ -0,0 +1,230 @@
+package provider
+
+import (
+	"errors"
+	nettypes "github.com/liqotech/liqo/apis/net/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"testing"
+	"time"
+)
+
+func forgeForeignNode(ready, memoryPressure bool) v1.Node {
+	toStatus := func(b bool) v1.ConditionStatus {
+		if b {
+			return v1.ConditionTrue
+		}
+		return v1.ConditionFalse
+	}
+	return v1.Node{
+		Status: v1.NodeStatus{
+			Conditions: []v1.NodeCondition{
+				{Type: v1.NodeReady, Status: toStatus(ready)},
+				{Type: v1.NodeMemoryPressure, Status: toStatus(memoryPressure)},
+				{Type: v1.NodeDiskPressure, Status: v1.ConditionFalse},
+			},
+		},
+	}
+}
+
+func forgeTunnelEndpoint(iface string, connected v1.ConditionStatus) *nettypes.TunnelEndpoint {
+	tep := &nettypes.TunnelEndpoint{Status: nettypes.TunnelEndpointStatus{Phase: "Ready", TunnelIFaceName: iface}}
+	if connected != "" {
+		tep.Status.Conditions = []nettypes.TunnelCondition{{Type: nettypes.TunnelConnected, Status: connected, Message: "no answer"}}
+	}
+	return tep
+}
+
+func getCondition(conditions []v1.NodeCondition, conditionType v1.NodeConditionType) v1.NodeCondition {
+	for _, condition := range conditions {
+		if condition.Type == conditionType {
+			return condition
+		}
+	}
+	return v1.NodeCondition{}
+}
+
+func TestForgeNodeConditions(t *testing.T) {
+	healthy := func() *foreignClusterStatus {
+		return &foreignClusterStatus{
+			nodes:               []v1.Node{forgeForeignNode(true, false), forgeForeignNode(true, false)},
+			tunnelEndpoint:      forgeTunnelEndpoint("liqo.tunnel", v1.ConditionTrue),
+			podCIDR:             "10.0.0.0/16",
+			resourcesAdvertised: true,
+		}
+	}
+
+	testCases := []struct {
+		name                       string
+		status                     func() *foreignClusterStatus
+		expectedReady              v1.ConditionStatus
+		expectedReadyReason        string
+		expectedMemoryPressure     v1.ConditionStatus
+		expectedNetworkUnavailable v1.ConditionStatus
+		expectedNetworkReason      string
+	}{
+		{
+			name:                       "healthy foreign cluster",
+			status:                     healthy,
+			expectedReady:              v1.ConditionTrue,
+			expectedReadyReason:        ReasonForeignClusterReady,
+			expectedMemoryPressure:     v1.ConditionFalse,
+			expectedNetworkUnavailable: v1.ConditionFalse,
+			expectedNetworkReason:      ReasonTunnelReady,
+		},
+		{
+			name: "API server unreachable",
+			status: func() *foreignClusterStatus {
+				status := healthy()
+				status.apiServerErr = errors.New("connection refused")
+				return status
+			},
+			expectedReady:              v1.ConditionFalse,
+			expectedReadyReason:        ReasonForeignAPIServerUnreachable,
+			expectedMemoryPressure:     v1.ConditionUnknown,
+			expectedNetworkUnavailable: v1.ConditionFalse,
+			expectedNetworkReason:      ReasonTunnelReady,
+		},
+		{
+			name: "tunnel endpoint missing",
+			status: func() *foreignClusterStatus {
+				status := healthy()
+				status.tunnelEndpoint = nil
+				return status
+			},
+			expectedReady:              v1.ConditionFalse,
+			expectedReadyReason:        ReasonTunnelEndpointMissing,
+			expectedMemoryPressure:     v1.ConditionFalse,
+			expectedNetworkUnavailable: v1.ConditionTrue,
+			expectedNetworkReason:      ReasonTunnelEndpointMissing,
+		},
+		{
+			name: "tunnel not installed",
+			status: func() *foreignClusterStatus {
+				status := healthy()
+				status.tunnelEndpoint = forgeTunnelEndpoint("", "")
+				return status
+			},
+			expectedReady:              v1.ConditionFalse,
+			expectedReadyReason:        ReasonTunnelNotReady,
+			expectedMemoryPressure:     v1.ConditionFalse,
+			expectedNetworkUnavailable: v1.ConditionTrue,
+			expectedNetworkReason:      ReasonTunnelNotReady,
+		},
+		{
+			name: "tunnel not probed yet",
+			status: func() *foreignClusterStatus {
+				status := healthy()
+				status.tunnelEndpoint = forgeTunnelEndpoint("liqo.tunnel", "")
+				return status
+			},
+			expectedReady:              v1.ConditionFalse,
+			expectedReadyReason:        ReasonTunnelNotReady,
+			expectedMemoryPressure:     v1.ConditionFalse,
+			expectedNetworkUnavailable: v1.ConditionTrue,
+			expectedNetworkReason:      ReasonTunnelNotReady,
+		},
+		{
+			name: "tunnel disconnected",
+			status: func() *foreignClusterStatus {
+				status := healthy()
+				status.tunnelEndpoint = forgeTunnelEndpoint("liqo.tunnel", v1.ConditionFalse)
+				return status
+			},
+			expectedReady:              v1.ConditionFalse,
+			expectedReadyReason:        ReasonTunnelDisconnected,
+			expectedMemoryPressure:     v1.ConditionFalse,
+			expectedNetworkUnavailable: v1.ConditionTrue,
+			expectedNetworkReason:      ReasonTunnelDisconnected,
+		},
+		{
+			name: "resources not advertised",
+			status: func() *foreignClusterStatus {
+				status := healthy()
+				status.resourcesAdvertised = false
+				return status
+			},
+			expectedReady:              v1.ConditionFalse,
+			expectedReadyReason:        ReasonResourcesNotAdvertised,
+			expectedMemoryPressure:     v1.ConditionFalse,
+			expectedNetworkUnavailable: v1.ConditionFalse,
+			expectedNetworkReason:      ReasonTunnelReady,
+		},
+		{
+			name: "no ready foreign nodes",
+			status: func() *foreignClusterStatus {
+				status := healthy()
+				status.nodes = []v1.Node{forgeForeignNode(false, true)}
+				return status
+			},
+			expectedReady:              v1.ConditionFalse,
+			expectedReadyReason:        ReasonNoReadyForeignNodes,
+			expectedMemoryPressure:     v1.ConditionFalse,
+			expectedNetworkUnavailable: v1.ConditionFalse,
+			expectedNetworkReason:      ReasonTunnelReady,
+		},
+		{
+			name: "some ready nodes under memory pressure",
+			status: func() *foreignClusterStatus {
+				status := healthy()
+				status.nodes = []v1.Node{forgeForeignNode(true, true), forgeForeignNode(true, false)}
+				return status
+			},
+			expectedReady:              v1.ConditionTrue,
+			expectedReadyReason:        ReasonForeignClusterReady,
+			expectedMemoryPressure:     v1.ConditionFalse,
+			expectedNetworkUnavailable: v1.ConditionFalse,
+			expectedNetworkReason:      ReasonTunnelReady,
+		},
+		{
+			name: "all ready nodes under memory pressure",
+			status: func() *foreignClusterStatus {
+				status := healthy()
+				status.nodes = []v1.Node{forgeForeignNode(true, true), forgeForeignNode(false, false)}
+				return status
+			},
+			expectedReady:              v1.ConditionTrue,
+			expectedReadyReason:        ReasonForeignClusterReady,
+			expectedMemoryPressure:     v1.ConditionTrue,
+			expectedNetworkUnavailable: v1.ConditionFalse,
+			expectedNetworkReason:      ReasonTunnelReady,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			conditions := forgeNodeConditions(tc.status(), nil, metav1.Now())
+
+			ready := getCondition(conditions, v1.NodeReady)
+			assert.Equal(t, tc.expectedReady, ready.Status)
+			assert.Equal(t, tc.expectedReadyReason, ready.Reason)
+			assert.Equal(t, tc.expectedMemoryPressure, getCondition(conditions, v1.NodeMemoryPressure).Status)
+			network := getCondition(conditions, v1.NodeNetworkUnavailable)
+			assert.Equal(t, tc.expectedNetworkUnavailable, network.Status)
+			assert.Equal(t, tc.expectedNetworkReason, network.Reason)
+		})
+	}
+}
+
+func TestForgeNodeConditionsTransitionTime(t *testing.T) {
+	before := metav1.NewTime(time.Now().Add(-time.Hour))
+	now := metav1.Now()
+	previous := []v1.NodeCondition{
+		{Type: v1.NodeReady, Status: v1.ConditionTrue, LastTransitionTime: before},
+		{Type: v1.NodeNetworkUnavailable, Status: v1.ConditionTrue, LastTransitionTime: before},
+	}
+
+	status := &foreignClusterStatus{
+		nodes:               []v1.Node{forgeForeignNode(true, false)},
+		tunnelEndpoint:      forgeTunnelEndpoint("liqo.tunnel", v1.ConditionTrue),
+		podCIDR:             "10.0.0.0/16",
+		resourcesAdvertised: true,
+	}
+	conditions := forgeNodeConditions(status, previous, now)
+
+	ready := getCondition(conditions, v1.NodeReady)
+	assert.Equal(t, before, ready.LastTransitionTime)
+	assert.Equal(t, now, ready.LastHeartbeatTime)
+	assert.Equal(t, now, getCondition(conditions, v1.NodeNetworkUnavailable).LastTransitionTime)
+}
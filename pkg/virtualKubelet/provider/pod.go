@@ -7,6 +7,7 @@ import (
 	"github.com/liqotech/liqo/internal/utils/trace"
 	"github.com/liqotech/liqo/internal/virtualKubelet/node/api"
 	apimgmgt "github.com/liqotech/liqo/pkg/virtualKubelet/apiReflection"
+	"github.com/liqotech/liqo/pkg/virtualKubelet/apiReflection/reflectors/outgoing"
 	"github.com/liqotech/liqo/pkg/virtualKubelet/translation"
 	"github.com/liqotech/liqo/pkg/virtualKubelet/translation/serviceEnv"
 	"github.com/pkg/errors"
@@ -15,11 +16,12 @@ import (
 	kerror "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/remotecommand"
+	"k8s.io/client-go/util/retry"
 	"k8s.io/klog"
 	stats "k8s.io/kubernetes/pkg/kubelet/apis/stats/v1alpha1"
-	"math/rand"
-	"time"
+	"strings"
 )
 
 // CreatePod accepts a Pod definition and stores it in memory.
@@ -43,6 +45,7 @@ func (p *KubernetesProvider) CreatePod(ctx context.Context, pod *v1.Pod) error {
 	}
 
 	podTranslated := translation.H2FTranslate(pod, nattedNS)
+	podTranslated = translation.TranslateDNSConfig(podTranslated, pod, p.dnsSearchDomain)
 
 	apiController, err := p.GetApiController()
 	if err != nil {
@@ -53,17 +56,124 @@ func (p *KubernetesProvider) CreatePod(ctx context.Context, pod *v1.Pod) error {
 		return err
 	}
 
-	_, err = p.foreignClient.Client().CoreV1().Pods(podTranslated.Namespace).Create(context.TODO(), podTranslated, metav1.CreateOptions{})
+	if err = p.reflectPersistentVolumeClaims(pod, nattedNS); err != nil {
+		return err
+	}
+
+	foreignPod, err := p.foreignClient.Client().CoreV1().Pods(podTranslated.Namespace).Create(context.TODO(), podTranslated, metav1.CreateOptions{})
 	if err != nil {
 		return err
 	}
 	klog.Infof("Pod %v/%v successfully created on remote cluster", podTranslated.Namespace, podTranslated.Name)
 
+	// the ephemeral containers cannot be set when the pod is created
+	if containers := translation.TranslateEphemeralContainers(pod); len(containers) > 0 {
+		return p.addEphemeralContainers(foreignPod, containers)
+	}
+
 	return nil
 }
 
 // UpdatePod accepts a Pod definition and updates its reference.
+// Only the fields that can be changed once a pod has been created are propagated to the foreign pod, while
+// changes to the immutable ones are notified through an event on the home pod.
 func (p *KubernetesProvider) UpdatePod(ctx context.Context, pod *v1.Pod) error {
+	if pod == nil {
+		return errors.New("pod cannot be nil")
+	}
+
+	klog.V(3).Infof("receive UpdatePod %q", pod.Name)
+
+	nattedNS, err := p.namespaceMapper.NatNamespace(pod.Namespace, false)
+	if err != nil {
+		return err
+	}
+
+	podTranslated := translation.H2FTranslate(pod, nattedNS)
+	podTranslated.Spec.EphemeralContainers = translation.TranslateEphemeralContainers(pod)
+	podsClient := p.foreignClient.Client().CoreV1().Pods(nattedNS)
+
+	var foreignPod *v1.Pod
+	err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		foreignPod, err = podsClient.Get(context.TODO(), pod.Name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+
+		podUpdated, changed := translation.UpdateForeignPod(foreignPod, podTranslated)
+		if !changed {
+			return nil
+		}
+
+		foreignPod, err = podsClient.Update(context.TODO(), podUpdated, metav1.UpdateOptions{})
+		return err
+	})
+	if err != nil {
+		return errors.Wrapf(err, "unable to update pod %v/%v on remote cluster", nattedNS, pod.Name)
+	}
+
+	if fields := translation.ImmutableFieldsChanged(foreignPod, podTranslated); len(fields) > 0 {
+		klog.Warningf("pod %v/%v: changes to immutable fields %v are not propagated to the remote cluster", pod.Namespace, pod.Name, fields)
+		p.recorder.Eventf(pod, v1.EventTypeWarning, ReasonImmutableFieldsChanged,
+			"changes to the immutable fields %v cannot be propagated to the remote pod", strings.Join(fields, ", "))
+	}
+
+	if containers := translation.NewEphemeralContainers(foreignPod, podTranslated); len(containers) > 0 {
+		if err = p.addEphemeralContainers(foreignPod, containers); err != nil {
+			return err
+		}
+	}
+
+	klog.V(3).Infof("Pod %v/%v successfully updated on remote cluster", nattedNS, pod.Name)
+
+	return nil
+}
+
+// reflectPersistentVolumeClaims creates in the foreign cluster the persistentVolumeClaims used by the pod,
+// since the outgoing reflector does not reflect the claims until they are used by an offloaded pod.
+func (p *KubernetesProvider) reflectPersistentVolumeClaims(pod *v1.Pod, nattedNS string) error {
+	apiController, err := p.GetApiController()
+	if err != nil {
+		return err
+	}
+
+	var storageClassMapping string
+	if p.storageClassMapping != nil {
+		storageClassMapping = p.storageClassMapping.Value().ToString()
+	}
+
+	for i := range pod.Spec.Volumes {
+		claim := pod.Spec.Volumes[i].PersistentVolumeClaim
+		if claim == nil {
+			continue
+		}
+
+		obj, err := apiController.CacheManager().GetHomeNamespacedObject(apimgmgt.PersistentVolumeClaims, pod.Namespace, claim.ClaimName)
+		if err != nil {
+			return errors.Wrapf(err, "unable to get persistentVolumeClaim %v/%v", pod.Namespace, claim.ClaimName)
+		}
+
+		foreignPvc := outgoing.ForgeForeignPersistentVolumeClaim(obj.(*v1.PersistentVolumeClaim), nattedNS, storageClassMapping)
+		_, err = p.foreignClient.Client().CoreV1().PersistentVolumeClaims(nattedNS).Create(context.TODO(), foreignPvc, metav1.CreateOptions{})
+		if err != nil && !kerror.IsAlreadyExists(err) {
+			return errors.Wrapf(err, "unable to create persistentVolumeClaim %v/%v on remote cluster", nattedNS, claim.ClaimName)
+		}
+	}
+
+	return nil
+}
+
+// addEphemeralContainers adds the ephemeral containers to the foreign pod through the ephemeralcontainers subresource.
+func (p *KubernetesProvider) addEphemeralContainers(foreignPod *v1.Pod, containers []v1.EphemeralContainer) error {
+	podsClient := p.foreignClient.Client().CoreV1().Pods(foreignPod.Namespace)
+	ephemeralContainers, err := podsClient.GetEphemeralContainers(context.TODO(), foreignPod.Name, metav1.GetOptions{})
+	if err != nil {
+		return errors.Wrapf(err, "unable to get the ephemeral containers of pod %v/%v", foreignPod.Namespace, foreignPod.Name)
+	}
+	ephemeralContainers.EphemeralContainers = append(ephemeralContainers.EphemeralContainers, containers...)
+	if _, err = podsClient.UpdateEphemeralContainers(context.TODO(), foreignPod.Name, ephemeralContainers, metav1.UpdateOptions{}); err != nil {
+		return errors.Wrapf(err, "unable to add the ephemeral containers to pod %v/%v", foreignPod.Namespace, foreignPod.Name)
+	}
 	return nil
 }
 
@@ -173,31 +283,108 @@ func (p *KubernetesProvider) RunInContainer(ctx context.Context, namespace strin
 		Resource("pods").
 		Name(podName).
 		SubResource("exec").
-		VersionedParams(&v1.PodExecOptions{
-			Container: containerName,
-			Command:   cmd,
-			Stdin:     true,
-			Stdout:    true,
-			Stderr:    true,
-			TTY:       true,
-		}, scheme.ParameterCodec)
+		VersionedParams(forgePodExecOptions(containerName, cmd, attach), scheme.ParameterCodec)
 
 	exec, err := remotecommand.NewSPDYExecutor(p.restConfig, "POST", req.URL())
 	if err != nil {
 		return fmt.Errorf("could not make remote command: %v", err)
 	}
 
-	err = exec.Stream(remotecommand.StreamOptions{
+	err = exec.Stream(forgeStreamOptions(ctx, attach))
+	if err != nil {
+		return fmt.Errorf("streaming error: %v", err)
+	}
+
+	return nil
+}
+
+// AttachToContainer attaches to the main process of a container in the remote pod, copying data
+// between in/out/err and the container's stdin/stdout/stderr.
+func (p *KubernetesProvider) AttachToContainer(ctx context.Context, namespace string, podName string, containerName string, attach api.AttachIO) error {
+	nattedNS, err := p.namespaceMapper.NatNamespace(namespace, false)
+	if err != nil {
+		return err
+	}
+
+	req := p.foreignClient.Client().CoreV1().RESTClient().
+		Post().
+		Namespace(nattedNS).
+		Resource("pods").
+		Name(podName).
+		SubResource("attach").
+		VersionedParams(forgePodAttachOptions(containerName, attach), scheme.ParameterCodec)
+
+	exec, err := remotecommand.NewSPDYExecutor(p.restConfig, "POST", req.URL())
+	if err != nil {
+		return fmt.Errorf("could not make remote attach: %v", err)
+	}
+
+	err = exec.Stream(forgeStreamOptions(ctx, attach))
+	if err != nil {
+		return fmt.Errorf("streaming error: %v", err)
+	}
+
+	return nil
+}
+
+// forgePodExecOptions builds the options of the remote exec request according to the streams
+// actually requested by the caller, which are the ones available in the AttachIO.
+func forgePodExecOptions(containerName string, cmd []string, attach api.AttachIO) *v1.PodExecOptions {
+	return &v1.PodExecOptions{
+		Container: containerName,
+		Command:   cmd,
+		Stdin:     attach.Stdin() != nil,
+		Stdout:    attach.Stdout() != nil,
+		Stderr:    attach.Stderr() != nil,
+		TTY:       attach.TTY(),
+	}
+}
+
+// forgePodAttachOptions builds the options of the remote attach request according to the streams
+// actually requested by the caller, which are the ones available in the AttachIO.
+func forgePodAttachOptions(containerName string, attach api.AttachIO) *v1.PodAttachOptions {
+	return &v1.PodAttachOptions{
+		Container: containerName,
+		Stdin:     attach.Stdin() != nil,
+		Stdout:    attach.Stdout() != nil,
+		Stderr:    attach.Stderr() != nil,
+		TTY:       attach.TTY(),
+	}
+}
+
+// forgeStreamOptions builds the stream options for the remote executor from the AttachIO.
+// When a TTY is requested, the terminal resize events are forwarded to the remote container.
+func forgeStreamOptions(ctx context.Context, attach api.AttachIO) remotecommand.StreamOptions {
+	options := remotecommand.StreamOptions{
 		Stdin:  attach.Stdin(),
 		Stdout: attach.Stdout(),
 		Stderr: attach.Stderr(),
 		Tty:    attach.TTY(),
-	})
-	if err != nil {
-		return fmt.Errorf("streaming error: %v", err)
 	}
+	if attach.TTY() && attach.Resize() != nil {
+		options.TerminalSizeQueue = &termSizeQueue{ctx: ctx, resize: attach.Resize()}
+	}
+	return options
+}
 
-	return nil
+// termSizeQueue implements remotecommand.TerminalSizeQueue, forwarding the resize events received from the client.
+type termSizeQueue struct {
+	ctx    context.Context
+	resize <-chan api.TermSize
+}
+
+// Next returns the new terminal size after the terminal has been resized. It returns nil when
+// the resize channel has been closed or the context is done, stopping the monitoring.
+func (q *termSizeQueue) Next() *remotecommand.TerminalSize {
+	select {
+	case size, ok := <-q.resize:
+		if !ok {
+			return nil
+		}
+		return &remotecommand.TerminalSize{Width: size.Width, Height: size.Height}
+	case <-q.ctx.Done():
+		return nil
+	}
 }
 
 // GetContainerLogs retrieves the logs of a container by name from the provider.
@@ -207,17 +394,54 @@ func (p *KubernetesProvider) GetContainerLogs(ctx context.Context, namespace str
 		return nil, err
 	}
 
-	options := &v1.PodLogOptions{
-		Container: containerName,
-	}
-	logs := p.foreignClient.Client().CoreV1().Pods(nattedNS).GetLogs(podName, options)
-	stream, err := logs.Stream(context.TODO())
+	options := forgePodLogOptions(containerName, opts)
+	stream, err := getContainerLogsStream(ctx, p.foreignClient.Client().CoreV1().RESTClient(), nattedNS, podName, options)
 	if err != nil {
 		return nil, fmt.Errorf("could not get stream from logs request: %v", err)
 	}
 	return stream, nil
 }
 
+// forgePodLogOptions converts the virtual-kubelet log options to the ones to be used for the remote request.
+func forgePodLogOptions(containerName string, opts api.ContainerLogOpts) *v1.PodLogOptions {
+	options := &v1.PodLogOptions{
+		Container:  containerName,
+		Follow:     opts.Follow,
+		Previous:   opts.Previous,
+		Timestamps: opts.Timestamps,
+	}
+
+	if opts.Tail != nil {
+		tailLines := int64(*opts.Tail)
+		options.TailLines = &tailLines
+	}
+	if opts.LimitBytes > 0 {
+		limitBytes := int64(opts.LimitBytes)
+		options.LimitBytes = &limitBytes
+	}
+	if opts.SinceSeconds > 0 {
+		sinceSeconds := int64(opts.SinceSeconds)
+		options.SinceSeconds = &sinceSeconds
+	}
+	if !opts.SinceTime.IsZero() {
+		sinceTime := metav1.NewTime(opts.SinceTime)
+		options.SinceTime = &sinceTime
+	}
+
+	return options
+}
+
+// getContainerLogsStream opens the stream of the logs of a remote pod.
+func getContainerLogsStream(ctx context.Context, client rest.Interface, namespace, podName string, options *v1.PodLogOptions) (io.ReadCloser, error) {
+	return client.Get().
+		Namespace(namespace).
+		Resource("pods").
+		Name(podName).
+		SubResource("log").
+		VersionedParams(options, scheme.ParameterCodec).
+		Stream(ctx)
+}
+
 // GetPods returns a list of all pods known to be "running".
 func (p *KubernetesProvider) GetPods(ctx context.Context) ([]*v1.Pod, error) {
 	klog.Info("receive GetPods")
@@ -242,89 +466,20 @@ func (p *KubernetesProvider) GetPods(ctx context.Context) ([]*v1.Pod, error) {
 	return podsHomeOut, nil
 }
 
-// GetStatsSummary returns dummy stats for all pods known by this provider.
+// GetStatsSummary returns the stats of the offloaded pods, retrieved from the foreign metrics API.
 func (p *KubernetesProvider) GetStatsSummary(ctx context.Context) (*stats.Summary, error) {
 	var span trace.Span
-	_, span = trace.StartSpan(ctx, "GetStatsSummary") //nolint: ineffassign
+	ctx, span = trace.StartSpan(ctx, "GetStatsSummary")
 	defer span.End()
 
-	// Grab the current timestamp so we can report it as the time the stats were generated.
-	t := metav1.NewTime(time.Now())
-
-	// Create the Summary object that will later be populated with node and pod stats.
-	res := &stats.Summary{}
-
-	// Populate the Summary object with basic node stats.
-	res.Node = stats.NodeStats{
-		NodeName:  p.nodeName.Value().ToString(),
-		StartTime: metav1.NewTime(p.startTime),
-	}
-
-	// Populate the Summary object with dummy stats for each pod known by this provider.
-	pods, err := p.foreignClient.Client().CoreV1().Pods("").List(context.TODO(), metav1.ListOptions{})
-	if err != nil {
-		if kerror.IsNotFound(err) {
-			return nil, errdefs.NotFoundf("pods in \"%s\" is not known to the provider", "")
-		}
-		return nil, errors.Wrap(err, "Unable to get pods")
-	}
-	for _, pod := range pods.Items {
-		var (
-			// totalUsageNanoCores will be populated with the sum of the values of UsageNanoCores computes across all containers in the pod.
-			totalUsageNanoCores uint64
-			// totalUsageBytes will be populated with the sum of the values of UsageBytes computed across all containers in the pod.
-			totalUsageBytes uint64
-		)
-
-		// Create a PodStats object to populate with pod stats.
-		pss := stats.PodStats{
-			PodRef: stats.PodReference{
-				Name:      pod.Name,
-				Namespace: pod.Namespace,
-				UID:       string(pod.UID),
-			},
-			StartTime: pod.CreationTimestamp,
-		}
-
-		// Iterate over all containers in the current pod to compute dummy stats.
-		for _, container := range pod.Spec.Containers {
-			// Grab a dummy value to be used as the total CPU usage.
-			// The value should fit a uint32 in order to avoid overflows later on when computing pod stats.
-			dummyUsageNanoCores := uint64(rand.Uint32())
-			totalUsageNanoCores += dummyUsageNanoCores
-			// Create a dummy value to be used as the total RAM usage.
-			// The value should fit a uint32 in order to avoid overflows later on when computing pod stats.
-			dummyUsageBytes := uint64(rand.Uint32())
-			totalUsageBytes += dummyUsageBytes
-			// Append a ContainerStats object containing the dummy stats to the PodStats object.
-			pss.Containers = append(pss.Containers, stats.ContainerStats{
-				Name:      container.Name,
-				StartTime: pod.CreationTimestamp,
-				CPU: &stats.CPUStats{
-					Time:           t,
-					UsageNanoCores: &dummyUsageNanoCores,
-				},
-				Memory: &stats.MemoryStats{
-					Time:       t,
-					UsageBytes: &dummyUsageBytes,
-				},
-			})
-		}
-
-		// Populate the CPU and RAM stats for the pod and append the PodsStats object to the Summary object to be returned.
-		pss.CPU = &stats.CPUStats{
-			Time:           t,
-			UsageNanoCores: &totalUsageNanoCores,
-		}
-		pss.Memory = &stats.MemoryStats{
-			Time:       t,
-			UsageBytes: &totalUsageBytes,
-		}
-		res.Pods = append(res.Pods, pss)
+	builder := &statsSummaryBuilder{
+		metricsClient: p.foreignMetricsClient.MetricsV1beta1(),
+		cacheManager:  p.apiController.CacheManager(),
+		nodeName:      p.nodeName.Value().ToString(),
+		startTime:     p.startTime,
 	}
 
-	// Return the dummy stats.
-	return res, nil
+	return builder.build(ctx, p.namespaceMapper.MappedNamespaces())
 }
 
 // NotifyPods is called to set a pod informing callback function. This should be called before any operations are ready
@@ -0,0 +1,179 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"github.com/liqotech/liqo/internal/virtualKubelet/node/api"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"io/ioutil"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest/fake"
+	"k8s.io/client-go/tools/remotecommand"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGetContainerLogsStream(t *testing.T) {
+	sinceTime := time.Date(2020, time.November, 10, 8, 30, 0, 0, time.UTC)
+	tail, noLines := 100, 0
+
+	testCases := []struct {
+		name          string
+		opts          api.ContainerLogOpts
+		expectedQuery map[string]string
+	}{
+		{
+			name:          "no options",
+			opts:          api.ContainerLogOpts{},
+			expectedQuery: map[string]string{"container": "container"},
+		},
+		{
+			name: "follow, tail, timestamps and previous",
+			opts: api.ContainerLogOpts{Follow: true, Tail: &tail, Timestamps: true, Previous: true},
+			expectedQuery: map[string]string{
+				"container":  "container",
+				"follow":     "true",
+				"tailLines":  "100",
+				"timestamps": "true",
+				"previous":   "true",
+			},
+		},
+		{
+			name: "tail without lines",
+			opts: api.ContainerLogOpts{Tail: &noLines},
+			expectedQuery: map[string]string{
+				"container": "container",
+				"tailLines": "0",
+			},
+		},
+		{
+			name: "since seconds and limit bytes",
+			opts: api.ContainerLogOpts{SinceSeconds: 60, LimitBytes: 1024},
+			expectedQuery: map[string]string{
+				"container":    "container",
+				"sinceSeconds": "60",
+				"limitBytes":   "1024",
+			},
+		},
+		{
+			name: "since time",
+			opts: api.ContainerLogOpts{SinceTime: sinceTime},
+			expectedQuery: map[string]string{
+				"container": "container",
+				"sinceTime": "2020-11-10T08:30:00Z",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var request *http.Request
+			client := &fake.RESTClient{
+				NegotiatedSerializer: scheme.Codecs.WithoutConversion(),
+				GroupVersion:         v1.SchemeGroupVersion,
+				Client: fake.CreateHTTPClient(func(req *http.Request) (*http.Response, error) {
+					request = req
+					return &http.Response{
+						StatusCode: http.StatusOK,
+						Body:       ioutil.NopCloser(strings.NewReader("logs")),
+					}, nil
+				}),
+			}
+
+			stream, err := getContainerLogsStream(context.TODO(), client, "namespace-natted", "pod",
+				forgePodLogOptions("container", tc.opts))
+			assert.NoError(t, err)
+			defer stream.Close()
+
+			logs, err := ioutil.ReadAll(stream)
+			assert.NoError(t, err)
+			assert.Equal(t, "logs", string(logs))
+
+			assert.Equal(t, http.MethodGet, request.Method)
+			assert.Equal(t, "/namespaces/namespace-natted/pods/pod/log", request.URL.Path)
+
+			query := request.URL.Query()
+			assert.Len(t, query, len(tc.expectedQuery))
+			for k, v := range tc.expectedQuery {
+				assert.Equal(t, v, query.Get(k), "query parameter %v", k)
+			}
+		})
+	}
+}
+
+// fakeAttachIO is an api.AttachIO exposing only the configured streams.
+type fakeAttachIO struct {
+	stdin          io.Reader
+	stdout, stderr io.WriteCloser
+	tty            bool
+	resize         chan api.TermSize
+}
+
+func (f *fakeAttachIO) Stdin() io.Reader            { return f.stdin }
+func (f *fakeAttachIO) Stdout() io.WriteCloser      { return f.stdout }
+func (f *fakeAttachIO) Stderr() io.WriteCloser      { return f.stderr }
+func (f *fakeAttachIO) TTY() bool                   { return f.tty }
+func (f *fakeAttachIO) Resize() <-chan api.TermSize { return f.resize }
+
+type nopWriteCloser struct{ io.Writer }
+
+func (nopWriteCloser) Close() error { return nil }
+
+func TestForgePodExecOptions(t *testing.T) {
+	stdout := nopWriteCloser{&bytes.Buffer{}}
+	stderr := nopWriteCloser{&bytes.Buffer{}}
+
+	testCases := []struct {
+		name     string
+		attach   *fakeAttachIO
+		expected v1.PodExecOptions
+	}{
+		{
+			name:     "non interactive",
+			attach:   &fakeAttachIO{stdout: stdout, stderr: stderr},
+			expected: v1.PodExecOptions{Container: "container", Command: []string{"cat", "file"}, Stdout: true, Stderr: true},
+		},
+		{
+			name:     "only stdout",
+			attach:   &fakeAttachIO{stdout: stdout},
+			expected: v1.PodExecOptions{Container: "container", Command: []string{"cat", "file"}, Stdout: true},
+		},
+		{
+			name:   "interactive with tty",
+			attach: &fakeAttachIO{stdin: strings.NewReader(""), stdout: stdout, tty: true},
+			expected: v1.PodExecOptions{Container: "container", Command: []string{"cat", "file"},
+				Stdin: true, Stdout: true, TTY: true},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, &tc.expected, forgePodExecOptions("container", []string{"cat", "file"}, tc.attach))
+
+			options := forgeStreamOptions(context.TODO(), tc.attach)
+			assert.Equal(t, tc.expected.Stdin, options.Stdin != nil)
+			assert.Equal(t, tc.expected.Stdout, options.Stdout != nil)
+			assert.Equal(t, tc.expected.Stderr, options.Stderr != nil)
+			assert.Equal(t, tc.expected.TTY, options.Tty)
+			assert.Nil(t, options.TerminalSizeQueue)
+		})
+	}
+}
+
+func TestTermSizeQueue(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	attach := &fakeAttachIO{stdin: strings.NewReader(""), tty: true, resize: make(chan api.TermSize, 1)}
+
+	options := forgeStreamOptions(ctx, attach)
+	assert.NotNil(t, options.TerminalSizeQueue)
+
+	attach.resize <- api.TermSize{Width: 80, Height: 24}
+	assert.Equal(t, &remotecommand.TerminalSize{Width: 80, Height: 24}, options.TerminalSizeQueue.Next())
+
+	cancel()
+	assert.Nil(t, options.TerminalSizeQueue.Next())
+}
@@ -0,0 +1,123 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/httpstream"
+	"k8s.io/client-go/tools/portforward"
+	"k8s.io/client-go/transport/spdy"
+	"k8s.io/klog"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// PortForward forwards the given stream to a port of the remote pod, tunnelling it through
+// the portforward subresource of the foreign API server.
+func (p *KubernetesProvider) PortForward(ctx context.Context, namespace string, podName string, port int32, stream io.ReadWriteCloser) error {
+	defer stream.Close()
+
+	nattedNS, err := p.namespaceMapper.NatNamespace(namespace, false)
+	if err != nil {
+		return err
+	}
+
+	req := p.foreignClient.Client().CoreV1().RESTClient().
+		Post().
+		Namespace(nattedNS).
+		Resource("pods").
+		Name(podName).
+		SubResource("portforward")
+
+	transport, upgrader, err := spdy.RoundTripperFor(p.restConfig)
+	if err != nil {
+		return fmt.Errorf("could not create round tripper: %v", err)
+	}
+	dialer := spdy.NewDialer(upgrader, &http.Client{Transport: transport}, http.MethodPost, req.URL())
+
+	conn, _, err := dialer.Dial(portforward.PortForwardProtocolV1Name)
+	if err != nil {
+		return fmt.Errorf("could not upgrade connection: %v", err)
+	}
+	defer conn.Close()
+
+	return forwardStream(ctx, conn, port, stream)
+}
+
+// forwardStream creates the error and data streams for the given port on the remote connection,
+// and copies data between the data stream and the local one until either side is done.
+func forwardStream(ctx context.Context, conn httpstream.Connection, port int32, stream io.ReadWriteCloser) error {
+	// each forwarded stream is served by a dedicated connection, hence a constant request ID is enough.
+	headers := http.Header{}
+	headers.Set(v1.StreamType, v1.StreamTypeError)
+	headers.Set(v1.PortHeader, strconv.Itoa(int(port)))
+	headers.Set(v1.PortForwardRequestIDHeader, "0")
+
+	errorStream, err := conn.CreateStream(headers)
+	if err != nil {
+		return fmt.Errorf("error creating error stream for port %d: %v", port, err)
+	}
+	// we're not writing to this stream
+	errorStream.Close()
+
+	errorChan := make(chan error, 1)
+	go func() {
+		message, err := ioutil.ReadAll(errorStream)
+		switch {
+		case err != nil:
+			errorChan <- fmt.Errorf("error reading from error stream for port %d: %v", port, err)
+		case len(message) > 0:
+			errorChan <- fmt.Errorf("an error occurred forwarding port %d: %v", port, string(message))
+		}
+		close(errorChan)
+	}()
+
+	headers.Set(v1.StreamType, v1.StreamTypeData)
+	dataStream, err := conn.CreateStream(headers)
+	if err != nil {
+		return fmt.Errorf("error creating forwarding stream for port %d: %v", port, err)
+	}
+
+	localError := make(chan error, 1)
+	remoteDone := make(chan struct{})
+
+	go func() {
+		// copy from the remote side to the local stream
+		if _, err := io.Copy(stream, dataStream); err != nil && !isClosedConnectionError(err) {
+			klog.V(4).Infof("error copying from remote stream to local stream for port %d: %v", port, err)
+		}
+		close(remoteDone)
+	}()
+
+	go func() {
+		// inform the remote side that we're done sending data
+		defer dataStream.Close()
+
+		// copy from the local stream to the remote side
+		if _, err := io.Copy(dataStream, stream); err != nil && !isClosedConnectionError(err) {
+			localError <- err
+		}
+	}()
+
+	// wait for either a local->remote error or for the copy from remote->local to finish
+	select {
+	case <-remoteDone:
+	case err = <-localError:
+	case <-ctx.Done():
+		return nil
+	}
+
+	// always expect something on errorChan (it may be nil)
+	if remoteErr := <-errorChan; remoteErr != nil {
+		return remoteErr
+	}
+	return err
+}
+
+// isClosedConnectionError returns true if the error is caused by reading from or writing to a closed connection.
+func isClosedConnectionError(err error) bool {
+	return strings.Contains(err.Error(), "use of closed network connection")
+}
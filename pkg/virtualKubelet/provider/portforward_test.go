@@ -0,0 +1,102 @@
+package provider
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"io"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/httpstream"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeStream is an httpstream.Stream supporting half-close, as SPDY streams do:
+// Close only signals that no more data will be written.
+type fakeStream struct {
+	*io.PipeReader
+	*io.PipeWriter
+	headers http.Header
+}
+
+func newFakeStreamPair(headers http.Header) (local, remote *fakeStream) {
+	localReader, remoteWriter := io.Pipe()
+	remoteReader, localWriter := io.Pipe()
+	return &fakeStream{PipeReader: localReader, PipeWriter: localWriter, headers: headers},
+		&fakeStream{PipeReader: remoteReader, PipeWriter: remoteWriter, headers: headers}
+}
+
+func (s *fakeStream) Close() error         { return s.PipeWriter.Close() }
+func (s *fakeStream) Reset() error         { s.PipeReader.Close(); return s.PipeWriter.Close() }
+func (s *fakeStream) Headers() http.Header { return s.headers }
+func (s *fakeStream) Identifier() uint32   { return 0 }
+
+// fakeConnection is an httpstream.Connection whose streams are handled by the remote function.
+type fakeConnection struct {
+	remoteErrorMessage string
+	remote             func(stream io.ReadWriteCloser)
+	headers            []http.Header
+}
+
+func (c *fakeConnection) CreateStream(headers http.Header) (httpstream.Stream, error) {
+	c.headers = append(c.headers, headers.Clone())
+	local, remote := newFakeStreamPair(headers.Clone())
+	switch headers.Get(v1.StreamType) {
+	case v1.StreamTypeError:
+		go func() {
+			_, _ = io.Copy(remote, strings.NewReader(c.remoteErrorMessage))
+			remote.Close()
+		}()
+	case v1.StreamTypeData:
+		go c.remote(remote)
+	}
+	return local, nil
+}
+
+func (c *fakeConnection) Close() error                   { return nil }
+func (c *fakeConnection) CloseChan() <-chan bool         { return make(chan bool) }
+func (c *fakeConnection) SetIdleTimeout(_ time.Duration) {}
+
+// echo replies to the first message received on the stream and then closes it.
+func echo(stream io.ReadWriteCloser) {
+	buf := make([]byte, 1024)
+	n, _ := stream.Read(buf)
+	_, _ = stream.Write(buf[:n])
+	stream.Close()
+}
+
+func TestForwardStream(t *testing.T) {
+	conn := &fakeConnection{remote: echo}
+	local, client := net.Pipe()
+
+	done := make(chan error)
+	go func() { done <- forwardStream(context.TODO(), conn, 8080, local) }()
+
+	_, err := client.Write([]byte("ping"))
+	assert.NoError(t, err)
+	reply := make([]byte, 4)
+	_, err = io.ReadFull(client, reply)
+	assert.NoError(t, err)
+	assert.Equal(t, "ping", string(reply))
+	client.Close()
+
+	assert.NoError(t, <-done)
+	assert.Len(t, conn.headers, 2)
+	for _, headers := range conn.headers {
+		assert.Equal(t, "8080", headers.Get(v1.PortHeader))
+	}
+	assert.Equal(t, v1.StreamTypeError, conn.headers[0].Get(v1.StreamType))
+	assert.Equal(t, v1.StreamTypeData, conn.headers[1].Get(v1.StreamType))
+}
+
+func TestForwardStreamRemoteError(t *testing.T) {
+	conn := &fakeConnection{remoteErrorMessage: "connection refused", remote: func(stream io.ReadWriteCloser) { stream.Close() }}
+	local, client := net.Pipe()
+	defer client.Close()
+
+	err := forwardStream(context.TODO(), conn, 8080, local)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "connection refused")
+}
@@ -2,31 +2,50 @@ package provider
 
 import (
 	"errors"
+	configv1alpha1 "github.com/liqotech/liqo/apis/config/v1alpha1"
+	discoveryv1alpha1 "github.com/liqotech/liqo/apis/discovery/v1alpha1"
 	nettypes "github.com/liqotech/liqo/apis/net/v1alpha1"
 	advtypes "github.com/liqotech/liqo/apis/sharing/v1alpha1"
 	nattingv1 "github.com/liqotech/liqo/apis/virtualKubelet/v1alpha1"
 	"github.com/liqotech/liqo/internal/virtualKubelet/node"
+	"github.com/liqotech/liqo/pkg/clusterConfig"
 	"github.com/liqotech/liqo/pkg/crdClient"
 	"github.com/liqotech/liqo/pkg/virtualKubelet/apiReflection/controller"
 	"github.com/liqotech/liqo/pkg/virtualKubelet/namespacesMapping"
 	"github.com/liqotech/liqo/pkg/virtualKubelet/options"
 	optTypes "github.com/liqotech/liqo/pkg/virtualKubelet/options/types"
+	"github.com/liqotech/liqo/pkg/virtualKubelet/translation"
+	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/dynamic"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/klog"
+	metricsclient "k8s.io/metrics/pkg/client/clientset/versioned"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 )
 
+// foreignKubeconfigReloadPeriod is the period between two consecutive reloads of the rotated foreign credentials.
+const foreignKubeconfigReloadPeriod = 30 * time.Second
+
 // KubernetesProvider implements the virtual-kubelet provider interface and stores pods in memory.
 type KubernetesProvider struct { // nolint:golint]
 	namespaceMapper *namespacesMapping.NamespaceMapperController
 	apiController   *controller.Controller
 
-	advClient     *crdClient.CRDClient
-	tunEndClient  *crdClient.CRDClient
-	foreignClient *crdClient.CRDClient
-	homeClient    *crdClient.CRDClient
+	advClient       *crdClient.CRDClient
+	discoveryClient *crdClient.CRDClient
+	tunEndClient    *crdClient.CRDClient
+	foreignClient   *crdClient.CRDClient
+	homeClient      *crdClient.CRDClient
+
+	foreignMetricsClient metricsclient.Interface
 
 	operatingSystem    string
 	internalIP         string
@@ -35,13 +54,27 @@ type KubernetesProvider struct { // nolint:golint]
 	notifier           func(interface{})
 	foreignClusterId   string
 	homeClusterID      string
+	dnsSearchDomain    string
 	nodeController     *node.NodeController
 	providerKubeconfig string
 	restConfig         *rest.Config
 
+	recorder record.EventRecorder
+
+	foreignStatusMutex  sync.Mutex
+	foreignAPIServerErr error
+	foreignNodes        []v1.Node
+	tunnelEndpoint      *nettypes.TunnelEndpoint
+
+	evictionPolicy          EvictionPolicy
+	evictionGracePeriod     time.Duration
+	foreignUnreachableSince time.Time
+	podsEvicted             bool
+
 	nodeName              options.Option
 	RemoteRemappedPodCidr options.Option
 	LocalRemappedPodCidr  options.Option
+	storageClassMapping   options.Option
 
 	foreignPodWatcherStop chan struct{}
 	nodeUpdateStop        chan struct{}
@@ -49,9 +82,15 @@ type KubernetesProvider struct { // nolint:golint]
 }
 
 // NewKubernetesProviderKubernetesConfig creates a new KubernetesV0Provider. Kubernetes legacy provider does not implement the new asynchronous podnotifier interface
-func NewKubernetesProvider(nodeName, foreignClusterId, homeClusterId string, internalIP string, daemonEndpointPort int32, kubeconfig, remoteKubeConfig string) (*KubernetesProvider, error) {
+func NewKubernetesProvider(nodeName, foreignClusterId, homeClusterId string, internalIP string, daemonEndpointPort int32, kubeconfig, remoteKubeConfig string, storageClassMapping map[string]string,
+	evictionPolicy string, evictionGracePeriod time.Duration, clusterDomain string, dnsTranslation bool) (*KubernetesProvider, error) {
 	var err error
 
+	policy, err := ParseEvictionPolicy(evictionPolicy)
+	if err != nil {
+		return nil, err
+	}
+
 	if err = nattingv1.AddToScheme(clientgoscheme.Scheme); err != nil {
 		return nil, err
 	}
@@ -71,55 +110,117 @@ func NewKubernetesProvider(nodeName, foreignClusterId, homeClusterId string, int
 		return nil, err
 	}
 
+	discoveryConfig, err := crdClient.NewKubeconfig(kubeconfig, &discoveryv1alpha1.GroupVersion)
+	if err != nil {
+		return nil, err
+	}
+	discoveryClient, err := crdClient.NewFromConfig(discoveryConfig)
+	if err != nil {
+		return nil, err
+	}
+
 	restConfig, err := crdClient.NewKubeconfig(remoteKubeConfig, &schema.GroupVersion{})
 	if err != nil {
 		return nil, err
 	}
+	// the foreign cluster periodically rotates the credentials, which are reloaded from the updated kubeconfig
+	if foreignToken := crdClient.NewReloadableToken(restConfig); foreignToken.Get() != "" {
+		go foreignToken.WatchKubeconfigFile(remoteKubeConfig, foreignKubeconfigReloadPeriod, wait.NeverStop)
+	}
 
 	foreignClient, err := crdClient.NewFromConfig(restConfig)
 	if err != nil {
 		return nil, err
 	}
 
-	mapper, err := namespacesMapping.NewNamespaceMapperController(client, foreignClient.Client(), homeClusterId, foreignClusterId)
+	foreignMetricsClient, err := metricsclient.NewForConfig(restConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	homeDynClient, err := dynamic.NewForConfig(client.Config())
+	if err != nil {
+		return nil, err
+	}
+
+	foreignDynClient, err := dynamic.NewForConfig(restConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	// the names of the home services are resolved in the foreign cluster through the search domain of the home cluster
+	var dnsDomain, dnsSearchDomain string
+	if dnsTranslation {
+		dnsDomain = clusterDomain
+		dnsSearchDomain = translation.DNSSearchDomain(homeClusterId)
+	}
+
+	mapper, err := namespacesMapping.NewNamespaceMapperController(client, foreignClient.Client(), homeClusterId, foreignClusterId, dnsDomain)
 	if err != nil {
 		klog.Fatal(err)
 	}
 	mapper.WaitForSync()
 
+	eb := record.NewBroadcaster()
+	eb.StartRecordingToSink(&corev1client.EventSinkImpl{Interface: client.Client().CoreV1().Events("")})
+
 	remoteRemappedPodCIDROpt := optTypes.NewNetworkingOption(optTypes.RemoteRemappedPodCIDR, "")
 	localRemappedPodCIDROpt := optTypes.NewNetworkingOption(optTypes.LocalRemappedPodCIDR, "")
 	nodeNameOpt := optTypes.NewNetworkingOption(optTypes.NodeName, optTypes.NetworkingValue(nodeName))
+	storageClassMappingOpt := optTypes.NewStorageOption(optTypes.StorageClassMapping, forgeStorageClassMapping(storageClassMapping))
 
 	opts := forgeOptionsMap(
 		remoteRemappedPodCIDROpt,
 		localRemappedPodCIDROpt,
-		nodeNameOpt)
+		nodeNameOpt,
+		storageClassMappingOpt)
 
 	provider := KubernetesProvider{
-		apiController:         controller.NewApiController(client.Client(), foreignClient.Client(), mapper, opts),
+		apiController:         controller.NewApiController(client.Client(), foreignClient.Client(), homeDynClient, foreignDynClient, mapper, opts),
 		namespaceMapper:       mapper,
 		nodeName:              nodeNameOpt,
+		storageClassMapping:   storageClassMappingOpt,
 		internalIP:            internalIP,
 		daemonEndpointPort:    daemonEndpointPort,
 		startTime:             time.Now(),
 		foreignClusterId:      foreignClusterId,
 		homeClusterID:         homeClusterId,
+		dnsSearchDomain:       dnsSearchDomain,
 		providerKubeconfig:    remoteKubeConfig,
 		homeClient:            client,
 		foreignPodWatcherStop: make(chan struct{}, 1),
 		restConfig:            restConfig,
 		foreignClient:         foreignClient,
+		foreignMetricsClient:  foreignMetricsClient,
 		advClient:             advClient,
 		tunEndClient:          tepClient,
+		discoveryClient:       discoveryClient,
+		evictionPolicy:        policy,
+		evictionGracePeriod:   evictionGracePeriod,
+		recorder:              eb.NewRecorder(clientgoscheme.Scheme, v1.EventSource{Component: nodeName}),
 
 		RemoteRemappedPodCidr: remoteRemappedPodCIDROpt,
 		LocalRemappedPodCidr:  localRemappedPodCIDROpt,
 	}
 
+	go clusterConfig.WatchConfiguration(provider.handleClusterConfig, nil, kubeconfig)
+
 	return &provider, nil
 }
 
+// handleClusterConfig configures the resources to be reflected in addition to the built-in ones,
+// and the objects not to be reflected by the built-in reflectors.
+func (p *KubernetesProvider) handleClusterConfig(config *configv1alpha1.ClusterConfig) {
+	p.apiController.SetReflectionBlacklist(config.Spec.VirtualKubeletConfig.ReflectionBlacklist)
+	p.apiController.SetReflectedResources(config.Spec.VirtualKubeletConfig.ResourcesToReflect)
+}
+
+const (
+	// ReasonImmutableFieldsChanged is the reason of the events notifying that an update of a pod
+	// involves fields that cannot be propagated to the remote cluster.
+	ReasonImmutableFieldsChanged = "ImmutableFieldsChanged"
+)
+
 func forgeOptionsMap(opts ...options.Option) map[options.OptionKey]options.Option {
 	outOpts := make(map[options.OptionKey]options.Option)
 
@@ -130,6 +231,16 @@ func forgeOptionsMap(opts ...options.Option) map[options.OptionKey]options.Optio
 	return outOpts
 }
 
+// forgeStorageClassMapping converts the storage class mapping to the home=foreign comma separated format.
+func forgeStorageClassMapping(mapping map[string]string) optTypes.StorageValue {
+	pairs := make([]string, 0, len(mapping))
+	for home, foreign := range mapping {
+		pairs = append(pairs, strings.Join([]string{home, foreign}, "="))
+	}
+	sort.Strings(pairs)
+	return optTypes.StorageValue(strings.Join(pairs, ","))
+}
+
 func (p *KubernetesProvider) GetNamespaceMapper() (*namespacesMapping.NamespaceMapperController, error) {
 	if p.namespaceMapper == nil {
 		return nil, errors.New("NamespaceMapper is nil")
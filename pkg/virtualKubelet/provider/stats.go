@@ -0,0 +1,168 @@
+package provider
+
+import (
+	"context"
+	apimgmgt "github.com/liqotech/liqo/pkg/virtualKubelet/apiReflection"
+	"github.com/liqotech/liqo/pkg/virtualKubelet/storage"
+	v1 "k8s.io/api/core/v1"
+	kerror "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog"
+	stats "k8s.io/kubernetes/pkg/kubelet/apis/stats/v1alpha1"
+	metricsv1beta1 "k8s.io/metrics/pkg/apis/metrics/v1beta1"
+	metricsclient "k8s.io/metrics/pkg/client/clientset/versioned/typed/metrics/v1beta1"
+	"time"
+)
+
+// statsSummaryBuilder collects the usage of the offloaded pods from the foreign metrics API and
+// forges a stats summary referring to the home cluster resources.
+type statsSummaryBuilder struct {
+	metricsClient metricsclient.MetricsV1beta1Interface
+	cacheManager  storage.CacheManagerReader
+
+	nodeName  string
+	startTime time.Time
+}
+
+// build returns the stats summary for the pods living in the given namespaces. The namespaces map
+// has the home namespaces as keys and the natted (foreign) namespaces as values.
+// If the foreign metrics API is not available, the pods are still listed, but without usage stats.
+func (b *statsSummaryBuilder) build(ctx context.Context, namespaces map[string]string) (*stats.Summary, error) {
+	now := metav1.NewTime(time.Now())
+
+	res := &stats.Summary{
+		Node: stats.NodeStats{
+			NodeName:  b.nodeName,
+			StartTime: metav1.NewTime(b.startTime),
+		},
+	}
+
+	var (
+		// nodeUsageNanoCores will be populated with the sum of the CPU usage of all the offloaded pods.
+		nodeUsageNanoCores uint64
+		// nodeUsageBytes will be populated with the sum of the memory usage of all the offloaded pods.
+		nodeUsageBytes uint64
+		// metricsAvailable is false if the foreign metrics API cannot be contacted.
+		metricsAvailable = true
+	)
+
+	for homeNamespace, foreignNamespace := range namespaces {
+		pods, err := b.cacheManager.ListForeignNamespacedObject(apimgmgt.Pods, foreignNamespace)
+		if err != nil {
+			klog.Errorf("unable to list the pods in foreign namespace %v - ERR: %v", foreignNamespace, err)
+			continue
+		}
+		if len(pods) == 0 {
+			continue
+		}
+
+		podMetrics := map[string]*metricsv1beta1.PodMetrics{}
+		if metricsAvailable {
+			metricsList, err := b.metricsClient.PodMetricses(foreignNamespace).List(ctx, metav1.ListOptions{})
+			switch {
+			case err == nil:
+				for i := range metricsList.Items {
+					podMetrics[metricsList.Items[i].Name] = &metricsList.Items[i]
+				}
+			case isMetricsAPIUnavailable(err):
+				klog.V(4).Infof("foreign metrics API not available, pod stats will not contain usage - ERR: %v", err)
+				metricsAvailable = false
+			default:
+				klog.Errorf("unable to get the metrics of the pods in foreign namespace %v - ERR: %v", foreignNamespace, err)
+			}
+		}
+
+		for _, obj := range pods {
+			pod := obj.(*v1.Pod)
+			pss := forgePodStats(pod, homeNamespace, podMetrics[pod.Name], now)
+			if pss.CPU != nil && pss.CPU.UsageNanoCores != nil {
+				nodeUsageNanoCores += *pss.CPU.UsageNanoCores
+			}
+			if pss.Memory != nil && pss.Memory.UsageBytes != nil {
+				nodeUsageBytes += *pss.Memory.UsageBytes
+			}
+			res.Pods = append(res.Pods, pss)
+		}
+	}
+
+	if metricsAvailable {
+		res.Node.CPU = &stats.CPUStats{
+			Time:           now,
+			UsageNanoCores: &nodeUsageNanoCores,
+		}
+		res.Node.Memory = &stats.MemoryStats{
+			Time:       now,
+			UsageBytes: &nodeUsageBytes,
+		}
+	}
+
+	return res, nil
+}
+
+// forgePodStats creates the PodStats of a foreign pod, referring to its home counterpart.
+// If metrics is nil, the returned stats do not contain any usage information.
+func forgePodStats(pod *v1.Pod, homeNamespace string, metrics *metricsv1beta1.PodMetrics, now metav1.Time) stats.PodStats {
+	startTime := pod.CreationTimestamp
+	if pod.Status.StartTime != nil {
+		startTime = *pod.Status.StartTime
+	}
+
+	pss := stats.PodStats{
+		PodRef: stats.PodReference{
+			Name:      pod.Name,
+			Namespace: homeNamespace,
+			UID:       pod.Annotations["home_uuid"],
+		},
+		StartTime: startTime,
+	}
+
+	if metrics == nil {
+		return pss
+	}
+
+	var (
+		// totalUsageNanoCores will be populated with the sum of the values of UsageNanoCores computes across all containers in the pod.
+		totalUsageNanoCores uint64
+		// totalUsageBytes will be populated with the sum of the values of UsageBytes computed across all containers in the pod.
+		totalUsageBytes uint64
+	)
+
+	for _, container := range metrics.Containers {
+		usageNanoCores := uint64(container.Usage.Cpu().ScaledValue(-9))
+		usageBytes := uint64(container.Usage.Memory().Value())
+		totalUsageNanoCores += usageNanoCores
+		totalUsageBytes += usageBytes
+
+		pss.Containers = append(pss.Containers, stats.ContainerStats{
+			Name:      container.Name,
+			StartTime: startTime,
+			CPU: &stats.CPUStats{
+				Time:           metrics.Timestamp,
+				UsageNanoCores: &usageNanoCores,
+			},
+			Memory: &stats.MemoryStats{
+				Time:            metrics.Timestamp,
+				UsageBytes:      &usageBytes,
+				WorkingSetBytes: &usageBytes,
+			},
+		})
+	}
+
+	pss.CPU = &stats.CPUStats{
+		Time:           now,
+		UsageNanoCores: &totalUsageNanoCores,
+	}
+	pss.Memory = &stats.MemoryStats{
+		Time:            now,
+		UsageBytes:      &totalUsageBytes,
+		WorkingSetBytes: &totalUsageBytes,
+	}
+
+	return pss
+}
+
+// isMetricsAPIUnavailable returns true if the error means that no metrics server is serving the metrics API.
+func isMetricsAPIUnavailable(err error) bool {
+	return kerror.IsNotFound(err) || kerror.IsServiceUnavailable(err) || meta.IsNoMatchError(err)
+}
@@ -0,0 +1,126 @@
+package provider
+
+import (
+	"context"
+	apimgmt "github.com/liqotech/liqo/pkg/virtualKubelet/apiReflection"
+	storageTest "github.com/liqotech/liqo/pkg/virtualKubelet/storage/test"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	kerror "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	k8stesting "k8s.io/client-go/testing"
+	metricsv1beta1 "k8s.io/metrics/pkg/apis/metrics/v1beta1"
+	"k8s.io/metrics/pkg/client/clientset/versioned/fake"
+	"testing"
+	"time"
+)
+
+const (
+	homeNamespace    = "homeNamespace"
+	foreignNamespace = "homeNamespace-natted"
+)
+
+func forgeForeignPod(name string) *v1.Pod {
+	return &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Namespace:   foreignNamespace,
+			Annotations: map[string]string{"home_uuid": name + "-uid"},
+		},
+	}
+}
+
+func forgePodMetrics(name, cpu, memory string) metricsv1beta1.PodMetrics {
+	return metricsv1beta1.PodMetrics{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: foreignNamespace,
+		},
+		Containers: []metricsv1beta1.ContainerMetrics{
+			{
+				Name: "container",
+				Usage: v1.ResourceList{
+					v1.ResourceCPU:    resource.MustParse(cpu),
+					v1.ResourceMemory: resource.MustParse(memory),
+				},
+			},
+		},
+	}
+}
+
+func newStatsSummaryBuilder(listReactor k8stesting.ReactionFunc, pods ...*v1.Pod) *statsSummaryBuilder {
+	cacheManager := &storageTest.MockManager{
+		HomeCache:    map[string]map[apimgmt.ApiType]map[string]metav1.Object{},
+		ForeignCache: map[string]map[apimgmt.ApiType]map[string]metav1.Object{},
+	}
+	for _, pod := range pods {
+		cacheManager.AddForeignEntry(foreignNamespace, apimgmt.Pods, pod)
+	}
+
+	metricsClient := fake.NewSimpleClientset()
+	metricsClient.PrependReactor("list", "pods", listReactor)
+
+	return &statsSummaryBuilder{
+		metricsClient: metricsClient.MetricsV1beta1(),
+		cacheManager:  cacheManager,
+		nodeName:      "virtual-node",
+		startTime:     time.Now(),
+	}
+}
+
+func TestStatsSummary(t *testing.T) {
+	var listedNamespace string
+	builder := newStatsSummaryBuilder(func(action k8stesting.Action) (bool, runtime.Object, error) {
+		listedNamespace = action.GetNamespace()
+		return true, &metricsv1beta1.PodMetricsList{
+			Items: []metricsv1beta1.PodMetrics{
+				forgePodMetrics("pod1", "100m", "10Mi"),
+				forgePodMetrics("pod2", "250m", "20Mi"),
+				forgePodMetrics("not-offloaded", "1", "1Gi"),
+			},
+		}, nil
+	}, forgeForeignPod("pod1"), forgeForeignPod("pod2"))
+
+	summary, err := builder.build(context.TODO(), map[string]string{homeNamespace: foreignNamespace})
+	assert.NoError(t, err)
+	assert.Equal(t, foreignNamespace, listedNamespace)
+	assert.Equal(t, "virtual-node", summary.Node.NodeName)
+	assert.Len(t, summary.Pods, 2)
+
+	for _, pod := range summary.Pods {
+		assert.Equal(t, homeNamespace, pod.PodRef.Namespace)
+		assert.Equal(t, pod.PodRef.Name+"-uid", pod.PodRef.UID)
+		assert.Len(t, pod.Containers, 1)
+		switch pod.PodRef.Name {
+		case "pod1":
+			assert.Equal(t, uint64(100000000), *pod.CPU.UsageNanoCores)
+			assert.Equal(t, uint64(10*1024*1024), *pod.Memory.UsageBytes)
+		case "pod2":
+			assert.Equal(t, uint64(250000000), *pod.CPU.UsageNanoCores)
+			assert.Equal(t, uint64(20*1024*1024), *pod.Memory.UsageBytes)
+		default:
+			t.Errorf("unexpected pod %v in stats summary", pod.PodRef.Name)
+		}
+	}
+
+	assert.Equal(t, uint64(350000000), *summary.Node.CPU.UsageNanoCores)
+	assert.Equal(t, uint64(30*1024*1024), *summary.Node.Memory.UsageBytes)
+}
+
+func TestStatsSummaryNoMetricsServer(t *testing.T) {
+	builder := newStatsSummaryBuilder(func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, kerror.NewNotFound(schema.GroupResource{Group: "metrics.k8s.io", Resource: "pods"}, "")
+	}, forgeForeignPod("pod1"))
+
+	summary, err := builder.build(context.TODO(), map[string]string{homeNamespace: foreignNamespace})
+	assert.NoError(t, err)
+	assert.Len(t, summary.Pods, 1)
+	assert.Equal(t, homeNamespace, summary.Pods[0].PodRef.Namespace)
+	assert.Nil(t, summary.Pods[0].CPU)
+	assert.Nil(t, summary.Pods[0].Memory)
+	assert.Nil(t, summary.Node.CPU)
+	assert.Nil(t, summary.Node.Memory)
+}
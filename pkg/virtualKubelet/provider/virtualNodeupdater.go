@@ -3,6 +3,7 @@ package provider
 import (
 	"context"
 	"errors"
+	"fmt"
 	nettypes "github.com/liqotech/liqo/apis/net/v1alpha1"
 	advtypes "github.com/liqotech/liqo/apis/sharing/v1alpha1"
 	advertisementOperator "github.com/liqotech/liqo/internal/advertisement-operator"
@@ -17,6 +18,7 @@ import (
 	"k8s.io/klog"
 	"k8s.io/kubernetes/pkg/util/slice"
 	"strings"
+	"time"
 )
 
 func (p *KubernetesProvider) StartNodeUpdater(nodeRunner *node.NodeController) (chan struct{}, chan struct{}, error) {
@@ -39,11 +41,14 @@ func (p *KubernetesProvider) StartNodeUpdater(nodeRunner *node.NodeController) (
 	}
 
 	p.nodeController = nodeRunner
+	p.checkForeignCluster()
 
 	ready := make(chan struct{}, 1)
 
 	go func() {
 		<-ready
+		healthTicker := time.NewTicker(foreignHealthCheckPeriod)
+		defer healthTicker.Stop()
 		for {
 			select {
 			case ev := <-advWatcher.ResultChan():
@@ -72,6 +77,11 @@ func (p *KubernetesProvider) StartNodeUpdater(nodeRunner *node.NodeController) (
 						klog.Error(err)
 					}
 				}
+			case <-healthTicker.C:
+				p.checkForeignCluster()
+				if err := p.refreshNodeConditions(); err != nil {
+					klog.Errorf("unable to refresh the conditions of node %v - ERR: %v", p.nodeName.Value(), err)
+				}
 			case <-stop:
 				advWatcher.Stop()
 				tepWatcher.Stop()
@@ -134,6 +144,7 @@ func (p *KubernetesProvider) ReconcileNodeFromTep(event watch.Event) error {
 	if event.Type == watch.Deleted {
 		klog.Infof("tunnelEndpoint %v deleted", tep.Name)
 		p.RemoteRemappedPodCidr.SetValue("")
+		p.setTunnelEndpoint(nil)
 		no, err := p.homeClient.Client().CoreV1().Nodes().Get(context.TODO(), p.nodeName.Value().ToString(), metav1.GetOptions{})
 		if err != nil {
 			klog.Error(err)
@@ -185,31 +196,6 @@ func (p *KubernetesProvider) updateFromAdv(adv advtypes.Advertisement) error {
 		no.Status.Capacity[k] = v
 		no.Status.Allocatable[k] = v
 	}
-	if no.Status.Conditions == nil {
-		no.Status.Conditions = []v1.NodeCondition{
-			{
-				Type:   v1.NodeReady,
-				Status: v1.ConditionFalse,
-			},
-			{
-				Type:   v1.NodeMemoryPressure,
-				Status: v1.ConditionFalse,
-			},
-			{
-				Type:   v1.NodeDiskPressure,
-				Status: v1.ConditionFalse,
-			},
-			{
-				Type:   v1.NodePIDPressure,
-				Status: v1.ConditionFalse,
-			},
-			{
-				Type:   v1.NodeNetworkUnavailable,
-				Status: v1.ConditionTrue,
-			},
-		}
-	}
-
 	no.Status.Images = []v1.ContainerImage{}
 	no.Status.Images = append(no.Status.Images, adv.Spec.Images...)
 
@@ -233,84 +219,51 @@ func (p *KubernetesProvider) updateFromTep(tep nettypes.TunnelEndpoint) error {
 	if tep.Status.LocalRemappedPodCIDR != "" && tep.Status.LocalRemappedPodCIDR != "None" {
 		p.LocalRemappedPodCidr.SetValue(options.OptionValue(tep.Status.LocalRemappedPodCIDR))
 	}
+	p.setTunnelEndpoint(tep.DeepCopy())
 
 	no, err := p.homeClient.Client().CoreV1().Nodes().Get(context.TODO(), p.nodeName.Value().ToString(), metav1.GetOptions{})
 	if err != nil {
 		return err
 	}
 
-	if no.Status.Conditions == nil {
-		no.Status.Conditions = []v1.NodeCondition{
-			{
-				Type:   v1.NodeReady,
-				Status: v1.ConditionFalse,
-			},
-			{
-				Type:   v1.NodeMemoryPressure,
-				Status: v1.ConditionFalse,
-			},
-			{
-				Type:   v1.NodeDiskPressure,
-				Status: v1.ConditionFalse,
-			},
-			{
-				Type:   v1.NodePIDPressure,
-				Status: v1.ConditionFalse,
-			},
-			{
-				Type:   v1.NodeNetworkUnavailable,
-				Status: v1.ConditionTrue,
-			},
-		}
-	}
-
 	return p.updateNode(no)
 }
 
+// updateNode sets the node conditions according to the status of the foreign cluster and updates the node.
 func (p *KubernetesProvider) updateNode(node *v1.Node) error {
-	if p.RemoteRemappedPodCidr.Value() != "" && node.Status.Allocatable != nil {
-		// both the podCIDR and the resources have been set: the node is ready
-		for i, condition := range node.Status.Conditions {
-			if condition.Type == v1.NodeReady {
-				node.Status.Conditions[i].Status = v1.ConditionTrue
-			}
-			if condition.Type == v1.NodeNetworkUnavailable {
-				node.Status.Conditions[i].Status = v1.ConditionFalse
-			}
-		}
-	} else if p.RemoteRemappedPodCidr.Value() != "" && node.Status.Allocatable == nil {
-		// the resources have not been set yet: set the node status to NotReady
-		for i, condition := range node.Status.Conditions {
-			if condition.Type == v1.NodeReady {
-				node.Status.Conditions[i].Status = v1.ConditionFalse
-			}
-		}
-	} else if p.RemoteRemappedPodCidr.Value() == "" && node.Status.Allocatable != nil {
-		// the podCIDR has not been set yet: set the node status to NetworkUnavailable
-		for i, condition := range node.Status.Conditions {
-			if condition.Type == v1.NodeReady {
-				node.Status.Conditions[i].Status = v1.ConditionFalse
-			}
-			if condition.Type == v1.NodeNetworkUnavailable {
-				node.Status.Conditions[i].Status = v1.ConditionTrue
-			}
-		}
-	} else {
-		// both the podCIDR and resources have not been set
-		for i, condition := range node.Status.Conditions {
-			if condition.Type == v1.NodeReady {
-				node.Status.Conditions[i].Status = v1.ConditionFalse
-			}
-		}
-	}
+	resourcesAdvertised := len(node.Status.Allocatable) > 0
+	node.Status.Conditions = forgeNodeConditions(p.getForeignClusterStatus(resourcesAdvertised), node.Status.Conditions, metav1.Now())
 	return p.nodeController.UpdateNodeFromOutside(false, node)
 }
 
+// refreshNodeConditions updates the node conditions after a check of the foreign cluster health.
+func (p *KubernetesProvider) refreshNodeConditions() error {
+	no, err := p.homeClient.Client().CoreV1().Nodes().Get(context.TODO(), p.nodeName.Value().ToString(), metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	return p.updateNode(no)
+}
+
 func (p *KubernetesProvider) handleAdvDelete(adv *advtypes.Advertisement) error {
 	if err := p.apiController.StopController(); err != nil {
 		return err
 	}
 
+	// the peering is being torn down, the DNS of the foreign cluster no longer resolves the names of the home services
+	if err := p.namespaceMapper.RemoveDNSRewriting(); err != nil {
+		klog.Errorf("cannot remove the DNS rewriting of the natted namespaces from the foreign cluster - ERR: %v", err)
+	}
+
+	return p.releaseAdvertisement(adv)
+}
+
+// releaseAdvertisement applies the eviction policy to the offloaded pods, which would otherwise be left on a node
+// being torn down, and then removes the finalizer of the Advertisement, which triggers the deletion of the virtual kubelet.
+func (p *KubernetesProvider) releaseAdvertisement(adv *advtypes.Advertisement) error {
+	message := fmt.Sprintf("the advertisement of foreign cluster %v has been deleted", p.foreignClusterId)
+	p.evictOffloadedPods(ReasonAdvertisementDeleted, message)
+
 	// remove finalizer
 	if slice.ContainsString(adv.Finalizers, advertisementOperator.FinalizerString, nil) {
 		adv.Finalizers = slice.RemoveString(adv.Finalizers, advertisementOperator.FinalizerString, nil)
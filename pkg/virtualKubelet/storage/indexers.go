@@ -10,12 +10,13 @@ import (
 )
 
 var InformerIndexers = map[apimgmt.ApiType]func() cache.Indexers{
-	apimgmt.Configmaps:     configmapsIndexers,
-	apimgmt.EndpointSlices: endpointSlicesIndexers,
-	apimgmt.Pods:           podsIndexers,
-	apimgmt.ReplicaSets:    replicaControllerIndexers,
-	apimgmt.Secrets:        secretsIndexers,
-	apimgmt.Services:       servicesIndexers,
+	apimgmt.Configmaps:             configmapsIndexers,
+	apimgmt.EndpointSlices:         endpointSlicesIndexers,
+	apimgmt.Pods:                   podsIndexers,
+	apimgmt.ReplicaSets:            replicaControllerIndexers,
+	apimgmt.Secrets:                secretsIndexers,
+	apimgmt.Services:               servicesIndexers,
+	apimgmt.PersistentVolumeClaims: persistentVolumeClaimsIndexers,
 }
 
 func configmapsIndexers() cache.Indexers {
@@ -103,3 +104,17 @@ func servicesIndexers() cache.Indexers {
 	}
 	return i
 }
+
+func persistentVolumeClaimsIndexers() cache.Indexers {
+	i := cache.Indexers{}
+	i["persistentvolumeclaims"] = func(obj interface{}) ([]string, error) {
+		pvc, ok := obj.(*corev1.PersistentVolumeClaim)
+		if !ok {
+			return []string{}, errors.New("cannot convert obj to persistentvolumeclaim")
+		}
+		return []string{
+			strings.Join([]string{pvc.Namespace, pvc.Name}, "/"),
+		}, nil
+	}
+	return i
+}
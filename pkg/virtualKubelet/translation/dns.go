@@ -0,0 +1,149 @@
+package translation
+
+import (
+	"errors"
+	"fmt"
+	v1 "k8s.io/api/core/v1"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// LiqoDNSZone is the zone under which the names of the home services are resolved in the foreign cluster.
+// Each home cluster gets its own subdomain, hence the rewrite rules of different home clusters never overlap.
+const LiqoDNSZone = "liqo.local"
+
+// ErrCorefileNotSupported is returned when the Corefile cannot be configured to resolve the names of the home
+// services, since it lacks a server block of the root zone where the cluster names are served by the kubernetes
+// plugin, as with the DNS services managed by the cloud providers.
+var ErrCorefileNotSupported = errors.New("the Corefile is not supported")
+
+// rootServerBlock matches the opening line of the server block of the root zone in a Corefile, e.g. ".:53 {".
+var rootServerBlock = regexp.MustCompile(`^\s*\.(:[0-9]+)?\s*\{\s*$`)
+
+// DNSSearchDomain returns the search domain added to the offloaded pods of the given home cluster.
+func DNSSearchDomain(homeClusterID string) string {
+	return strings.Join([]string{strings.ToLower(homeClusterID), LiqoDNSZone}, ".")
+}
+
+// TranslateDNSConfig sets on the foreign pod the DNS policy and configuration of the home one. If the pod
+// resolves the names through the cluster DNS, the search domain of the home cluster is appended, so that
+// the names of the home services (e.g. svc.namespace.svc.cluster.local) are resolved by the foreign cluster
+// to their reflected counterparts in the natted namespaces. An empty search domain leaves the DNS configuration
+// of the home pod untouched.
+func TranslateDNSConfig(foreignPod, homePod *v1.Pod, searchDomain string) *v1.Pod {
+	foreignPod.Spec.DNSPolicy = homePod.Spec.DNSPolicy
+	foreignPod.Spec.DNSConfig = homePod.Spec.DNSConfig.DeepCopy()
+	if searchDomain == "" {
+		return foreignPod
+	}
+	switch foreignPod.Spec.DNSPolicy {
+	case "", v1.DNSClusterFirst, v1.DNSClusterFirstWithHostNet:
+	default:
+		return foreignPod
+	}
+	if foreignPod.Spec.DNSConfig == nil {
+		foreignPod.Spec.DNSConfig = &v1.PodDNSConfig{}
+	}
+	for _, search := range foreignPod.Spec.DNSConfig.Searches {
+		if search == searchDomain {
+			return foreignPod
+		}
+	}
+	foreignPod.Spec.DNSConfig.Searches = append(foreignPod.Spec.DNSConfig.Searches, searchDomain)
+	return foreignPod
+}
+
+// UpdateCorefile returns the Corefile with the rewrite rules of the given home cluster replaced by the ones
+// matching the namespaces of the natting table. The rules translate the names of the home services in the home
+// cluster domain, qualified with the search domain of the home cluster, to the names of the reflected services
+// in the natted namespaces, in the domain served by the kubernetes plugin of the foreign cluster, and the names
+// in the answers back. They are placed at the beginning of the server block of the root zone, delimited by
+// comments, and removed if the natting table is empty.
+func UpdateCorefile(corefile, homeClusterID, homeClusterDomain string, nattingTable map[string]string) (string, error) {
+	begin := fmt.Sprintf("# liqo: begin of the rewrite rules for cluster %s", homeClusterID)
+	end := fmt.Sprintf("# liqo: end of the rewrite rules for cluster %s", homeClusterID)
+
+	// remove the rules previously configured
+	var lines []string
+	skip := false
+	for _, line := range strings.Split(corefile, "\n") {
+		switch strings.TrimSpace(line) {
+		case begin:
+			skip = true
+		case end:
+			skip = false
+		default:
+			if !skip {
+				lines = append(lines, line)
+			}
+		}
+	}
+	if len(nattingTable) == 0 {
+		return strings.Join(lines, "\n"), nil
+	}
+
+	for i, line := range lines {
+		if !rootServerBlock.MatchString(line) {
+			continue
+		}
+		foreignClusterDomain, err := getClusterDomain(lines[i+1:])
+		if err != nil {
+			return "", err
+		}
+		rules := append([]string{begin}, getRewriteRules(homeClusterID, homeClusterDomain, foreignClusterDomain, nattingTable)...)
+		rules = append(rules, end)
+		for j := range rules {
+			rules[j] = "    " + rules[j]
+		}
+		lines = append(lines[:i+1], append(rules, lines[i+1:]...)...)
+		return strings.Join(lines, "\n"), nil
+	}
+	return "", fmt.Errorf("%w: server block of the root zone not found", ErrCorefileNotSupported)
+}
+
+// getClusterDomain returns the first zone served by the kubernetes plugin in the given server block,
+// whose opening line has already been consumed.
+func getClusterDomain(block []string) (string, error) {
+	depth := 0
+	for _, line := range block {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if depth == 0 && fields[0] == "kubernetes" && len(fields) > 1 && fields[1] != "{" {
+			return strings.TrimSuffix(fields[1], "."), nil
+		}
+		depth += strings.Count(line, "{") - strings.Count(line, "}")
+		if depth < 0 {
+			break
+		}
+	}
+	return "", fmt.Errorf("%w: no zone served by the kubernetes plugin in the server block of the root zone", ErrCorefileNotSupported)
+}
+
+// getRewriteRules returns a rewrite rule for each form of the name of a home service (svc.namespace,
+// svc.namespace.svc and svc.namespace.svc.<home cluster domain>) and for each natted namespace. The longest forms
+// come first, since the first matching rule stops the processing of the others.
+func getRewriteRules(homeClusterID, homeClusterDomain, foreignClusterDomain string, nattingTable map[string]string) []string {
+	searchDomain := DNSSearchDomain(homeClusterID)
+	namespaces := make([]string, 0, len(nattingTable))
+	for namespace := range nattingTable {
+		namespaces = append(namespaces, namespace)
+	}
+	sort.Strings(namespaces)
+
+	var rules []string
+	for _, suffix := range []string{".svc." + homeClusterDomain, ".svc", ""} {
+		for _, namespace := range namespaces {
+			homeName := namespace + suffix + "." + searchDomain
+			foreignName := nattingTable[namespace] + ".svc." + foreignClusterDomain
+			rules = append(rules,
+				"rewrite stop {",
+				fmt.Sprintf("    name regex ^(.*)\\.%s\\.?$ {1}.%s", regexp.QuoteMeta(homeName), foreignName),
+				fmt.Sprintf("    answer name ^(.*)\\.%s\\.?$ {1}.%s", regexp.QuoteMeta(foreignName), homeName),
+				"}")
+		}
+	}
+	return rules
+}
@@ -0,0 +1,99 @@
+package translation
+
+import (
+	"errors"
+	"gotest.tools/assert"
+	v1 "k8s.io/api/core/v1"
+	"strings"
+	"testing"
+)
+
+const testCorefile = `.:53 {
+    errors
+    health
+    kubernetes cluster.local in-addr.arpa ip6.arpa {
+       pods insecure
+       fallthrough in-addr.arpa ip6.arpa
+    }
+    forward . /etc/resolv.conf
+    cache 30
+    reload
+}`
+
+func TestTranslateDNSConfig(t *testing.T) {
+	searchDomain := DNSSearchDomain("Home-Cluster")
+	assert.Equal(t, "home-cluster.liqo.local", searchDomain)
+
+	// the search domain is added to the pods using the cluster DNS
+	homePod := &v1.Pod{}
+	foreignPod := TranslateDNSConfig(&v1.Pod{}, homePod, searchDomain)
+	assert.DeepEqual(t, []string{searchDomain}, foreignPod.Spec.DNSConfig.Searches)
+	assert.Assert(t, homePod.Spec.DNSConfig == nil)
+
+	// the configuration of the home pod is preserved, and the search domain is not duplicated
+	homePod.Spec.DNSPolicy = v1.DNSClusterFirst
+	homePod.Spec.DNSConfig = &v1.PodDNSConfig{Searches: []string{"example.com", searchDomain}}
+	foreignPod = TranslateDNSConfig(&v1.Pod{}, homePod, searchDomain)
+	assert.Equal(t, v1.DNSClusterFirst, foreignPod.Spec.DNSPolicy)
+	assert.DeepEqual(t, []string{"example.com", searchDomain}, foreignPod.Spec.DNSConfig.Searches)
+
+	// the pods not using the cluster DNS are left untouched
+	homePod.Spec.DNSPolicy = v1.DNSNone
+	homePod.Spec.DNSConfig = &v1.PodDNSConfig{Nameservers: []string{"1.1.1.1"}}
+	foreignPod = TranslateDNSConfig(&v1.Pod{}, homePod, searchDomain)
+	assert.Equal(t, v1.DNSNone, foreignPod.Spec.DNSPolicy)
+	assert.Equal(t, 0, len(foreignPod.Spec.DNSConfig.Searches))
+
+	// the translation can be disabled
+	foreignPod = TranslateDNSConfig(&v1.Pod{}, &v1.Pod{}, "")
+	assert.Assert(t, foreignPod.Spec.DNSConfig == nil)
+}
+
+func TestUpdateCorefile(t *testing.T) {
+	nattingTable := map[string]string{
+		"default": "default-home",
+		"app":     "app-home",
+	}
+
+	corefile, err := UpdateCorefile(testCorefile, "home", "cluster.local", nattingTable)
+	assert.NilError(t, err)
+	lines := strings.Split(corefile, "\n")
+	assert.Equal(t, ".:53 {", lines[0])
+	assert.Equal(t, "    # liqo: begin of the rewrite rules for cluster home", lines[1])
+	// the rules of the longest form of the names come first
+	assert.Equal(t, `        name regex ^(.*)\.app\.svc\.cluster\.local\.home\.liqo\.local\.?$ {1}.app-home.svc.cluster.local`, lines[3])
+	assert.Equal(t, `        answer name ^(.*)\.app-home\.svc\.cluster\.local\.?$ {1}.app.svc.cluster.local.home.liqo.local`, lines[4])
+	assert.Equal(t, `        name regex ^(.*)\.default\.home\.liqo\.local\.?$ {1}.default-home.svc.cluster.local`, lines[23])
+	assert.Equal(t, "    # liqo: end of the rewrite rules for cluster home", lines[26])
+	assert.Equal(t, "    errors", lines[27])
+
+	// the update is idempotent and preserves the rules of other home clusters
+	updated, err := UpdateCorefile(corefile, "home", "cluster.local", nattingTable)
+	assert.NilError(t, err)
+	assert.Equal(t, corefile, updated)
+	updated, err = UpdateCorefile(corefile, "other", "cluster.local", map[string]string{"default": "default-other"})
+	assert.NilError(t, err)
+	assert.Assert(t, strings.Contains(updated, "# liqo: begin of the rewrite rules for cluster home"))
+	assert.Assert(t, strings.Contains(updated, "# liqo: begin of the rewrite rules for cluster other"))
+
+	// the rules are removed with the natted namespaces
+	updated, err = UpdateCorefile(corefile, "home", "cluster.local", map[string]string{})
+	assert.NilError(t, err)
+	assert.Equal(t, testCorefile, updated)
+
+	// the names are translated to the domain of the foreign cluster
+	updated, err = UpdateCorefile(strings.Replace(testCorefile, "kubernetes cluster.local", "kubernetes foreign.local.", 1), "home", "cluster.local", nattingTable)
+	assert.NilError(t, err)
+	lines = strings.Split(updated, "\n")
+	assert.Equal(t, `        name regex ^(.*)\.app\.svc\.cluster\.local\.home\.liqo\.local\.?$ {1}.app-home.svc.foreign.local`, lines[3])
+	assert.Equal(t, `        answer name ^(.*)\.app-home\.svc\.foreign\.local\.?$ {1}.app.svc.cluster.local.home.liqo.local`, lines[4])
+
+	// the Corefile must contain the server block of the root zone, served by the kubernetes plugin
+	_, err = UpdateCorefile("example.org {\n}", "home", "cluster.local", nattingTable)
+	assert.Assert(t, errors.Is(err, ErrCorefileNotSupported))
+	_, err = UpdateCorefile(".:53 {\n    forward . /etc/resolv.conf\n}\ncluster.local:53 {\n    kubernetes cluster.local\n}", "home", "cluster.local", nattingTable)
+	assert.Assert(t, errors.Is(err, ErrCorefileNotSupported))
+	// the rules can be removed anyway
+	_, err = UpdateCorefile("example.org {\n}", "home", "cluster.local", map[string]string{})
+	assert.NilError(t, err)
+}
@@ -0,0 +1,88 @@
+package translation
+
+import (
+	"errors"
+	"fmt"
+	"k8s.io/klog"
+	"net"
+)
+
+// ErrFamilyMismatch is returned when remapping an IP to a CIDR of a different address family.
+var ErrFamilyMismatch = errors.New("IP and CIDR belong to different address families")
+
+// ChangePodIp translates a pod IP to the given (remapped) pod CIDR, keeping the host part of the IP
+// and replacing the network part with the one of the CIDR. The IP is returned unchanged if the CIDR
+// is empty, if it belongs to a different address family (e.g. in dual-stack clusters) or if the
+// translation is not possible.
+func ChangePodIp(newPodCidr string, oldPodIp string) (newPodIp string) {
+	if newPodCidr == "" {
+		return oldPodIp
+	}
+
+	newPodIp, err := RemapIp(newPodCidr, oldPodIp)
+	if errors.Is(err, ErrFamilyMismatch) {
+		return oldPodIp
+	}
+	if err != nil {
+		klog.Errorf("unable to translate pod IP %v to CIDR %v - ERR: %v", oldPodIp, newPodCidr, err)
+		return oldPodIp
+	}
+	return newPodIp
+}
+
+// RestorePodIp is the inverse of ChangePodIp: it translates an IP belonging to the remapped pod CIDR
+// back to the original pod CIDR. The two CIDRs must have the same prefix length.
+func RestorePodIp(originalPodCidr, remappedPodCidr, remappedPodIp string) (string, error) {
+	_, original, err := net.ParseCIDR(originalPodCidr)
+	if err != nil {
+		return "", err
+	}
+	_, remapped, err := net.ParseCIDR(remappedPodCidr)
+	if err != nil {
+		return "", err
+	}
+
+	originalOnes, originalBits := original.Mask.Size()
+	remappedOnes, remappedBits := remapped.Mask.Size()
+	if originalOnes != remappedOnes || originalBits != remappedBits {
+		return "", fmt.Errorf("CIDRs %v and %v have different prefix lengths", originalPodCidr, remappedPodCidr)
+	}
+
+	ip := net.ParseIP(remappedPodIp)
+	if ip == nil {
+		return "", fmt.Errorf("invalid IP address %q", remappedPodIp)
+	}
+	if !remapped.Contains(ip) {
+		return "", fmt.Errorf("IP %v does not belong to CIDR %v", remappedPodIp, remappedPodCidr)
+	}
+
+	return RemapIp(originalPodCidr, remappedPodIp)
+}
+
+// RemapIp returns the IP obtained by combining the network part of the given CIDR with the host part
+// of the given IP, according to the prefix length of the CIDR. Both IPv4 and IPv6 are supported,
+// as long as the IP and the CIDR belong to the same family.
+func RemapIp(cidr string, ip string) (string, error) {
+	_, network, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return "", err
+	}
+
+	parsedIp := net.ParseIP(ip)
+	if parsedIp == nil {
+		return "", fmt.Errorf("invalid IP address %q", ip)
+	}
+
+	if ip4 := parsedIp.To4(); ip4 != nil {
+		parsedIp = ip4
+	}
+	if len(parsedIp) != len(network.IP) {
+		return "", fmt.Errorf("%v, %v: %w", ip, cidr, ErrFamilyMismatch)
+	}
+
+	remapped := make(net.IP, len(parsedIp))
+	for i := range parsedIp {
+		remapped[i] = network.IP[i] | (parsedIp[i] &^ network.Mask[i])
+	}
+	return remapped.String(), nil
+}
@@ -0,0 +1,131 @@
+package translation
+
+import (
+	"errors"
+	"gotest.tools/assert"
+	"testing"
+)
+
+func TestRemapIp(t *testing.T) {
+	testCases := []struct {
+		name     string
+		cidr     string
+		ip       string
+		expected string
+	}{
+		{name: "ipv4 /8", cidr: "11.0.0.0/8", ip: "10.1.2.3", expected: "11.1.2.3"},
+		{name: "ipv4 /12", cidr: "172.16.0.0/12", ip: "10.33.2.3", expected: "172.17.2.3"},
+		{name: "ipv4 /16", cidr: "10.200.0.0/16", ip: "10.0.2.3", expected: "10.200.2.3"},
+		{name: "ipv4 /20", cidr: "192.168.16.0/20", ip: "10.0.47.3", expected: "192.168.31.3"},
+		{name: "ipv4 /24", cidr: "192.168.100.0/24", ip: "10.0.2.3", expected: "192.168.100.3"},
+		{name: "ipv4 /25", cidr: "192.168.100.128/25", ip: "10.0.2.3", expected: "192.168.100.131"},
+		{name: "ipv4 /32", cidr: "192.168.100.7/32", ip: "10.0.2.3", expected: "192.168.100.7"},
+		{name: "ipv4 /0", cidr: "0.0.0.0/0", ip: "10.0.2.3", expected: "10.0.2.3"},
+		{name: "ipv4 cidr not aligned", cidr: "10.201.5.6/16", ip: "10.0.2.3", expected: "10.201.2.3"},
+		{name: "ipv6 /48", cidr: "fd00:1:2::/48", ip: "fd00:aaaa:bbbb:cccc::1", expected: "fd00:1:2:cccc::1"},
+		{name: "ipv6 /56", cidr: "fd00:1:2:3300::/56", ip: "fd00:0:0:44::1", expected: "fd00:1:2:3344::1"},
+		{name: "ipv6 /64", cidr: "2001:db8:0:1::/64", ip: "fd00::abcd:1", expected: "2001:db8:0:1::abcd:1"},
+		{name: "ipv6 /112", cidr: "fd00::1:0/112", ip: "fd00::9:beef", expected: "fd00::1:beef"},
+		{name: "ipv6 /128", cidr: "fd00::5/128", ip: "fd00::9:beef", expected: "fd00::5"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			remapped, err := RemapIp(tc.cidr, tc.ip)
+			assert.NilError(t, err)
+			assert.Equal(t, remapped, tc.expected)
+		})
+	}
+}
+
+func TestRemapIpErrors(t *testing.T) {
+	testCases := []struct {
+		name   string
+		cidr   string
+		ip     string
+		family bool
+	}{
+		{name: "invalid cidr", cidr: "10.0.0.0", ip: "10.0.0.1"},
+		{name: "invalid ip", cidr: "10.0.0.0/16", ip: "10.0.0"},
+		{name: "ipv6 ip with ipv4 cidr", cidr: "10.0.0.0/16", ip: "fd00::1", family: true},
+		{name: "ipv4 ip with ipv6 cidr", cidr: "fd00::/64", ip: "10.0.0.1", family: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := RemapIp(tc.cidr, tc.ip)
+			assert.Assert(t, err != nil)
+			assert.Equal(t, errors.Is(err, ErrFamilyMismatch), tc.family)
+		})
+	}
+}
+
+func TestChangePodIp(t *testing.T) {
+	testCases := []struct {
+		name     string
+		cidr     string
+		ip       string
+		expected string
+	}{
+		{name: "empty cidr", cidr: "", ip: "10.0.2.3", expected: "10.0.2.3"},
+		{name: "ipv4 /16", cidr: "10.200.0.0/16", ip: "10.0.2.3", expected: "10.200.2.3"},
+		{name: "ipv4 /24", cidr: "192.168.100.0/24", ip: "10.0.2.3", expected: "192.168.100.3"},
+		{name: "dual-stack ipv6 left untouched", cidr: "10.200.0.0/16", ip: "fd00::1", expected: "fd00::1"},
+		{name: "invalid cidr left untouched", cidr: "10.200.0.0", ip: "10.0.2.3", expected: "10.0.2.3"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, ChangePodIp(tc.cidr, tc.ip), tc.expected)
+		})
+	}
+}
+
+func TestRestorePodIp(t *testing.T) {
+	testCases := []struct {
+		original string
+		remapped string
+		ip       string
+	}{
+		{original: "10.0.0.0/8", remapped: "11.0.0.0/8", ip: "10.1.2.3"},
+		{original: "10.32.0.0/12", remapped: "172.16.0.0/12", ip: "10.33.2.3"},
+		{original: "10.0.0.0/16", remapped: "10.200.0.0/16", ip: "10.0.2.3"},
+		{original: "10.0.32.0/20", remapped: "192.168.16.0/20", ip: "10.0.47.3"},
+		{original: "10.0.2.0/24", remapped: "192.168.100.0/24", ip: "10.0.2.3"},
+		{original: "10.0.2.0/28", remapped: "192.168.100.16/28", ip: "10.0.2.13"},
+		{original: "fd00:aaaa:bbbb::/48", remapped: "fd00:1:2::/48", ip: "fd00:aaaa:bbbb:cccc::1"},
+		{original: "fd00::/64", remapped: "2001:db8:0:1::/64", ip: "fd00::abcd:1"},
+		{original: "fd00::9:0/112", remapped: "fd00::1:0/112", ip: "fd00::9:beef"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.original, func(t *testing.T) {
+			remapped := ChangePodIp(tc.remapped, tc.ip)
+			restored, err := RestorePodIp(tc.original, tc.remapped, remapped)
+			assert.NilError(t, err)
+			assert.Equal(t, restored, tc.ip)
+		})
+	}
+}
+
+func TestRestorePodIpErrors(t *testing.T) {
+	testCases := []struct {
+		name     string
+		original string
+		remapped string
+		ip       string
+	}{
+		{name: "different prefix lengths", original: "10.0.0.0/16", remapped: "10.200.0.0/24", ip: "10.200.0.1"},
+		{name: "different families", original: "10.0.0.0/16", remapped: "fd00::/112", ip: "fd00::1"},
+		{name: "ip outside remapped cidr", original: "10.0.0.0/16", remapped: "10.200.0.0/16", ip: "10.0.0.1"},
+		{name: "invalid ip", original: "10.0.0.0/16", remapped: "10.200.0.0/16", ip: "10.200"},
+		{name: "invalid original cidr", original: "10.0.0.0", remapped: "10.200.0.0/16", ip: "10.200.0.1"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := RestorePodIp(tc.original, tc.remapped, tc.ip)
+			assert.Assert(t, err != nil)
+		})
+	}
+}
@@ -5,6 +5,7 @@ import (
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"reflect"
 	"strconv"
 	"strings"
 	"time"
@@ -24,9 +25,10 @@ func F2HTranslate(podForeignIn *v1.Pod, newCidr, namespace string) (podHomeOut *
 		podHomeOut.DeletionGracePeriodSeconds = nil
 	}
 	if podHomeOut.Status.PodIP != "" {
-		newIp := ChangePodIp(newCidr, podHomeOut.Status.PodIP)
-		podHomeOut.Status.PodIP = newIp
-		podHomeOut.Status.PodIPs[0].IP = newIp
+		podHomeOut.Status.PodIP = ChangePodIp(newCidr, podHomeOut.Status.PodIP)
+	}
+	for i := range podHomeOut.Status.PodIPs {
+		podHomeOut.Status.PodIPs[i].IP = ChangePodIp(newCidr, podHomeOut.Status.PodIPs[i].IP)
 	}
 
 	podHomeOut.SetCreationTimestamp(metav1.NewTime(t))
@@ -39,12 +41,17 @@ func F2HTranslate(podForeignIn *v1.Pod, newCidr, namespace string) (podHomeOut *
 }
 
 func H2FTranslate(pod *v1.Pod, nattedNS string) *v1.Pod {
-	// create an empty ObjectMeta for the output pod, copying only "Name" and "Namespace" fields
+	// create an empty ObjectMeta for the output pod, copying only "Name", "Namespace", "Labels" and "Annotations" fields
 	objectMeta := metav1.ObjectMeta{
 		Name:      pod.ObjectMeta.Name,
 		Namespace: nattedNS,
 		Labels:    pod.Labels,
 	}
+	for k, v := range pod.Annotations {
+		if isForwardedAnnotation(k) {
+			metav1.SetMetaDataAnnotation(&objectMeta, k, v)
+		}
+	}
 
 	// filter volumes which can be mounted on the foreign cluster
 	volumes := FilterVolumes(pod.Spec.Volumes)
@@ -94,6 +101,8 @@ func H2FTranslate(pod *v1.Pod, nattedNS string) *v1.Pod {
 		SecurityContext:               pod.Spec.SecurityContext,
 		Hostname:                      pod.Spec.Hostname,
 		NodeSelector:                  pod.Spec.NodeSelector,
+		ActiveDeadlineSeconds:         pod.Spec.ActiveDeadlineSeconds,
+		Tolerations:                   pod.Spec.Tolerations,
 		//TODO: check if we need other fields
 	}
 
@@ -128,10 +137,243 @@ func translateContainer(container v1.Container, volumes []v1.VolumeMount) v1.Con
 	}
 }
 
+func translateEphemeralContainer(container v1.EphemeralContainer, volumes []v1.Volume) v1.EphemeralContainer {
+	container.VolumeMounts = FilterVolumeMounts(volumes, container.VolumeMounts)
+	return container
+}
+
+// homeAnnotations are the annotations set by H2FTranslate to keep track of the home pod, which are only
+// meaningful when the foreign pod is created
+var homeAnnotations = []string{"home_nodename", "home_resourceVersion", "home_uuid", "home_creationTimestamp"}
+
+// isForwardedAnnotation returns whether the annotation of a home pod is set on the foreign pod. The annotations
+// reserved to the Kubernetes components (e.g. kubectl.kubernetes.io/last-applied-configuration) only make sense
+// in the home cluster.
+func isForwardedAnnotation(key string) bool {
+	for _, annotation := range homeAnnotations {
+		if key == annotation {
+			return false
+		}
+	}
+	if i := strings.Index(key, "/"); i >= 0 {
+		prefix := key[:i]
+		for _, reserved := range []string{"kubernetes.io", "k8s.io"} {
+			if prefix == reserved || strings.HasSuffix(prefix, "."+reserved) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// UpdateForeignPod applies to foreignPod the fields of translatedPod that can be modified once the pod
+// has been created, namely labels, annotations, container images, activeDeadlineSeconds and tolerations.
+// It returns the updated pod and whether at least a field has been changed.
+// The annotations set on foreignPod and not present in translatedPod are kept, since they may have been
+// added by the foreign cluster.
+func UpdateForeignPod(foreignPod, translatedPod *v1.Pod) (*v1.Pod, bool) {
+	podOut := foreignPod.DeepCopy()
+
+	if !reflect.DeepEqual(podOut.Labels, translatedPod.Labels) && (len(podOut.Labels) != 0 || len(translatedPod.Labels) != 0) {
+		podOut.Labels = translatedPod.Labels
+	}
+	for k, v := range translatedPod.Annotations {
+		if isForwardedAnnotation(k) {
+			metav1.SetMetaDataAnnotation(&podOut.ObjectMeta, k, v)
+		}
+	}
+
+	updateContainerImages(podOut.Spec.Containers, translatedPod.Spec.Containers)
+	updateContainerImages(podOut.Spec.InitContainers, translatedPod.Spec.InitContainers)
+
+	if activeDeadlineSecondsChangeAllowed(podOut.Spec.ActiveDeadlineSeconds, translatedPod.Spec.ActiveDeadlineSeconds) {
+		podOut.Spec.ActiveDeadlineSeconds = translatedPod.Spec.ActiveDeadlineSeconds
+	}
+
+	// tolerations can only be added
+	for _, toleration := range translatedPod.Spec.Tolerations {
+		if !containsToleration(podOut.Spec.Tolerations, &toleration) {
+			podOut.Spec.Tolerations = append(podOut.Spec.Tolerations, toleration)
+		}
+	}
+
+	return podOut, !reflect.DeepEqual(foreignPod.ObjectMeta, podOut.ObjectMeta) || !reflect.DeepEqual(foreignPod.Spec, podOut.Spec)
+}
+
+// TranslateEphemeralContainers returns the ephemeral containers of the home pod translated for the foreign cluster.
+// They are not set by H2FTranslate, since they can only be added once the pod has been created.
+func TranslateEphemeralContainers(pod *v1.Pod) []v1.EphemeralContainer {
+	volumes := FilterVolumes(pod.Spec.Volumes)
+	var containers []v1.EphemeralContainer
+	for i := range pod.Spec.EphemeralContainers {
+		containers = append(containers, translateEphemeralContainer(pod.Spec.EphemeralContainers[i], volumes))
+	}
+	return containers
+}
+
+// NewEphemeralContainers returns the ephemeral containers of translatedPod not yet existing in foreignPod.
+func NewEphemeralContainers(foreignPod, translatedPod *v1.Pod) []v1.EphemeralContainer {
+	var containers []v1.EphemeralContainer
+	for _, c := range translatedPod.Spec.EphemeralContainers {
+		found := false
+		for i := range foreignPod.Spec.EphemeralContainers {
+			if foreignPod.Spec.EphemeralContainers[i].Name == c.Name {
+				found = true
+				break
+			}
+		}
+		if !found {
+			containers = append(containers, c)
+		}
+	}
+	return containers
+}
+
+// ImmutableFieldsChanged returns the list of the fields that differ between translatedPod and foreignPod,
+// but cannot be updated once the foreign pod has been created, i.e. all the fields set by H2FTranslate except
+// the ones applied by UpdateForeignPod. The foreign pod is allowed to contain what has been added by the foreign
+// cluster, such as the volume of its service account token, the default values and the translated environment
+// variables.
+func ImmutableFieldsChanged(foreignPod, translatedPod *v1.Pod) []string {
+	var fields []string
+	check := func(field string, unchanged bool) {
+		if !unchanged {
+			fields = append(fields, field)
+		}
+	}
+	foreignSpec, translatedSpec := &foreignPod.Spec, &translatedPod.Spec
+
+	check("spec.containers", sameContainers(foreignSpec.Containers, translatedSpec.Containers))
+	check("spec.initContainers", sameContainers(foreignSpec.InitContainers, translatedSpec.InitContainers))
+	check("spec.volumes", containsVolumes(foreignSpec.Volumes, translatedSpec.Volumes))
+	check("spec.imagePullSecrets", containsPullSecrets(foreignSpec.ImagePullSecrets, translatedSpec.ImagePullSecrets))
+	check("spec.restartPolicy", translatedSpec.RestartPolicy == "" || foreignSpec.RestartPolicy == translatedSpec.RestartPolicy)
+	check("spec.terminationGracePeriodSeconds", translatedSpec.TerminationGracePeriodSeconds == nil ||
+		reflect.DeepEqual(foreignSpec.TerminationGracePeriodSeconds, translatedSpec.TerminationGracePeriodSeconds))
+	check("spec.securityContext", translatedSpec.SecurityContext == nil ||
+		reflect.DeepEqual(foreignSpec.SecurityContext, translatedSpec.SecurityContext))
+	check("spec.hostname", foreignSpec.Hostname == translatedSpec.Hostname)
+	check("spec.nodeSelector", (len(foreignSpec.NodeSelector) == 0 && len(translatedSpec.NodeSelector) == 0) ||
+		reflect.DeepEqual(foreignSpec.NodeSelector, translatedSpec.NodeSelector))
+	check("spec.affinity", reflect.DeepEqual(foreignSpec.Affinity, translatedSpec.Affinity))
+	check("spec.activeDeadlineSeconds", reflect.DeepEqual(foreignSpec.ActiveDeadlineSeconds, translatedSpec.ActiveDeadlineSeconds) ||
+		activeDeadlineSecondsChangeAllowed(foreignSpec.ActiveDeadlineSeconds, translatedSpec.ActiveDeadlineSeconds))
+
+	for _, foreignContainer := range foreignSpec.EphemeralContainers {
+		for _, translatedContainer := range translatedSpec.EphemeralContainers {
+			if foreignContainer.Name == translatedContainer.Name && (foreignContainer.Image != translatedContainer.Image ||
+				!reflect.DeepEqual(foreignContainer.Command, translatedContainer.Command) ||
+				!reflect.DeepEqual(foreignContainer.Args, translatedContainer.Args)) {
+				fields = append(fields, fmt.Sprintf("spec.ephemeralContainers[%s]", foreignContainer.Name))
+			}
+		}
+	}
+
+	return fields
+}
+
+// activeDeadlineSecondsChangeAllowed returns whether the activeDeadlineSeconds of a pod can be changed from current
+// to desired: it can only be set, if it was not, or decreased.
+func activeDeadlineSecondsChangeAllowed(current, desired *int64) bool {
+	if desired == nil || *desired <= 0 {
+		return false
+	}
+	return current == nil || *desired < *current
+}
+
+func updateContainerImages(foreignContainers, translatedContainers []v1.Container) {
+	for i := range foreignContainers {
+		for j := range translatedContainers {
+			if foreignContainers[i].Name == translatedContainers[j].Name {
+				foreignContainers[i].Image = translatedContainers[j].Image
+			}
+		}
+	}
+}
+
+// sameContainers compares the immutable fields copied by translateContainer, except the environment variables,
+// which are translated when the foreign pod is created
+func sameContainers(foreignContainers, translatedContainers []v1.Container) bool {
+	if len(foreignContainers) != len(translatedContainers) {
+		return false
+	}
+	for i := range foreignContainers {
+		foreign, translated := &foreignContainers[i], &translatedContainers[i]
+		if foreign.Name != translated.Name ||
+			!reflect.DeepEqual(foreign.Command, translated.Command) ||
+			!reflect.DeepEqual(foreign.Args, translated.Args) ||
+			foreign.WorkingDir != translated.WorkingDir ||
+			!(len(foreign.Ports) == 0 && len(translated.Ports) == 0 || reflect.DeepEqual(foreign.Ports, translated.Ports)) ||
+			!reflect.DeepEqual(foreign.Resources, translated.Resources) ||
+			!reflect.DeepEqual(foreign.LivenessProbe, translated.LivenessProbe) ||
+			!reflect.DeepEqual(foreign.ReadinessProbe, translated.ReadinessProbe) ||
+			!reflect.DeepEqual(foreign.StartupProbe, translated.StartupProbe) ||
+			!reflect.DeepEqual(foreign.SecurityContext, translated.SecurityContext) {
+			return false
+		}
+		for j := range translated.VolumeMounts {
+			if !containsVolumeMount(foreign.VolumeMounts, &translated.VolumeMounts[j]) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func containsVolumeMount(volumeMounts []v1.VolumeMount, volumeMount *v1.VolumeMount) bool {
+	for i := range volumeMounts {
+		if reflect.DeepEqual(volumeMounts[i], *volumeMount) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsVolumes(foreignVolumes, translatedVolumes []v1.Volume) bool {
+	for i := range translatedVolumes {
+		found := false
+		for j := range foreignVolumes {
+			if reflect.DeepEqual(foreignVolumes[j], translatedVolumes[i]) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func containsPullSecrets(foreignSecrets, translatedSecrets []v1.LocalObjectReference) bool {
+	for _, translated := range translatedSecrets {
+		found := false
+		for _, foreign := range foreignSecrets {
+			if foreign.Name == translated.Name {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func containsToleration(tolerations []v1.Toleration, toleration *v1.Toleration) bool {
+	for i := range tolerations {
+		if tolerations[i].MatchToleration(toleration) && reflect.DeepEqual(tolerations[i].TolerationSeconds, toleration.TolerationSeconds) {
+			return true
+		}
+	}
+	return false
+}
+
 func FilterVolumes(volumesIn []v1.Volume) []v1.Volume {
 	volumesOut := make([]v1.Volume, 0)
 	for _, v := range volumesIn {
-		if v.ConfigMap != nil || v.EmptyDir != nil || v.DownwardAPI != nil {
+		if v.ConfigMap != nil || v.EmptyDir != nil || v.DownwardAPI != nil || v.PersistentVolumeClaim != nil {
 			volumesOut = append(volumesOut, v)
 		}
 		// copy all volumes of type Secret except for the default token
@@ -154,29 +396,3 @@ func FilterVolumeMounts(volumes []v1.Volume, volumeMountsIn []v1.VolumeMount) []
 	}
 	return volumeMounts
 }
-
-func ChangePodIp(newPodCidr string, oldPodIp string) (newPodIp string) {
-	if newPodCidr == "" {
-		return oldPodIp
-	}
-	//the last two slices are the suffix of the newPodIp
-	oldPodIpTokenized := strings.Split(oldPodIp, ".")
-	newPodCidrTokenized := strings.Split(newPodCidr, "/")
-	//the first two slices are the prefix of the newPodIP
-	ipFromPodCidrTokenized := strings.Split(newPodCidrTokenized[0], ".")
-	//used to build the new IP
-	var newPodIpBuilder strings.Builder
-	for i, s := range ipFromPodCidrTokenized {
-		if i < 2 {
-			newPodIpBuilder.WriteString(s)
-			newPodIpBuilder.WriteString(".")
-		}
-	}
-	for i, s := range oldPodIpTokenized {
-		if i > 1 && i < 4 {
-			newPodIpBuilder.WriteString(s)
-			newPodIpBuilder.WriteString(".")
-		}
-	}
-	return strings.TrimSuffix(newPodIpBuilder.String(), ".")
-}
@@ -123,7 +123,8 @@ func TestCreateVkDeployment(t *testing.T) {
 	initVkImage := "liqo/init-vk"
 	homeClusterId := "cluster2"
 
-	deploy := pkg.CreateVkDeployment(adv, vkName, vkNamespace, vkImage, initVkImage, nodeName, homeClusterId)
+	deploy := pkg.CreateVkDeployment(adv, vkName, vkNamespace, vkImage, initVkImage, nodeName, homeClusterId,
+		map[string]string{"standard": "gp2", "fast": "io1"})
 
 	assert.Equal(t, vkName, deploy.Name)
 	assert.Equal(t, vkNamespace, deploy.Namespace)
@@ -138,6 +139,8 @@ func TestCreateVkDeployment(t *testing.T) {
 	assert.Contains(t, deploy.Spec.Template.Spec.Containers[0].Args, nodeName)
 	assert.Contains(t, deploy.Spec.Template.Spec.Containers[0].Args, vkNamespace)
 	assert.Contains(t, deploy.Spec.Template.Spec.Containers[0].Args, homeClusterId)
+	assert.Contains(t, deploy.Spec.Template.Spec.Containers[0].Args, "--storage-class-mapping")
+	assert.Contains(t, deploy.Spec.Template.Spec.Containers[0].Args, "fast=io1,standard=gp2")
 	assert.NotEmpty(t, deploy.Spec.Template.Spec.Containers[0].Command)
 	assert.NotEmpty(t, deploy.Spec.Template.Spec.Containers[0].VolumeMounts)
 	assert.NotEmpty(t, deploy.Spec.Template.Spec.Containers[0].Env)
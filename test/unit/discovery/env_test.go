@@ -70,6 +70,8 @@ func getClientCluster() *Cluster {
 		"broadcaster",
 		"br-sa",
 		"br-sa",
+		0,
+		0,
 	)
 	err = cluster.prReconciler.SetupWithManager(mgr)
 	if err != nil {
@@ -136,6 +138,8 @@ func getServerCluster() *Cluster {
 		"broadcaster",
 		"br-sa",
 		"br-sa",
+		0,
+		0,
 	)
 	err = cluster.prReconciler.SetupWithManager(mgr)
 	if err != nil {
@@ -82,10 +82,11 @@ func setupTunnelEndpointCreatorOperator() error {
 		ForeignClusterStartWatcher: make(chan bool, 1),
 		ForeignClusterStopWatcher:  make(chan struct{}),
 		IPManager: liqonet.IpManager{
-			UsedSubnets:        make(map[string]*net.IPNet),
-			FreeSubnets:        make(map[string]*net.IPNet),
-			SubnetPerCluster:   make(map[string]*net.IPNet),
-			ConflictingSubnets: make(map[string]*net.IPNet),
+			UsedSubnets:          make(map[string]*net.IPNet),
+			FreeSubnets:          make(map[string]*net.IPNet),
+			SubnetPerCluster:     make(map[string]*net.IPNet),
+			IPv6SubnetPerCluster: make(map[string]*net.IPNet),
+			ConflictingSubnets:   make(map[string]*net.IPNet),
 		},
 		RetryTimeout: 30 * time.Second,
 	}
@@ -6,6 +6,7 @@ import (
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/pointer"
 	"testing"
 )
 
@@ -36,11 +37,17 @@ func createFakeVolumesAndVolumeMounts() ([]v1.Volume, []v1.VolumeMount) {
 				},
 			},
 			{
-				Name: "unmanaged-test",
+				Name: "pvc-test",
 				VolumeSource: v1.VolumeSource{
 					PersistentVolumeClaim: &v1.PersistentVolumeClaimVolumeSource{},
 				},
 			},
+			{
+				Name: "unmanaged-test",
+				VolumeSource: v1.VolumeSource{
+					HostPath: &v1.HostPathVolumeSource{},
+				},
+			},
 			{
 				Name: "default-token-test",
 				VolumeSource: v1.VolumeSource{
@@ -63,6 +70,9 @@ func createFakeVolumesAndVolumeMounts() ([]v1.Volume, []v1.VolumeMount) {
 			{
 				Name: "downwardAPI-test",
 			},
+			{
+				Name: "pvc-test",
+			},
 			{
 				Name: "unmanaged-test",
 			},
@@ -108,11 +118,18 @@ func TestH2FCreation(t *testing.T) {
 		TypeMeta: metav1.TypeMeta{},
 		ObjectMeta: metav1.ObjectMeta{
 			Name: "toto", Namespace: "test", UID: "0973c9af-35aa-4050-929d-bc8bc3fc3b5a",
+			Annotations: map[string]string{
+				"note": "home",
+				"kubectl.kubernetes.io/last-applied-configuration": "{}",
+			},
 		},
 		Spec: v1.PodSpec{
 			NodeName:   "trololo",
 			Containers: containers,
 			Volumes:    volumes,
+			EphemeralContainers: []v1.EphemeralContainer{
+				{EphemeralContainerCommon: v1.EphemeralContainerCommon{Name: "debug", Image: "busybox"}},
+			},
 		},
 		Status: v1.PodStatus{},
 	}
@@ -130,6 +147,9 @@ func TestH2FCreation(t *testing.T) {
 	assert.ElementsMatch(t, containers, pForeign.Spec.Containers)
 	assert.ElementsMatch(t, filteredVolumeMounts, pForeign.Spec.Containers[0].VolumeMounts)
 	assert.ElementsMatch(t, filteredVolumes, pForeign.Spec.Volumes)
+	assert.Empty(t, pForeign.Spec.EphemeralContainers, "The EphemeralContainers cannot be set on creation")
+	assert.Equal(t, "home", pForeign.GetAnnotations()["note"])
+	assert.NotContains(t, pForeign.GetAnnotations(), "kubectl.kubernetes.io/last-applied-configuration")
 }
 
 func TestF2HCreation(t *testing.T) {
@@ -166,10 +186,10 @@ func TestF2HCreation(t *testing.T) {
 }
 
 func TestFilterVolumes(t *testing.T) {
-	// create a list of 6 volumes:
-	// the first 4 should be copied
-	// the fifth one is of an unmanaged type and should be filtered
-	// the sixth one is a default-token secret and should be filtered
+	// create a list of 7 volumes:
+	// the first 5 should be copied
+	// the sixth one is of an unmanaged type and should be filtered
+	// the seventh one is a default-token secret and should be filtered
 	volumes, _ := createFakeVolumesAndVolumeMounts()
 	expectedResult := []v1.Volume{
 		{
@@ -196,6 +216,12 @@ func TestFilterVolumes(t *testing.T) {
 				DownwardAPI: &v1.DownwardAPIVolumeSource{},
 			},
 		},
+		{
+			Name: "pvc-test",
+			VolumeSource: v1.VolumeSource{
+				PersistentVolumeClaim: &v1.PersistentVolumeClaimVolumeSource{},
+			},
+		},
 	}
 	result := translation.FilterVolumes(volumes)
 
@@ -218,8 +244,136 @@ func TestFilterVolumeMounts(t *testing.T) {
 		{
 			Name: "downwardAPI-test",
 		},
+		{
+			Name: "pvc-test",
+		},
 	}
 	result := translation.FilterVolumeMounts(filteredVolumes, volumeMounts)
 
 	assert.ElementsMatch(t, expectedResult, result)
 }
+
+func TestUpdateForeignPod(t *testing.T) {
+	deadline := int64(60)
+	pHome := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "toto",
+			Namespace:       "test",
+			ResourceVersion: "42",
+			Labels:          map[string]string{"app": "toto", "version": "v2"},
+			Annotations: map[string]string{
+				"note": "updated",
+				"kubectl.kubernetes.io/last-applied-configuration": "{}",
+			},
+		},
+		Spec: v1.PodSpec{
+			Containers: []v1.Container{
+				{Name: "test", Image: "test:v2"},
+			},
+			ActiveDeadlineSeconds: &deadline,
+			Tolerations: []v1.Toleration{
+				{Key: "virtual-node.liqo.io/not-allowed", Operator: v1.TolerationOpExists, Effect: v1.TaintEffectNoExecute},
+			},
+		},
+	}
+	pForeign := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "toto",
+			Namespace:   "test-natted",
+			Labels:      map[string]string{"app": "toto", "version": "v1"},
+			Annotations: map[string]string{"remote": "annotation", "home_resourceVersion": "41"},
+		},
+		Spec: v1.PodSpec{
+			Containers: []v1.Container{
+				{Name: "test", Image: "test:v1", ImagePullPolicy: v1.PullIfNotPresent},
+			},
+		},
+	}
+
+	pTranslated := translation.H2FTranslate(pHome, "test-natted")
+	pForeign.Spec.Affinity = pTranslated.Spec.Affinity
+	pUpdated, changed := translation.UpdateForeignPod(pForeign, pTranslated)
+
+	assert.True(t, changed)
+	assert.Equal(t, pHome.Labels, pUpdated.Labels)
+	assert.Equal(t, "updated", pUpdated.Annotations["note"])
+	assert.Equal(t, "annotation", pUpdated.Annotations["remote"])
+	assert.Equal(t, "41", pUpdated.Annotations["home_resourceVersion"], "The annotations of the creation should not be updated")
+	assert.NotContains(t, pUpdated.Annotations, "kubectl.kubernetes.io/last-applied-configuration")
+	assert.Equal(t, "test:v2", pUpdated.Spec.Containers[0].Image)
+	assert.Equal(t, v1.PullIfNotPresent, pUpdated.Spec.Containers[0].ImagePullPolicy)
+	assert.Equal(t, &deadline, pUpdated.Spec.ActiveDeadlineSeconds)
+	assert.ElementsMatch(t, pHome.Spec.Tolerations, pUpdated.Spec.Tolerations)
+	assert.Equal(t, "test:v1", pForeign.Spec.Containers[0].Image, "The input pod should not be modified")
+	assert.Empty(t, translation.ImmutableFieldsChanged(pUpdated, pTranslated))
+
+	_, changed = translation.UpdateForeignPod(pUpdated, pTranslated)
+	assert.False(t, changed, "The update should be idempotent")
+
+	// the activeDeadlineSeconds can be decreased, but not increased nor removed
+	for _, tc := range []struct {
+		deadline *int64
+		allowed  bool
+	}{
+		{deadline: pointer.Int64Ptr(30), allowed: true},
+		{deadline: pointer.Int64Ptr(120), allowed: false},
+		{deadline: nil, allowed: false},
+	} {
+		pTranslated.Spec.ActiveDeadlineSeconds = tc.deadline
+		pDeadline, _ := translation.UpdateForeignPod(pUpdated, pTranslated)
+		if tc.allowed {
+			assert.Equal(t, tc.deadline, pDeadline.Spec.ActiveDeadlineSeconds)
+			assert.Empty(t, translation.ImmutableFieldsChanged(pDeadline, pTranslated))
+		} else {
+			assert.Equal(t, &deadline, pDeadline.Spec.ActiveDeadlineSeconds)
+			assert.Equal(t, []string{"spec.activeDeadlineSeconds"}, translation.ImmutableFieldsChanged(pDeadline, pTranslated))
+		}
+	}
+}
+
+func TestImmutableFieldsChanged(t *testing.T) {
+	pHome := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "toto", Namespace: "test"},
+		Spec: v1.PodSpec{
+			Containers: []v1.Container{{Name: "test", Image: "test:v2", Command: []string{"run"},
+				VolumeMounts: []v1.VolumeMount{{Name: "config", MountPath: "/etc/config"}}}},
+			Volumes: []v1.Volume{{Name: "config", VolumeSource: v1.VolumeSource{
+				ConfigMap: &v1.ConfigMapVolumeSource{LocalObjectReference: v1.LocalObjectReference{Name: "config"}}}}},
+			RestartPolicy: v1.RestartPolicyAlways,
+			EphemeralContainers: []v1.EphemeralContainer{
+				{EphemeralContainerCommon: v1.EphemeralContainerCommon{Name: "debug", Image: "alpine"}},
+				{EphemeralContainerCommon: v1.EphemeralContainerCommon{Name: "debug2", Image: "alpine"}},
+			},
+		},
+	}
+	pTranslated := translation.H2FTranslate(pHome, "test-natted")
+	pTranslated.Spec.EphemeralContainers = translation.TranslateEphemeralContainers(pHome)
+
+	// the foreign pod contains the default values, the token of its service account and a different image
+	pForeign := pTranslated.DeepCopy()
+	pForeign.Spec.Containers[0].Image = "test:v1"
+	pForeign.Spec.Containers[0].ImagePullPolicy = v1.PullIfNotPresent
+	pForeign.Spec.Containers[0].Env = []v1.EnvVar{{Name: "KUBERNETES_SERVICE_HOST", Value: "10.0.0.1"}}
+	pForeign.Spec.Containers[0].VolumeMounts = append(pForeign.Spec.Containers[0].VolumeMounts,
+		v1.VolumeMount{Name: "default-token", MountPath: "/var/run/secrets/kubernetes.io/serviceaccount"})
+	pForeign.Spec.Volumes = append(pForeign.Spec.Volumes, v1.Volume{Name: "default-token"})
+	pForeign.Spec.DNSPolicy = v1.DNSClusterFirst
+	pForeign.Spec.EphemeralContainers = pForeign.Spec.EphemeralContainers[:1]
+	assert.Empty(t, translation.ImmutableFieldsChanged(pForeign, pTranslated))
+
+	newContainers := translation.NewEphemeralContainers(pForeign, pTranslated)
+	assert.Len(t, newContainers, 1)
+	assert.Equal(t, "debug2", newContainers[0].Name)
+
+	// every other change of the spec is reported
+	pForeign.Spec.Containers[0].Command = []string{"sleep"}
+	pForeign.Spec.Volumes[0].ConfigMap.Name = "other"
+	pForeign.Spec.RestartPolicy = v1.RestartPolicyNever
+	pForeign.Spec.Hostname = "other"
+	pForeign.Spec.EphemeralContainers[0].Image = "busybox"
+	assert.ElementsMatch(t, []string{"spec.containers", "spec.volumes", "spec.restartPolicy", "spec.hostname",
+		"spec.ephemeralContainers[debug]"}, translation.ImmutableFieldsChanged(pForeign, pTranslated))
+
+	pForeign.Spec.Containers = append(pForeign.Spec.Containers, v1.Container{Name: "test2", Image: "test2"})
+	assert.Contains(t, translation.ImmutableFieldsChanged(pForeign, pTranslated), "spec.containers")
+}
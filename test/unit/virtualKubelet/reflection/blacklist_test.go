@@ -0,0 +1,43 @@
+package reflection
+
+import (
+	configv1alpha1 "github.com/liqotech/liqo/apis/config/v1alpha1"
+	api "github.com/liqotech/liqo/pkg/virtualKubelet/apiReflection/reflectors"
+	"github.com/liqotech/liqo/pkg/virtualKubelet/apiReflection/reflectors/outgoing"
+	"gotest.tools/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"testing"
+)
+
+func TestReflectionBlacklist(t *testing.T) {
+	defer outgoing.SetBlacklist(nil)
+
+	services := &outgoing.ServicesReflector{APIReflector: &api.GenericAPIReflector{}}
+	services.SetSpecializedPreProcessingHandlers()
+	configmaps := &outgoing.ConfigmapsReflector{APIReflector: &api.GenericAPIReflector{}}
+	configmaps.SetSpecializedPreProcessingHandlers()
+
+	kubernetes := &v1.Service{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "kubernetes"}}
+	svc := &v1.Service{ObjectMeta: metav1.ObjectMeta{Namespace: "homeNamespace", Name: "svc"}}
+	cm := &v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "homeNamespace", Name: "cm"}}
+
+	assert.Assert(t, !services.PreProcessIsAllowed(kubernetes))
+	assert.Assert(t, services.PreProcessIsAllowed(svc))
+	assert.Assert(t, configmaps.PreProcessIsAllowed(cm))
+
+	outgoing.SetBlacklist([]configv1alpha1.BlacklistedResource{
+		{Resource: "services", Objects: []string{"homeNamespace/svc"}},
+		{Resource: "configmaps", Objects: []string{"homeNamespace/cm"}},
+		{Resource: "pods", Objects: []string{"homeNamespace/pod"}},
+	})
+	assert.Assert(t, !services.PreProcessIsAllowed(kubernetes))
+	assert.Assert(t, !services.PreProcessIsAllowed(svc))
+	assert.Assert(t, !configmaps.PreProcessIsAllowed(cm))
+
+	// the configured objects are reflected again once removed from the blacklist
+	outgoing.SetBlacklist(nil)
+	assert.Assert(t, !services.PreProcessIsAllowed(kubernetes))
+	assert.Assert(t, services.PreProcessIsAllowed(svc))
+	assert.Assert(t, configmaps.PreProcessIsAllowed(cm))
+}
@@ -0,0 +1,133 @@
+package reflection
+
+import (
+	"context"
+	configv1alpha1 "github.com/liqotech/liqo/apis/config/v1alpha1"
+	apimgmt "github.com/liqotech/liqo/pkg/virtualKubelet/apiReflection"
+	"github.com/liqotech/liqo/pkg/virtualKubelet/apiReflection/reflectors/generic"
+	"github.com/liqotech/liqo/pkg/virtualKubelet/namespacesMapping/test"
+	"gotest.tools/assert"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/dynamic/fake"
+	"testing"
+)
+
+var ingressResource = configv1alpha1.ReflectedResource{
+	Resource: configv1alpha1.Resource{
+		Group:    "networking.k8s.io",
+		Version:  "v1beta1",
+		Resource: "ingresses",
+	},
+	StrippedFields: []string{"spec.tls"},
+	Blacklist:      []string{"homeNamespace/blacklisted"},
+}
+
+func forgeIngress(name string, labels map[string]string) *unstructured.Unstructured {
+	ingress := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "networking.k8s.io/v1beta1",
+		"kind":       "Ingress",
+		"metadata": map[string]interface{}{
+			"name":            name,
+			"namespace":       "homeNamespace",
+			"uid":             "home-uid",
+			"resourceVersion": "42",
+		},
+		"spec": map[string]interface{}{
+			"backend": map[string]interface{}{"serviceName": "svc", "servicePort": int64(80)},
+			"tls":     []interface{}{map[string]interface{}{"secretName": "tls"}},
+		},
+		"status": map[string]interface{}{
+			"loadBalancer": map[string]interface{}{},
+		},
+	}}
+	ingress.SetLabels(labels)
+	return ingress
+}
+
+func newGenericReflector(objects ...runtime.Object) (*generic.Reflector, *fake.FakeDynamicClient) {
+	foreignClient := fake.NewSimpleDynamicClient(runtime.NewScheme(), objects...)
+	nattingTable := &test.MockNamespaceMapper{Cache: map[string]string{}}
+	_, _ = nattingTable.NatNamespace("homeNamespace", true)
+
+	return generic.NewReflector(ingressResource, fake.NewSimpleDynamicClient(runtime.NewScheme()), foreignClient, nattingTable), foreignClient
+}
+
+func getForeignIngress(t *testing.T, client *fake.FakeDynamicClient, name string) (*unstructured.Unstructured, error) {
+	t.Helper()
+	gvr := generic.GroupVersionResource(ingressResource)
+	return client.Resource(gvr).Namespace("homeNamespace-natted").Get(context.TODO(), name, metav1.GetOptions{})
+}
+
+func TestGenericForgeForeignObject(t *testing.T) {
+	reflector, _ := newGenericReflector()
+
+	foreign := reflector.ForgeForeignObject(forgeIngress("name", map[string]string{"app": "test"}), "homeNamespace-natted")
+
+	assert.Equal(t, foreign.GetNamespace(), "homeNamespace-natted")
+	assert.Equal(t, foreign.GetLabels()["app"], "test")
+	assert.Equal(t, foreign.GetLabels()[apimgmt.LiqoLabelKey], apimgmt.LiqoLabelValue)
+	assert.Equal(t, string(foreign.GetUID()), "")
+	assert.Equal(t, foreign.GetResourceVersion(), "")
+
+	_, found, _ := unstructured.NestedFieldNoCopy(foreign.Object, "status")
+	assert.Assert(t, !found)
+	_, found, _ = unstructured.NestedFieldNoCopy(foreign.Object, "spec", "tls")
+	assert.Assert(t, !found)
+	_, found, _ = unstructured.NestedFieldNoCopy(foreign.Object, "spec", "backend")
+	assert.Assert(t, found)
+}
+
+func TestGenericReflectCreateAndUpdate(t *testing.T) {
+	reflector, foreignClient := newGenericReflector()
+
+	assert.NilError(t, reflector.Reflect(forgeIngress("name", nil)))
+	foreign, err := getForeignIngress(t, foreignClient, "name")
+	assert.NilError(t, err)
+	assert.Equal(t, foreign.GetLabels()[apimgmt.LiqoLabelKey], apimgmt.LiqoLabelValue)
+
+	assert.NilError(t, reflector.Reflect(forgeIngress("name", map[string]string{"app": "updated"})))
+	foreign, err = getForeignIngress(t, foreignClient, "name")
+	assert.NilError(t, err)
+	assert.Equal(t, foreign.GetLabels()["app"], "updated")
+}
+
+func TestGenericReflectBlacklisted(t *testing.T) {
+	reflector, foreignClient := newGenericReflector()
+
+	assert.NilError(t, reflector.Reflect(forgeIngress("blacklisted", nil)))
+	_, err := getForeignIngress(t, foreignClient, "blacklisted")
+	assert.Assert(t, kerrors.IsNotFound(err))
+}
+
+func TestGenericReflectNotManaged(t *testing.T) {
+	existing := forgeIngress("name", map[string]string{"app": "foreign"})
+	existing.SetNamespace("homeNamespace-natted")
+	reflector, foreignClient := newGenericReflector(existing)
+
+	assert.NilError(t, reflector.Reflect(forgeIngress("name", map[string]string{"app": "home"})))
+	foreign, err := getForeignIngress(t, foreignClient, "name")
+	assert.NilError(t, err)
+	assert.Equal(t, foreign.GetLabels()["app"], "foreign")
+
+	assert.NilError(t, reflector.Delete(forgeIngress("name", nil)))
+	_, err = getForeignIngress(t, foreignClient, "name")
+	assert.NilError(t, err)
+}
+
+func TestGenericDeleteAndCleanup(t *testing.T) {
+	reflector, foreignClient := newGenericReflector()
+
+	assert.NilError(t, reflector.Reflect(forgeIngress("first", nil)))
+	assert.NilError(t, reflector.Reflect(forgeIngress("second", nil)))
+
+	assert.NilError(t, reflector.Delete(forgeIngress("first", nil)))
+	_, err := getForeignIngress(t, foreignClient, "first")
+	assert.Assert(t, kerrors.IsNotFound(err))
+
+	reflector.CleanupNamespace("homeNamespace")
+	_, err = getForeignIngress(t, foreignClient, "second")
+	assert.Assert(t, kerrors.IsNotFound(err))
+}
@@ -0,0 +1,159 @@
+package reflection
+
+import (
+	"github.com/liqotech/liqo/pkg/virtualKubelet"
+	apimgmt "github.com/liqotech/liqo/pkg/virtualKubelet/apiReflection"
+	api "github.com/liqotech/liqo/pkg/virtualKubelet/apiReflection/reflectors"
+	"github.com/liqotech/liqo/pkg/virtualKubelet/apiReflection/reflectors/incoming"
+	"github.com/liqotech/liqo/pkg/virtualKubelet/apiReflection/reflectors/outgoing"
+	"github.com/liqotech/liqo/pkg/virtualKubelet/namespacesMapping/test"
+	"github.com/liqotech/liqo/pkg/virtualKubelet/options/types"
+	storageTest "github.com/liqotech/liqo/pkg/virtualKubelet/storage/test"
+	"gotest.tools/assert"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"testing"
+)
+
+func TestPersistentVolumeClaimAdd(t *testing.T) {
+	foreignClient := fake.NewSimpleClientset()
+	cacheManager := &storageTest.MockManager{
+		HomeCache:    map[string]map[apimgmt.ApiType]map[string]metav1.Object{},
+		ForeignCache: map[string]map[apimgmt.ApiType]map[string]metav1.Object{},
+	}
+	nattingTable := &test.MockNamespaceMapper{Cache: map[string]string{}}
+
+	Greflector := &api.GenericAPIReflector{
+		ForeignClient:    foreignClient,
+		NamespaceNatting: nattingTable,
+		CacheManager:     cacheManager,
+	}
+
+	reflector := &outgoing.PersistentVolumeClaimsReflector{
+		APIReflector:        Greflector,
+		StorageClassMapping: types.NewStorageOption(types.StorageClassMapping, "standard=gp2,fast=io1"),
+	}
+	reflector.SetSpecializedPreProcessingHandlers()
+
+	storageClass := "fast"
+	pvc := v1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "name",
+			Namespace:   "homeNamespace",
+			Labels:      map[string]string{"app": "test"},
+			Annotations: map[string]string{"pv.kubernetes.io/bind-completed": "yes"},
+		},
+		Spec: v1.PersistentVolumeClaimSpec{
+			AccessModes: []v1.PersistentVolumeAccessMode{v1.ReadWriteOnce},
+			Resources: v1.ResourceRequirements{
+				Requests: v1.ResourceList{v1.ResourceStorage: resource.MustParse("1Gi")},
+			},
+			StorageClassName: &storageClass,
+			VolumeName:       "home-volume",
+		},
+	}
+
+	_, _ = nattingTable.NatNamespace("homeNamespace", true)
+	postadd := reflector.PreProcessAdd(&pvc).(*v1.PersistentVolumeClaim)
+
+	assert.Equal(t, postadd.Namespace, "homeNamespace-natted")
+	assert.Equal(t, *postadd.Spec.StorageClassName, "io1")
+	assert.Equal(t, postadd.Spec.VolumeName, "")
+	assert.Equal(t, postadd.Labels["app"], "test")
+	assert.Equal(t, postadd.Labels[apimgmt.LiqoLabelKey], apimgmt.LiqoLabelValue)
+	assert.Assert(t, len(postadd.Annotations) == 0, "home annotations are not removed")
+	assert.DeepEqual(t, postadd.Spec.Resources, pvc.Spec.Resources)
+}
+
+func TestMapStorageClass(t *testing.T) {
+	standard, unknown := "standard", "unknown"
+
+	assert.Equal(t, *outgoing.MapStorageClass("standard=gp2, fast=io1", &standard), "gp2")
+	assert.Assert(t, outgoing.MapStorageClass("standard=gp2", &unknown) == nil, "unmapped storage class should be nil")
+	assert.Assert(t, outgoing.MapStorageClass("standard=gp2", nil) == nil, "default storage class should be kept")
+	assert.Assert(t, outgoing.MapStorageClass("", &standard) == nil, "empty mapping should return nil")
+}
+
+func TestPersistentVolumeClaimIsAllowed(t *testing.T) {
+	cacheManager := &storageTest.MockManager{
+		HomeCache:    map[string]map[apimgmt.ApiType]map[string]metav1.Object{},
+		ForeignCache: map[string]map[apimgmt.ApiType]map[string]metav1.Object{},
+	}
+	nattingTable := &test.MockNamespaceMapper{Cache: map[string]string{}}
+
+	Greflector := &api.GenericAPIReflector{
+		NamespaceNatting: nattingTable,
+		CacheManager:     cacheManager,
+	}
+
+	reflector := &outgoing.PersistentVolumeClaimsReflector{
+		APIReflector: Greflector,
+		NodeName:     types.NewNetworkingOption(types.NodeName, "liqo-node"),
+	}
+	reflector.SetSpecializedPreProcessingHandlers()
+
+	_, _ = nattingTable.NatNamespace("homeNamespace", true)
+	forgePvc := func(name string) *v1.PersistentVolumeClaim {
+		return &v1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "homeNamespace"}}
+	}
+	forgePod := func(name, nodeName, claimName string) *v1.Pod {
+		return &v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "homeNamespace"},
+			Spec: v1.PodSpec{
+				NodeName: nodeName,
+				Volumes: []v1.Volume{{
+					Name: "data",
+					VolumeSource: v1.VolumeSource{
+						PersistentVolumeClaim: &v1.PersistentVolumeClaimVolumeSource{ClaimName: claimName},
+					},
+				}},
+			},
+		}
+	}
+
+	cacheManager.AddHomeEntry("homeNamespace", apimgmt.Pods, forgePod("offloaded", "liqo-node", "offloaded-claim"))
+	cacheManager.AddHomeEntry("homeNamespace", apimgmt.Pods, forgePod("local", "local-node", "local-claim"))
+	cacheManager.AddForeignEntry("homeNamespace-natted", apimgmt.PersistentVolumeClaims, forgePvc("reflected-claim"))
+
+	assert.Assert(t, reflector.PreProcessIsAllowed(forgePvc("offloaded-claim")), "claim used by an offloaded pod should be reflected")
+	assert.Assert(t, reflector.PreProcessIsAllowed(forgePvc("reflected-claim")), "claim already reflected should be reflected")
+	assert.Assert(t, !reflector.PreProcessIsAllowed(forgePvc("local-claim")), "claim used by a local pod should not be reflected")
+	assert.Assert(t, !reflector.PreProcessIsAllowed(forgePvc("unused-claim")), "unused claim should not be reflected")
+}
+
+func TestPersistentVolumeClaimIncomingPhase(t *testing.T) {
+	cacheManager := &storageTest.MockManager{
+		HomeCache:    map[string]map[apimgmt.ApiType]map[string]metav1.Object{},
+		ForeignCache: map[string]map[apimgmt.ApiType]map[string]metav1.Object{},
+	}
+	nattingTable := &test.MockNamespaceMapper{Cache: map[string]string{}}
+
+	Greflector := &api.GenericAPIReflector{
+		NamespaceNatting: nattingTable,
+		CacheManager:     cacheManager,
+	}
+
+	reflector := &incoming.PersistentVolumeClaimsIncomingReflector{APIReflector: Greflector}
+	reflector.SetSpecializedPreProcessingHandlers()
+
+	_, _ = nattingTable.NatNamespace("homeNamespace", true)
+	homePvc := &v1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{Name: "name", Namespace: "homeNamespace"},
+		Status:     v1.PersistentVolumeClaimStatus{Phase: v1.ClaimPending},
+	}
+	cacheManager.AddHomeEntry("homeNamespace", apimgmt.PersistentVolumeClaims, homePvc)
+
+	foreignPvc := &v1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{Name: "name", Namespace: "homeNamespace-natted"},
+		Status:     v1.PersistentVolumeClaimStatus{Phase: v1.ClaimBound},
+	}
+	postupdate := reflector.PreProcessUpdate(foreignPvc, nil).(*v1.PersistentVolumeClaim)
+	assert.Equal(t, postupdate.Namespace, "homeNamespace")
+	assert.Equal(t, postupdate.Annotations[virtualKubelet.RemotePvcPhaseAnnotation], string(v1.ClaimBound))
+	assert.Equal(t, postupdate.Status.Phase, v1.ClaimPending, "the status owned by the home PV controller should not be changed")
+
+	cacheManager.AddHomeEntry("homeNamespace", apimgmt.PersistentVolumeClaims, postupdate)
+	assert.Assert(t, reflector.PreProcessUpdate(foreignPvc, nil) == nil, "unchanged phase should not be reflected")
+}